@@ -0,0 +1,14 @@
+CREATE TABLE login_attempt (
+    login_attempt_id int PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    email text NOT NULL,
+    ip_address text NOT NULL,
+    succeeded boolean NOT NULL,
+    is_cleared boolean NOT NULL DEFAULT FALSE,
+    attempted_at timestamptz NOT NULL
+);
+
+CREATE INDEX login_attempt_email_idx
+    ON login_attempt (email, attempted_at);
+
+CREATE INDEX login_attempt_ip_address_idx
+    ON login_attempt (ip_address, attempted_at);
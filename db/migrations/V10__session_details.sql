@@ -0,0 +1,11 @@
+ALTER TABLE session
+    ADD COLUMN user_agent text NOT NULL DEFAULT '',
+    ADD COLUMN ip_address text NOT NULL DEFAULT '',
+    ADD COLUMN last_seen_at timestamptz
+;
+
+UPDATE session SET last_seen_at = created_at;
+
+ALTER TABLE session
+    ALTER COLUMN last_seen_at SET NOT NULL
+;
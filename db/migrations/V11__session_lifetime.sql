@@ -0,0 +1,12 @@
+ALTER TABLE session
+    ADD COLUMN max_expires_at timestamptz,
+    ADD COLUMN is_remember_me boolean NOT NULL DEFAULT FALSE
+;
+
+-- Existing sessions had a fixed lifetime, so their idle and permanent
+-- expirations are one and the same.
+UPDATE session SET max_expires_at = expires_at;
+
+ALTER TABLE session
+    ALTER COLUMN max_expires_at SET NOT NULL
+;
@@ -0,0 +1,16 @@
+ALTER TABLE session
+    ADD COLUMN token_hash text UNIQUE
+;
+
+-- Existing tokens were stored in plain text and may have been exposed by any
+-- copy of the database, so every existing session is revoked. They are still
+-- hashed to satisfy the constraints on the new column.
+UPDATE session SET
+    token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex'),
+    is_revoked = TRUE
+;
+
+ALTER TABLE session
+    ALTER COLUMN token_hash SET NOT NULL,
+    DROP COLUMN token
+;
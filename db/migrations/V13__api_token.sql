@@ -0,0 +1,15 @@
+CREATE TABLE api_token (
+    api_token_id int PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    token_hash text NOT NULL UNIQUE,
+    person_id int NOT NULL
+        REFERENCES person(person_id)
+        ON DELETE CASCADE,
+    name text NOT NULL,
+    scopes text[] NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    expires_at timestamptz NOT NULL,
+    last_used_at timestamptz,
+    is_revoked boolean NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX api_token_person_id_idx ON api_token (person_id);
@@ -0,0 +1,36 @@
+CREATE TABLE organization_sso (
+    organization_id int PRIMARY KEY
+        REFERENCES organization(organization_id)
+        ON DELETE CASCADE,
+    issuer_url text NOT NULL,
+    client_id text NOT NULL,
+    client_secret text NOT NULL,
+    email_domains text[] NOT NULL DEFAULT '{}',
+    is_enabled boolean NOT NULL DEFAULT TRUE
+);
+
+-- Sign on attempts in progress. Rows are deleted once used.
+CREATE TABLE oidc_login (
+    oidc_login_id int PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    state_hash text NOT NULL UNIQUE,
+    organization_id int NOT NULL
+        REFERENCES organization(organization_id)
+        ON DELETE CASCADE,
+    code_verifier text NOT NULL,
+    nonce text NOT NULL,
+    remember_me boolean NOT NULL DEFAULT FALSE,
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    expires_at timestamptz NOT NULL
+);
+
+-- Identities at external providers that people may sign on with. Subjects are
+-- only unique for a particular issuer.
+CREATE TABLE person_identity (
+    issuer_url text NOT NULL,
+    subject text NOT NULL,
+    person_id int NOT NULL
+        REFERENCES person(person_id)
+        ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (issuer_url, subject)
+);
@@ -0,0 +1,65 @@
+CREATE TABLE permission (
+    permission_id int PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    name text NOT NULL UNIQUE,
+    description text NOT NULL
+);
+
+CREATE TABLE role_permission (
+    role_id int NOT NULL
+        REFERENCES role(role_id)
+        ON DELETE CASCADE,
+    permission_id int NOT NULL
+        REFERENCES permission(permission_id)
+        ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+INSERT INTO permission (
+    name,
+    description
+) VALUES (
+    'profile.manage',
+    'Manage own profile, sessions, and API tokens.'
+), (
+    'users.manage',
+    'Manage the accounts of all people.'
+), (
+    'organizations.manage',
+    'Manage all sponsor organizations.'
+), (
+    'organization.manage',
+    'Manage the profile of own organization.'
+), (
+    'catalog.manage',
+    'Manage the catalog of own organization.'
+), (
+    'drivers.manage',
+    'Manage the drivers of own organization.'
+), (
+    'points.award',
+    'Award and deduct points for drivers of own organization.'
+), (
+    'applications.review',
+    'Review driver applications to own organization.'
+);
+
+-- These grants match what each role could do before permissions existed.
+INSERT INTO role_permission (
+    role_id,
+    permission_id
+)
+SELECT r.role_id, p.permission_id
+FROM (VALUES
+    ('admin', 'profile.manage'),
+    ('admin', 'users.manage'),
+    ('admin', 'organizations.manage'),
+    ('sponsor', 'profile.manage'),
+    ('sponsor', 'organization.manage'),
+    ('sponsor', 'catalog.manage'),
+    ('sponsor', 'drivers.manage'),
+    ('sponsor', 'points.award'),
+    ('sponsor', 'applications.review'),
+    ('driver', 'profile.manage')
+) AS g (role_title, permission_name)
+JOIN role r ON r.title = g.role_title
+JOIN permission p ON p.name = g.permission_name;
@@ -0,0 +1,7 @@
+-- Sponsors affiliated with several organizations pick which one they are
+-- working on, which is remembered for the rest of their session.
+ALTER TABLE session
+    ADD COLUMN active_organization_id int
+        REFERENCES organization(organization_id)
+        ON DELETE SET NULL
+;
@@ -0,0 +1,16 @@
+-- Administrators may view the app as another person through a session of
+-- theirs that is linked to their own session.
+ALTER TABLE session
+    ADD COLUMN impersonator_id int
+        REFERENCES person(person_id)
+        ON DELETE CASCADE,
+    ADD COLUMN impersonator_session_id int
+        REFERENCES session(session_id)
+        ON DELETE CASCADE
+;
+
+ALTER TABLE session
+    ADD CONSTRAINT session_impersonator_check CHECK (
+        (impersonator_id IS NULL) = (impersonator_session_id IS NULL)
+    )
+;
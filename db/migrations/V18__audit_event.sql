@@ -0,0 +1,59 @@
+-- Audit events record privileged changes. They are written in the same
+-- transaction as the change they describe, and may never be altered.
+--
+-- Identifiers are deliberately not foreign keys, so that events outlive the
+-- people and organizations that they refer to.
+CREATE TABLE audit_event (
+    audit_event_id int PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    actor_id int,
+    impersonator_id int,
+    request_id text,
+    action text NOT NULL,
+    target_type text NOT NULL,
+    target_id int NOT NULL,
+    organization_id int,
+    before jsonb,
+    after jsonb
+);
+
+CREATE INDEX audit_event_actor_idx ON audit_event (actor_id);
+CREATE INDEX audit_event_target_idx ON audit_event (target_type, target_id);
+CREATE INDEX audit_event_organization_idx ON audit_event (organization_id);
+
+CREATE FUNCTION audit_event_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit events are append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_event_no_update_delete
+    BEFORE UPDATE OR DELETE ON audit_event
+    FOR EACH ROW EXECUTE FUNCTION audit_event_append_only();
+
+CREATE TRIGGER audit_event_no_truncate
+    BEFORE TRUNCATE ON audit_event
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_event_append_only();
+
+INSERT INTO permission (
+    name,
+    description
+) VALUES (
+    'audit.view',
+    'View the audit log of all privileged changes.'
+), (
+    'organization.audit.view',
+    'View the audit log of changes to own organization.'
+);
+
+INSERT INTO role_permission (
+    role_id,
+    permission_id
+)
+SELECT r.role_id, p.permission_id
+FROM (VALUES
+    ('admin', 'audit.view'),
+    ('sponsor', 'organization.audit.view')
+) AS g (role_title, permission_name)
+JOIN role r ON r.title = g.role_title
+JOIN permission p ON p.name = g.permission_name;
@@ -0,0 +1,12 @@
+-- Passwords that a person has replaced, so that they may not be reused.
+CREATE TABLE password_history (
+    password_history_id int PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    person_id int NOT NULL
+        REFERENCES person(person_id)
+        ON DELETE CASCADE,
+    pass_hash text NOT NULL,
+    replaced_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX password_history_person_idx
+    ON password_history (person_id, replaced_at DESC);
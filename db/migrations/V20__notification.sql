@@ -0,0 +1,21 @@
+-- Notifications tell people about changes that concern them, such as a driver
+-- of their organization being deactivated. They are written in the same
+-- transaction as the change that they describe.
+CREATE TABLE notification (
+    notification_id int PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    person_id int NOT NULL
+        REFERENCES person(person_id)
+        ON DELETE CASCADE,
+    kind text NOT NULL,
+    message text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    read_at timestamptz
+);
+
+CREATE INDEX notification_person_idx
+    ON notification (person_id, created_at DESC);
+
+-- Administrators must explain why they activate or deactivate an account.
+ALTER TABLE audit_event
+    ADD COLUMN reason text
+;
@@ -0,0 +1,18 @@
+-- People created by an administrator choose their own password by accepting
+-- an invitation, sent to them by email. Only the hash of each token is stored.
+CREATE TABLE account_invitation (
+    account_invitation_id int PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    token_hash text NOT NULL UNIQUE,
+    person_id int NOT NULL
+        REFERENCES person(person_id)
+        ON DELETE CASCADE,
+    invited_by int
+        REFERENCES person(person_id)
+        ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    expires_at timestamptz NOT NULL,
+    accepted_at timestamptz,
+    is_revoked boolean NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX account_invitation_person_idx ON account_invitation (person_id);
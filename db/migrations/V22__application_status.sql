@@ -0,0 +1,76 @@
+-- Applications move through an explicit set of states. The approved, reason
+-- and approved_at columns are still set when an application is decided.
+ALTER TABLE application
+    ADD COLUMN status text NOT NULL DEFAULT 'submitted'
+        CHECK (status IN (
+            'submitted',
+            'on_hold',
+            'info_requested',
+            'approved',
+            'rejected',
+            'withdrawn'
+        ));
+
+-- Each change of status is recorded, along with the note that went with it.
+CREATE TABLE application_event (
+    application_event_id int PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    application_id int NOT NULL
+        REFERENCES application(application_id)
+        ON DELETE CASCADE,
+    from_status text,
+    to_status text NOT NULL,
+    actor_id int
+        REFERENCES person(person_id)
+        ON DELETE SET NULL,
+    message text,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX application_event_application_idx
+    ON application_event (application_id, created_at);
+
+-- Backfill the history of existing applications.
+INSERT INTO application_event (
+    application_id,
+    to_status,
+    actor_id,
+    created_at
+)
+SELECT application_id, 'submitted', applicant_id, created_at
+FROM application;
+
+-- Applications were rejected when their applicant was deactivated, which is
+-- now a withdrawal rather than a decision.
+UPDATE application SET
+    status = 'withdrawn'
+WHERE
+    approved = FALSE
+    AND reason = 'Withdrawn because the applicant''s account was deactivated.';
+
+UPDATE application SET
+    status = CASE WHEN approved THEN 'approved' ELSE 'rejected' END
+WHERE
+    approved IS NOT NULL
+    AND status = 'submitted';
+
+INSERT INTO application_event (
+    application_id,
+    from_status,
+    to_status,
+    message,
+    created_at
+)
+SELECT
+    application_id,
+    'submitted',
+    status,
+    reason,
+    COALESCE(approved_at, created_at)
+FROM application
+WHERE status <> 'submitted';
+
+UPDATE application SET
+    approved = NULL,
+    reason = NULL,
+    approved_at = NULL
+WHERE status = 'withdrawn';
@@ -0,0 +1,41 @@
+-- Each organization may ask applicants a list of typed questions.
+CREATE TABLE application_question (
+    application_question_id int PRIMARY KEY
+        GENERATED BY DEFAULT AS IDENTITY,
+    organization_id int NOT NULL
+        REFERENCES organization(organization_id)
+        ON DELETE CASCADE,
+    position int NOT NULL,
+    prompt text NOT NULL,
+    question_type text NOT NULL
+        CHECK (question_type IN (
+            'text',
+            'number',
+            'choice',
+            'yes_no',
+            'date'
+        )),
+    is_required boolean NOT NULL DEFAULT FALSE,
+    choices text[] NOT NULL DEFAULT '{}',
+    min_value double precision,
+    max_value double precision
+);
+
+CREATE INDEX application_question_organization_idx
+    ON application_question (organization_id, position);
+
+-- Answers keep the prompt and type of their question, so that they still make
+-- sense after the form changes or the question is removed.
+CREATE TABLE application_answer (
+    application_id int NOT NULL
+        REFERENCES application(application_id)
+        ON DELETE CASCADE,
+    position int NOT NULL,
+    application_question_id int
+        REFERENCES application_question(application_question_id)
+        ON DELETE SET NULL,
+    prompt text NOT NULL,
+    question_type text NOT NULL,
+    value jsonb NOT NULL,
+    PRIMARY KEY (application_id, position)
+);
@@ -0,0 +1,19 @@
+-- Applicants may attach documents, such as proof of a license, to their
+-- applications. The files themselves are kept in file storage under their
+-- storage key.
+CREATE TABLE attachment (
+    attachment_id int PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    application_id int NOT NULL
+        REFERENCES application(application_id)
+        ON DELETE CASCADE,
+    storage_key text NOT NULL UNIQUE,
+    file_name text NOT NULL,
+    content_type text NOT NULL,
+    size_bytes bigint NOT NULL,
+    uploaded_by int
+        REFERENCES person(person_id)
+        ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX attachment_application_idx ON attachment (application_id);
@@ -0,0 +1,42 @@
+-- Organizations may make applicants wait a number of days after a rejection
+-- before applying again.
+ALTER TABLE organization
+    ADD COLUMN reapply_cooldown_days int NOT NULL DEFAULT 0
+        CHECK (reapply_cooldown_days BETWEEN 0 AND 365);
+
+-- A person may only have one open application to each organization. Where
+-- there are several already, all but the newest are withdrawn.
+WITH withdrawn AS (
+    UPDATE application a SET
+        status = 'withdrawn'
+    FROM application prev
+    WHERE
+        a.application_id = prev.application_id
+        AND a.status IN ('submitted', 'on_hold', 'info_requested')
+        AND EXISTS (
+            SELECT 1
+            FROM application newer
+            WHERE
+                newer.applicant_id = a.applicant_id
+                AND newer.organization_id = a.organization_id
+                AND newer.application_id > a.application_id
+                AND newer.status IN ('submitted', 'on_hold', 'info_requested')
+        )
+    RETURNING a.application_id, prev.status
+)
+INSERT INTO application_event (
+    application_id,
+    from_status,
+    to_status,
+    message
+)
+SELECT
+    application_id,
+    status,
+    'withdrawn',
+    'Withdrawn because a newer application was submitted.'
+FROM withdrawn;
+
+CREATE UNIQUE INDEX application_one_open_idx
+    ON application (applicant_id, organization_id)
+    WHERE status IN ('submitted', 'on_hold', 'info_requested');
@@ -0,0 +1,27 @@
+-- Sponsors may invite drivers and other sponsors to their organization by
+-- email. Accepting an invitation creates the invitee's account if need be and
+-- affiliates them, without an application. Only the hash of each token is
+-- stored, and expired invitations remain pending until they are read.
+CREATE TABLE invitation (
+    invitation_id int PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    token_hash text NOT NULL UNIQUE,
+    organization_id int NOT NULL
+        REFERENCES organization(organization_id)
+        ON DELETE CASCADE,
+    email text NOT NULL,
+    role_id int NOT NULL
+        REFERENCES role(role_id),
+    invited_by int
+        REFERENCES person(person_id)
+        ON DELETE SET NULL,
+    status text NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'accepted', 'revoked')),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    expires_at timestamptz NOT NULL,
+    accepted_at timestamptz,
+    accepted_by int
+        REFERENCES person(person_id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX invitation_organization_idx ON invitation (organization_id);
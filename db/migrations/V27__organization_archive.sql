@@ -0,0 +1,10 @@
+-- Organizations are archived rather than deleted, so that their history is
+-- kept. Archived organizations are hidden from drivers, and the points of their
+-- drivers are frozen until they are restored. An administrator may purge an
+-- organization for good once it has been archived long enough.
+ALTER TABLE organization
+    ADD COLUMN archived_at timestamptz;
+
+CREATE INDEX organization_active_idx
+    ON organization (name)
+    WHERE archived_at IS NULL;
@@ -0,0 +1,15 @@
+-- Organizations describe themselves to drivers with a profile and a logo, and
+-- may call their points by another name. Logos are kept in the file store, so
+-- only their storage keys are kept here. Organizations that are closed to
+-- applications are hidden from drivers looking for one to join.
+ALTER TABLE organization
+    ADD COLUMN description text NOT NULL DEFAULT '',
+    ADD COLUMN contact_email text NOT NULL DEFAULT '',
+    ADD COLUMN website text NOT NULL DEFAULT '',
+    ADD COLUMN logo_key text,
+    ADD COLUMN logo_content_type text,
+    ADD COLUMN applications_closed boolean NOT NULL DEFAULT false,
+    ADD COLUMN points_name text NOT NULL DEFAULT 'points'
+        CHECK (length(points_name) BETWEEN 1 AND 32),
+    ADD CONSTRAINT organization_logo_check
+        CHECK ((logo_key IS NULL) = (logo_content_type IS NULL));
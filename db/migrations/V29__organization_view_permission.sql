@@ -0,0 +1,18 @@
+-- Sponsors need a permission of their own to see and switch between the
+-- organizations they belong to, since every role may reach the sponsor routes.
+INSERT INTO permission (
+    name,
+    description
+) VALUES (
+    'organization.view',
+    'See and switch between own organizations.'
+);
+
+INSERT INTO role_permission (
+    role_id,
+    permission_id
+)
+SELECT r.role_id, p.permission_id
+FROM role r
+JOIN permission p ON p.name = 'organization.view'
+WHERE r.title = 'sponsor';
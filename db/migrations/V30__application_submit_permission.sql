@@ -0,0 +1,18 @@
+-- Only users and drivers may apply to organizations, so the driver routes need
+-- a permission of their own rather than admitting every role.
+INSERT INTO permission (
+    name,
+    description
+) VALUES (
+    'applications.submit',
+    'Apply to join organizations and manage own applications.'
+);
+
+INSERT INTO role_permission (
+    role_id,
+    permission_id
+)
+SELECT r.role_id, p.permission_id
+FROM role r
+JOIN permission p ON p.name = 'applications.submit'
+WHERE r.title IN ('user', 'driver');
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// DefaultAccountInvitationLifetime is how long a person has to accept an
+// invitation to their new account, unless configured otherwise.
+const DefaultAccountInvitationLifetime = 72 * time.Hour
+
+// An AccountInvitation lets a person whose account was created by an
+// administrator choose their password, through a single-use link.
+type AccountInvitation struct {
+	// ID is the identifying number of this invitation. It is not secret.
+	ID int `db:"account_invitation_id" json:"id"`
+	// Token is the secret sent to the invitee in their invitation link.
+	//
+	// Only the hash of the token is stored, so this will be the zero value
+	// for invitations retrieved from a DataStore.
+	Token SecureToken `db:"-" json:"-"`
+	// PersonID identifies the account that the invitee is invited to.
+	PersonID int `db:"person_id" json:"person_id"`
+	// InvitedBy identifies the administrator who sent this invitation. Will
+	// be null if they no longer exist.
+	InvitedBy null.Int `db:"invited_by" json:"invited_by"`
+	// CreatedAt is the timestamp this invitation was sent at.
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	// ExpiresAt is the timestamp after which this invitation cannot be
+	// accepted.
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+	// AcceptedAt is the timestamp this invitation was accepted at. Will be
+	// null if it has not been accepted.
+	AcceptedAt null.Time `db:"accepted_at" json:"accepted_at"`
+	// IsRevoked is true when this invitation was replaced by another.
+	IsRevoked bool `db:"is_revoked" json:"is_revoked"`
+}
+
+// NewAccountInvitation creates a new invitation with a secure random token for
+// a given person, which expires after the given lifetime.
+func NewAccountInvitation(
+	personID int,
+	invitedBy null.Int,
+	lifetime time.Duration,
+) (*AccountInvitation, error) {
+
+	now := time.Now().UTC().Round(time.Second)
+
+	token, err := NewSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountInvitation{
+		Token:     token,
+		PersonID:  personID,
+		InvitedBy: invitedBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(lifetime),
+	}, nil
+}
+
+// IsValid determines whether or not this invitation may still be accepted.
+func (i *AccountInvitation) IsValid() bool {
+	return !i.AcceptedAt.Valid && !i.IsRevoked &&
+		time.Now().Before(i.ExpiresAt)
+}
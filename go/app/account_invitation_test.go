@@ -0,0 +1,42 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestAccountInvitationIsValid(t *testing.T) {
+	fresh := func(lifetime time.Duration) AccountInvitation {
+		inv, err := NewAccountInvitation(1, null.IntFrom(2), lifetime)
+		require.NoError(t, err)
+		require.False(t, inv.Token.IsZero())
+		return *inv
+	}
+
+	accepted := fresh(time.Hour)
+	accepted.AcceptedAt = null.TimeFrom(time.Now())
+
+	revoked := fresh(time.Hour)
+	revoked.IsRevoked = true
+
+	testCases := []struct {
+		alias  string
+		inv    AccountInvitation
+		expect bool
+	}{
+		{alias: "Fresh", inv: fresh(time.Hour), expect: true},
+		{alias: "Expired", inv: fresh(-time.Second)},
+		{alias: "Accepted", inv: accepted},
+		{alias: "Revoked", inv: revoked},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.inv.IsValid())
+		})
+	}
+}
@@ -6,9 +6,13 @@ import (
 
 // An Affiliation describes a person's relationship with an organization.
 type Affiliation struct {
-	PersonID       int `db:"person_id"`
-	OrganizationID int `db:"organization_id"`
+	PersonID         int    `db:"person_id" json:"person_id"`
+	OrganizationID   int    `db:"organization_id" json:"organization_id"`
+	OrganizationName string `db:"name" json:"organization_name"`
 	// Points is the quantity of points this person has with this Organization,
 	// will be null for non-drivers.
-	Points null.Int `db:"points"`
+	Points null.Int `db:"points" json:"points"`
+	// IsFrozen is true when the organization is archived, so that the points
+	// may not change until it is restored.
+	IsFrozen bool `db:"is_frozen" json:"is_frozen"`
 }
@@ -0,0 +1,327 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// An adminUserCreateRequest describes a new account for an administrator to
+// create. Admins and users may not have any organizations, while drivers and
+// sponsors must have at least one.
+type adminUserCreateRequest struct {
+	FirstName       string   `json:"first_name"`
+	LastName        string   `json:"last_name"`
+	Email           string   `json:"email"`
+	Role            app.Role `json:"role_id"`
+	OrganizationIDs []int    `json:"organization_ids"`
+}
+
+func (req *adminUserCreateRequest) validateFields() (
+	message string,
+	err error,
+) {
+
+	defer func() {
+		if message != "" {
+			err = errors.New(message)
+		}
+	}()
+
+	if !validateEmail.MatchString(req.Email) {
+		message = "Invalid email address."
+	} else if len(req.FirstName) < 1 {
+		message = "First Name cannot be blank."
+	} else if len(req.LastName) < 1 {
+		message = "Last Name cannot be blank."
+	}
+
+	if message != "" {
+		return
+	}
+
+	switch req.Role {
+	case app.RoleAdmin, app.RoleUser:
+		if len(req.OrganizationIDs) > 0 {
+			message = "Admins and users cannot belong to an organization."
+		}
+	case app.RoleDriver, app.RoleSponsor:
+		if len(req.OrganizationIDs) < 1 {
+			message = "Drivers and sponsors must belong to an organization."
+		}
+	default:
+		message = "Unknown role."
+	}
+
+	return
+}
+
+// sendAccountInvitation emails an invitation link to its invitee.
+func (svr *Server) sendAccountInvitation(
+	ctx context.Context,
+	p app.Person,
+	inv app.AccountInvitation,
+) error {
+
+	link := svr.baseURL() + "/account/invitation?token=" +
+		url.QueryEscape(inv.Token.String())
+
+	err := svr.mailer.SendMail(ctx, app.Mail{
+		To:      p.Email,
+		Subject: "You're invited to the Driver Incentive Program",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"An account has been created for you in the Driver Incentive "+
+			"Program. To finish setting it up, choose your password by "+
+			"visiting this link:\n\n%s\n\n"+
+			"This link can only be used once, and expires on %s.\n",
+			p.FirstName, link,
+			inv.ExpiresAt.Format("January 2, 2006 at 3:04 PM MST")),
+	})
+
+	return errors.Wrap(err, "failed to send account invitation")
+}
+
+func (svr *Server) handleAdminCreateUser(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	s := getSessionFromContext(r.Context())
+	if s == nil {
+		svr.sendErrorResponse(w, errors.New("missing session for admin"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var data adminUserCreateRequest
+	if err := d.Decode(&data); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "received bad json data"),
+			http.StatusBadRequest, "Bad JSON data.")
+		return
+	} else if message, err := data.validateFields(); err != nil {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest, message)
+		return
+	}
+
+	p := app.Person{
+		FirstName:    data.FirstName,
+		LastName:     data.LastName,
+		Email:        data.Email,
+		Role:         data.Role,
+		Affiliations: make([]int, 0, len(data.OrganizationIDs)),
+	}
+
+	seen := make(map[int]bool)
+	for _, orgID := range data.OrganizationIDs {
+		if seen[orgID] {
+			continue
+		}
+		seen[orgID] = true
+
+		_, err := svr.db.GetOrganizationByID(r.Context(), orgID)
+		if errors.Is(err, app.ErrNotFound) {
+			svr.sendErrorResponse(w, err, http.StatusBadRequest,
+				"No such organization with ID %d.", orgID)
+			return
+		} else if err != nil {
+			svr.sendErrorResponse(w,
+				errors.Wrap(err, "failed to get organization"),
+				http.StatusInternalServerError, "")
+			return
+		}
+
+		p.Affiliations = append(p.Affiliations, orgID)
+	}
+
+	_, err := svr.db.GetPersonByEmail(r.Context(), p.Email)
+	if err == nil {
+		svr.sendErrorResponse(w, errors.New("email address already in use"),
+			http.StatusConflict,
+			"A user with this email address already exists.")
+		return
+	} else if !errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to check email"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	inv, err := app.NewAccountInvitation(0, null.IntFrom(int64(s.Person.ID)),
+		svr.config.Invitations)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to create account invitation"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	p.ID, err = svr.db.CreateInvitedPerson(r.Context(), p, *inv)
+	if errors.Is(err, app.ErrOrganizationArchived) {
+		svr.sendErrorResponse(w, err, http.StatusConflict,
+			"Nobody may join an archived organization.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to create user"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	if err = svr.sendAccountInvitation(r.Context(), p, *inv); err != nil {
+		svr.sendErrorResponse(w, err, http.StatusInternalServerError,
+			"The user was created, but their invitation could not be "+
+				"sent. Please try resending it.")
+		return
+	}
+
+	svr.sendJSONResponse(w, p)
+}
+
+func (svr *Server) handleAdminResendInvitation(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	s := getSessionFromContext(r.Context())
+	if s == nil {
+		svr.sendErrorResponse(w, errors.New("missing session for admin"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	pathParams := mux.Vars(r)
+
+	userID, err := strconv.Atoi(pathParams["userID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "userID must be an integer"),
+			http.StatusBadRequest, "User ID must be an integer.")
+		return
+	}
+
+	p, err := svr.db.GetPersonByID(r.Context(), userID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound, "No such user.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to get user"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	inv, err := app.NewAccountInvitation(p.ID,
+		null.IntFrom(int64(s.Person.ID)), svr.config.Invitations)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to create account invitation"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	_, err = svr.db.ReissueAccountInvitation(r.Context(), *inv)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound,
+			"This user has no pending invitation.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to reissue account invitation"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	if err = svr.sendAccountInvitation(r.Context(), p, *inv); err != nil {
+		svr.sendErrorResponse(w, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type acceptInvitationRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (svr *Server) handleAcceptAccountInvitation(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	const invalidMessage = "This invitation is invalid or has expired. " +
+		"Please ask an administrator to send you a new one."
+
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var data acceptInvitationRequest
+	if err := d.Decode(&data); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "received bad json data"),
+			http.StatusBadRequest, "Bad JSON data.")
+		return
+	}
+
+	token, err := app.ParseSecureToken(data.Token)
+	if err != nil {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest, invalidMessage)
+		return
+	}
+
+	inv, err := svr.db.GetAccountInvitationByToken(r.Context(), token)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest, invalidMessage)
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to get account invitation"),
+			http.StatusInternalServerError, "")
+		return
+	} else if !inv.IsValid() {
+		svr.sendErrorResponse(w,
+			errors.Errorf("account invitation %d is no longer valid",
+				inv.ID),
+			http.StatusBadRequest, invalidMessage)
+		return
+	}
+
+	p, err := svr.db.GetPersonByID(r.Context(), inv.PersonID)
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to get invitee"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	if !svr.validateNewPassword(w, r, data.Password, p) {
+		return
+	}
+
+	hashedPass, err := app.NewPassword(data.Password)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to hash password"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	err = svr.db.AcceptAccountInvitation(r.Context(), inv.ID, hashedPass)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest, invalidMessage)
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to accept account invitation"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
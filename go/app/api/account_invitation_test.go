@@ -0,0 +1,365 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+type invitationMockDB struct {
+	*authMockDB
+
+	people      map[int]app.Person
+	invitations map[app.SecureToken]app.AccountInvitation
+
+	created   *app.Person
+	reissued  *app.AccountInvitation
+	acceptedP app.Password
+}
+
+func (db *invitationMockDB) GetOrganizationByID(
+	_ context.Context,
+	orgID int,
+) (app.Organization, error) {
+
+	if orgID != 7 && orgID != 8 {
+		return app.Organization{}, app.ErrNotFound
+	}
+	return app.Organization{ID: orgID}, nil
+}
+
+func (db *invitationMockDB) GetPersonByID(
+	_ context.Context,
+	personID int,
+) (app.Person, error) {
+
+	p, ok := db.people[personID]
+	if !ok {
+		return p, app.ErrNotFound
+	}
+	return p, nil
+}
+
+func (db *invitationMockDB) GetPersonByEmail(
+	_ context.Context,
+	email string,
+) (app.Person, error) {
+
+	for _, p := range db.people {
+		if p.Email == email {
+			return p, nil
+		}
+	}
+	return app.Person{}, app.ErrNotFound
+}
+
+func (db *invitationMockDB) CreateInvitedPerson(
+	_ context.Context,
+	p app.Person,
+	inv app.AccountInvitation,
+) (int, error) {
+
+	db.created = &p
+	return 9, nil
+}
+
+func (db *invitationMockDB) ReissueAccountInvitation(
+	_ context.Context,
+	inv app.AccountInvitation,
+) (int, error) {
+
+	if inv.PersonID != 5 {
+		return 0, app.ErrNotFound
+	}
+
+	db.reissued = &inv
+	return 2, nil
+}
+
+func (db *invitationMockDB) GetAccountInvitationByToken(
+	_ context.Context,
+	token app.SecureToken,
+) (app.AccountInvitation, error) {
+
+	inv, ok := db.invitations[token]
+	if !ok {
+		return inv, app.ErrNotFound
+	}
+	return inv, nil
+}
+
+func (db *invitationMockDB) AcceptAccountInvitation(
+	_ context.Context,
+	_ int,
+	p app.Password,
+) error {
+
+	db.acceptedP = p
+	return nil
+}
+
+func newInvitationTestAPI(
+	t *testing.T,
+	admin app.Session,
+) (*Server, *invitationMockDB) {
+
+	db := &invitationMockDB{
+		authMockDB: &authMockDB{
+			DB:          &mock.DB{},
+			sessions:    map[app.SecureToken]app.Session{admin.Token: admin},
+			permissions: testRolePermissions,
+		},
+		people: map[int]app.Person{
+			5: {
+				ID:        5,
+				FirstName: "Roger",
+				LastName:  "Van Scoy",
+				Email:     "vanscoy@clemson.edu",
+				Role:      app.RoleSponsor,
+			},
+		},
+		invitations: make(map[app.SecureToken]app.AccountInvitation),
+	}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	return api, db
+}
+
+func TestAdminCreateUser(t *testing.T) {
+	admin, err := app.NewSession(app.Person{ID: 1, Role: app.RoleAdmin},
+		app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		alias        string
+		body         string
+		expectCode   int
+		expectPerson *app.Person
+	}{
+		{
+			alias: "Driver",
+			body: `{"first_name": "Ben", "last_name": "Godfrey",
+				"email": "bfgodfr@clemson.edu", "role_id": 4,
+				"organization_ids": [7, 8, 7]}`,
+			expectCode: http.StatusOK,
+			expectPerson: &app.Person{
+				FirstName:    "Ben",
+				LastName:     "Godfrey",
+				Email:        "bfgodfr@clemson.edu",
+				Role:         app.RoleDriver,
+				Affiliations: []int{7, 8},
+			},
+		},
+		{
+			alias: "Admin",
+			body: `{"first_name": "Ben", "last_name": "Godfrey",
+				"email": "bfgodfr@clemson.edu", "role_id": 1}`,
+			expectCode: http.StatusOK,
+			expectPerson: &app.Person{
+				FirstName:    "Ben",
+				LastName:     "Godfrey",
+				Email:        "bfgodfr@clemson.edu",
+				Role:         app.RoleAdmin,
+				Affiliations: []int{},
+			},
+		},
+		{
+			alias: "AdminWithOrganization",
+			body: `{"first_name": "Ben", "last_name": "Godfrey",
+				"email": "bfgodfr@clemson.edu", "role_id": 1,
+				"organization_ids": [7]}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias: "SponsorWithoutOrganization",
+			body: `{"first_name": "Ben", "last_name": "Godfrey",
+				"email": "bfgodfr@clemson.edu", "role_id": 2}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias: "UnknownRole",
+			body: `{"first_name": "Ben", "last_name": "Godfrey",
+				"email": "bfgodfr@clemson.edu", "role_id": 12}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias: "NoSuchOrganization",
+			body: `{"first_name": "Ben", "last_name": "Godfrey",
+				"email": "bfgodfr@clemson.edu", "role_id": 2,
+				"organization_ids": [99]}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias: "EmailTaken",
+			body: `{"first_name": "Roger", "last_name": "Van Scoy",
+				"email": "vanscoy@clemson.edu", "role_id": 2,
+				"organization_ids": [7]}`,
+			expectCode: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			api, db := newInvitationTestAPI(t, *admin)
+
+			r := httptest.NewRequest("POST", "/admin/users/create",
+				strings.NewReader(tc.body))
+			testSessionTokenInject(t, r, admin.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+			require.Equal(t, tc.expectCode, w.Code)
+
+			mailer := api.mailer.(*mock.Mailer)
+			if tc.expectPerson == nil {
+				assert.Nil(t, db.created)
+				assert.Empty(t, mailer.Sent)
+				return
+			}
+
+			assert.Equal(t, tc.expectPerson, db.created)
+			require.Len(t, mailer.Sent, 1)
+			assert.Equal(t, tc.expectPerson.Email, mailer.Sent[0].To)
+			link := api.baseURL() + "/account/invitation?token="
+			assert.Contains(t, mailer.Sent[0].Body, link)
+		})
+	}
+}
+
+func TestAdminResendInvitation(t *testing.T) {
+	admin, err := app.NewSession(app.Person{ID: 1, Role: app.RoleAdmin},
+		app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		alias      string
+		path       string
+		expectCode int
+		expectSent bool
+	}{
+		{
+			alias:      "Pending",
+			path:       "/admin/users/5/invitation/resend",
+			expectCode: http.StatusNoContent,
+			expectSent: true,
+		},
+		{
+			alias:      "NoSuchUser",
+			path:       "/admin/users/6/invitation/resend",
+			expectCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			api, db := newInvitationTestAPI(t, *admin)
+
+			r := httptest.NewRequest("POST", tc.path, nil)
+			testSessionTokenInject(t, r, admin.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+			require.Equal(t, tc.expectCode, w.Code)
+
+			mailer := api.mailer.(*mock.Mailer)
+			if !tc.expectSent {
+				assert.Empty(t, mailer.Sent)
+				return
+			}
+
+			require.NotNil(t, db.reissued)
+			assert.Equal(t, 5, db.reissued.PersonID)
+			require.Len(t, mailer.Sent, 1)
+			assert.Contains(t, mailer.Sent[0].Body, db.reissued.Token.String())
+		})
+	}
+}
+
+func TestAcceptAccountInvitation(t *testing.T) {
+	admin, err := app.NewSession(app.Person{ID: 1, Role: app.RoleAdmin},
+		app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	valid, err := app.NewAccountInvitation(5, null.IntFrom(1), time.Hour)
+	require.NoError(t, err)
+
+	expired, err := app.NewAccountInvitation(5, null.IntFrom(1), -time.Hour)
+	require.NoError(t, err)
+
+	unknown, err := app.NewSecureToken()
+	require.NoError(t, err)
+
+	const password = "long-enough-phrase-2021"
+
+	testCases := []struct {
+		alias      string
+		token      string
+		password   string
+		expectCode int
+	}{
+		{
+			alias:      "Valid",
+			token:      valid.Token.String(),
+			password:   password,
+			expectCode: http.StatusNoContent,
+		},
+		{
+			alias:      "Expired",
+			token:      expired.Token.String(),
+			password:   password,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "Unknown",
+			token:      unknown.String(),
+			password:   password,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "Malformed",
+			token:      "aaaaaack",
+			password:   password,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "WeakPassword",
+			token:      valid.Token.String(),
+			password:   "short",
+			expectCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			api, db := newInvitationTestAPI(t, *admin)
+			db.invitations[valid.Token] = *valid
+			db.invitations[expired.Token] = *expired
+
+			r := httptest.NewRequest("POST", "/account/invitation/accept",
+				strings.NewReader(`{"token": "`+tc.token+
+					`", "password": "`+tc.password+`"}`))
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+			require.Equal(t, tc.expectCode, w.Code)
+
+			if tc.expectCode != http.StatusNoContent {
+				assert.Empty(t, db.acceptedP)
+				return
+			}
+
+			ok, _ := db.acceptedP.Verify(tc.password)
+			assert.True(t, ok)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -163,6 +164,20 @@ func (svr *Server) handleAdminUpdateUserPassword(
 		return
 	}
 
+	p, err := svr.db.GetPersonByID(r.Context(), userID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound, "No such user.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to get user"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	if !svr.validateNewPassword(w, r, data.NewPassword, p) {
+		return
+	}
+
 	hashedPass, err := app.NewPassword(data.NewPassword)
 	if err != nil {
 		svr.sendErrorResponse(w,
@@ -189,6 +204,21 @@ func (svr *Server) handleAdminUpdateUserPassword(
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// An accountStatusRequest gives the reason that an administrator activates or
+// deactivates an account.
+type accountStatusRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (r *accountStatusRequest) validateFields() (message string, err error) {
+	r.Reason = strings.TrimSpace(r.Reason)
+	if len(r.Reason) < 1 {
+		message = "Must give a reason."
+		err = errors.New("missing reason")
+	}
+	return
+}
+
 func (svr *Server) handleAdminDeactivateUser(
 	w http.ResponseWriter,
 	r *http.Request,
@@ -203,8 +233,25 @@ func (svr *Server) handleAdminDeactivateUser(
 		return
 	}
 
-	err = svr.db.DeactivatePerson(r.Context(), userID)
-	if err != nil {
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var data accountStatusRequest
+	var message string
+	if err = d.Decode(&data); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "received bad json data"),
+			http.StatusBadRequest, "Bad JSON data.")
+		return
+	} else if message, err = data.validateFields(); err != nil {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest, message)
+		return
+	}
+
+	err = svr.db.DeactivatePerson(r.Context(), userID, data.Reason)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound, "No such user.")
+		return
+	} else if err != nil {
 		svr.sendErrorResponse(w, errors.Wrap(err, "failed to deactivate user"),
 			http.StatusInternalServerError, "")
 		return
@@ -227,8 +274,25 @@ func (svr *Server) handleAdminActivateUser(
 		return
 	}
 
-	err = svr.db.ActivatePerson(r.Context(), userID)
-	if err != nil {
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var data accountStatusRequest
+	var message string
+	if err = d.Decode(&data); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "received bad json data"),
+			http.StatusBadRequest, "Bad JSON data.")
+		return
+	} else if message, err = data.validateFields(); err != nil {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest, message)
+		return
+	}
+
+	err = svr.db.ActivatePerson(r.Context(), userID, data.Reason)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound, "No such user.")
+		return
+	} else if err != nil {
 		svr.sendErrorResponse(w, errors.Wrap(err, "failed to activate user"),
 			http.StatusInternalServerError, "")
 		return
@@ -237,6 +301,199 @@ func (svr *Server) handleAdminActivateUser(
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (svr *Server) handleAdminGetUserAffiliations(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	userID, err := strconv.Atoi(pathParams["userID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "userID must be an integer"),
+			http.StatusBadRequest, "User ID must be an integer.")
+		return
+	}
+
+	_, err = svr.db.GetPersonByID(r.Context(), userID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound, "No such user.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to get user"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	as, err := svr.db.GetAffiliationsForPerson(r.Context(), userID)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to get affiliations"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	svr.sendJSONResponse(w, as)
+}
+
+// An affiliationsRequest lists the organizations that a person should join and
+// leave. Affiliations not listed are left alone, so that changes made by
+// others in the meantime are not undone.
+type affiliationsRequest struct {
+	Add    []int `json:"add"`
+	Remove []int `json:"remove"`
+}
+
+func (svr *Server) handleAdminChangeUserAffiliations(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	userID, err := strconv.Atoi(pathParams["userID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "userID must be an integer"),
+			http.StatusBadRequest, "User ID must be an integer.")
+		return
+	}
+
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var data affiliationsRequest
+	if err = d.Decode(&data); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "received bad json data"),
+			http.StatusBadRequest, "Bad JSON data.")
+		return
+	}
+
+	p, err := svr.db.GetPersonByID(r.Context(), userID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound, "No such user.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to get user"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	if p.Role == app.RoleAdmin && len(data.Add) > 0 {
+		svr.sendErrorResponse(w,
+			errors.Errorf("cannot affiliate admin %d", userID),
+			http.StatusBadRequest,
+			"Admins cannot belong to an organization.")
+		return
+	}
+
+	add := make([]int, 0, len(data.Add))
+	seen := make(map[int]bool)
+	for _, orgID := range data.Add {
+		if seen[orgID] {
+			continue
+		}
+		seen[orgID] = true
+
+		_, err = svr.db.GetOrganizationByID(r.Context(), orgID)
+		if errors.Is(err, app.ErrNotFound) {
+			svr.sendErrorResponse(w, err, http.StatusBadRequest,
+				"No such organization with ID %d.", orgID)
+			return
+		} else if err != nil {
+			svr.sendErrorResponse(w,
+				errors.Wrap(err, "failed to get organization"),
+				http.StatusInternalServerError, "")
+			return
+		}
+
+		add = append(add, orgID)
+	}
+
+	remove := make([]int, 0, len(data.Remove))
+	removing := make(map[int]bool)
+	for _, orgID := range data.Remove {
+		if seen[orgID] {
+			svr.sendErrorResponse(w,
+				errors.Errorf("org %d both added and removed", orgID),
+				http.StatusBadRequest,
+				"Organization %d cannot be both added and removed.", orgID)
+			return
+		} else if removing[orgID] {
+			continue
+		}
+		removing[orgID] = true
+
+		remove = append(remove, orgID)
+	}
+
+	err = svr.db.ChangePersonAffiliations(r.Context(), userID, add, remove)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound, "No such user.")
+		return
+	} else if errors.Is(err, app.ErrOrganizationArchived) {
+		svr.sendErrorResponse(w, err, http.StatusConflict,
+			"Nobody may join an archived organization.")
+		return
+	} else if errors.Is(err, app.ErrConflict) {
+		svr.sendErrorResponse(w, err, http.StatusConflict,
+			"This user's organizations have changed. Please refresh and "+
+				"try again.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to change affiliations"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAdminUnlockUser lifts any delay or lockout on a user's account.
+//
+// Failures counted against client IP addresses are deliberately left alone.
+// An address is not tied to any one account, and may be guessing passwords
+// for many of them, so unlocking one account must not lift the throttle on
+// the addresses that attempted it. The IP policy is lenient enough that a
+// user who shares an address with others is rarely held back by it, and any
+// such lockout passes on its own.
+func (svr *Server) handleAdminUnlockUser(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	userID, err := strconv.Atoi(pathParams["userID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "userID must be an integer"),
+			http.StatusBadRequest, "User ID must be an integer.")
+		return
+	}
+
+	p, err := svr.db.GetPersonByID(r.Context(), userID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w,
+			errors.Wrapf(err, "no person with ID of %d", userID),
+			http.StatusNotFound, "No such user.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to retrieve person"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	err = svr.db.ClearLoginFailuresForEmail(r.Context(),
+		loginAttemptKey(p.Email))
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to unlock user"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (svr *Server) handleAdminGetOrganizationByID(
 	w http.ResponseWriter,
 	r *http.Request,
@@ -259,6 +516,7 @@ func (svr *Server) handleAdminGetOrganizationByID(
 		return
 	}
 
+	org.LogoURL = svr.logoURL(org)
 	svr.sendJSONResponse(w, org)
 }
 
@@ -281,10 +539,7 @@ func (svr *Server) handleAdminCreateOrganization(
 		return
 	}
 
-	_, err := svr.db.CreateOrganization(r.Context(), app.Organization{
-		Name:       data.Name,
-		PointValue: data.PointValue,
-	})
+	_, err := svr.db.CreateOrganization(r.Context(), data.organization(0))
 	if err != nil {
 		svr.sendErrorResponse(w,
 			errors.Wrap(err, "failed to create organization"),
@@ -323,12 +578,12 @@ func (svr *Server) handleAdminUpdateOrganization(
 		return
 	}
 
-	err = svr.db.UpdateOrganization(r.Context(), app.Organization{
-		ID:         orgID,
-		Name:       data.Name,
-		PointValue: data.PointValue,
-	})
-	if err != nil {
+	err = svr.db.UpdateOrganization(r.Context(), data.organization(orgID))
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound,
+			"No such organization.")
+		return
+	} else if err != nil {
 		svr.sendErrorResponse(w,
 			errors.Wrap(err, "failed to update organization"),
 			http.StatusInternalServerError, "")
@@ -338,7 +593,23 @@ func (svr *Server) handleAdminUpdateOrganization(
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (svr *Server) handleAdminDeleteOrganization(
+func (svr *Server) handleAdminGetArchivedOrganizations(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	orgs, err := svr.db.GetArchivedOrganizations(r.Context())
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to get archived organizations"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	svr.sendJSONResponse(w, svr.withLogoURLs(orgs))
+}
+
+func (svr *Server) handleAdminArchiveOrganization(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
@@ -352,13 +623,102 @@ func (svr *Server) handleAdminDeleteOrganization(
 		return
 	}
 
-	err = svr.db.DeleteOrganization(r.Context(), orgID)
+	err = svr.db.ArchiveOrganization(r.Context(), orgID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound,
+			"No such organization.")
+		return
+	} else if errors.Is(err, app.ErrConflict) {
+		svr.sendErrorResponse(w, err, http.StatusConflict,
+			"This organization is already archived.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to archive organization"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (svr *Server) handleAdminRestoreOrganization(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	orgID, err := strconv.Atoi(pathParams["orgID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "orgID must be an integer"),
+			http.StatusBadRequest, "Organization ID must be an integer.")
+		return
+	}
+
+	err = svr.db.RestoreOrganization(r.Context(), orgID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound,
+			"No such organization.")
+		return
+	} else if errors.Is(err, app.ErrConflict) {
+		svr.sendErrorResponse(w, err, http.StatusConflict,
+			"This organization is not archived.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to restore organization"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAdminPurgeOrganization permanently deletes an organization, along
+// with its affiliations, catalog, applications, and their files. This cannot
+// be undone, so only organizations that have been archived for the retention
+// period may be purged.
+func (svr *Server) handleAdminPurgeOrganization(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	orgID, err := strconv.Atoi(pathParams["orgID"])
 	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "orgID must be an integer"),
+			http.StatusBadRequest, "Organization ID must be an integer.")
+		return
+	}
+
+	retention := svr.config.OrganizationRetention
+	fileKeys, err := svr.db.PurgeOrganization(r.Context(), orgID, retention)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound,
+			"No such organization.")
+		return
+	} else if errors.Is(err, app.ErrConflict) {
+		svr.sendErrorResponse(w, err, http.StatusConflict,
+			"Only organizations that have been archived for at least %d "+
+				"days may be purged.", int(retention.Hours()/24))
+		return
+	} else if err != nil {
 		svr.sendErrorResponse(w,
-			errors.Wrap(err, "failed to delete organization"),
+			errors.Wrap(err, "failed to purge organization"),
 			http.StatusInternalServerError, "")
 		return
 	}
 
+	// Failures are only logged, since the organization is already gone.
+	for _, key := range fileKeys {
+		if err = svr.files.DeleteFile(r.Context(), key); err != nil {
+			svr.logger.WithError(err).
+				Errorf("failed to delete file %s of purged org %d", key,
+					orgID)
+		}
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,455 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+type accountStatusMockDB struct {
+	*authMockDB
+
+	deactivated map[int]string
+	activated   map[int]string
+}
+
+func (db *accountStatusMockDB) ActivatePerson(
+	_ context.Context,
+	personID int,
+	reason string,
+) error {
+
+	if personID != 3 {
+		return app.ErrNotFound
+	}
+
+	db.activated[personID] = reason
+	return nil
+}
+
+func (db *accountStatusMockDB) DeactivatePerson(
+	_ context.Context,
+	personID int,
+	reason string,
+) error {
+
+	if personID != 3 {
+		return app.ErrNotFound
+	}
+
+	db.deactivated[personID] = reason
+	return nil
+}
+
+func TestAdminAccountStatus(t *testing.T) {
+	admin, err := app.NewSession(app.Person{ID: 1, Role: app.RoleAdmin},
+		app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		alias        string
+		path         string
+		body         string
+		expectCode   int
+		expectActive map[int]string
+		expectDeact  map[int]string
+	}{
+		{
+			alias:        "Deactivate",
+			path:         "/admin/users/3/deactivate",
+			body:         `{"reason": "  Left the company. "}`,
+			expectCode:   http.StatusNoContent,
+			expectActive: map[int]string{},
+			expectDeact:  map[int]string{3: "Left the company."},
+		},
+		{
+			alias:        "Activate",
+			path:         "/admin/users/3/activate",
+			body:         `{"reason": "Rehired."}`,
+			expectCode:   http.StatusNoContent,
+			expectActive: map[int]string{3: "Rehired."},
+			expectDeact:  map[int]string{},
+		},
+		{
+			alias:        "MissingReason",
+			path:         "/admin/users/3/deactivate",
+			body:         `{"reason": "   "}`,
+			expectCode:   http.StatusBadRequest,
+			expectActive: map[int]string{},
+			expectDeact:  map[int]string{},
+		},
+		{
+			alias:        "NoBody",
+			path:         "/admin/users/3/activate",
+			expectCode:   http.StatusBadRequest,
+			expectActive: map[int]string{},
+			expectDeact:  map[int]string{},
+		},
+		{
+			alias:        "NoSuchUser",
+			path:         "/admin/users/4/deactivate",
+			body:         `{"reason": "Left the company."}`,
+			expectCode:   http.StatusNotFound,
+			expectActive: map[int]string{},
+			expectDeact:  map[int]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			db := &accountStatusMockDB{
+				authMockDB: &authMockDB{
+					DB: &mock.DB{},
+					sessions: map[app.SecureToken]app.Session{
+						admin.Token: *admin,
+					},
+					permissions: testRolePermissions,
+				},
+				deactivated: make(map[int]string),
+				activated:   make(map[int]string),
+			}
+			api, _, _ := newTestAPI(t, db, nil)
+
+			r := httptest.NewRequest("POST", tc.path,
+				strings.NewReader(tc.body))
+			testSessionTokenInject(t, r, admin.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectActive, db.activated)
+			assert.Equal(t, tc.expectDeact, db.deactivated)
+		})
+	}
+}
+
+type affiliationMockDB struct {
+	*authMockDB
+
+	people  map[int]app.Person
+	added   map[int][]int
+	removed map[int][]int
+}
+
+func (db *affiliationMockDB) GetPersonByID(
+	_ context.Context,
+	personID int,
+) (app.Person, error) {
+
+	p, ok := db.people[personID]
+	if !ok {
+		return p, app.ErrNotFound
+	}
+	return p, nil
+}
+
+func (db *affiliationMockDB) GetOrganizationByID(
+	_ context.Context,
+	orgID int,
+) (app.Organization, error) {
+
+	if orgID != 7 && orgID != 8 {
+		return app.Organization{}, app.ErrNotFound
+	}
+	return app.Organization{ID: orgID}, nil
+}
+
+func (db *affiliationMockDB) ChangePersonAffiliations(
+	_ context.Context,
+	personID int,
+	add, remove []int,
+) error {
+
+	for _, orgID := range remove {
+		if orgID == 9 {
+			// Simulates an affiliation that was removed in the meantime.
+			return app.ErrConflict
+		}
+	}
+
+	db.added[personID] = add
+	db.removed[personID] = remove
+	return nil
+}
+
+func TestAdminChangeUserAffiliations(t *testing.T) {
+	admin, err := app.NewSession(app.Person{ID: 1, Role: app.RoleAdmin},
+		app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		alias         string
+		path          string
+		body          string
+		expectCode    int
+		expectAdded   map[int][]int
+		expectRemoved map[int][]int
+	}{
+		{
+			alias:         "Driver",
+			path:          "/admin/users/4/affiliations",
+			body:          `{"add": [8, 7, 8], "remove": [6, 6]}`,
+			expectCode:    http.StatusNoContent,
+			expectAdded:   map[int][]int{4: {8, 7}},
+			expectRemoved: map[int][]int{4: {6}},
+		},
+		{
+			alias:         "RemoveOnly",
+			path:          "/admin/users/4/affiliations",
+			body:          `{"remove": [7]}`,
+			expectCode:    http.StatusNoContent,
+			expectAdded:   map[int][]int{4: {}},
+			expectRemoved: map[int][]int{4: {7}},
+		},
+		{
+			alias:         "AddAndRemove",
+			path:          "/admin/users/4/affiliations",
+			body:          `{"add": [7], "remove": [7]}`,
+			expectCode:    http.StatusBadRequest,
+			expectAdded:   map[int][]int{},
+			expectRemoved: map[int][]int{},
+		},
+		{
+			alias:         "Changed",
+			path:          "/admin/users/4/affiliations",
+			body:          `{"add": [7], "remove": [9]}`,
+			expectCode:    http.StatusConflict,
+			expectAdded:   map[int][]int{},
+			expectRemoved: map[int][]int{},
+		},
+		{
+			alias:         "Admin",
+			path:          "/admin/users/1/affiliations",
+			body:          `{"add": [7]}`,
+			expectCode:    http.StatusBadRequest,
+			expectAdded:   map[int][]int{},
+			expectRemoved: map[int][]int{},
+		},
+		{
+			alias:         "NoSuchOrganization",
+			path:          "/admin/users/4/affiliations",
+			body:          `{"add": [7, 99]}`,
+			expectCode:    http.StatusBadRequest,
+			expectAdded:   map[int][]int{},
+			expectRemoved: map[int][]int{},
+		},
+		{
+			alias:         "NoSuchUser",
+			path:          "/admin/users/5/affiliations",
+			body:          `{"add": [7]}`,
+			expectCode:    http.StatusNotFound,
+			expectAdded:   map[int][]int{},
+			expectRemoved: map[int][]int{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			db := &affiliationMockDB{
+				authMockDB: &authMockDB{
+					DB: &mock.DB{},
+					sessions: map[app.SecureToken]app.Session{
+						admin.Token: *admin,
+					},
+					permissions: testRolePermissions,
+				},
+				people: map[int]app.Person{
+					1: admin.Person,
+					4: {ID: 4, Role: app.RoleDriver},
+				},
+				added:   make(map[int][]int),
+				removed: make(map[int][]int),
+			}
+			api, _, _ := newTestAPI(t, db, nil)
+
+			r := httptest.NewRequest("POST", tc.path,
+				strings.NewReader(tc.body))
+			testSessionTokenInject(t, r, admin.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectAdded, db.added)
+			assert.Equal(t, tc.expectRemoved, db.removed)
+		})
+	}
+}
+
+type orgArchiveMockDB struct {
+	*authMockDB
+
+	archived map[int]time.Time
+	purged   []int
+	// fileKeys are the keys of the files that belong to each organization.
+	fileKeys map[int][]string
+}
+
+func (db *orgArchiveMockDB) ArchiveOrganization(
+	_ context.Context,
+	orgID int,
+) error {
+
+	if orgID != 7 && orgID != 8 {
+		return app.ErrNotFound
+	} else if _, ok := db.archived[orgID]; ok {
+		return app.ErrOrganizationArchived
+	}
+
+	db.archived[orgID] = time.Now()
+	return nil
+}
+
+func (db *orgArchiveMockDB) RestoreOrganization(
+	_ context.Context,
+	orgID int,
+) error {
+
+	if orgID != 7 && orgID != 8 {
+		return app.ErrNotFound
+	} else if _, ok := db.archived[orgID]; !ok {
+		return app.ErrConflict
+	}
+
+	delete(db.archived, orgID)
+	return nil
+}
+
+func (db *orgArchiveMockDB) PurgeOrganization(
+	_ context.Context,
+	orgID int,
+	retention time.Duration,
+) ([]string, error) {
+
+	archivedAt, ok := db.archived[orgID]
+	if orgID != 7 && orgID != 8 {
+		return nil, app.ErrNotFound
+	} else if !ok || time.Since(archivedAt) < retention {
+		return nil, app.ErrConflict
+	}
+
+	delete(db.archived, orgID)
+	db.purged = append(db.purged, orgID)
+	return db.fileKeys[orgID], nil
+}
+
+func TestAdminOrganizationArchive(t *testing.T) {
+	admin, err := app.NewSession(app.Person{ID: 1, Role: app.RoleAdmin},
+		app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	longAgo := time.Now().Add(-2 * app.DefaultOrganizationRetention)
+
+	testCases := []struct {
+		alias          string
+		path           string
+		expectCode     int
+		expectArchived []int
+		expectPurged   []int
+		expectFiles    int
+	}{
+		{
+			alias:          "Archive",
+			path:           "/admin/organizations/7/archive",
+			expectCode:     http.StatusNoContent,
+			expectArchived: []int{7, 8},
+		},
+		{
+			alias:          "AlreadyArchived",
+			path:           "/admin/organizations/8/archive",
+			expectCode:     http.StatusConflict,
+			expectArchived: []int{8},
+		},
+		{
+			alias:          "Restore",
+			path:           "/admin/organizations/8/restore",
+			expectCode:     http.StatusNoContent,
+			expectArchived: []int{},
+		},
+		{
+			alias:          "NotArchived",
+			path:           "/admin/organizations/7/restore",
+			expectCode:     http.StatusConflict,
+			expectArchived: []int{8},
+		},
+		{
+			alias:          "Purge",
+			path:           "/admin/organizations/8/purge",
+			expectCode:     http.StatusNoContent,
+			expectArchived: []int{},
+			expectPurged:   []int{8},
+			expectFiles:    1,
+		},
+		{
+			alias:          "PurgeNotArchived",
+			path:           "/admin/organizations/7/purge",
+			expectCode:     http.StatusConflict,
+			expectArchived: []int{8},
+		},
+		{
+			alias:          "NoSuchOrganization",
+			path:           "/admin/organizations/9/archive",
+			expectCode:     http.StatusNotFound,
+			expectArchived: []int{8},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			db := &orgArchiveMockDB{
+				authMockDB: &authMockDB{
+					DB: &mock.DB{},
+					sessions: map[app.SecureToken]app.Session{
+						admin.Token: *admin,
+					},
+					permissions: testRolePermissions,
+				},
+				archived: map[int]time.Time{8: longAgo},
+				fileKeys: make(map[int][]string),
+			}
+			api, _, _ := newTestAPI(t, db, nil)
+			files := api.files.(*mock.FileStore)
+
+			for _, orgID := range []int{7, 8} {
+				key, err := files.PutFile(context.Background(),
+					strings.NewReader("logo"))
+				require.NoError(t, err)
+				db.fileKeys[orgID] = []string{key}
+			}
+
+			r := httptest.NewRequest("POST", tc.path, nil)
+			testSessionTokenInject(t, r, admin.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectPurged, db.purged)
+			if tc.expectPurged != nil {
+				// Only the files of the purged organization are deleted.
+				assert.Len(t, files.Files, tc.expectFiles)
+				assert.NotContains(t, files.Files, db.fileKeys[8][0])
+			} else {
+				assert.Len(t, files.Files, 2)
+			}
+
+			archived := make([]int, 0)
+			for _, orgID := range []int{7, 8} {
+				if _, ok := db.archived[orgID]; ok {
+					archived = append(archived, orgID)
+				}
+			}
+			assert.Equal(t, tc.expectArchived, archived)
+		})
+	}
+}
@@ -15,16 +15,21 @@ import (
 
 // Server is a wrapper for http.Server that exposes our app's endpoints.
 type Server struct {
-	config Config
-	db     app.DataStore
-	cv     app.CommerceVendor
-	logger *logrus.Logger
-	httpd  *http.Server
-	router *mux.Router
+	config  Config
+	db      app.DataStore
+	cv      app.CommerceVendor
+	mailer  app.Mailer
+	files   app.FileStore
+	scanner app.FileScanner
+	logger  *logrus.Logger
+	httpd   *http.Server
+	router  *mux.Router
 }
 
-// NewServer creates a new Server given a logger, data store, and configuration.
+// NewServer creates a new Server given a logger, data store, commerce vendor,
+// mailer, file store, file scanner, and configuration.
 func NewServer(logger *logrus.Logger, db app.DataStore, cv app.CommerceVendor,
+	mailer app.Mailer, files app.FileStore, scanner app.FileScanner,
 	cfg Config) (*Server, error) {
 
 	if logger == nil {
@@ -40,17 +45,22 @@ func NewServer(logger *logrus.Logger, db app.DataStore, cv app.CommerceVendor,
 	}
 
 	svr := &Server{
-		httpd:  httpd,
-		config: cfg,
-		db:     db,
-		cv:     cv,
-		logger: logger,
-		router: router,
+		httpd:   httpd,
+		config:  cfg,
+		db:      db,
+		cv:      cv,
+		mailer:  mailer,
+		files:   files,
+		scanner: scanner,
+		logger:  logger,
+		router:  router,
 	}
 
 	// Register global middleware.
+	router.Use(svr.requestIDMiddleware)
 	router.Use(svr.panicRecoveryMiddleware)
 	router.Use(svr.authContextMiddleware)
+	router.Use(svr.csrfMiddleware)
 
 	// Set custom error handlers.
 	router.NotFoundHandler = http.
@@ -58,92 +68,203 @@ func NewServer(logger *logrus.Logger, db app.DataStore, cv app.CommerceVendor,
 	router.MethodNotAllowedHandler = http.
 		HandlerFunc(svr.handleMethodNotAllowed)
 
-	// Define routes.
-	router.Path("/login").Methods("POST").HandlerFunc(svr.handleLogin)
+	// Credentials may only be managed from a login session, so that a leaked
+	// API token cannot be used to create more of them. Administrators
+	// impersonating someone may not manage their credentials either, since
+	// they are only meant to see what that person sees.
+	credentialsOnly := authConfig{sessionOnly: true, noImpersonation: true}
+
+	// Define routes. Those that change state without a session cannot check a
+	// CSRF token, so they must come from the web app itself.
+	router.Path("/login").Methods("POST").
+		HandlerFunc(svr.requireSameOrigin(svr.handleLogin))
 	router.Path("/logout").Methods("POST").HandlerFunc(svr.handleLogout)
 	router.Path("/whoami").Methods("GET").HandlerFunc(svr.handleWhoAmI)
+	router.Path("/impersonation/stop").Methods("POST").
+		HandlerFunc(svr.handleStopImpersonation)
+
+	// Single sign-on for sponsor organizations.
+	router.Path("/sso/{orgID}/login").Methods("GET").
+		HandlerFunc(svr.handleSSOLogin)
+	router.Path("/sso/callback").Methods("GET").
+		HandlerFunc(svr.handleSSOCallback)
 
 	// Bogus endpoint. Always returns 501.
 	router.Path("/todo").Methods("GET").HandlerFunc(svr.handleTODO)
 
 	// Account subroutes.
 	accountRouter := router.PathPrefix("/account").Subrouter()
-	accountRouter.Path("/register").HandlerFunc(svr.handleRegistration)
+	accountRouter.Path("/register").
+		HandlerFunc(svr.requireSameOrigin(svr.handleRegistration))
 
 	// adminRouter := router.PathPrefix("/admin").Subrouter()
 	accountRouter.Path("/forgot").Methods("POST").
 		HandlerFunc(svr.handleTODO) // TODO
 	accountRouter.Path("/register").Methods("POST").
-		HandlerFunc(svr.handleRegistration)
+		HandlerFunc(svr.requireSameOrigin(svr.handleRegistration))
+	accountRouter.Path("/invitation/accept").Methods("POST").
+		HandlerFunc(svr.requireSameOrigin(svr.handleAcceptAccountInvitation))
+	accountRouter.Path("/organization-invitation").Methods("POST").
+		HandlerFunc(svr.handleGetInvitation)
+	accountRouter.Path("/organization-invitation/accept").Methods("POST").
+		HandlerFunc(svr.requireSameOrigin(svr.handleAcceptInvitation))
+
+	// Links to attachments are signed, so they need no session.
+	router.Path("/attachments/{attachmentID}").Methods("GET").
+		HandlerFunc(svr.handleDownloadAttachment)
+
+	// Logos are public, so they need no session either.
+	router.Path("/organizations/{orgID}/logo").Methods("GET").
+		HandlerFunc(svr.handleGetOrganizationLogo)
 
 	// My subroutes.
 	myRouter := router.PathPrefix("/my").Subrouter()
 	myRouter.Use(svr.requireAuthMiddleware(authConfig{
-		requireRole: true,
-		allowedRoles: []app.Role{
-			app.RoleAdmin,
-			app.RoleSponsor,
-			app.RoleDriver,
-		},
+		permission: app.PermissionProfileManage,
+		scope:      "my",
 	}))
 
+	// Impersonators may not take over the account that they are viewing.
+	myAccountOnly := authConfig{noImpersonation: true, scope: "my"}
+
 	myProfileRouter := myRouter.PathPrefix("/profile").Subrouter()
 	myProfileRouter.Path("/name").Methods("POST").
 		HandlerFunc(svr.handleMyProfileUpdateName)
 	myProfileRouter.Path("/email").Methods("POST").
-		HandlerFunc(svr.handleMyProfileUpdateEmail)
+		HandlerFunc(svr.requireAuth(myAccountOnly,
+			svr.handleMyProfileUpdateEmail))
 	myProfileRouter.Path("/password").Methods("POST").
-		HandlerFunc(svr.handleMyProfileUpdatePassword)
+		HandlerFunc(svr.requireAuth(credentialsOnly,
+			svr.handleMyProfileUpdatePassword))
 	myProfileRouter.Path("/deactivate").Methods("POST").
-		HandlerFunc(svr.handleMyProfileDeactivate)
+		HandlerFunc(svr.requireAuth(myAccountOnly,
+			svr.handleMyProfileDeactivate))
+
+	mySessionRouter := myRouter.PathPrefix("/sessions").Subrouter()
+	mySessionRouter.Use(svr.requireAuthMiddleware(credentialsOnly))
+	mySessionRouter.Path("").Methods("GET").
+		HandlerFunc(svr.handleMyGetSessions)
+	mySessionRouter.Path("/others/revoke").Methods("POST").
+		HandlerFunc(svr.handleMyRevokeOtherSessions)
+	mySessionRouter.Path("/{sessionID}/revoke").Methods("POST").
+		HandlerFunc(svr.handleMyRevokeSession)
+
+	myTokenRouter := myRouter.PathPrefix("/tokens").Subrouter()
+	myTokenRouter.Use(svr.requireAuthMiddleware(credentialsOnly))
+	myTokenRouter.Path("").Methods("GET").
+		HandlerFunc(svr.handleMyGetAPITokens)
+	myTokenRouter.Path("/create").Methods("POST").
+		HandlerFunc(svr.handleMyCreateAPIToken)
+	myTokenRouter.Path("/{tokenID}/revoke").Methods("POST").
+		HandlerFunc(svr.handleMyRevokeAPIToken)
+
+	myRouter.Path("/notifications").Methods("GET").
+		HandlerFunc(svr.handleMyGetNotifications)
+	myRouter.Path("/notifications/{notificationID}/read").Methods("POST").
+		HandlerFunc(svr.handleMyMarkNotificationRead)
 
 	// Admin subroutes.
 	adminRouter := router.PathPrefix("/admin").Subrouter()
 	adminRouter.Use(svr.requireAuthMiddleware(authConfig{
-		requireRole:  true,
-		allowedRoles: []app.Role{app.RoleAdmin},
+		scope: "admin",
 	}))
 
+	adminRouter.Path("/audit").Methods("GET").
+		HandlerFunc(svr.requireAuth(authConfig{
+			permission: app.PermissionAuditView,
+			scope:      "admin",
+		}, svr.handleAdminGetAuditEvents))
+
 	adminUserRouter := adminRouter.PathPrefix("/users").Subrouter()
+	adminUserRouter.Use(svr.requireAuthMiddleware(authConfig{
+		permission: app.PermissionUsersManage,
+		scope:      "admin",
+	}))
 	adminUserRouter.Path("").Methods("GET").
 		HandlerFunc(svr.handleAdminGetAllUsers)
 	adminUserRouter.Path("/create").Methods("POST").
-		HandlerFunc(svr.handleTODO) // TODO
+		HandlerFunc(svr.handleAdminCreateUser)
 	adminUserRouter.Path("/{userID}").Methods("GET").
 		HandlerFunc(svr.handleAdminGetUserByID)
 	adminUserRouter.Path("/{userID}/name").Methods("POST").
 		HandlerFunc(svr.handleAdminUpdateUserName)
 	adminUserRouter.Path("/{userID}/email").Methods("POST").
 		HandlerFunc(svr.handleAdminUpdateUserEmail)
+	adminUserRouter.Path("/{userID}/affiliations").Methods("GET").
+		HandlerFunc(svr.handleAdminGetUserAffiliations)
 	adminUserRouter.Path("/{userID}/affiliations").Methods("POST").
-		HandlerFunc(svr.handleTODO) // TODO
+		HandlerFunc(svr.handleAdminChangeUserAffiliations)
 	adminUserRouter.Path("/{userID}/password").Methods("POST").
 		HandlerFunc(svr.handleAdminUpdateUserPassword)
 	adminUserRouter.Path("/{userID}/activate").Methods("POST").
 		HandlerFunc(svr.handleAdminActivateUser)
 	adminUserRouter.Path("/{userID}/deactivate").Methods("POST").
 		HandlerFunc(svr.handleAdminDeactivateUser)
+	adminUserRouter.Path("/{userID}/unlock").Methods("POST").
+		HandlerFunc(svr.handleAdminUnlockUser)
+	adminUserRouter.Path("/{userID}/invitation/resend").Methods("POST").
+		HandlerFunc(svr.handleAdminResendInvitation)
+	adminUserRouter.Path("/{userID}/impersonate").Methods("POST").
+		HandlerFunc(svr.requireAuth(credentialsOnly,
+			svr.handleAdminImpersonateUser))
+	adminUserRouter.Path("/{userID}/sessions").Methods("GET").
+		HandlerFunc(svr.handleAdminGetUserSessions)
+	adminUserRouter.Path("/{userID}/sessions/revoke").Methods("POST").
+		HandlerFunc(svr.handleAdminRevokeUserSessions)
+	adminUserRouter.Path("/{userID}/sessions/{sessionID}/revoke").
+		Methods("POST").HandlerFunc(svr.handleAdminRevokeUserSession)
+
+	adminRouter.Path("/applications/{appID}/attachments").Methods("GET").
+		HandlerFunc(svr.requireAuth(authConfig{
+			permission: app.PermissionUsersManage,
+			scope:      "admin",
+		}, svr.handleAdminGetApplicationAttachments))
 
 	adminOrgRouter := adminRouter.PathPrefix("/organizations").Subrouter()
+	adminOrgRouter.Use(svr.requireAuthMiddleware(authConfig{
+		permission: app.PermissionOrganizationsManage,
+		scope:      "admin",
+	}))
 	adminOrgRouter.Path("").Methods("GET").
 		HandlerFunc(svr.handleGetAllOrganizations)
+	adminOrgRouter.Path("/archived").Methods("GET").
+		HandlerFunc(svr.handleAdminGetArchivedOrganizations)
 	adminOrgRouter.Path("/create").Methods("POST").
 		HandlerFunc(svr.handleAdminCreateOrganization)
 	adminOrgRouter.Path("/{orgID}").Methods("GET").
 		HandlerFunc(svr.handleAdminGetOrganizationByID)
 	adminOrgRouter.Path("/{orgID}/update").Methods("POST").
 		HandlerFunc(svr.handleAdminUpdateOrganization)
-	adminOrgRouter.Path("/{orgID}/delete").Methods("POST").
-		HandlerFunc(svr.handleAdminDeleteOrganization)
+	adminOrgRouter.Path("/{orgID}/logo").Methods("POST").
+		HandlerFunc(svr.handleAdminSetOrganizationLogo)
+	adminOrgRouter.Path("/{orgID}/logo/delete").Methods("POST").
+		HandlerFunc(svr.handleAdminDeleteOrganizationLogo)
+	adminOrgRouter.Path("/{orgID}/archive").Methods("POST").
+		HandlerFunc(svr.handleAdminArchiveOrganization)
+	adminOrgRouter.Path("/{orgID}/restore").Methods("POST").
+		HandlerFunc(svr.handleAdminRestoreOrganization)
+	adminOrgRouter.Path("/{orgID}/purge").Methods("POST").
+		HandlerFunc(svr.handleAdminPurgeOrganization)
+	adminOrgRouter.Path("/{orgID}/sso").Methods("GET").
+		HandlerFunc(svr.handleAdminGetOrganizationSSO)
+	adminOrgRouter.Path("/{orgID}/sso").Methods("POST").
+		HandlerFunc(svr.handleAdminSetOrganizationSSO)
+	adminOrgRouter.Path("/{orgID}/sso/delete").Methods("POST").
+		HandlerFunc(svr.handleAdminDeleteOrganizationSSO)
 
 	// Sponsor subroutes.
 	sponsorRouter := router.PathPrefix("/sponsor").Subrouter()
 	sponsorRouter.Use(svr.requireAuthMiddleware(authConfig{
-		requireRole:  true,
-		allowedRoles: []app.Role{app.RoleSponsor},
+		scope: "sponsor",
 	}))
 
+	sponsorCatalogAuth := svr.requireAuthMiddleware(authConfig{
+		permission: app.PermissionCatalogManage,
+		scope:      "sponsor",
+	})
+
 	sponsorVendorRouter := sponsorRouter.PathPrefix("/vendor").Subrouter()
+	sponsorVendorRouter.Use(sponsorCatalogAuth)
 	sponsorVendorRouter.Path("/search").Methods("GET").
 		HandlerFunc(svr.handleSponsorVendorSearch)
 	sponsorVendorRouter.Path("/products/{productID}").Methods("GET").
@@ -152,6 +273,7 @@ func NewServer(logger *logrus.Logger, db app.DataStore, cv app.CommerceVendor,
 		HandlerFunc(svr.handleSponsorAddVendorProduct)
 
 	sponsorCatalogRouter := sponsorRouter.PathPrefix("/catalog").Subrouter()
+	sponsorCatalogRouter.Use(sponsorCatalogAuth)
 	sponsorCatalogRouter.Path("").Methods("GET").
 		HandlerFunc(svr.handleGetSponsorCatalog)
 	sponsorCatalogRouter.Path("/products/{productID}").Methods("GET").
@@ -159,43 +281,126 @@ func NewServer(logger *logrus.Logger, db app.DataStore, cv app.CommerceVendor,
 	sponsorCatalogRouter.Path("/products/{productID}/remove").Methods("POST").
 		HandlerFunc(svr.handleSponsorRemoveProduct)
 
+	// Any sponsor may see and switch between their own organizations.
+	sponsorRouter.Path("/organizations").Methods("GET").
+		HandlerFunc(svr.requireAuth(authConfig{
+			permission: app.PermissionOrganizationView,
+			scope:      "sponsor",
+		}, svr.handleSponsorGetMyOrganizations))
+	// API tokens have no session to switch, so they use a header instead.
+	sponsorRouter.Path("/organization/switch").Methods("POST").
+		HandlerFunc(svr.requireAuth(authConfig{
+			permission:  app.PermissionOrganizationView,
+			scope:       "sponsor",
+			sessionOnly: true,
+		}, svr.handleSponsorSwitchOrganization))
+
+	sponsorRouter.Path("/audit").Methods("GET").
+		HandlerFunc(svr.requireAuth(authConfig{
+			permission: app.PermissionOrganizationAuditView,
+			scope:      "sponsor",
+		}, svr.handleSponsorGetAuditEvents))
+
 	sponsorOrgRouter := sponsorRouter.PathPrefix("/organization").Subrouter()
+	sponsorOrgRouter.Use(svr.requireAuthMiddleware(authConfig{
+		permission: app.PermissionOrganizationManage,
+		scope:      "sponsor",
+	}))
 	sponsorOrgRouter.Path("").Methods("GET").
 		HandlerFunc(svr.handleSponsorGetOwnOrganization)
 	sponsorOrgRouter.Path("/update").Methods("POST").
 		HandlerFunc(svr.handleSponsorUpdateOwnOrganization)
+	sponsorOrgRouter.Path("/logo").Methods("POST").
+		HandlerFunc(svr.handleSponsorSetOrganizationLogo)
+	sponsorOrgRouter.Path("/logo/delete").Methods("POST").
+		HandlerFunc(svr.handleSponsorDeleteOrganizationLogo)
+	sponsorOrgRouter.Path("/application-form").Methods("GET").
+		HandlerFunc(svr.handleSponsorGetApplicationForm)
+	sponsorOrgRouter.Path("/application-form/update").Methods("POST").
+		HandlerFunc(svr.handleSponsorUpdateApplicationForm)
 
 	sponsorDriverRouter := sponsorRouter.PathPrefix("/drivers").Subrouter()
+	sponsorDriverRouter.Use(svr.requireAuthMiddleware(authConfig{
+		permission: app.PermissionDriversManage,
+		scope:      "sponsor",
+	}))
 	sponsorDriverRouter.Path("").Methods("GET").
 		HandlerFunc(svr.handleTODO) // TODO
 	sponsorDriverRouter.Path("/{driverID}").Methods("GET").
 		HandlerFunc(svr.handleTODO) // TODO
 	sponsorDriverRouter.Path("/{driverID}/points").Methods("POST").
-		HandlerFunc(svr.handleTODO) // TODO
+		HandlerFunc(svr.requireAuth(authConfig{
+			permission: app.PermissionPointsAward,
+			scope:      "sponsor",
+		}, svr.handleTODO)) // TODO
 	sponsorDriverRouter.Path("/{driverID}/remove").Methods("POST").
 		HandlerFunc(svr.handleTODO) // TODO
 
+	sponsorInviteRouter := sponsorRouter.PathPrefix("/invitations").Subrouter()
+	sponsorInviteRouter.Use(svr.requireAuthMiddleware(authConfig{
+		permission: app.PermissionDriversManage,
+		scope:      "sponsor",
+	}))
+	sponsorInviteRouter.Path("").Methods("GET").
+		HandlerFunc(svr.handleSponsorGetInvitations)
+	sponsorInviteRouter.Path("/create").Methods("POST").
+		HandlerFunc(svr.handleSponsorCreateInvitation)
+	sponsorInviteRouter.Path("/{invitationID}/revoke").Methods("POST").
+		HandlerFunc(svr.handleSponsorRevokeInvitation)
+
 	sponsorAppRouter := sponsorRouter.PathPrefix("/applications").Subrouter()
+	sponsorAppRouter.Use(svr.requireAuthMiddleware(authConfig{
+		permission: app.PermissionApplicationsReview,
+		scope:      "sponsor",
+	}))
 	sponsorAppRouter.Path("").Methods("GET").
 		HandlerFunc(svr.handleGetApplicationsForOrganization)
+	sponsorAppRouter.Path("/decide").Methods("POST").
+		HandlerFunc(svr.handleBulkDecideApplications)
 	sponsorAppRouter.Path("/{appID}").Methods("GET").
-		HandlerFunc(svr.handleGetApplicationByID)
-	sponsorAppRouter.Path("{appID}/approve").Methods("POST").
+		HandlerFunc(svr.handleSponsorGetApplicationByID)
+	sponsorAppRouter.Path("/{appID}/history").Methods("GET").
+		HandlerFunc(svr.handleSponsorGetApplicationHistory)
+	sponsorAppRouter.Path("/{appID}/attachments").Methods("GET").
+		HandlerFunc(svr.handleSponsorGetApplicationAttachments)
+	sponsorAppRouter.Path("/{appID}/approve").Methods("POST").
 		HandlerFunc(svr.handleApproveApplication)
+	sponsorAppRouter.Path("/{appID}/hold").Methods("POST").
+		HandlerFunc(svr.handleHoldApplication)
+	sponsorAppRouter.Path("/{appID}/resume").Methods("POST").
+		HandlerFunc(svr.handleResumeApplication)
+	sponsorAppRouter.Path("/{appID}/request-info").Methods("POST").
+		HandlerFunc(svr.handleRequestApplicationInfo)
 
 	driverRouter := router.PathPrefix("/driver").Subrouter()
+	driverRouter.Use(svr.requireAuthMiddleware(authConfig{
+		permission: app.PermissionApplicationsSubmit,
+		scope:      "driver",
+	}))
 
 	driverRouter.Path("/applications/submit").Methods("POST").
 		HandlerFunc(svr.handleSubmitApplication)
 	driverRouter.Path("/applications/{appID}").Methods("GET").
 		HandlerFunc(svr.handleGetApplicationByID)
+	driverRouter.Path("/applications/{appID}/history").Methods("GET").
+		HandlerFunc(svr.handleGetApplicationHistory)
+	driverRouter.Path("/applications/{appID}/withdraw").Methods("POST").
+		HandlerFunc(svr.handleWithdrawApplication)
+	driverRouter.Path("/applications/{appID}/respond").Methods("POST").
+		HandlerFunc(svr.handleRespondToApplication)
+	driverRouter.Path("/applications/{appID}/attachments").Methods("GET").
+		HandlerFunc(svr.handleGetApplicationAttachments)
+	driverRouter.Path("/applications/{appID}/attachments").Methods("POST").
+		HandlerFunc(svr.handleUploadAttachment)
 	driverRouter.Path("/applications").Methods("GET").
 		HandlerFunc(svr.handleGetMyApplications)
 
 	driverRouter.Path("/balances").Methods("GET").
 		HandlerFunc(svr.handleDriverGetBalances)
 	driverRouter.Path("/organizations/all").Methods("GET").
-		HandlerFunc(svr.handleGetAllOrganizations)
+		HandlerFunc(svr.handleDriverGetOrganizations)
+	driverRouter.Path("/organizations/{orgID}/application-form").
+		Methods("GET").HandlerFunc(svr.handleGetApplicationForm)
 	driverRouter.Path("/catalog/{orgID}/search").Methods("GET").
 		HandlerFunc(svr.handleTODO) // TODO
 
@@ -235,7 +440,6 @@ func (svr *Server) useHTTPS() bool {
 }
 
 // protocol returns the protocol the app will use for communication.
-// nolint: unused // FIXME remove this once used.
 func (svr *Server) protocol() string {
 	if svr.useHTTPS() {
 		return "https"
@@ -243,6 +447,17 @@ func (svr *Server) protocol() string {
 	return "http"
 }
 
+// baseURL returns the URL that the web app is served from, without a trailing
+// slash. The API is available under the /api path.
+func (svr *Server) baseURL() string {
+	url := svr.protocol() + "://" + svr.hostname()
+	if svr.config.Tier == TierLocal {
+		// Nginx listens on a different port locally.
+		url += ":8000"
+	}
+	return url
+}
+
 // sendJSONResponse will marshal the given data to JSON and write it to the
 // http ResponseWriter.
 func (svr *Server) sendJSONResponse(w http.ResponseWriter, data interface{}) {
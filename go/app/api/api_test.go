@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
 )
 
 func newTestAPI(
@@ -18,11 +19,46 @@ func newTestAPI(
 
 	logger, hook := logtest.NewNullLogger()
 
-	api, err := NewServer(logger, db, cv, Config{
-		Tier: TierLocal,
-		Port: 8080,
-	})
+	api, err := NewServer(logger, db, cv, &mock.Mailer{}, &mock.FileStore{},
+		&mock.FileScanner{Infected: []byte("EICAR")}, Config{
+			Tier:                    TierLocal,
+			Port:                    8080,
+			Passwords:               app.DefaultPasswordPolicy,
+			Invitations:             app.DefaultAccountInvitationLifetime,
+			OrganizationInvitations: app.DefaultInvitationLifetime,
+			OrganizationRetention:   app.DefaultOrganizationRetention,
+			Attachments:             app.DefaultAttachmentPolicy,
+			AttachmentURLKey:        []byte("attachment url key"),
+		})
 	require.NoError(t, err, "failed to instantiate test api server")
 
 	return api, logger, hook
 }
+
+// testRolePermissions mirrors the permissions that the database migrations
+// grant to each role, for mocks of GetPermissionsForRole.
+var testRolePermissions = map[app.Role][]app.Permission{
+	app.RoleAdmin: {
+		app.PermissionAuditView,
+		app.PermissionOrganizationsManage,
+		app.PermissionProfileManage,
+		app.PermissionUsersManage,
+	},
+	app.RoleSponsor: {
+		app.PermissionApplicationsReview,
+		app.PermissionCatalogManage,
+		app.PermissionDriversManage,
+		app.PermissionOrganizationAuditView,
+		app.PermissionOrganizationManage,
+		app.PermissionOrganizationView,
+		app.PermissionPointsAward,
+		app.PermissionProfileManage,
+	},
+	app.RoleDriver: {
+		app.PermissionApplicationsSubmit,
+		app.PermissionProfileManage,
+	},
+	app.RoleUser: {
+		app.PermissionApplicationsSubmit,
+	},
+}
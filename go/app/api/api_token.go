@@ -0,0 +1,325 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+const contextKeyAPIToken contextKey = "apiToken"
+
+// bearerPrefix precedes the token in an Authorization header.
+const bearerPrefix = "Bearer "
+
+// apiTokenTouchInterval is the minimum amount of time between recording uses
+// of an API token, to avoid writing to the database on every request.
+const apiTokenTouchInterval = time.Minute
+
+// maxAPITokenNameLength is the maximum number of characters in the name of an
+// API token.
+const maxAPITokenNameLength = 100
+
+// rejectAPIToken responds to a request whose bearer token could not be used,
+// including a challenge so that clients know to supply a bearer token.
+func (svr *Server) rejectAPIToken(
+	w http.ResponseWriter,
+	err error,
+	userMessage string,
+) {
+
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	svr.sendErrorResponse(w, err, http.StatusUnauthorized, userMessage)
+}
+
+// authenticateAPIToken authenticates a request bearing an API token in its
+// Authorization header. On success, returns a request whose context holds the
+// token and a session for its owner, so that handlers need not care how the
+// request was authenticated.
+//
+// Writes an error response and returns false upon failure. Unlike cookies, a
+// bad bearer token is always an error, since the client chose to send it.
+func (svr *Server) authenticateAPIToken(
+	w http.ResponseWriter,
+	r *http.Request,
+	header string,
+) (*http.Request, bool) {
+
+	if !strings.HasPrefix(header, bearerPrefix) {
+		svr.rejectAPIToken(w, errors.New("unsupported authorization scheme"),
+			"Authorization header must use the Bearer scheme.")
+		return nil, false
+	}
+
+	value := strings.TrimSpace(strings.TrimPrefix(header, bearerPrefix))
+	token, err := app.ParseSecureToken(value)
+	if err != nil {
+		svr.rejectAPIToken(w, errors.Wrap(err, "malformed api token"),
+			"Invalid API token.")
+		return nil, false
+	}
+
+	t, err := svr.db.GetAPITokenByToken(r.Context(), token)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.rejectAPIToken(w, err, "Invalid API token.")
+		return nil, false
+	} else if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to get api token"),
+			http.StatusInternalServerError, "")
+		return nil, false
+	}
+
+	if !t.IsValid() {
+		svr.rejectAPIToken(w,
+			errors.Errorf("api token %d is expired or revoked", t.ID),
+			"This API token has expired or been revoked.")
+		return nil, false
+	}
+
+	p, err := svr.db.GetPersonByID(r.Context(), t.PersonID)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to get owner of api token"),
+			http.StatusInternalServerError, "")
+		return nil, false
+	}
+
+	s := app.Session{
+		Person:       p,
+		CreatedAt:    t.CreatedAt,
+		ExpiresAt:    t.ExpiresAt,
+		MaxExpiresAt: t.ExpiresAt,
+		IPAddress:    clientIP(r),
+		LastSeenAt:   time.Now().UTC().Round(time.Second),
+	}
+
+	if !s.IsValid() {
+		svr.rejectAPIToken(w,
+			errors.Errorf("owner of api token %d is deactivated", t.ID),
+			"This API token has expired or been revoked.")
+		return nil, false
+	}
+
+	if err = svr.loadPermissions(r.Context(), &s); err != nil {
+		svr.sendErrorResponse(w, err, http.StatusInternalServerError, "")
+		return nil, false
+	}
+
+	svr.touchAPIToken(r, &t)
+
+	ctx := contextWithSession(r.Context(), s)
+	ctx = context.WithValue(ctx, contextKeyAPIToken, t)
+	return r.WithContext(ctx), true
+}
+
+// touchAPIToken records the use of an API token, unless its use was recorded
+// recently.
+//
+// Failures are logged but otherwise ignored, since they need not prevent the
+// request from being handled.
+func (svr *Server) touchAPIToken(r *http.Request, t *app.APIToken) {
+	now := time.Now().UTC().Round(time.Second)
+
+	if t.LastUsedAt.Valid &&
+		now.Sub(t.LastUsedAt.Time) < apiTokenTouchInterval {
+
+		return
+	}
+
+	if err := svr.db.TouchAPIToken(r.Context(), t.ID, now); err != nil {
+		svr.logger.
+			WithError(err).
+			WithField("api_token_id", t.ID).
+			Warn("failed to record api token use")
+		return
+	}
+
+	t.LastUsedAt = null.TimeFrom(now)
+}
+
+// getAPITokenFromContext retrieves the API token used to authenticate the
+// request from the request context, and returns nil if the request was not
+// authenticated by an API token.
+//
+// This only works if authContextMiddleware has already run for this request.
+func getAPITokenFromContext(ctx context.Context) *app.APIToken {
+	t, ok := ctx.Value(contextKeyAPIToken).(app.APIToken)
+	if !ok {
+		return nil
+	}
+	return &t
+}
+
+type apiTokenCreateRequest struct {
+	Name      string              `json:"name"`
+	Scopes    []app.APITokenScope `json:"scopes"`
+	ExpiresAt time.Time           `json:"expires_at"`
+}
+
+func (req *apiTokenCreateRequest) validateFields() (message string, err error) {
+	defer func() {
+		if message != "" {
+			err = errors.New(message)
+		}
+	}()
+
+	req.Name = strings.TrimSpace(req.Name)
+
+	if len(req.Name) < 1 {
+		message = "Token name cannot be blank."
+	} else if len(req.Name) > maxAPITokenNameLength {
+		message = "Token name is too long."
+	} else if len(req.Scopes) < 1 {
+		message = "Token must have at least one scope."
+	}
+
+	for _, scope := range req.Scopes {
+		if message == "" && !scope.IsValid() {
+			message = "Unknown scope '" + string(scope) + "'."
+		}
+	}
+
+	if message == "" && req.ExpiresAt.IsZero() {
+		message = "Token must have an expiration."
+	}
+
+	return
+}
+
+// apiTokenCreateResponse is sent only once, upon creation of a token. The
+// secret value can never be retrieved again.
+type apiTokenCreateResponse struct {
+	app.APIToken
+	Token string `json:"token"`
+}
+
+func (svr *Server) handleMyGetAPITokens(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	_, userID, ok := svr.getMyProfileUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ts, err := svr.db.GetAPITokensForPerson(r.Context(), userID, false)
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to get api tokens"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	if ts == nil {
+		ts = make([]app.APIToken, 0)
+	}
+
+	svr.sendJSONResponse(w, ts)
+}
+
+func (svr *Server) handleMyCreateAPIToken(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	_, userID, ok := svr.getMyProfileUserID(w, r)
+	if !ok {
+		return
+	}
+
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var req apiTokenCreateRequest
+	if err := d.Decode(&req); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to decode body"),
+			http.StatusBadRequest, "Malformed request body.")
+		return
+	}
+
+	if msg, err := req.validateFields(); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "invalid token request"),
+			http.StatusBadRequest, msg)
+		return
+	}
+
+	t, err := app.NewAPIToken(userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "invalid token request"),
+			http.StatusBadRequest,
+			"Token must expire in the future and within %d days.",
+			int(app.MaxAPITokenLifetime/(24*time.Hour)))
+		return
+	}
+
+	if t.ID, err = svr.db.CreateAPIToken(r.Context(), *t); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to store api token"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	svr.sendJSONResponse(w, apiTokenCreateResponse{
+		APIToken: *t,
+		Token:    t.Token.String(),
+	})
+}
+
+func (svr *Server) handleMyRevokeAPIToken(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	_, userID, ok := svr.getMyProfileUserID(w, r)
+	if !ok {
+		return
+	}
+
+	pathParams := mux.Vars(r)
+
+	tokenID, err := strconv.Atoi(pathParams["tokenID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "tokenID must be an integer"),
+			http.StatusBadRequest, "Token ID must be an integer.")
+		return
+	}
+
+	ts, err := svr.db.GetAPITokensForPerson(r.Context(), userID, false)
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to get api tokens"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	owned := false
+	for _, t := range ts {
+		if t.ID == tokenID {
+			owned = true
+			break
+		}
+	}
+
+	if !owned {
+		svr.sendErrorResponse(w,
+			errors.Errorf("person %d has no active api token %d",
+				userID, tokenID),
+			http.StatusNotFound, "No such token.")
+		return
+	}
+
+	if err = svr.db.RevokeAPIToken(r.Context(), tokenID); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to revoke api token"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
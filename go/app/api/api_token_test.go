@@ -0,0 +1,320 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+type apiTokenMockDB struct {
+	*mock.DB
+
+	person  app.Person
+	session app.Session
+	tokens  map[app.SecureToken]app.APIToken
+
+	created   []app.APIToken
+	touchedID int
+	revokedID int
+}
+
+func (db *apiTokenMockDB) GetPersonByID(
+	_ context.Context,
+	_ int,
+) (app.Person, error) {
+
+	return db.person, nil
+}
+
+func (db *apiTokenMockDB) GetSessionByToken(
+	_ context.Context,
+	_ app.SecureToken,
+) (app.Session, error) {
+
+	return db.session, nil
+}
+
+func (db *apiTokenMockDB) GetAPITokenByToken(
+	_ context.Context,
+	token app.SecureToken,
+) (app.APIToken, error) {
+
+	t, ok := db.tokens[token]
+	if !ok {
+		return app.APIToken{}, app.ErrNotFound
+	}
+	return t, nil
+}
+
+func (db *apiTokenMockDB) GetAPITokensForPerson(
+	_ context.Context,
+	personID int,
+	_ bool,
+) ([]app.APIToken, error) {
+
+	var ts []app.APIToken
+	for _, t := range db.tokens {
+		if t.PersonID == personID && t.IsValid() {
+			ts = append(ts, t)
+		}
+	}
+	return ts, nil
+}
+
+func (db *apiTokenMockDB) CreateAPIToken(
+	_ context.Context,
+	t app.APIToken,
+) (int, error) {
+
+	db.created = append(db.created, t)
+	return len(db.created), nil
+}
+
+func (db *apiTokenMockDB) TouchAPIToken(
+	_ context.Context,
+	tokenID int,
+	_ time.Time,
+) error {
+
+	db.touchedID = tokenID
+	return nil
+}
+
+func (db *apiTokenMockDB) RevokeAPIToken(
+	_ context.Context,
+	tokenID int,
+) error {
+
+	db.revokedID = tokenID
+	return nil
+}
+
+func (db *apiTokenMockDB) GetPermissionsForRole(
+	_ context.Context,
+	role app.Role,
+) ([]app.Permission, error) {
+
+	return testRolePermissions[role], nil
+}
+
+func TestAPITokenAuth(t *testing.T) {
+	me := app.Person{ID: 1, Role: app.RoleDriver}
+
+	newToken := func(id int, scopes ...app.APITokenScope) app.APIToken {
+		tok, err := app.NewAPIToken(me.ID, "script", scopes,
+			time.Now().Add(time.Hour))
+		require.NoError(t, err)
+
+		tok.ID = id
+		return *tok
+	}
+
+	reader := newToken(1, app.ScopeDriverRead)
+	writer := newToken(2, app.ScopeDriverWrite, app.ScopeMyWrite)
+	expired := newToken(3, app.ScopeDriverRead)
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	unknown := newToken(4, app.ScopeDriverRead)
+
+	db := &apiTokenMockDB{
+		person: me,
+		tokens: map[app.SecureToken]app.APIToken{
+			reader.Token:  reader,
+			writer.Token:  writer,
+			expired.Token: expired,
+		},
+	}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	testCases := []struct {
+		alias           string
+		method          string
+		path            string
+		header          string
+		expectCode      int
+		expectTouchedID int
+	}{
+		{
+			alias:           "ReadScope",
+			method:          "GET",
+			path:            "/driver/balances",
+			header:          bearerPrefix + reader.Token.String(),
+			expectCode:      http.StatusOK,
+			expectTouchedID: reader.ID,
+		},
+		{
+			alias:           "WriteImpliesRead",
+			method:          "GET",
+			path:            "/driver/balances",
+			header:          bearerPrefix + writer.Token.String(),
+			expectCode:      http.StatusOK,
+			expectTouchedID: writer.ID,
+		},
+		{
+			alias:           "MissingWriteScope",
+			method:          "POST",
+			path:            "/driver/applications/submit",
+			header:          bearerPrefix + reader.Token.String(),
+			expectCode:      http.StatusForbidden,
+			expectTouchedID: reader.ID,
+		},
+		{
+			alias:           "MissingArea",
+			method:          "GET",
+			path:            "/sponsor/catalog",
+			header:          bearerPrefix + writer.Token.String(),
+			expectCode:      http.StatusForbidden,
+			expectTouchedID: writer.ID,
+		},
+		{
+			alias:           "SessionOnly",
+			method:          "GET",
+			path:            "/my/tokens",
+			header:          bearerPrefix + writer.Token.String(),
+			expectCode:      http.StatusForbidden,
+			expectTouchedID: writer.ID,
+		},
+		{
+			alias:      "Expired",
+			method:     "GET",
+			path:       "/driver/balances",
+			header:     bearerPrefix + expired.Token.String(),
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			alias:      "Unknown",
+			method:     "GET",
+			path:       "/driver/balances",
+			header:     bearerPrefix + unknown.Token.String(),
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			alias:      "Malformed",
+			method:     "GET",
+			path:       "/driver/balances",
+			header:     bearerPrefix + "abc123",
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			alias:      "WrongScheme",
+			method:     "GET",
+			path:       "/driver/balances",
+			header:     "Basic " + reader.Token.String(),
+			expectCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			db.touchedID = 0
+
+			r := httptest.NewRequest(tc.method, tc.path, nil)
+			r.Header.Set("Authorization", tc.header)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectTouchedID, db.touchedID)
+
+			if tc.expectCode == http.StatusUnauthorized {
+				assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestMyAPITokens(t *testing.T) {
+	me := app.Person{ID: 1, Role: app.RoleDriver}
+	them := app.Person{ID: 2, Role: app.RoleDriver}
+
+	s, err := app.NewSession(me, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	theirs, err := app.NewAPIToken(them.ID, "theirs",
+		[]app.APITokenScope{app.ScopeDriverRead}, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	theirs.ID = 7
+
+	db := &apiTokenMockDB{
+		person:  me,
+		session: *s,
+		tokens:  map[app.SecureToken]app.APIToken{theirs.Token: *theirs},
+	}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		testSessionTokenInject(t, r, s.Token)
+
+		api.router.ServeHTTP(w, r)
+		return w
+	}
+
+	expires := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	t.Run("ListEmpty", func(t *testing.T) {
+		w := serve("GET", "/my/tokens", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		w := serve("POST", "/my/tokens/create", `{
+			"name": "fleet sync",
+			"scopes": ["driver:read"],
+			"expires_at": "`+expires+`"
+		}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var res struct {
+			ID    int    `json:"id"`
+			Token string `json:"token"`
+		}
+		err := json.NewDecoder(w.Body).Decode(&res)
+		require.NoError(t, err)
+
+		require.Len(t, db.created, 1)
+		assert.Equal(t, me.ID, db.created[0].PersonID)
+		assert.Equal(t, "fleet sync", db.created[0].Name)
+
+		token, err := app.ParseSecureToken(res.Token)
+		require.NoError(t, err)
+		assert.Equal(t, db.created[0].Token, token)
+	})
+
+	t.Run("CreateUnknownScope", func(t *testing.T) {
+		w := serve("POST", "/my/tokens/create", `{
+			"name": "fleet sync",
+			"scopes": ["everything"],
+			"expires_at": "`+expires+`"
+		}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("CreateTooLong", func(t *testing.T) {
+		tooLate := time.Now().Add(2 * app.MaxAPITokenLifetime)
+		w := serve("POST", "/my/tokens/create", `{
+			"name": "fleet sync",
+			"scopes": ["driver:read"],
+			"expires_at": "`+tooLate.Format(time.RFC3339)+`"
+		}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("RevokeOthers", func(t *testing.T) {
+		w := serve("POST", "/my/tokens/7/revoke", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Zero(t, db.revokedID)
+	})
+}
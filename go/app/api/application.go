@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -12,14 +13,23 @@ import (
 )
 
 type applicationApprovalRequest struct {
-	IsApproved    bool   `json:"is_approved"`
-	Reason        string `json:"reason"`
-	ApplicationID int    `json:"application_id"`
+	IsApproved bool   `json:"is_approved"`
+	Reason     string `json:"reason"`
+}
+
+type applicationMessageRequest struct {
+	Message string `json:"message"`
+}
+
+type applicationAnswerRequest struct {
+	QuestionID int             `json:"question_id"`
+	Value      json.RawMessage `json:"value"`
 }
 
 type applicationSubmissionRequest struct {
-	OrganizationID int    `json:"organization_id"`
-	Comment        string `json:"comment"`
+	OrganizationID int                        `json:"organization_id"`
+	Comment        string                     `json:"comment"`
+	Answers        []applicationAnswerRequest `json:"answers"`
 }
 
 func (svr *Server) handleSubmitApplication(
@@ -46,13 +56,71 @@ func (svr *Server) handleSubmitApplication(
 		return
 	}
 
-	_, err := svr.db.CreateApplication(r.Context(), app.Application{
+	_, err := svr.db.GetOrganizationByID(r.Context(), appReq.OrganizationID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest,
+			"No such organization.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to get organization"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	form, err := svr.db.GetApplicationForm(r.Context(), appReq.OrganizationID)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to get application form"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	answers := make([]app.ApplicationAnswer, len(appReq.Answers))
+	for i, a := range appReq.Answers {
+		answers[i] = app.ApplicationAnswer{
+			QuestionID: a.QuestionID,
+			Value:      a.Value,
+		}
+	}
+
+	answers, message, err := app.ValidateApplicationAnswers(form, answers)
+	if err != nil {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest, message)
+		return
+	}
+
+	_, err = svr.db.CreateApplication(r.Context(), app.Application{
 		ApplicantID:    s.Person.ID,
 		OrganizationID: appReq.OrganizationID,
 		Comment:        appReq.Comment,
+		Answers:        answers,
 	})
 
-	if err != nil {
+	var cooldown *app.ReapplyCooldownError
+	if errors.Is(err, app.ErrOrganizationArchived) {
+		svr.sendErrorResponse(w, err, http.StatusConflict,
+			"This organization is no longer accepting applications.")
+		return
+	} else if errors.Is(err, app.ErrNotAcceptingApplications) {
+		svr.sendErrorResponse(w, err, http.StatusConflict,
+			"This organization is not accepting applications right now.")
+		return
+	} else if errors.Is(err, app.ErrApplicationOpen) {
+		svr.sendErrorResponse(w, err, http.StatusConflict,
+			"You already have an open application to this organization.")
+		return
+	} else if errors.Is(err, app.ErrAlreadyAffiliated) {
+		svr.sendErrorResponse(w, err, http.StatusConflict,
+			"You already belong to this organization.")
+		return
+	} else if errors.As(err, &cooldown) {
+		svr.sendErrorResponse(w, err, http.StatusConflict,
+			"Your last application to this organization was rejected. "+
+				"You may apply again after %s.",
+			cooldown.Until.UTC().Format("January 2, 2006 at 3:04 PM MST"))
+		return
+	} else if err != nil {
 		svr.sendErrorResponse(w,
 			errors.Wrap(err, "failed to create application"),
 			http.StatusInternalServerError, "")
@@ -62,10 +130,14 @@ func (svr *Server) handleSubmitApplication(
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (svr *Server) handleGetApplicationByID(
+// getApplicationFromURL fetches the application identified by the appID path
+// parameter.
+//
+// Upon failure, writes an error to the ResponseWriter and returns false.
+func (svr *Server) getApplicationFromURL(
 	w http.ResponseWriter,
 	r *http.Request,
-) {
+) (app.Application, bool) {
 
 	pathParams := mux.Vars(r)
 
@@ -73,7 +145,7 @@ func (svr *Server) handleGetApplicationByID(
 	if err != nil {
 		svr.sendErrorResponse(w, errors.Wrap(err, "appID must be an integer"),
 			http.StatusBadRequest, "Application ID must be an integer.")
-		return
+		return app.Application{}, false
 	}
 
 	a, err := svr.db.GetApplicationByID(r.Context(), appID)
@@ -81,7 +153,7 @@ func (svr *Server) handleGetApplicationByID(
 		svr.sendErrorResponse(w,
 			errors.Wrapf(err, "no application with ID of %d", appID),
 			http.StatusNotFound, "No such application.")
-		return
+		return a, false
 	} else if err != nil {
 		svr.sendErrorResponse(
 			w,
@@ -89,88 +161,334 @@ func (svr *Server) handleGetApplicationByID(
 			http.StatusInternalServerError,
 			"",
 		)
-		return
+		return a, false
 	}
 
-	svr.sendJSONResponse(w, a)
+	return a, true
+}
+
+// getMyApplicationFromURL fetches the application identified by the appID
+// path parameter, which must have been submitted by the requester.
+//
+// Upon failure, writes an error to the ResponseWriter and returns false.
+func (svr *Server) getMyApplicationFromURL(
+	w http.ResponseWriter,
+	r *http.Request,
+) (app.Application, bool) {
+
+	s := getSessionFromContext(r.Context())
+	if s == nil {
+		svr.sendErrorResponse(w, errors.New("no session"),
+			http.StatusUnauthorized, "")
+		return app.Application{}, false
+	}
+
+	a, ok := svr.getApplicationFromURL(w, r)
+	if !ok {
+		return a, false
+	}
+
+	// Other people's applications are none of this driver's business, so do
+	// not reveal that they exist.
+	if a.ApplicantID != s.Person.ID {
+		svr.sendErrorResponse(w,
+			errors.Errorf("application %d is from person %d, not %d",
+				a.ID, a.ApplicantID, s.Person.ID),
+			http.StatusNotFound, "No such application.")
+		return a, false
+	}
+
+	return a, true
 }
 
-func (svr *Server) handleGetApplicationsForOrganization(
+// getSponsorApplicationFromURL fetches the application identified by the appID
+// path parameter, which must be for the sponsor's organization.
+//
+// Upon failure, writes an error to the ResponseWriter and returns false.
+func (svr *Server) getSponsorApplicationFromURL(
 	w http.ResponseWriter,
 	r *http.Request,
+) (app.Application, bool) {
+
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
+		return app.Application{}, false
+	}
+
+	a, ok := svr.getApplicationFromURL(w, r)
+	if !ok {
+		return a, false
+	}
+
+	// Applications to other organizations are none of this sponsor's business,
+	// so do not reveal that they exist.
+	if a.OrganizationID != orgID {
+		svr.sendErrorResponse(w,
+			errors.Errorf("application %d is for org %d, not active org %d",
+				a.ID, a.OrganizationID, orgID),
+			http.StatusNotFound, "No such application.")
+		return a, false
+	}
+
+	return a, true
+}
+
+// decodeApplicationMessage decodes the message that goes with a change to an
+// application, which may be blank unless required.
+//
+// Upon failure, writes an error to the ResponseWriter and returns false.
+func (svr *Server) decodeApplicationMessage(
+	w http.ResponseWriter,
+	r *http.Request,
+	required bool,
+) (string, bool) {
+
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var data applicationMessageRequest
+	if err := d.Decode(&data); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "received bad json data"),
+			http.StatusBadRequest, "Bad JSON data.")
+		return "", false
+	}
+
+	message := strings.TrimSpace(data.Message)
+	if required && len(message) < 1 {
+		svr.sendErrorResponse(w, errors.New("message is blank"),
+			http.StatusBadRequest, "A message is required.")
+		return "", false
+	}
+
+	return message, true
+}
+
+// transitionApplication moves an application from one status to another and
+// responds with no content.
+func (svr *Server) transitionApplication(
+	w http.ResponseWriter,
+	r *http.Request,
+	a app.Application,
+	from, to app.ApplicationStatus,
+	message string,
 ) {
 
-	orgID, err := getOrganizationIDOfSponsor(r)
-	if err != nil {
+	err := svr.db.TransitionApplication(r.Context(), a.ID, from, to, message)
+	if errors.Is(err, app.ErrOrganizationArchived) {
+		svr.sendErrorResponse(w, err, http.StatusConflict,
+			"This organization has been archived.")
+		return
+	} else if errors.Is(err, app.ErrConflict) {
 		svr.sendErrorResponse(w,
-			errors.Wrap(err, "cannot determine sponsor organization identity"),
+			errors.Wrapf(err, "application %d is %s", a.ID, a.Status),
+			http.StatusConflict,
+			"This application can no longer be changed that way.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to transition application"),
 			http.StatusInternalServerError, "")
 		return
 	}
 
-	apps, err := svr.db.GetApplicationsForOrganization(r.Context(), orgID)
-	if err != nil {
-		svr.sendErrorResponse(
-			w,
-			errors.Wrap(err, "failed to retrieve application"),
-			http.StatusInternalServerError,
-			"",
-		)
-		return
-	}
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	if apps == nil {
-		apps = make([]app.Application, 0)
+func (svr *Server) handleGetApplicationByID(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	a, ok := svr.getMyApplicationFromURL(w, r)
+	if !ok {
+		return
 	}
 
-	svr.sendJSONResponse(w, apps)
+	svr.sendJSONResponse(w, a)
 }
 
-func (svr *Server) handleApproveApplication(
+func (svr *Server) handleSponsorGetApplicationByID(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
 
-	pathParams := mux.Vars(r)
-
-	appID, err := strconv.Atoi(pathParams["appID"])
-	if err != nil {
-		svr.sendErrorResponse(w, errors.Wrap(err, "appID must be an integer"),
-			http.StatusBadRequest, "Application ID must be an integer.")
+	a, ok := svr.getSponsorApplicationFromURL(w, r)
+	if !ok {
 		return
 	}
 
+	svr.sendJSONResponse(w, a)
+}
+
+func (svr *Server) handleApproveApplication(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
 	d := json.NewDecoder(r.Body)
 	d.DisallowUnknownFields()
 
 	var data applicationApprovalRequest
-	if err = d.Decode(&data); err != nil {
+	if err := d.Decode(&data); err != nil {
 		svr.sendErrorResponse(w, errors.Wrap(err, "received bad json data"),
 			http.StatusBadRequest, "Bad JSON data.")
 		return
 	}
 
-	app, err := svr.db.GetApplicationByID(r.Context(), appID)
-	if err != nil {
-		svr.sendErrorResponse(w, errors.Wrap(err, "could not get application"),
-			http.StatusInternalServerError, "")
+	a, ok := svr.getSponsorApplicationFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	to := app.ApplicationRejected
+	if data.IsApproved {
+		to = app.ApplicationApproved
+	}
+
+	svr.transitionApplication(w, r, a, a.Status, to, data.Reason)
+}
+
+func (svr *Server) handleHoldApplication(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	message, ok := svr.decodeApplicationMessage(w, r, false)
+	if !ok {
+		return
+	}
+
+	a, ok := svr.getSponsorApplicationFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	svr.transitionApplication(w, r, a, a.Status, app.ApplicationOnHold,
+		message)
+}
+
+func (svr *Server) handleResumeApplication(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	message, ok := svr.decodeApplicationMessage(w, r, false)
+	if !ok {
+		return
+	}
+
+	a, ok := svr.getSponsorApplicationFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	svr.transitionApplication(w, r, a, app.ApplicationOnHold,
+		app.ApplicationSubmitted, message)
+}
+
+func (svr *Server) handleRequestApplicationInfo(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	message, ok := svr.decodeApplicationMessage(w, r, true)
+	if !ok {
+		return
+	}
+
+	a, ok := svr.getSponsorApplicationFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	svr.transitionApplication(w, r, a, a.Status,
+		app.ApplicationInfoRequested, message)
+}
+
+func (svr *Server) handleWithdrawApplication(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	message, ok := svr.decodeApplicationMessage(w, r, false)
+	if !ok {
 		return
 	}
 
-	if svr.requireOrganization(orgConfig{orgID: app.OrganizationID}, w, r) {
+	a, ok := svr.getMyApplicationFromURL(w, r)
+	if !ok {
 		return
 	}
 
-	err = svr.db.UpdateApplicationApproval(r.Context(),
-		data.ApplicationID, data.IsApproved, data.Reason)
+	svr.transitionApplication(w, r, a, a.Status, app.ApplicationWithdrawn,
+		message)
+}
+
+func (svr *Server) handleRespondToApplication(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	message, ok := svr.decodeApplicationMessage(w, r, true)
+	if !ok {
+		return
+	}
+
+	a, ok := svr.getMyApplicationFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	svr.transitionApplication(w, r, a, app.ApplicationInfoRequested,
+		app.ApplicationSubmitted, message)
+}
+
+// sendApplicationHistory responds with the history of an application.
+func (svr *Server) sendApplicationHistory(
+	w http.ResponseWriter,
+	r *http.Request,
+	a app.Application,
+) {
+
+	events, err := svr.db.GetApplicationHistory(r.Context(), a.ID)
 	if err != nil {
 		svr.sendErrorResponse(w,
-			errors.Wrap(err, "failed to approve app"),
+			errors.Wrap(err, "failed to retrieve application history"),
 			http.StatusInternalServerError, "")
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	if events == nil {
+		events = make([]app.ApplicationEvent, 0)
+	}
+
+	svr.sendJSONResponse(w, events)
+}
+
+func (svr *Server) handleGetApplicationHistory(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	a, ok := svr.getMyApplicationFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	svr.sendApplicationHistory(w, r, a)
+}
+
+func (svr *Server) handleSponsorGetApplicationHistory(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	a, ok := svr.getSponsorApplicationFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	svr.sendApplicationHistory(w, r, a)
 }
 
 func (svr *Server) handleGetMyApplications(
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+type applicationFormRequest struct {
+	Questions []app.ApplicationQuestion `json:"questions"`
+}
+
+// sendApplicationForm responds with the application form of an organization.
+func (svr *Server) sendApplicationForm(
+	w http.ResponseWriter,
+	r *http.Request,
+	orgID int,
+) {
+
+	questions, err := svr.db.GetApplicationForm(r.Context(), orgID)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to get application form"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	if questions == nil {
+		questions = make([]app.ApplicationQuestion, 0)
+	}
+
+	svr.sendJSONResponse(w, questions)
+}
+
+func (svr *Server) handleGetApplicationForm(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	orgID, err := strconv.Atoi(pathParams["orgID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "orgID must be an integer"),
+			http.StatusBadRequest, "Organization ID must be an integer.")
+		return
+	}
+
+	_, err = svr.db.GetOrganizationByID(r.Context(), orgID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound,
+			"No such organization.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to get organization"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	svr.sendApplicationForm(w, r, orgID)
+}
+
+func (svr *Server) handleSponsorGetApplicationForm(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
+		return
+	}
+
+	svr.sendApplicationForm(w, r, orgID)
+}
+
+func (svr *Server) handleSponsorUpdateApplicationForm(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
+		return
+	}
+
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var data applicationFormRequest
+	if err := d.Decode(&data); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "received bad json data"),
+			http.StatusBadRequest, "Bad JSON data.")
+		return
+	}
+
+	for i := range data.Questions {
+		q := &data.Questions[i]
+		q.OrganizationID = orgID
+		q.Prompt = strings.TrimSpace(q.Prompt)
+		for j := range q.Choices {
+			q.Choices[j] = strings.TrimSpace(q.Choices[j])
+		}
+
+		if message, err := q.Validate(); err != nil {
+			svr.sendErrorResponse(w, err, http.StatusBadRequest, message)
+			return
+		}
+	}
+
+	err := svr.db.SetApplicationForm(r.Context(), orgID, data.Questions)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest,
+			"One of the questions is not on the application form.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to set application form"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
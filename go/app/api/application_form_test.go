@@ -0,0 +1,296 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+type applicationFormMockDB struct {
+	*authMockDB
+
+	form      []app.ApplicationQuestion
+	created   []app.Application
+	saved     []app.ApplicationQuestion
+	createErr error
+}
+
+func (db *applicationFormMockDB) GetOrganizationByID(
+	_ context.Context,
+	orgID int,
+) (app.Organization, error) {
+
+	if orgID != 1 {
+		return app.Organization{}, errors.Wrapf(app.ErrNotFound, "%d", orgID)
+	}
+	return app.Organization{ID: orgID}, nil
+}
+
+func (db *applicationFormMockDB) GetApplicationForm(
+	_ context.Context,
+	orgID int,
+) ([]app.ApplicationQuestion, error) {
+
+	return db.form, nil
+}
+
+func (db *applicationFormMockDB) SetApplicationForm(
+	_ context.Context,
+	orgID int,
+	questions []app.ApplicationQuestion,
+) error {
+
+	for _, q := range questions {
+		if q.ID > len(db.form) {
+			return errors.Wrapf(app.ErrNotFound, "question %d", q.ID)
+		}
+	}
+
+	db.saved = questions
+	return nil
+}
+
+func (db *applicationFormMockDB) CreateApplication(
+	_ context.Context,
+	a app.Application,
+) (int, error) {
+
+	if db.createErr != nil {
+		return 0, db.createErr
+	}
+
+	db.created = append(db.created, a)
+	return len(db.created), nil
+}
+
+func newApplicationFormMockDB(sessions ...app.Session) *applicationFormMockDB {
+	db := &applicationFormMockDB{
+		authMockDB: &authMockDB{
+			DB:          &mock.DB{},
+			sessions:    make(map[app.SecureToken]app.Session),
+			permissions: testRolePermissions,
+		},
+		form: []app.ApplicationQuestion{
+			{
+				ID:             1,
+				OrganizationID: 1,
+				Prompt:         "CDL number",
+				Type:           app.QuestionText,
+				IsRequired:     true,
+			},
+			{
+				ID:             2,
+				OrganizationID: 1,
+				Prompt:         "Years driving",
+				Type:           app.QuestionNumber,
+				Min:            null.FloatFrom(0),
+			},
+		},
+	}
+
+	for _, s := range sessions {
+		db.sessions[s.Token] = s
+	}
+
+	return db
+}
+
+func TestSubmitApplication(t *testing.T) {
+	driver, err := app.NewSession(app.Person{ID: 2, Role: app.RoleDriver},
+		app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	validBody := `{"organization_id": 1, "comment": "Hi.", "answers": [
+		{"question_id": 1, "value": "X1234"}
+	]}`
+
+	testCases := []struct {
+		alias         string
+		body          string
+		createErr     error
+		expectCode    int
+		expectAnswers int
+		expectMessage string
+	}{
+		{
+			alias: "Valid",
+			body: `{"organization_id": 1, "comment": "Hi.", "answers": [
+				{"question_id": 2, "value": 12},
+				{"question_id": 1, "value": "X1234"}
+			]}`,
+			expectCode:    http.StatusNoContent,
+			expectAnswers: 2,
+		},
+		{
+			alias: "OptionalLeftOut",
+			body: `{"organization_id": 1, "comment": "Hi.", "answers": [
+				{"question_id": 1, "value": "X1234"}
+			]}`,
+			expectCode:    http.StatusNoContent,
+			expectAnswers: 1,
+		},
+		{
+			alias:      "RequiredLeftOut",
+			body:       `{"organization_id": 1, "comment": "Hi."}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias: "WrongType",
+			body: `{"organization_id": 1, "comment": "Hi.", "answers": [
+				{"question_id": 1, "value": "X1234"},
+				{"question_id": 2, "value": "twelve"}
+			]}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias: "NoSuchOrganization",
+			body: `{"organization_id": 7, "comment": "Hi.", "answers": [
+				{"question_id": 1, "value": "X1234"}
+			]}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "AlreadyOpen",
+			body:       validBody,
+			createErr:  errors.Wrap(app.ErrApplicationOpen, "test"),
+			expectCode: http.StatusConflict,
+			expectMessage: "You already have an open application to this " +
+				"organization.",
+		},
+		{
+			alias:         "AlreadyAffiliated",
+			body:          validBody,
+			createErr:     errors.Wrap(app.ErrAlreadyAffiliated, "test"),
+			expectCode:    http.StatusConflict,
+			expectMessage: "You already belong to this organization.",
+		},
+		{
+			alias: "Cooldown",
+			body:  validBody,
+			createErr: errors.Wrap(&app.ReapplyCooldownError{
+				Until: time.Date(2021, 4, 5, 14, 30, 0, 0, time.UTC),
+			}, "test"),
+			expectCode: http.StatusConflict,
+			expectMessage: "Your last application to this organization was " +
+				"rejected. You may apply again after April 5, 2021 at " +
+				"2:30 PM UTC.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			db := newApplicationFormMockDB(*driver)
+			db.createErr = tc.createErr
+			api, _, _ := newTestAPI(t, db, nil)
+
+			r := httptest.NewRequest("POST", "/driver/applications/submit",
+				strings.NewReader(tc.body))
+			testSessionTokenInject(t, r, driver.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			if tc.expectCode != http.StatusNoContent {
+				assert.Empty(t, db.created)
+
+				if tc.expectMessage != "" {
+					var e apiError
+					require.NoError(t, json.NewDecoder(w.Body).Decode(&e))
+					assert.Equal(t, tc.expectMessage, e.UserMessage)
+				}
+				return
+			}
+
+			require.Len(t, db.created, 1)
+			a := db.created[0]
+			assert.Equal(t, 2, a.ApplicantID)
+			require.Len(t, a.Answers, tc.expectAnswers)
+
+			// Answers are kept in the order of the form.
+			assert.Equal(t, "CDL number", a.Answers[0].Prompt)
+			assert.JSONEq(t, `"X1234"`, string(a.Answers[0].Value))
+		})
+	}
+}
+
+func TestSponsorUpdateApplicationForm(t *testing.T) {
+	sponsor, err := app.NewSession(app.Person{
+		ID:           1,
+		Role:         app.RoleSponsor,
+		Affiliations: []int{1},
+	}, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		alias      string
+		body       string
+		expectCode int
+	}{
+		{
+			alias: "Valid",
+			body: `{"questions": [
+				{"id": 2, "prompt": " Years driving ", "type": "number"},
+				{"prompt": "Class", "type": "choice", "choices": ["A", "B"]}
+			]}`,
+			expectCode: http.StatusNoContent,
+		},
+		{
+			alias:      "Empty",
+			body:       `{"questions": []}`,
+			expectCode: http.StatusNoContent,
+		},
+		{
+			alias: "InvalidQuestion",
+			body: `{"questions": [
+				{"prompt": "Class", "type": "choice", "choices": []}
+			]}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias: "NotOnForm",
+			body: `{"questions": [
+				{"id": 9, "prompt": "CDL number", "type": "text"}
+			]}`,
+			expectCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			db := newApplicationFormMockDB(*sponsor)
+			api, _, _ := newTestAPI(t, db, nil)
+
+			r := httptest.NewRequest("POST",
+				"/sponsor/organization/application-form/update",
+				strings.NewReader(tc.body))
+			testSessionTokenInject(t, r, sponsor.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			if tc.expectCode != http.StatusNoContent {
+				assert.Nil(t, db.saved)
+				return
+			}
+
+			for _, q := range db.saved {
+				assert.Equal(t, 1, q.OrganizationID)
+				assert.Equal(t, strings.TrimSpace(q.Prompt), q.Prompt)
+			}
+		})
+	}
+}
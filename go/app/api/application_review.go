@@ -0,0 +1,240 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// maxBulkDecisions is the largest number of applications that may be decided
+// in one request.
+const maxBulkDecisions = 100
+
+// parseApplicationFilter reads an app.ApplicationFilter from the query
+// parameters of a request. Statuses may be repeated or separated by commas, and
+// times must be given in RFC 3339 format.
+//
+// Upon failure, returns an error and a message for the user.
+func parseApplicationFilter(q url.Values) (f app.ApplicationFilter,
+	message string, err error) {
+
+	for _, value := range q["status"] {
+		for _, s := range strings.Split(value, ",") {
+			status := app.ApplicationStatus(strings.TrimSpace(s))
+			if !status.IsKnown() {
+				err = errors.Errorf("unknown application status '%s'", s)
+				message = "Parameter status must be an application status."
+				return
+			}
+			f.Statuses = append(f.Statuses, status)
+		}
+	}
+
+	parseTime := func(key string) (null.Time, bool) {
+		value := q.Get(key)
+		if len(value) < 1 {
+			return null.Time{}, true
+		}
+
+		t, parseErr := time.Parse(time.RFC3339, value)
+		if parseErr != nil {
+			err = errors.Wrapf(parseErr, "%s must be a time", key)
+			message = "Parameter " + key + " must be an RFC 3339 time."
+			return null.Time{}, false
+		}
+		return null.TimeFrom(t), true
+	}
+
+	var ok bool
+	if f.Since, ok = parseTime("since"); !ok {
+		return
+	} else if f.Until, ok = parseTime("until"); !ok {
+		return
+	}
+
+	f.Sort = app.ApplicationSort(q.Get("sort"))
+	switch f.Sort {
+	case "", app.ApplicationSortNewest, app.ApplicationSortOldest,
+		app.ApplicationSortApplicant:
+	default:
+		err = errors.Errorf("unknown application sort '%s'", f.Sort)
+		message = "Parameter sort must be newest, oldest, or applicant."
+		return
+	}
+
+	return
+}
+
+func (svr *Server) handleGetApplicationsForOrganization(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
+		return
+	}
+
+	f, message, err := parseApplicationFilter(r.URL.Query())
+	if err != nil {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest, message)
+		return
+	}
+
+	apps, err := svr.db.GetApplicationsForOrganization(r.Context(), orgID, f)
+	if err != nil {
+		svr.sendErrorResponse(
+			w,
+			errors.Wrap(err, "failed to retrieve application"),
+			http.StatusInternalServerError,
+			"",
+		)
+		return
+	}
+
+	if apps == nil {
+		apps = make([]app.Application, 0)
+	}
+
+	svr.sendJSONResponse(w, apps)
+}
+
+type bulkDecisionRequest struct {
+	ApplicationIDs []int  `json:"application_ids"`
+	IsApproved     bool   `json:"is_approved"`
+	Reason         string `json:"reason"`
+}
+
+// A bulkDecisionResult reports what became of one application in a bulk
+// decision. Failures carry the status code and message that deciding the
+// application alone would have responded with.
+type bulkDecisionResult struct {
+	ApplicationID int                   `json:"application_id"`
+	Succeeded     bool                  `json:"succeeded"`
+	Status        app.ApplicationStatus `json:"status,omitempty"`
+	Code          int                   `json:"code,omitempty"`
+	Message       string                `json:"message,omitempty"`
+}
+
+// decideApplication approves or rejects one application of a bulk decision,
+// which must be for the given organization.
+func (svr *Server) decideApplication(
+	r *http.Request,
+	orgID, appID int,
+	to app.ApplicationStatus,
+	reason string,
+) bulkDecisionResult {
+
+	result := bulkDecisionResult{ApplicationID: appID}
+	fail := func(err error, code int, message string) bulkDecisionResult {
+		svr.logger.WithError(err).
+			Warnf("could not decide application %d in bulk", appID)
+
+		result.Code = code
+		result.Message = message
+		return result
+	}
+
+	a, err := svr.db.GetApplicationByID(r.Context(), appID)
+	if errors.Is(err, app.ErrNotFound) {
+		return fail(err, http.StatusNotFound, "No such application.")
+	} else if err != nil {
+		return fail(err, http.StatusInternalServerError,
+			"The application could not be retrieved.")
+	}
+
+	// Applications to other organizations are none of this sponsor's business,
+	// so do not reveal that they exist.
+	if a.OrganizationID != orgID {
+		return fail(
+			errors.Errorf("application %d is for org %d, not active org %d",
+				a.ID, a.OrganizationID, orgID),
+			http.StatusNotFound, "No such application.")
+	}
+
+	err = svr.db.TransitionApplication(r.Context(), a.ID, a.Status, to,
+		reason)
+	if errors.Is(err, app.ErrOrganizationArchived) {
+		return fail(err, http.StatusConflict,
+			"This organization has been archived.")
+	} else if errors.Is(err, app.ErrConflict) {
+		return fail(err, http.StatusConflict,
+			"This application can no longer be changed that way.")
+	} else if err != nil {
+		return fail(err, http.StatusInternalServerError,
+			"The application could not be decided.")
+	}
+
+	result.Succeeded = true
+	result.Status = to
+	return result
+}
+
+// handleBulkDecideApplications approves or rejects many applications with one
+// reason. Each is decided on its own, just as if it were decided alone, so
+// some may succeed while others fail.
+func (svr *Server) handleBulkDecideApplications(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
+		return
+	}
+
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var data bulkDecisionRequest
+	if err := d.Decode(&data); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "received bad json data"),
+			http.StatusBadRequest, "Bad JSON data.")
+		return
+	}
+
+	if len(data.ApplicationIDs) < 1 {
+		svr.sendErrorResponse(w, errors.New("no applications to decide"),
+			http.StatusBadRequest, "No applications were chosen.")
+		return
+	} else if len(data.ApplicationIDs) > maxBulkDecisions {
+		svr.sendErrorResponse(w,
+			errors.Errorf("%d applications exceeds bulk limit",
+				len(data.ApplicationIDs)),
+			http.StatusBadRequest,
+			"At most %d applications may be decided at once.",
+			maxBulkDecisions)
+		return
+	}
+
+	seen := make(map[int]bool)
+	for _, id := range data.ApplicationIDs {
+		if seen[id] {
+			svr.sendErrorResponse(w,
+				errors.Errorf("application %d listed twice", id),
+				http.StatusBadRequest,
+				"Each application may only be chosen once.")
+			return
+		}
+		seen[id] = true
+	}
+
+	to := app.ApplicationRejected
+	if data.IsApproved {
+		to = app.ApplicationApproved
+	}
+
+	results := make([]bulkDecisionResult, len(data.ApplicationIDs))
+	for i, id := range data.ApplicationIDs {
+		results[i] = svr.decideApplication(r, orgID, id, to, data.Reason)
+	}
+
+	svr.sendJSONResponse(w, results)
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+func TestParseApplicationFilter(t *testing.T) {
+	since := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		alias     string
+		query     string
+		expect    app.ApplicationFilter
+		expectErr bool
+	}{
+		{
+			alias: "Empty",
+		},
+		{
+			alias: "Everything",
+			query: "status=submitted,on_hold&status=info_requested" +
+				"&since=2021-04-01T00:00:00Z&sort=oldest",
+			expect: app.ApplicationFilter{
+				Statuses: []app.ApplicationStatus{
+					app.ApplicationSubmitted,
+					app.ApplicationOnHold,
+					app.ApplicationInfoRequested,
+				},
+				Since: null.TimeFrom(since),
+				Sort:  app.ApplicationSortOldest,
+			},
+		},
+		{
+			alias:     "UnknownStatus",
+			query:     "status=pending",
+			expectErr: true,
+		},
+		{
+			alias:     "BadTime",
+			query:     "until=yesterday",
+			expectErr: true,
+		},
+		{
+			alias:     "UnknownSort",
+			query:     "sort=created_at+DESC",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			q, err := url.ParseQuery(tc.query)
+			require.NoError(t, err)
+
+			f, message, err := parseApplicationFilter(q)
+			if tc.expectErr {
+				assert.Error(t, err)
+				assert.NotEmpty(t, message)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, f)
+		})
+	}
+}
+
+func TestBulkDecideApplications(t *testing.T) {
+	sponsor, err := app.NewSession(app.Person{
+		ID:           1,
+		Role:         app.RoleSponsor,
+		Affiliations: []int{1},
+	}, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	newDB := func() *applicationMockDB {
+		return &applicationMockDB{
+			authMockDB: &authMockDB{
+				DB: &mock.DB{},
+				sessions: map[app.SecureToken]app.Session{
+					sponsor.Token: *sponsor,
+				},
+				permissions: testRolePermissions,
+			},
+			apps: map[int]app.Application{
+				10: {
+					ID:             10,
+					OrganizationID: 1,
+					Status:         app.ApplicationSubmitted,
+				},
+				11: {
+					ID:             11,
+					OrganizationID: 1,
+					Status:         app.ApplicationOnHold,
+				},
+				12: {
+					ID:             12,
+					OrganizationID: 1,
+					Status:         app.ApplicationWithdrawn,
+				},
+				13: {
+					ID:             13,
+					OrganizationID: 2,
+					Status:         app.ApplicationSubmitted,
+				},
+			},
+			messages: make(map[int]string),
+		}
+	}
+
+	post := func(
+		db *applicationMockDB,
+		body string,
+	) *httptest.ResponseRecorder {
+
+		api, _, _ := newTestAPI(t, db, nil)
+
+		r := httptest.NewRequest("POST", "/sponsor/applications/decide",
+			strings.NewReader(body))
+		testSessionTokenInject(t, r, sponsor.Token)
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("PerItemResults", func(t *testing.T) {
+		db := newDB()
+		w := post(db, `{
+			"application_ids": [10, 11, 12, 13, 14],
+			"is_approved": true,
+			"reason": "Welcome aboard!"
+		}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var results []bulkDecisionResult
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&results))
+
+		assert.Equal(t, []bulkDecisionResult{
+			{
+				ApplicationID: 10,
+				Succeeded:     true,
+				Status:        app.ApplicationApproved,
+			},
+			{
+				ApplicationID: 11,
+				Succeeded:     true,
+				Status:        app.ApplicationApproved,
+			},
+			{
+				ApplicationID: 12,
+				Code:          http.StatusConflict,
+				Message: "This application can no longer be changed " +
+					"that way.",
+			},
+			{
+				ApplicationID: 13,
+				Code:          http.StatusNotFound,
+				Message:       "No such application.",
+			},
+			{
+				ApplicationID: 14,
+				Code:          http.StatusNotFound,
+				Message:       "No such application.",
+			},
+		}, results)
+
+		assert.Equal(t, app.ApplicationApproved, db.apps[10].Status)
+		assert.Equal(t, app.ApplicationApproved, db.apps[11].Status)
+		assert.Equal(t, "Welcome aboard!", db.messages[11])
+		assert.Equal(t, app.ApplicationWithdrawn, db.apps[12].Status)
+		assert.Equal(t, app.ApplicationSubmitted, db.apps[13].Status)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		db := newDB()
+		w := post(db, `{"application_ids": [10], "reason": "Full up."}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		assert.Equal(t, app.ApplicationRejected, db.apps[10].Status)
+		assert.Equal(t, "Full up.", db.messages[10])
+	})
+
+	badRequests := []struct {
+		alias string
+		body  string
+	}{
+		{alias: "NoApplications", body: `{"application_ids": []}`},
+		{alias: "Duplicates", body: `{"application_ids": [10, 10]}`},
+		{alias: "UnknownField", body: `{"application_ids": [10], "x": 1}`},
+		{
+			alias: "TooMany",
+			body: `{"application_ids": [` +
+				strings.Repeat("10, ", maxBulkDecisions) + `10]}`,
+		},
+	}
+
+	for _, tc := range badRequests {
+		t.Run(tc.alias, func(t *testing.T) {
+			db := newDB()
+			w := post(db, tc.body)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, app.ApplicationSubmitted, db.apps[10].Status)
+		})
+	}
+}
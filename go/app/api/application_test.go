@@ -0,0 +1,309 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+type applicationMockDB struct {
+	*authMockDB
+
+	apps     map[int]app.Application
+	messages map[int]string
+}
+
+func (db *applicationMockDB) GetApplicationByID(
+	_ context.Context,
+	appID int,
+) (app.Application, error) {
+
+	a, ok := db.apps[appID]
+	if !ok {
+		return a, errors.Wrapf(app.ErrNotFound, "application %d", appID)
+	}
+	return a, nil
+}
+
+func (db *applicationMockDB) TransitionApplication(
+	_ context.Context,
+	appID int,
+	from, to app.ApplicationStatus,
+	message string,
+) error {
+
+	a := db.apps[appID]
+	if a.Status != from || !from.CanTransitionTo(to) {
+		return errors.Wrapf(app.ErrConflict, "application %d", appID)
+	}
+
+	a.Status = to
+	db.apps[appID] = a
+	db.messages[appID] = message
+	return nil
+}
+
+func (db *applicationMockDB) GetApplicationHistory(
+	_ context.Context,
+	appID int,
+) ([]app.ApplicationEvent, error) {
+
+	return []app.ApplicationEvent{{
+		ApplicationID: appID,
+		ToStatus:      app.ApplicationSubmitted,
+	}}, nil
+}
+
+func TestApplicationTransitions(t *testing.T) {
+	newSession := func(p app.Person) app.Session {
+		s, err := app.NewSession(p, app.DefaultSessionPolicy(), false)
+		require.NoError(t, err)
+		return *s
+	}
+
+	sponsor := newSession(app.Person{
+		ID:           1,
+		Role:         app.RoleSponsor,
+		Affiliations: []int{1},
+	})
+	driver := newSession(app.Person{ID: 2, Role: app.RoleDriver})
+
+	testCases := []struct {
+		alias         string
+		session       app.Session
+		path          string
+		body          string
+		expectCode    int
+		expectStatus  app.ApplicationStatus
+		expectMessage string
+	}{
+		{
+			alias:         "Approve",
+			session:       sponsor,
+			path:          "/sponsor/applications/10/approve",
+			body:          `{"is_approved": true, "reason": "Welcome!"}`,
+			expectCode:    http.StatusNoContent,
+			expectStatus:  app.ApplicationApproved,
+			expectMessage: "Welcome!",
+		},
+		{
+			alias:         "Reject",
+			session:       sponsor,
+			path:          "/sponsor/applications/10/approve",
+			body:          `{"is_approved": false, "reason": "Sorry."}`,
+			expectCode:    http.StatusNoContent,
+			expectStatus:  app.ApplicationRejected,
+			expectMessage: "Sorry.",
+		},
+		{
+			alias:        "AlreadyDecided",
+			session:      sponsor,
+			path:         "/sponsor/applications/11/approve",
+			body:         `{"is_approved": true, "reason": "Welcome!"}`,
+			expectCode:   http.StatusConflict,
+			expectStatus: app.ApplicationRejected,
+		},
+		{
+			alias:        "ApproveOtherOrganization",
+			session:      sponsor,
+			path:         "/sponsor/applications/12/approve",
+			body:         `{"is_approved": true, "reason": "Welcome!"}`,
+			expectCode:   http.StatusNotFound,
+			expectStatus: app.ApplicationSubmitted,
+		},
+		{
+			alias:      "ApproveNoSuchApplication",
+			session:    sponsor,
+			path:       "/sponsor/applications/13/approve",
+			body:       `{"is_approved": true, "reason": "Welcome!"}`,
+			expectCode: http.StatusNotFound,
+		},
+		{
+			alias:        "ApproveBodyApplicationID",
+			session:      sponsor,
+			path:         "/sponsor/applications/10/approve",
+			body:         `{"is_approved": true, "application_id": 11}`,
+			expectCode:   http.StatusBadRequest,
+			expectStatus: app.ApplicationSubmitted,
+		},
+		{
+			alias:        "Hold",
+			session:      sponsor,
+			path:         "/sponsor/applications/10/hold",
+			body:         `{}`,
+			expectCode:   http.StatusNoContent,
+			expectStatus: app.ApplicationOnHold,
+		},
+		{
+			alias:        "ResumeWithoutHold",
+			session:      sponsor,
+			path:         "/sponsor/applications/10/resume",
+			body:         `{}`,
+			expectCode:   http.StatusConflict,
+			expectStatus: app.ApplicationSubmitted,
+		},
+		{
+			alias:         "RequestInfo",
+			session:       sponsor,
+			path:          "/sponsor/applications/10/request-info",
+			body:          `{"message": " How long have you driven? "}`,
+			expectCode:    http.StatusNoContent,
+			expectStatus:  app.ApplicationInfoRequested,
+			expectMessage: "How long have you driven?",
+		},
+		{
+			alias:        "RequestInfoBlank",
+			session:      sponsor,
+			path:         "/sponsor/applications/10/request-info",
+			body:         `{"message": "  "}`,
+			expectCode:   http.StatusBadRequest,
+			expectStatus: app.ApplicationSubmitted,
+		},
+		{
+			alias:         "Withdraw",
+			session:       driver,
+			path:          "/driver/applications/10/withdraw",
+			body:          `{"message": "Found another job."}`,
+			expectCode:    http.StatusNoContent,
+			expectStatus:  app.ApplicationWithdrawn,
+			expectMessage: "Found another job.",
+		},
+		{
+			alias:        "WithdrawOtherApplicant",
+			session:      driver,
+			path:         "/driver/applications/14/withdraw",
+			body:         `{}`,
+			expectCode:   http.StatusNotFound,
+			expectStatus: app.ApplicationSubmitted,
+		},
+		{
+			// Sponsors may not apply to, or act as applicants of, any
+			// organization.
+			alias:        "SponsorOnDriverRoute",
+			session:      sponsor,
+			path:         "/driver/applications/10/withdraw",
+			body:         `{}`,
+			expectCode:   http.StatusForbidden,
+			expectStatus: app.ApplicationSubmitted,
+		},
+		{
+			alias:        "RespondWithoutRequest",
+			session:      driver,
+			path:         "/driver/applications/10/respond",
+			body:         `{"message": "Ten years."}`,
+			expectCode:   http.StatusConflict,
+			expectStatus: app.ApplicationSubmitted,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			db := &applicationMockDB{
+				authMockDB: &authMockDB{
+					DB: &mock.DB{},
+					sessions: map[app.SecureToken]app.Session{
+						sponsor.Token: sponsor,
+						driver.Token:  driver,
+					},
+					permissions: testRolePermissions,
+				},
+				apps: map[int]app.Application{
+					10: {
+						ID:             10,
+						ApplicantID:    2,
+						OrganizationID: 1,
+						Status:         app.ApplicationSubmitted,
+					},
+					11: {
+						ID:             11,
+						ApplicantID:    3,
+						OrganizationID: 1,
+						Status:         app.ApplicationRejected,
+					},
+					12: {
+						ID:             12,
+						ApplicantID:    2,
+						OrganizationID: 2,
+						Status:         app.ApplicationSubmitted,
+					},
+					14: {
+						ID:             14,
+						ApplicantID:    3,
+						OrganizationID: 1,
+						Status:         app.ApplicationSubmitted,
+					},
+				},
+				messages: make(map[int]string),
+			}
+			api, _, _ := newTestAPI(t, db, nil)
+
+			r := httptest.NewRequest("POST", tc.path,
+				strings.NewReader(tc.body))
+			testSessionTokenInject(t, r, tc.session.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+
+			appID, err := strconv.Atoi(strings.Split(tc.path, "/")[3])
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectStatus, db.apps[appID].Status)
+			assert.Equal(t, tc.expectMessage, db.messages[appID])
+		})
+	}
+}
+
+func TestApplicationHistory(t *testing.T) {
+	driver, err := app.NewSession(app.Person{ID: 2, Role: app.RoleDriver},
+		app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	db := &applicationMockDB{
+		authMockDB: &authMockDB{
+			DB: &mock.DB{},
+			sessions: map[app.SecureToken]app.Session{
+				driver.Token: *driver,
+			},
+			permissions: testRolePermissions,
+		},
+		apps: map[int]app.Application{
+			10: {ID: 10, ApplicantID: 2, OrganizationID: 1},
+			11: {ID: 11, ApplicantID: 3, OrganizationID: 1},
+		},
+	}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		testSessionTokenInject(t, r, driver.Token)
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("/driver/applications/10/history")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var events []app.ApplicationEvent
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&events))
+	require.Len(t, events, 1)
+	assert.Equal(t, 10, events[0].ApplicationID)
+
+	// Drivers may not see the history of other people's applications.
+	w = get("/driver/applications/11/history")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// maxAttachmentFileName is the longest file name kept for an attachment.
+const maxAttachmentFileName = 255
+
+// attachmentURL produces a signed link to download an attachment, which
+// expires after the lifetime set by the attachment policy.
+func (svr *Server) attachmentURL(a app.Attachment) string {
+	expires, signature := app.SignAttachment(svr.config.AttachmentURLKey, a.ID,
+		time.Now().Add(svr.config.Attachments.URLLifetime))
+
+	return fmt.Sprintf("%s/api/attachments/%d?expires=%s&signature=%s",
+		svr.baseURL(), a.ID, expires, signature)
+}
+
+// cleanAttachmentFileName reduces the name of an uploaded file to something
+// safe to keep and send back: no directories, no control characters, and not
+// too long.
+func cleanAttachmentFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	if runes := []rune(name); len(runes) > maxAttachmentFileName {
+		name = string(runes[:maxAttachmentFileName])
+	}
+
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
+
+// sendAttachments responds with the attachments to an application, each with
+// a signed link to download it.
+func (svr *Server) sendAttachments(
+	w http.ResponseWriter,
+	r *http.Request,
+	a app.Application,
+) {
+
+	attachments, err := svr.db.GetAttachmentsForApplication(r.Context(), a.ID)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to retrieve attachments"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	for i := range attachments {
+		attachments[i].URL = svr.attachmentURL(attachments[i])
+	}
+
+	svr.sendJSONResponse(w, attachments)
+}
+
+func (svr *Server) handleGetApplicationAttachments(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	a, ok := svr.getMyApplicationFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	svr.sendAttachments(w, r, a)
+}
+
+func (svr *Server) handleSponsorGetApplicationAttachments(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	a, ok := svr.getSponsorApplicationFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	svr.sendAttachments(w, r, a)
+}
+
+func (svr *Server) handleAdminGetApplicationAttachments(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	a, ok := svr.getApplicationFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	svr.sendAttachments(w, r, a)
+}
+
+func (svr *Server) handleUploadAttachment(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	policy := svr.config.Attachments
+
+	a, ok := svr.getMyApplicationFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	if a.Status.IsFinal() {
+		svr.sendErrorResponse(w,
+			errors.Errorf("application %d is %s", a.ID, a.Status),
+			http.StatusConflict,
+			"Documents cannot be attached to an application that is %s.",
+			strings.ReplaceAll(string(a.Status), "_", " "))
+		return
+	}
+
+	existing, err := svr.db.GetAttachmentsForApplication(r.Context(), a.ID)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to retrieve attachments"),
+			http.StatusInternalServerError, "")
+		return
+	} else if len(existing) >= policy.MaxPerApplication {
+		svr.sendErrorResponse(w,
+			errors.Errorf("application %d has %d attachments",
+				a.ID, len(existing)),
+			http.StatusConflict,
+			"An application may have at most %d documents attached.",
+			policy.MaxPerApplication)
+		return
+	}
+
+	u, ok := svr.receiveUpload(w, r, policy.MaxSize,
+		policy.AllowsContentType,
+		"Only PDF, JPEG, and PNG files may be attached.")
+	if !ok {
+		return
+	}
+
+	key, err := svr.files.PutFile(r.Context(), bytes.NewReader(u.content))
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to store uploaded file"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	attachment := app.Attachment{
+		ApplicationID: a.ID,
+		FileName:      cleanAttachmentFileName(u.fileName),
+		ContentType:   u.contentType,
+		Size:          int64(len(u.content)),
+		StorageKey:    key,
+		CreatedAt:     time.Now(),
+	}
+
+	attachment.ID, err = svr.db.CreateAttachment(r.Context(), attachment)
+	if err != nil {
+		// Do not leave behind a file that nothing refers to.
+		if delErr := svr.files.DeleteFile(r.Context(), key); delErr != nil {
+			svr.logger.WithError(delErr).
+				Errorf("failed to delete orphaned file %s", key)
+		}
+
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to create attachment"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	attachment.URL = svr.attachmentURL(attachment)
+	svr.sendJSONResponse(w, attachment)
+}
+
+// handleDownloadAttachment sends the contents of an attachment. No session is
+// required, since the signature on the link proves that it was handed out to
+// someone allowed to see the attachment.
+func (svr *Server) handleDownloadAttachment(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	attachmentID, err := strconv.Atoi(pathParams["attachmentID"])
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "attachmentID must be an integer"),
+			http.StatusBadRequest, "Attachment ID must be an integer.")
+		return
+	}
+
+	query := r.URL.Query()
+	if !app.VerifyAttachment(svr.config.AttachmentURLKey, attachmentID,
+		query.Get("expires"), query.Get("signature"), time.Now()) {
+
+		svr.sendErrorResponse(w,
+			errors.Errorf("bad or expired link to attachment %d",
+				attachmentID),
+			http.StatusForbidden,
+			"This link is invalid or has expired.")
+		return
+	}
+
+	a, err := svr.db.GetAttachmentByID(r.Context(), attachmentID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound,
+			"No such attachment.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to retrieve attachment"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	f, err := svr.files.OpenFile(r.Context(), a.StorageKey)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrapf(err, "failed to open file of attachment %d", a.ID),
+			http.StatusInternalServerError, "")
+		return
+	}
+	defer f.Close()
+
+	h := w.Header()
+	h.Set("Content-Type", a.ContentType)
+	h.Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	h.Set("Content-Disposition", mime.FormatMediaType("attachment",
+		map[string]string{"filename": a.FileName}))
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Cache-Control", "private, no-store")
+
+	if _, err = io.Copy(w, f); err != nil {
+		svr.logger.WithError(err).
+			Errorf("failed to send file of attachment %d", a.ID)
+	}
+}
@@ -0,0 +1,410 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+type attachmentMockDB struct {
+	*applicationMockDB
+
+	attachments map[int]app.Attachment
+}
+
+func (db *attachmentMockDB) CreateAttachment(
+	_ context.Context,
+	a app.Attachment,
+) (int, error) {
+
+	a.ID = len(db.attachments) + 1
+	db.attachments[a.ID] = a
+	return a.ID, nil
+}
+
+func (db *attachmentMockDB) GetAttachmentByID(
+	_ context.Context,
+	attachmentID int,
+) (app.Attachment, error) {
+
+	a, ok := db.attachments[attachmentID]
+	if !ok {
+		return a, errors.Wrapf(app.ErrNotFound, "attachment %d", attachmentID)
+	}
+	return a, nil
+}
+
+func (db *attachmentMockDB) GetAttachmentsForApplication(
+	_ context.Context,
+	appID int,
+) ([]app.Attachment, error) {
+
+	var attachments []app.Attachment
+	for id := 1; id <= len(db.attachments); id++ {
+		if db.attachments[id].ApplicationID == appID {
+			attachments = append(attachments, db.attachments[id])
+		}
+	}
+	return attachments, nil
+}
+
+// testPDF is the start of a PDF document, which is enough for its type to be
+// detected.
+var testPDF = []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n1 0 obj\n<<>>\nendobj\n")
+
+func newAttachmentUpload(
+	t *testing.T,
+	path, fileName string,
+	content []byte,
+) *http.Request {
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	fw, err := mw.CreateFormFile(uploadFormField, fileName)
+	require.NoError(t, err)
+	_, err = fw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	r := httptest.NewRequest("POST", path, &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func newAttachmentMockDB(sessions ...app.Session) *attachmentMockDB {
+	byToken := make(map[app.SecureToken]app.Session)
+	for _, s := range sessions {
+		byToken[s.Token] = s
+	}
+
+	return &attachmentMockDB{
+		applicationMockDB: &applicationMockDB{
+			authMockDB: &authMockDB{
+				DB:          &mock.DB{},
+				sessions:    byToken,
+				permissions: testRolePermissions,
+			},
+			apps: map[int]app.Application{
+				10: {
+					ID:             10,
+					ApplicantID:    2,
+					OrganizationID: 1,
+					Status:         app.ApplicationSubmitted,
+				},
+				11: {
+					ID:             11,
+					ApplicantID:    2,
+					OrganizationID: 1,
+					Status:         app.ApplicationRejected,
+				},
+				12: {
+					ID:             12,
+					ApplicantID:    3,
+					OrganizationID: 2,
+					Status:         app.ApplicationSubmitted,
+				},
+			},
+		},
+		attachments: make(map[int]app.Attachment),
+	}
+}
+
+func TestUploadAttachment(t *testing.T) {
+	driver, err := app.NewSession(app.Person{ID: 2, Role: app.RoleDriver},
+		app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	tooBig := make([]byte, app.DefaultAttachmentPolicy.MaxSize+1)
+	copy(tooBig, testPDF)
+
+	testCases := []struct {
+		alias      string
+		path       string
+		fileName   string
+		content    []byte
+		expectCode int
+	}{
+		{
+			alias:      "PDF",
+			path:       "/driver/applications/10/attachments",
+			fileName:   "license.pdf",
+			content:    testPDF,
+			expectCode: http.StatusOK,
+		},
+		{
+			alias:      "TypeNotAllowed",
+			path:       "/driver/applications/10/attachments",
+			fileName:   "license.pdf",
+			content:    []byte("<html><script>alert(1)</script></html>"),
+			expectCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			alias:      "TooBig",
+			path:       "/driver/applications/10/attachments",
+			fileName:   "license.pdf",
+			content:    tooBig,
+			expectCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			alias:      "Infected",
+			path:       "/driver/applications/10/attachments",
+			fileName:   "license.pdf",
+			content:    append(append([]byte{}, testPDF...), "EICAR"...),
+			expectCode: http.StatusUnprocessableEntity,
+		},
+		{
+			alias:      "Empty",
+			path:       "/driver/applications/10/attachments",
+			fileName:   "license.pdf",
+			content:    nil,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "DecidedApplication",
+			path:       "/driver/applications/11/attachments",
+			fileName:   "license.pdf",
+			content:    testPDF,
+			expectCode: http.StatusConflict,
+		},
+		{
+			alias:      "OtherApplicant",
+			path:       "/driver/applications/12/attachments",
+			fileName:   "license.pdf",
+			content:    testPDF,
+			expectCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			db := newAttachmentMockDB(*driver)
+			api, _, _ := newTestAPI(t, db, nil)
+
+			r := newAttachmentUpload(t, tc.path, tc.fileName, tc.content)
+			testSessionTokenInject(t, r, driver.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+
+			files := api.files.(*mock.FileStore).Files
+			if tc.expectCode != http.StatusOK {
+				assert.Empty(t, db.attachments)
+				assert.Empty(t, files)
+				return
+			}
+
+			var a app.Attachment
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&a))
+			assert.Equal(t, 1, a.ID)
+			assert.Equal(t, tc.fileName, a.FileName)
+			assert.Equal(t, "application/pdf", a.ContentType)
+			assert.NotEmpty(t, a.URL)
+
+			require.Len(t, db.attachments, 1)
+			assert.Equal(t, tc.content, files[db.attachments[1].StorageKey])
+		})
+	}
+}
+
+func TestUploadAttachmentLimit(t *testing.T) {
+	driver, err := app.NewSession(app.Person{ID: 2, Role: app.RoleDriver},
+		app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	db := newAttachmentMockDB(*driver)
+	api, _, _ := newTestAPI(t, db, nil)
+
+	for i := 0; i <= app.DefaultAttachmentPolicy.MaxPerApplication; i++ {
+		r := newAttachmentUpload(t, "/driver/applications/10/attachments",
+			"license.pdf", testPDF)
+		testSessionTokenInject(t, r, driver.Token)
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+
+		if i < app.DefaultAttachmentPolicy.MaxPerApplication {
+			assert.Equal(t, http.StatusOK, w.Code)
+		} else {
+			assert.Equal(t, http.StatusConflict, w.Code)
+		}
+	}
+
+	assert.Len(t, db.attachments, app.DefaultAttachmentPolicy.MaxPerApplication)
+}
+
+func TestCleanAttachmentFileName(t *testing.T) {
+	testCases := []struct {
+		alias  string
+		name   string
+		expect string
+	}{
+		{alias: "Plain", name: "license.pdf", expect: "license.pdf"},
+		{alias: "Path", name: "../../etc/passwd", expect: "passwd"},
+		{alias: "WindowsPath", name: `C:\Users\me\a.png`, expect: "a.png"},
+		{alias: "Control", name: "a\r\nb.pdf", expect: "ab.pdf"},
+		{alias: "Blank", name: "  ", expect: "attachment"},
+		{alias: "Dots", name: "..", expect: "attachment"},
+		{
+			alias:  "Long",
+			name:   strings.Repeat("a", 300),
+			expect: strings.Repeat("a", maxAttachmentFileName),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			assert.Equal(t, tc.expect, cleanAttachmentFileName(tc.name))
+		})
+	}
+}
+
+func TestDownloadAttachment(t *testing.T) {
+	newSession := func(p app.Person) app.Session {
+		s, err := app.NewSession(p, app.DefaultSessionPolicy(), false)
+		require.NoError(t, err)
+		return *s
+	}
+
+	driver := newSession(app.Person{ID: 2, Role: app.RoleDriver})
+	otherDriver := newSession(app.Person{ID: 3, Role: app.RoleDriver})
+	sponsor := newSession(app.Person{
+		ID:           4,
+		Role:         app.RoleSponsor,
+		Affiliations: []int{1},
+	})
+	otherSponsor := newSession(app.Person{
+		ID:           5,
+		Role:         app.RoleSponsor,
+		Affiliations: []int{2},
+	})
+	admin := newSession(app.Person{ID: 6, Role: app.RoleAdmin})
+
+	db := newAttachmentMockDB(driver, otherDriver, sponsor, otherSponsor,
+		admin)
+	api, _, _ := newTestAPI(t, db, nil)
+
+	r := newAttachmentUpload(t, "/driver/applications/10/attachments",
+		"my \"license\".pdf", testPDF)
+	testSessionTokenInject(t, r, driver.Token)
+	w := httptest.NewRecorder()
+	api.router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	get := func(s app.Session, path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		testSessionTokenInject(t, r, s.Token)
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+		return w
+	}
+
+	// Only the applicant, the organization's sponsors, and admins may list
+	// the attachments and so receive links to them.
+	listCases := []struct {
+		alias      string
+		session    app.Session
+		path       string
+		expectCode int
+	}{
+		{
+			alias:      "Applicant",
+			session:    driver,
+			path:       "/driver/applications/10/attachments",
+			expectCode: http.StatusOK,
+		},
+		{
+			alias:      "OtherDriver",
+			session:    otherDriver,
+			path:       "/driver/applications/10/attachments",
+			expectCode: http.StatusNotFound,
+		},
+		{
+			alias:      "Sponsor",
+			session:    sponsor,
+			path:       "/sponsor/applications/10/attachments",
+			expectCode: http.StatusOK,
+		},
+		{
+			alias:      "OtherSponsor",
+			session:    otherSponsor,
+			path:       "/sponsor/applications/10/attachments",
+			expectCode: http.StatusNotFound,
+		},
+		{
+			alias:      "Admin",
+			session:    admin,
+			path:       "/admin/applications/10/attachments",
+			expectCode: http.StatusOK,
+		},
+		{
+			alias:      "DriverAsAdmin",
+			session:    driver,
+			path:       "/admin/applications/10/attachments",
+			expectCode: http.StatusForbidden,
+		},
+	}
+
+	var link *url.URL
+	for _, tc := range listCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			w := get(tc.session, tc.path)
+			require.Equal(t, tc.expectCode, w.Code)
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var attachments []app.Attachment
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&attachments))
+			require.Len(t, attachments, 1)
+
+			u, err := url.Parse(attachments[0].URL)
+			require.NoError(t, err)
+			assert.Equal(t, "/api/attachments/1", u.Path)
+			link = u
+		})
+	}
+	require.NotNil(t, link)
+
+	// The API itself is served under /api, which the proxy strips.
+	download := func(query url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET",
+			"/attachments/1?"+query.Encode(), nil)
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+		return w
+	}
+
+	w = download(link.Query())
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, testPDF, w.Body.Bytes())
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, `attachment; filename="my \"license\".pdf"`,
+		w.Header().Get("Content-Disposition"))
+
+	tampered := link.Query()
+	tampered.Set("expires", tampered.Get("expires")+"0")
+	w = download(tampered)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = download(url.Values{})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
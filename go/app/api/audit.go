@@ -0,0 +1,162 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+const (
+	// defaultAuditLimit is the number of audit events returned when the
+	// request does not specify a limit.
+	defaultAuditLimit = 50
+	// maxAuditLimit is the largest number of audit events that may be
+	// requested at once.
+	maxAuditLimit = 200
+)
+
+// parseAuditFilter reads an app.AuditFilter from the query parameters of a
+// request. Times must be given in RFC 3339 format.
+//
+// Upon failure, returns an error and a message for the user.
+func parseAuditFilter(q url.Values) (f app.AuditFilter, message string,
+	err error) {
+
+	parseInt := func(key string) (null.Int, bool) {
+		value := q.Get(key)
+		if len(value) < 1 {
+			return null.Int{}, true
+		}
+
+		n, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			err = errors.Wrapf(convErr, "%s must be an integer", key)
+			message = "Parameter " + key + " must be an integer."
+			return null.Int{}, false
+		}
+		return null.IntFrom(int64(n)), true
+	}
+
+	parseTime := func(key string) (null.Time, bool) {
+		value := q.Get(key)
+		if len(value) < 1 {
+			return null.Time{}, true
+		}
+
+		t, parseErr := time.Parse(time.RFC3339, value)
+		if parseErr != nil {
+			err = errors.Wrapf(parseErr, "%s must be a time", key)
+			message = "Parameter " + key + " must be an RFC 3339 time."
+			return null.Time{}, false
+		}
+		return null.TimeFrom(t), true
+	}
+
+	var ok bool
+	if f.ActorID, ok = parseInt("actor_id"); !ok {
+		return
+	} else if f.TargetID, ok = parseInt("target_id"); !ok {
+		return
+	} else if f.OrganizationID, ok = parseInt("organization_id"); !ok {
+		return
+	} else if f.BeforeID, ok = parseInt("before_id"); !ok {
+		return
+	} else if f.Since, ok = parseTime("since"); !ok {
+		return
+	} else if f.Until, ok = parseTime("until"); !ok {
+		return
+	}
+
+	f.Action = app.AuditAction(q.Get("action"))
+	f.TargetType = app.AuditTargetType(q.Get("target_type"))
+
+	limit, ok := parseInt("limit")
+	if !ok {
+		return
+	}
+
+	f.Limit = defaultAuditLimit
+	if limit.Valid {
+		f.Limit = int(limit.Int64)
+	}
+
+	if f.Limit < 1 || f.Limit > maxAuditLimit {
+		err = errors.Errorf("limit of %d is out of range", f.Limit)
+		message = "Parameter limit must be between 1 and " +
+			strconv.Itoa(maxAuditLimit) + "."
+		return
+	}
+
+	return
+}
+
+// sendAuditEvents responds with the audit events matching a filter.
+func (svr *Server) sendAuditEvents(
+	w http.ResponseWriter,
+	r *http.Request,
+	f app.AuditFilter,
+) {
+
+	events, err := svr.db.GetAuditEvents(r.Context(), f)
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to get audit events"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	if events == nil {
+		events = make([]app.AuditEvent, 0)
+	}
+
+	svr.sendJSONResponse(w, events)
+}
+
+func (svr *Server) handleAdminGetAuditEvents(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	f, message, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest, message)
+		return
+	}
+
+	svr.sendAuditEvents(w, r, f)
+}
+
+func (svr *Server) handleSponsorGetAuditEvents(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
+		return
+	}
+
+	f, message, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest, message)
+		return
+	}
+
+	// Sponsors may only see the events of their own organization.
+	if f.OrganizationID.Valid && f.OrganizationID.Int64 != int64(orgID) {
+		svr.sendErrorResponse(w,
+			errors.Errorf("sponsor requested events of organization %d",
+				f.OrganizationID.Int64),
+			http.StatusForbidden,
+			"You may only view the audit log of your own organization.")
+		return
+	}
+	f.OrganizationID = null.IntFrom(int64(orgID))
+
+	svr.sendAuditEvents(w, r, f)
+}
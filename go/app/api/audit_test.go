@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+type auditMockDB struct {
+	*authMockDB
+
+	filter *app.AuditFilter
+	actor  *app.AuditActor
+}
+
+func (db *auditMockDB) GetAuditEvents(
+	_ context.Context,
+	f app.AuditFilter,
+) ([]app.AuditEvent, error) {
+
+	db.filter = &f
+	return nil, nil
+}
+
+func (db *auditMockDB) DeactivatePerson(
+	ctx context.Context,
+	_ int,
+	_ string,
+) error {
+
+	actor := app.AuditActorFromContext(ctx)
+	db.actor = &actor
+	return nil
+}
+
+func TestAuditLog(t *testing.T) {
+	newSession := func(role app.Role, affiliations ...int) app.Session {
+		s, err := app.NewSession(app.Person{
+			ID:           int(role),
+			Role:         role,
+			Affiliations: affiliations,
+		}, app.DefaultSessionPolicy(), false)
+		require.NoError(t, err)
+		return *s
+	}
+
+	admin := newSession(app.RoleAdmin)
+	sponsor := newSession(app.RoleSponsor, 7)
+	driver := newSession(app.RoleDriver)
+
+	db := &auditMockDB{
+		authMockDB: &authMockDB{
+			DB: &mock.DB{},
+			sessions: map[app.SecureToken]app.Session{
+				admin.Token:   admin,
+				sponsor.Token: sponsor,
+				driver.Token:  driver,
+			},
+			permissions: testRolePermissions,
+		},
+	}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	since := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		alias        string
+		session      app.Session
+		path         string
+		expectCode   int
+		expectFilter app.AuditFilter
+	}{
+		{
+			alias:      "AdminDefaults",
+			session:    admin,
+			path:       "/admin/audit",
+			expectCode: http.StatusOK,
+			expectFilter: app.AuditFilter{
+				Limit: defaultAuditLimit,
+			},
+		},
+		{
+			alias:   "AdminFilters",
+			session: admin,
+			path: "/admin/audit?actor_id=3&action=person.deactivate" +
+				"&target_type=person&target_id=4&organization_id=5" +
+				"&since=2021-03-01T12:00:00Z&before_id=99&limit=10",
+			expectCode: http.StatusOK,
+			expectFilter: app.AuditFilter{
+				ActorID:        null.IntFrom(3),
+				Action:         app.AuditActionPersonDeactivate,
+				TargetType:     app.AuditTargetPerson,
+				TargetID:       null.IntFrom(4),
+				OrganizationID: null.IntFrom(5),
+				Since:          null.TimeFrom(since),
+				BeforeID:       null.IntFrom(99),
+				Limit:          10,
+			},
+		},
+		{
+			alias:      "AdminBadInteger",
+			session:    admin,
+			path:       "/admin/audit?actor_id=me",
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "AdminBadTime",
+			session:    admin,
+			path:       "/admin/audit?since=yesterday",
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "AdminLimitTooLarge",
+			session:    admin,
+			path:       "/admin/audit?limit=1000",
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "SponsorOnAdminLog",
+			session:    sponsor,
+			path:       "/admin/audit",
+			expectCode: http.StatusForbidden,
+		},
+		{
+			alias:      "SponsorScopedToOrganization",
+			session:    sponsor,
+			path:       "/sponsor/audit?action=product.add",
+			expectCode: http.StatusOK,
+			expectFilter: app.AuditFilter{
+				Action:         app.AuditActionProductAdd,
+				OrganizationID: null.IntFrom(7),
+				Limit:          defaultAuditLimit,
+			},
+		},
+		{
+			alias:      "SponsorOtherOrganization",
+			session:    sponsor,
+			path:       "/sponsor/audit?organization_id=8",
+			expectCode: http.StatusForbidden,
+		},
+		{
+			alias:      "Driver",
+			session:    driver,
+			path:       "/sponsor/audit",
+			expectCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			db.filter = nil
+
+			r := httptest.NewRequest("GET", tc.path, nil)
+			testSessionTokenInject(t, r, tc.session.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+
+			require.Equal(t, tc.expectCode, w.Code)
+			if tc.expectCode != http.StatusOK {
+				assert.Nil(t, db.filter)
+				return
+			}
+
+			require.NotNil(t, db.filter)
+			assert.Equal(t, tc.expectFilter, *db.filter)
+			assert.Equal(t, "[]\n", w.Body.String())
+		})
+	}
+
+	t.Run("AttributesChanges", func(t *testing.T) {
+		requestID := uuid.New().String()
+
+		r := httptest.NewRequest("POST", "/admin/users/3/deactivate",
+			strings.NewReader(`{"reason": "Left the company."}`))
+		testSessionTokenInject(t, r, admin.Token)
+		r.Header.Set(requestIDHeaderKey, requestID)
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		assert.Equal(t, requestID, w.Header().Get(requestIDHeaderKey))
+		require.NotNil(t, db.actor)
+		assert.Equal(t, app.AuditActor{
+			PersonID:  null.IntFrom(int64(admin.Person.ID)),
+			RequestID: null.StringFrom(requestID),
+		}, *db.actor)
+	})
+
+	t.Run("GeneratesRequestID", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/whoami", nil)
+		r.Header.Set(requestIDHeaderKey, "not a uuid")
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+
+		_, err := uuid.Parse(w.Header().Get(requestIDHeaderKey))
+		assert.NoError(t, err)
+	})
+}
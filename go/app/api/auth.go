@@ -3,10 +3,11 @@ package api
 import (
 	"context"
 	"net/http"
+	"time"
 
-	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
 
 	"github.com/BenJetson/CPSC491-project/go/app"
 )
@@ -17,49 +18,188 @@ const contextKeySession contextKey = "session"
 
 func (svr *Server) authContextMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		c, err := r.Cookie(sessionCookieKey)
-		if err == nil {
-			token, err := uuid.Parse(c.Value)
-			if err != nil {
-				svr.sendErrorResponse(
-					w,
-					errors.Wrap(err, "bad login token"),
-					http.StatusUnauthorized,
-					"",
-				)
-				return
+		// Requests bearing an API token do not use cookies at all.
+		if header := r.Header.Get("Authorization"); len(header) > 0 {
+			authed, ok := svr.authenticateAPIToken(w, r, header)
+			if ok {
+				next.ServeHTTP(w, authed)
 			}
+			return
+		}
 
-			s, err := svr.db.GetSessionByToken(r.Context(), token)
-			if errors.Is(err, app.ErrNotFound) {
-				svr.logger.
-					WithField("token", token).
-					Info("received unknown session token; destroying cookie")
-				svr.destroySessionCookie(w)
+		s, ok := svr.getSessionByCookie(w, r, sessionCookieKey,
+			svr.destroySessionCookie)
+		if !ok {
+			return
+		} else if s != nil && s.IsImpersonation() {
+			// Impersonations are only valid alongside the session of the
+			// administrator, so one presented on its own is refused.
+			svr.logger.
+				WithField("session_id", s.ID).
+				Warn("impersonation presented as primary session; " +
+					"destroying cookie")
+			svr.destroySessionCookie(w)
+			s = nil
+		}
 
-				// Attach an invalid session so it is not attached to context.
-				s = app.Session{IsRevoked: true}
-			} else if err != nil {
-				svr.sendErrorResponse(
-					w,
-					errors.Wrap(err, "failed to retrieve session"),
-					http.StatusInternalServerError,
-					"",
-				)
-				return
+		if s == nil {
+			// Impersonation cannot outlive the session of the administrator.
+			if _, err := r.Cookie(impersonationCookieKey); err == nil {
+				svr.destroyImpersonationCookie(w)
 			}
 
-			if s.IsValid() {
-				// Attach session to context; attach new context to request.
-				ctx := context.WithValue(r.Context(), contextKeySession, s)
-				r = r.WithContext(ctx)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		svr.renewSession(r, s)
+
+		imp, ok := svr.getSessionByCookie(w, r, impersonationCookieKey,
+			svr.destroyImpersonationCookie)
+		if !ok {
+			return
+		} else if imp != nil {
+			if imp.ImpersonatorSessionID != null.IntFrom(int64(s.ID)) {
+				svr.logger.
+					WithField("session_id", s.ID).
+					WithField("impersonation_session_id", imp.ID).
+					Warn("impersonation belongs to another session; " +
+						"destroying cookie")
+				svr.destroyImpersonationCookie(w)
+			} else {
+				svr.renewSession(r, imp)
+				s = imp
+
+				svr.logger.
+					WithField("impersonator_id", s.ImpersonatorID.Int64).
+					WithField("person_id", s.Person.ID).
+					WithField("method", r.Method).
+					WithField("path", r.URL.Path).
+					Info("handling request as impersonated person")
 			}
 		}
 
-		next.ServeHTTP(w, r)
+		if err := svr.loadPermissions(r.Context(), s); err != nil {
+			svr.sendErrorResponse(w, err, http.StatusInternalServerError, "")
+			return
+		}
+
+		// Attach session to context; attach new context to request.
+		ctx := contextWithSession(r.Context(), *s)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// getSessionByCookie fetches the valid session whose token is held by the
+// cookie of the given name, if any. Cookies holding unknown tokens are
+// destroyed using the given function.
+//
+// Writes an error response and returns false upon failure.
+func (svr *Server) getSessionByCookie(
+	w http.ResponseWriter,
+	r *http.Request,
+	name string,
+	destroy func(w http.ResponseWriter),
+) (*app.Session, bool) {
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return nil, true
+	}
+
+	var s app.Session
+
+	// Cookies issued before tokens were hashed held UUIDs instead, so a
+	// malformed token is treated the same as an unknown one.
+	token, err := app.ParseSecureToken(c.Value)
+	if err == nil {
+		s, err = svr.db.GetSessionByToken(r.Context(), token)
+	} else {
+		err = errors.Wrapf(app.ErrNotFound, "bad token: %v", err)
+	}
+
+	if errors.Is(err, app.ErrNotFound) {
+		svr.logger.
+			WithError(err).
+			WithField("cookie", name).
+			Info("received unknown session token; destroying cookie")
+		destroy(w)
+		return nil, true
+	} else if err != nil {
+		svr.sendErrorResponse(
+			w,
+			errors.Wrap(err, "failed to retrieve session"),
+			http.StatusInternalServerError,
+			"",
+		)
+		return nil, false
+	}
+
+	if !s.IsValid() {
+		return nil, true
+	}
+	return &s, true
+}
+
+// sessionRenewInterval is the minimum amount of time between renewals of a
+// session, to avoid writing to the database on every request.
+const sessionRenewInterval = time.Minute
+
+// renewSession records the activity of a session and extends its idle
+// expiration, unless it was renewed recently from the same IP address.
+//
+// Failures are logged but otherwise ignored, since they need not prevent the
+// request from being handled.
+func (svr *Server) renewSession(r *http.Request, s *app.Session) {
+	now := time.Now().UTC().Round(time.Second)
+	ipAddress := clientIP(r)
+
+	if now.Sub(s.LastSeenAt) < sessionRenewInterval &&
+		ipAddress == s.IPAddress {
+
+		return
+	}
+
+	renewed := *s
+	renewed.Renew(svr.config.Sessions, now)
+	renewed.IPAddress = ipAddress
+
+	err := svr.db.RenewSession(r.Context(), renewed.ID, renewed.IPAddress,
+		renewed.LastSeenAt, renewed.ExpiresAt)
+	if err != nil {
+		svr.logger.
+			WithError(err).
+			WithField("session_id", s.ID).
+			Warn("failed to renew session")
+		return
+	}
+
+	*s = renewed
+}
+
+// loadPermissions attaches the permissions granted to the role of the person
+// of a session.
+func (svr *Server) loadPermissions(ctx context.Context, s *app.Session) error {
+	perms, err := svr.db.GetPermissionsForRole(ctx, s.Person.Role)
+	if err != nil {
+		return errors.Wrap(err, "failed to get permissions for session")
+	}
+
+	s.Permissions = perms
+	return nil
+}
+
+// contextWithSession attaches a session to a context, and attributes any
+// audited changes made with the context to the person of the session.
+func contextWithSession(ctx context.Context, s app.Session) context.Context {
+	actor := app.AuditActorFromContext(ctx)
+	actor.PersonID = null.IntFrom(int64(s.Person.ID))
+	actor.ImpersonatorID = s.ImpersonatorID
+
+	ctx = app.ContextWithAuditActor(ctx, actor)
+	return context.WithValue(ctx, contextKeySession, s)
+}
+
 // getSessionFromContext retrieves the session object from the request context
 // if one is available, and returns nil otherwise.
 //
@@ -75,11 +215,20 @@ func getSessionFromContext(ctx context.Context) *app.Session {
 // An authConfig specifies what authentication parameters are required for an
 // endpoint.
 type authConfig struct {
-	// requireRole determines whether or not required roles are enforced.
-	requireRole bool
-	// allowedRoles is the list of roles allowed to access this endpoint.
-	// Must be blank when !requireRole and have at least one value otherwise.
-	allowedRoles []app.Role
+	// permission is the permission a person must have been granted to access
+	// this endpoint. When blank, any authenticated person may access it.
+	permission app.Permission
+	// scope is the area of the API this endpoint belongs to, such as "admin".
+	// Requests authenticated by an API token must have the read scope for
+	// this area on GET requests and the write scope otherwise. When blank,
+	// API tokens may not access this endpoint.
+	scope string
+	// sessionOnly refuses all requests authenticated by an API token, such as
+	// for endpoints that manage credentials.
+	sessionOnly bool
+	// noImpersonation refuses all requests made by an administrator who is
+	// impersonating someone, such as for endpoints that manage credentials.
+	noImpersonation bool
 }
 
 // requireAuth is a middleware that may be applied to a route or subrouter that
@@ -89,14 +238,6 @@ func (svr *Server) requireAuth(
 	handler http.HandlerFunc,
 ) http.HandlerFunc {
 
-	// This will check the authConfig parameter values ONCE when the router is
-	// first instantiated and the app will not be allowed to start if there is
-	// a parameter mismatch.
-	if (!cfg.requireRole && len(cfg.allowedRoles) > 0) ||
-		(cfg.requireRole && len(cfg.allowedRoles) < 1) {
-		panic("authConfig parameter mismatch")
-	}
-
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		s := getSessionFromContext(r.Context())
 		if s == nil {
@@ -109,24 +250,59 @@ func (svr *Server) requireAuth(
 			return
 		}
 
-		if cfg.requireRole {
-			hasRole := false
-			for _, role := range cfg.allowedRoles {
-				if role == s.Person.Role {
-					hasRole = true
-					break
-				}
-			}
+		if len(cfg.permission) > 0 && !s.HasPermission(cfg.permission) {
+			svr.sendErrorResponse(
+				w,
+				errors.Errorf(
+					"endpoint at path %v requires permission %v but person "+
+						"%d has %v",
+					r.URL.Path, cfg.permission, s.Person.ID, s.Permissions,
+				),
+				http.StatusForbidden,
+				"",
+			)
+			return
+		}
 
-			if !hasRole {
+		if cfg.noImpersonation && s.IsImpersonation() {
+			svr.sendErrorResponse(
+				w,
+				errors.Errorf(
+					"endpoint at path %v does not allow impersonation by %d",
+					r.URL.Path, s.ImpersonatorID.Int64,
+				),
+				http.StatusForbidden,
+				"This cannot be done while impersonating someone.",
+			)
+			return
+		}
+
+		if t := getAPITokenFromContext(r.Context()); t != nil {
+			write := r.Method != http.MethodGet && r.Method != http.MethodHead
+			scope := app.ScopeFor(cfg.scope, write)
+
+			if cfg.sessionOnly || len(cfg.scope) < 1 {
+				svr.sendErrorResponse(
+					w,
+					errors.Errorf(
+						"endpoint at path %v does not allow api tokens",
+						r.URL.Path,
+					),
+					http.StatusForbidden,
+					"This endpoint cannot be used with an API token.",
+				)
+				return
+			} else if !t.HasScope(scope) {
 				svr.sendErrorResponse(
 					w,
 					errors.Errorf(
-						"endpoint at path %v allows roles %v but person has %v",
-						r.URL.Path, cfg.allowedRoles, s.Person.Role,
+						"endpoint at path %v requires scope %v but token %d "+
+							"has %v",
+						r.URL.Path, scope, t.ID, t.Scopes,
 					),
 					http.StatusForbidden,
-					"",
+					"This API token lacks the %s scope.",
+					scope,
 				)
 				return
 			}
@@ -147,11 +323,13 @@ func (svr *Server) requireAuthMiddleware(cfg authConfig) mux.MiddlewareFunc {
 type identityConfig struct {
 	// personID is the identifier for the target Person.
 	personID int
-	// adminOverride should be true when admins may override this identity
-	// requirement.
-	adminOverride bool
-	// sponsorOverride should be true when sponsors OF THE TARGET PERSON
-	sponsorOverride bool
+	// overridePermission, when set, allows people who have been granted it to
+	// override this identity requirement.
+	overridePermission app.Permission
+	// sponsorPermission, when set, allows people who have been granted it to
+	// override this identity requirement for drivers affiliated with one of
+	// their own organizations.
+	sponsorPermission app.Permission
 }
 
 // requireIdentity may be used within a handler to guard an operation when only
@@ -190,13 +368,16 @@ func (svr *Server) requireIdentity(
 		return false
 	}
 
-	if cfg.adminOverride && s.Person.Role == app.RoleAdmin {
-		// PASS: Current user's Person has role of admin, and admins are
-		// allowed to override this identity requirement.
+	if len(cfg.overridePermission) > 0 &&
+		s.HasPermission(cfg.overridePermission) {
+		// PASS: Current user's Person has been granted a permission that
+		// allows them to override this identity requirement.
 		return false
 	}
 
-	if cfg.sponsorOverride && s.Person.Role == app.RoleSponsor {
+	if len(cfg.sponsorPermission) > 0 &&
+		s.HasPermission(cfg.sponsorPermission) {
+
 		// We must determine which organizations the user in the config is
 		// sponsored by, then check to see if the current user is a sponsor
 		// for that organization.
@@ -217,27 +398,17 @@ func (svr *Server) requireIdentity(
 			// Only drivers have sponsors, so this is the only time when a
 			// sponsor override may apply.
 
-			if len(s.Person.Affiliations) != 1 {
-				// FAIL: sponsor should only have one organization.
-				svr.sendErrorResponse(
-					w,
-					errors.Errorf(
-						"sponsor (person id %d) has multiple affiliations (%d)",
-						s.Person.ID,
-						len(s.Person.Affiliations),
-					),
-					http.StatusInternalServerError,
-					"",
-				)
+			target, ok := svr.getSponsorOrganizationID(w, r)
+			if !ok {
+				// FAIL: could not determine which organization the current
+				// user is working on.
 				return true
 			}
 
-			target := s.Person.Affiliations[0]
 			for _, orgID := range p.Affiliations {
 				if target == orgID {
 					// PASS: Current user is a sponsor and the required identity
-					// is affiliated with the organization they have a sponsor
-					// role within.
+					// is affiliated with the organization they are working on.
 					return false
 				}
 			}
@@ -258,9 +429,9 @@ type orgConfig struct {
 	// orgID is the target organization ID that the current user must be
 	// affiliated with for this check to pass.
 	orgID int
-	// adminOverride should be true when administrators may override this
-	// organization requirement.
-	adminOverride bool
+	// overridePermission, when set, allows people who have been granted it to
+	// override this organization requirement.
+	overridePermission app.Permission
 }
 
 // requireOrganization may be used within a handler to guard a particular
@@ -275,6 +446,8 @@ type orgConfig struct {
 //
 // Upon failure of this check, this method will write an appropriate
 // authorization or internal server error to the ResponseWriter for you.
+//
+// nolint: unused // sponsor handlers use getSponsorOrganizationID instead.
 func (svr *Server) requireOrganization(
 	cfg orgConfig,
 	w http.ResponseWriter,
@@ -293,9 +466,10 @@ func (svr *Server) requireOrganization(
 		return true
 	}
 
-	if cfg.adminOverride && s.Person.Role == app.RoleAdmin {
-		// PASS: Current user's Person has role of admin, and admins are
-		// allowed to override this organization requirement.
+	if len(cfg.overridePermission) > 0 &&
+		s.HasPermission(cfg.overridePermission) {
+		// PASS: Current user's Person has been granted a permission that
+		// allows them to override this organization requirement.
 		return false
 	}
 
@@ -1 +1,169 @@
 package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+type authMockDB struct {
+	*mock.DB
+
+	sessions map[app.SecureToken]app.Session
+
+	permissions    map[app.Role][]app.Permission
+	permissionsErr error
+}
+
+func (db *authMockDB) GetSessionByToken(
+	_ context.Context,
+	token app.SecureToken,
+) (app.Session, error) {
+
+	s, ok := db.sessions[token]
+	if !ok {
+		return s, app.ErrNotFound
+	}
+	return s, nil
+}
+
+func (db *authMockDB) GetPermissionsForRole(
+	_ context.Context,
+	role app.Role,
+) ([]app.Permission, error) {
+
+	return db.permissions[role], db.permissionsErr
+}
+
+func TestRequirePermission(t *testing.T) {
+	newSession := func(role app.Role) app.Session {
+		s, err := app.NewSession(app.Person{ID: int(role), Role: role},
+			app.DefaultSessionPolicy(), false)
+		require.NoError(t, err)
+		return *s
+	}
+
+	admin := newSession(app.RoleAdmin)
+	sponsor := newSession(app.RoleSponsor)
+	driver := newSession(app.RoleDriver)
+
+	db := &authMockDB{
+		sessions: map[app.SecureToken]app.Session{
+			admin.Token:   admin,
+			sponsor.Token: sponsor,
+			driver.Token:  driver,
+		},
+		permissions: testRolePermissions,
+	}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	testCases := []struct {
+		alias          string
+		session        app.Session
+		path           string
+		permissions    map[app.Role][]app.Permission
+		permissionsErr error
+		expectCode     int
+	}{
+		{
+			alias:      "NoSession",
+			path:       "/admin/users",
+			expectCode: http.StatusUnauthorized,
+		},
+		{
+			alias:      "Granted",
+			session:    admin,
+			path:       "/admin/users",
+			expectCode: http.StatusOK,
+		},
+		{
+			alias:      "NotGranted",
+			session:    sponsor,
+			path:       "/admin/users",
+			expectCode: http.StatusForbidden,
+		},
+		{
+			alias:   "GrantedToOtherRole",
+			session: sponsor,
+			path:    "/admin/organizations",
+			permissions: map[app.Role][]app.Permission{
+				app.RoleSponsor: {app.PermissionOrganizationsManage},
+			},
+			expectCode: http.StatusOK,
+		},
+		{
+			alias:   "GrantRevoked",
+			session: admin,
+			path:    "/admin/organizations",
+			permissions: map[app.Role][]app.Permission{
+				app.RoleAdmin: {app.PermissionUsersManage},
+			},
+			expectCode: http.StatusForbidden,
+		},
+		{
+			alias:      "NoPermissionRequired",
+			session:    driver,
+			path:       "/driver/balances",
+			expectCode: http.StatusOK,
+		},
+		{
+			alias:          "StoreFailure",
+			session:        admin,
+			path:           "/admin/users",
+			permissionsErr: errors.New("connection refused"),
+			expectCode:     http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			db.permissions = testRolePermissions
+			if tc.permissions != nil {
+				db.permissions = tc.permissions
+			}
+			db.permissionsErr = tc.permissionsErr
+
+			r := httptest.NewRequest("GET", tc.path, nil)
+			testSessionTokenInject(t, r, tc.session.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+		})
+	}
+}
+
+func TestWhoAmIPermissions(t *testing.T) {
+	s, err := app.NewSession(app.Person{ID: 1, Role: app.RoleSponsor},
+		app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	db := &authMockDB{
+		sessions:    map[app.SecureToken]app.Session{s.Token: *s},
+		permissions: testRolePermissions,
+	}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	r := httptest.NewRequest("GET", "/whoami", nil)
+	testSessionTokenInject(t, r, s.Token)
+	w := httptest.NewRecorder()
+
+	api.router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res whoAmIResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+
+	assert.Equal(t, 1, res.ID)
+	assert.Equal(t, testRolePermissions[app.RoleSponsor], res.Permissions)
+}
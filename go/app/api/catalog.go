@@ -10,28 +10,6 @@ import (
 	"github.com/BenJetson/CPSC491-project/go/app"
 )
 
-func getOrganizationIDOfSponsor(r *http.Request) (orgID int, err error) {
-	s := getSessionFromContext(r.Context())
-	if s == nil {
-		err = errors.New("no session")
-		return
-	}
-
-	if s.Role != app.RoleSponsor {
-		err = errors.New("not a sponsor")
-		return
-	}
-
-	if len(s.Affiliations) != 1 {
-		err = errors.Errorf("expected 1 affiliation but found %d",
-			len(s.Affiliations))
-		return
-	}
-
-	orgID = s.Affiliations[0]
-	return
-}
-
 func (svr *Server) handleSponsorVendorSearch(
 	w http.ResponseWriter,
 	r *http.Request,
@@ -104,11 +82,8 @@ func (svr *Server) handleSponsorAddVendorProduct(
 	r *http.Request,
 ) {
 
-	orgID, err := getOrganizationIDOfSponsor(r)
-	if err != nil {
-		svr.sendErrorResponse(w,
-			errors.Wrap(err, "cannot determine sponsor organization identity"),
-			http.StatusInternalServerError, "")
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
 		return
 	}
 
@@ -119,7 +94,7 @@ func (svr *Server) handleSponsorAddVendorProduct(
 
 	p := vp.ToProduct(orgID)
 
-	_, err = svr.db.AddProduct(r.Context(), p)
+	_, err := svr.db.AddProduct(r.Context(), p)
 	if err != nil {
 		svr.sendErrorResponse(w,
 			errors.Wrap(err, "failed to save new product"),
@@ -135,11 +110,8 @@ func (svr *Server) handleGetSponsorCatalog(
 	r *http.Request,
 ) {
 
-	orgID, err := getOrganizationIDOfSponsor(r)
-	if err != nil {
-		svr.sendErrorResponse(w,
-			errors.Wrap(err, "cannot determine sponsor organization identity"),
-			http.StatusInternalServerError, "")
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
 		return
 	}
 
@@ -159,11 +131,8 @@ func (svr *Server) handleGetSponsorCatalogProduct(
 	r *http.Request,
 ) {
 
-	orgID, err := getOrganizationIDOfSponsor(r)
-	if err != nil {
-		svr.sendErrorResponse(w,
-			errors.Wrap(err, "cannot determine sponsor organization identity"),
-			http.StatusInternalServerError, "")
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
 		return
 	}
 
@@ -199,11 +168,8 @@ func (svr *Server) handleSponsorRemoveProduct(
 	r *http.Request,
 ) {
 
-	orgID, err := getOrganizationIDOfSponsor(r)
-	if err != nil {
-		svr.sendErrorResponse(w,
-			errors.Wrap(err, "cannot determine sponsor organization identity"),
-			http.StatusInternalServerError, "")
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
 		return
 	}
 
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// clientIP determines the IP address of the client that made a request.
+//
+// Our API server always sits behind Nginx, which sets the X-Forwarded-For
+// header to the address it received the request from. Any entries to the left
+// of the last one were supplied by the client and cannot be trusted, so only
+// the rightmost entry is used. When the header is absent, the address of the
+// connection itself is used.
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		entries := strings.Split(forwarded, ",")
+		if last := strings.TrimSpace(entries[len(entries)-1]); last != "" {
+			return last
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// RemoteAddr had no port, so it must be the address alone.
+		return r.RemoteAddr
+	}
+	return host
+}
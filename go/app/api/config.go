@@ -3,8 +3,12 @@ package api
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
 )
 
 // Tier represents a different instance of this application.
@@ -26,6 +30,34 @@ const (
 type Config struct {
 	Port int
 	Tier Tier
+
+	// AccountLockout controls throttling of failed logins per account.
+	AccountLockout app.LockoutPolicy
+	// IPLockout controls throttling of failed logins per client IP address.
+	IPLockout app.LockoutPolicy
+
+	// Sessions controls the lifetime of login sessions for each role.
+	Sessions app.SessionPolicy
+
+	// Passwords specifies the requirements that new passwords must meet.
+	Passwords app.PasswordPolicy
+
+	// Invitations is how long people have to accept an invitation to an
+	// account created by an administrator.
+	Invitations time.Duration
+	// OrganizationInvitations is how long people have to accept an invitation
+	// from a sponsor to join their organization.
+	OrganizationInvitations time.Duration
+
+	// OrganizationRetention is how long an organization must have been
+	// archived before an administrator may purge it.
+	OrganizationRetention time.Duration
+
+	// Attachments limits the files that applicants may upload.
+	Attachments app.AttachmentPolicy
+	// AttachmentURLKey is the secret that links to download attachments are
+	// signed with.
+	AttachmentURLKey []byte
 }
 
 // NewConfigFromEnv attempts to construct a new Config using data from
@@ -59,5 +91,192 @@ func NewConfigFromEnv() (c Config, err error) {
 		return
 	}
 
+	c.AccountLockout = app.DefaultAccountLockoutPolicy
+	c.IPLockout = app.DefaultIPLockoutPolicy
+
+	lockoutThreshold := &c.AccountLockout.LockoutThreshold
+	if err = envInt("LOGIN_LOCKOUT_THRESHOLD", lockoutThreshold); err != nil {
+		return
+	}
+
+	ipThreshold := &c.IPLockout.LockoutThreshold
+	if err = envInt("LOGIN_IP_LOCKOUT_THRESHOLD", ipThreshold); err != nil {
+		return
+	}
+
+	// Both lockout policies share a duration and window.
+	duration := &c.AccountLockout.LockoutDuration
+	if err = envDuration("LOGIN_LOCKOUT_DURATION", duration); err != nil {
+		return
+	}
+	c.IPLockout.LockoutDuration = *duration
+
+	window := &c.AccountLockout.Window
+	if err = envDuration("LOGIN_LOCKOUT_WINDOW", window); err != nil {
+		return
+	}
+	c.IPLockout.Window = *window
+
+	c.Sessions = app.DefaultSessionPolicy()
+	if err = sessionPolicyFromEnv(&c.Sessions); err != nil {
+		return
+	}
+
+	c.Passwords = app.DefaultPasswordPolicy
+	if err = passwordPolicyFromEnv(&c.Passwords); err != nil {
+		return
+	}
+
+	c.Invitations = app.DefaultAccountInvitationLifetime
+	if err = envDuration("INVITATION_LIFETIME", &c.Invitations); err != nil {
+		return
+	}
+
+	c.OrganizationInvitations = app.DefaultInvitationLifetime
+	err = envDuration("ORGANIZATION_INVITATION_LIFETIME",
+		&c.OrganizationInvitations)
+	if err != nil {
+		return
+	}
+
+	c.OrganizationRetention = app.DefaultOrganizationRetention
+	err = envDuration("ORGANIZATION_RETENTION", &c.OrganizationRetention)
+	if err != nil {
+		return
+	}
+
+	c.Attachments = app.DefaultAttachmentPolicy
+	if err = attachmentsFromEnv(&c); err != nil {
+		return
+	}
+
 	return
 }
+
+// attachmentsFromEnv applies optional overrides to the attachment policy, and
+// reads the key that download links are signed with from ATTACHMENT_URL_KEY.
+// Local tiers without a key get a random one, which changes upon restart.
+func attachmentsFromEnv(c *Config) error {
+	maxSize := int(c.Attachments.MaxSize)
+	if err := envInt("ATTACHMENT_MAX_SIZE", &maxSize); err != nil {
+		return err
+	}
+	c.Attachments.MaxSize = int64(maxSize)
+
+	err := envDuration("ATTACHMENT_URL_LIFETIME", &c.Attachments.URLLifetime)
+	if err != nil {
+		return err
+	}
+
+	key := os.Getenv("ATTACHMENT_URL_KEY")
+	if len(key) > 0 {
+		c.AttachmentURLKey = []byte(key)
+		return nil
+	} else if c.Tier != TierLocal {
+		return errors.New("must set ATTACHMENT_URL_KEY")
+	}
+
+	token, err := app.NewSecureToken()
+	if err != nil {
+		return errors.Wrap(err, "failed to generate attachment URL key")
+	}
+
+	c.AttachmentURLKey = token[:]
+	return nil
+}
+
+// passwordPolicyFromEnv applies optional overrides to a password policy, and
+// loads its blocklist from the file named by PASSWORD_BLOCKLIST_FILE.
+func passwordPolicyFromEnv(p *app.PasswordPolicy) error {
+	ints := map[string]*int{
+		"PASSWORD_MIN_LENGTH":   &p.MinLength,
+		"PASSWORD_MAX_LENGTH":   &p.MaxLength,
+		"PASSWORD_HISTORY_SIZE": &p.HistorySize,
+	}
+
+	for key, out := range ints {
+		if err := envInt(key, out); err != nil {
+			return err
+		}
+	}
+
+	path := os.Getenv("PASSWORD_BLOCKLIST_FILE")
+	if len(path) < 1 {
+		return nil
+	}
+
+	blocklist, err := app.LoadPasswordBlocklist(path)
+	if err != nil {
+		return errors.Wrap(err, "PASSWORD_BLOCKLIST_FILE could not be loaded")
+	}
+
+	p.Blocklist = blocklist
+	return nil
+}
+
+// sessionPolicyFromEnv applies optional per-role overrides to the lifetimes of
+// a session policy. For example, SESSION_IDLE_TIMEOUT_DRIVER sets the idle
+// timeout of standard driver sessions, and SESSION_REMEMBER_MAX_LIFETIME_ADMIN
+// sets the permanent lifetime of remembered admin sessions.
+func sessionPolicyFromEnv(p *app.SessionPolicy) error {
+	lifetimes := map[string]map[app.Role]app.SessionLifetime{
+		"SESSION_":          p.Standard,
+		"SESSION_REMEMBER_": p.RememberMe,
+	}
+
+	for prefix, byRole := range lifetimes {
+		for role, lifetime := range byRole {
+			suffix := strings.ToUpper(role.String())
+
+			err := envDuration(prefix+"IDLE_TIMEOUT_"+suffix,
+				&lifetime.IdleTimeout)
+			if err != nil {
+				return err
+			}
+
+			err = envDuration(prefix+"MAX_LIFETIME_"+suffix,
+				&lifetime.MaxLifetime)
+			if err != nil {
+				return err
+			}
+
+			byRole[role] = lifetime
+		}
+	}
+
+	return nil
+}
+
+// envInt will parse an optional integer environment variable into out, leaving
+// out untouched when the variable is not set.
+func envInt(key string, out *int) error {
+	value := os.Getenv(key)
+	if len(value) < 1 {
+		return nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return errors.Errorf("%s must be an integer", key)
+	}
+
+	*out = n
+	return nil
+}
+
+// envDuration will parse an optional duration environment variable (such as
+// "15m" or "6h") into out, leaving out untouched when the variable is not set.
+func envDuration(key string, out *time.Duration) error {
+	value := os.Getenv(key)
+	if len(value) < 1 {
+		return nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return errors.Errorf("%s must be a duration", key)
+	}
+
+	*out = d
+	return nil
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// csrfHeaderKey is the header that the web app must echo the CSRF token of its
+// session in, for every request that may change state.
+const csrfHeaderKey = "X-CSRF-Token"
+
+// csrfToken derives the CSRF token of a login session from its session token.
+// Other sites cannot read the session cookie, so they cannot derive the CSRF
+// token either, but the server need not store it.
+func csrfToken(sessionToken app.SecureToken) string {
+	mac := hmac.New(sha256.New, sessionToken[:])
+	mac.Write([]byte("csrf")) // nolint: errcheck // hash writes never fail.
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// getCSRFToken fetches the CSRF token for the session cookie of a request.
+// Returns false when the request has no well-formed session cookie.
+func getCSRFToken(r *http.Request) (string, bool) {
+	c, err := r.Cookie(sessionCookieKey)
+	if err != nil {
+		return "", false
+	}
+
+	token, err := app.ParseSecureToken(c.Value)
+	if err != nil {
+		return "", false
+	}
+
+	return csrfToken(token), true
+}
+
+// isSafeMethod is true for HTTP methods that never change state.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// csrfMiddleware refuses requests that may change state and are authenticated
+// by a session cookie, unless they carry the CSRF token of that session.
+// Requests bearing an API token are exempt, since browsers never attach those
+// automatically.
+//
+// This must run after authContextMiddleware.
+func (svr *Server) csrfMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isSafeMethod(r.Method) ||
+			getSessionFromContext(r.Context()) == nil ||
+			getAPITokenFromContext(r.Context()) != nil {
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		expected, ok := getCSRFToken(r)
+		actual := r.Header.Get(csrfHeaderKey)
+
+		if !ok || !hmac.Equal([]byte(expected), []byte(actual)) {
+			err := errors.New("missing or invalid csrf token")
+			if len(actual) < 1 {
+				err = errors.Errorf("missing %s header", csrfHeaderKey)
+			}
+
+			svr.sendErrorResponse(w, err, http.StatusForbidden,
+				"This request could not be verified. Please reload the "+
+					"page and try again.")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestOrigin fetches the origin that a browser reports a request came from,
+// using the Origin header or else the Referer header. Returns false when the
+// request reports neither.
+func requestOrigin(r *http.Request) (string, bool) {
+	if origin := r.Header.Get("Origin"); len(origin) > 0 {
+		return origin, true
+	}
+
+	referer := r.Header.Get("Referer")
+	if len(referer) < 1 {
+		return "", false
+	}
+
+	u, err := url.Parse(referer)
+	if err != nil {
+		// An unparseable referer cannot match, but was still reported.
+		return referer, true
+	}
+	return u.Scheme + "://" + u.Host, true
+}
+
+// requireSameOrigin protects a handler that may be used without a session,
+// such as logging in, from cross-site request forgery. Without a session there
+// is no CSRF token to check, so requests that change state are refused when
+// the browser reports that they came from another origin than the web app.
+//
+// Requests that report no origin at all are allowed, since browsers report
+// one on every cross-origin request that changes state, and other clients are
+// not at risk.
+func (svr *Server) requireSameOrigin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if isSafeMethod(r.Method) {
+			next(w, r)
+			return
+		}
+
+		origin, ok := requestOrigin(r)
+		if ok && origin != svr.baseURL() {
+			svr.sendErrorResponse(w,
+				errors.Errorf("request from foreign origin %s", origin),
+				http.StatusForbidden,
+				"This request could not be verified. Please reload the "+
+					"page and try again.")
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+func TestCSRFProtection(t *testing.T) {
+	me := app.Person{ID: 1, Role: app.RoleDriver}
+
+	s, err := app.NewSession(me, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	other, err := app.NewSession(me, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	db := &authMockDB{
+		DB:          &mock.DB{},
+		sessions:    map[app.SecureToken]app.Session{s.Token: *s},
+		permissions: testRolePermissions,
+	}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	testCases := []struct {
+		alias      string
+		method     string
+		path       string
+		withCookie bool
+		csrfToken  string
+		expectCode int
+	}{
+		{
+			alias:      "Valid",
+			method:     "POST",
+			path:       "/my/profile/name",
+			withCookie: true,
+			csrfToken:  csrfToken(s.Token),
+			expectCode: http.StatusNoContent,
+		},
+		{
+			alias:      "Missing",
+			method:     "POST",
+			path:       "/my/profile/name",
+			withCookie: true,
+			expectCode: http.StatusForbidden,
+		},
+		{
+			alias:      "OtherSession",
+			method:     "POST",
+			path:       "/my/profile/name",
+			withCookie: true,
+			csrfToken:  csrfToken(other.Token),
+			expectCode: http.StatusForbidden,
+		},
+		{
+			alias:      "Garbage",
+			method:     "POST",
+			path:       "/my/profile/name",
+			withCookie: true,
+			csrfToken:  "aaaaaack",
+			expectCode: http.StatusForbidden,
+		},
+		{
+			alias:      "SafeMethod",
+			method:     "GET",
+			path:       "/driver/balances",
+			withCookie: true,
+			expectCode: http.StatusOK,
+		},
+		{
+			alias:      "NoSession",
+			method:     "POST",
+			path:       "/my/profile/name",
+			expectCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.path,
+				strings.NewReader(`{"first_name": "A", "last_name": "B"}`))
+			if tc.withCookie {
+				r.AddCookie(&http.Cookie{
+					Name:  sessionCookieKey,
+					Value: s.Token.String(),
+				})
+			}
+			if len(tc.csrfToken) > 0 {
+				r.Header.Set(csrfHeaderKey, tc.csrfToken)
+			}
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+		})
+	}
+
+	t.Run("WhoAmI", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/whoami", nil)
+		testSessionTokenInject(t, r, s.Token)
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res whoAmIResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+		assert.Equal(t, csrfToken(s.Token), res.CSRFToken)
+	})
+}
+
+func TestCSRFExemptsAPITokens(t *testing.T) {
+	me := app.Person{ID: 1, Role: app.RoleDriver}
+
+	tok, err := app.NewAPIToken(me.ID, "script",
+		[]app.APITokenScope{app.ScopeMyWrite}, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	db := &apiTokenMockDB{
+		person: me,
+		tokens: map[app.SecureToken]app.APIToken{tok.Token: *tok},
+	}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	r := httptest.NewRequest("POST", "/my/profile/name",
+		strings.NewReader(`{"first_name": "A", "last_name": "B"}`))
+	r.Header.Set("Authorization", bearerPrefix+tok.Token.String())
+	w := httptest.NewRecorder()
+
+	api.router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	r = httptest.NewRequest("GET", "/whoami", nil)
+	r.Header.Set("Authorization", bearerPrefix+tok.Token.String())
+	w = httptest.NewRecorder()
+
+	api.router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "csrf_token")
+}
+
+func TestCSRFRequiresSameOriginWithoutSession(t *testing.T) {
+	db := &loginMockDB{}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	testCases := []struct {
+		alias      string
+		path       string
+		origin     string
+		referer    string
+		expectCode int
+	}{
+		{
+			alias:      "SameOrigin",
+			path:       "/login",
+			origin:     api.baseURL(),
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "SameOriginReferer",
+			path:       "/login",
+			referer:    api.baseURL() + "/login?next=%2F",
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "NoOrigin",
+			path:       "/login",
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "ForeignLogin",
+			path:       "/login",
+			origin:     "https://evil.example",
+			expectCode: http.StatusForbidden,
+		},
+		{
+			alias:      "NullOrigin",
+			path:       "/login",
+			origin:     "null",
+			expectCode: http.StatusForbidden,
+		},
+		{
+			alias:      "ForeignRefererRegister",
+			path:       "/account/register",
+			referer:    "https://evil.example/register",
+			expectCode: http.StatusForbidden,
+		},
+		{
+			alias:      "ForeignInvitationAccept",
+			path:       "/account/invitation/accept",
+			origin:     "https://evil.example",
+			expectCode: http.StatusForbidden,
+		},
+		{
+			alias:      "ForeignOrganizationInvitationAccept",
+			path:       "/account/organization-invitation/accept",
+			origin:     "https://evil.example",
+			expectCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			r := httptest.NewRequest("POST", tc.path, strings.NewReader(`{}`))
+			if len(tc.origin) > 0 {
+				r.Header.Set("Origin", tc.origin)
+			}
+			if len(tc.referer) > 0 {
+				r.Header.Set("Referer", tc.referer)
+			}
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+		})
+	}
+
+	assert.Empty(t, db.attempts)
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// impersonationCookieKey names the cookie holding the token of an
+// impersonation session. The administrator keeps their own session cookie, so
+// that stopping impersonation returns them to their own account.
+const impersonationCookieKey = "IMPERSONATION_TOKEN"
+
+func (svr *Server) destroyImpersonationCookie(w http.ResponseWriter) {
+	cookie := svr.newAuthCookie(impersonationCookieKey, "")
+
+	// A MaxAge less than zero will cause clients to destroy this cookie.
+	cookie.MaxAge = -1
+
+	http.SetCookie(w, cookie)
+}
+
+func (svr *Server) handleAdminImpersonateUser(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	s := getSessionFromContext(r.Context())
+	if s == nil {
+		svr.sendErrorResponse(w, errors.New("no session"),
+			http.StatusUnauthorized, "")
+		return
+	}
+
+	pathParams := mux.Vars(r)
+
+	userID, err := strconv.Atoi(pathParams["userID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "userID must be an integer"),
+			http.StatusBadRequest, "User ID must be an integer.")
+		return
+	}
+
+	target, err := svr.db.GetPersonByID(r.Context(), userID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound, "No such user.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to get user"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	if target.ID == s.Person.ID {
+		svr.sendErrorResponse(w, errors.New("cannot impersonate self"),
+			http.StatusBadRequest, "You cannot impersonate yourself.")
+		return
+	} else if target.IsDeactivated {
+		svr.sendErrorResponse(w,
+			errors.Errorf("person %d is deactivated", target.ID),
+			http.StatusBadRequest,
+			"Deactivated users cannot be impersonated.")
+		return
+	}
+
+	// Impersonating someone who could impersonate others would allow an
+	// administrator to act as yet another administrator.
+	targetPerms, err := svr.db.GetPermissionsForRole(r.Context(), target.Role)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to get permissions of target"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	for _, perm := range targetPerms {
+		if perm == app.PermissionUsersManage {
+			svr.sendErrorResponse(w,
+				errors.Errorf("person %d has permission %s",
+					target.ID, perm),
+				http.StatusForbidden,
+				"Administrators cannot be impersonated.")
+			return
+		}
+	}
+
+	imp, err := app.NewImpersonationSession(*s, target, svr.config.Sessions)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to create impersonation session"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	imp.UserAgent = truncateUserAgent(r.UserAgent())
+	imp.IPAddress = clientIP(r)
+
+	if imp.ID, err = svr.db.CreateSession(r.Context(), *imp); err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to store impersonation session"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	cookie := svr.newAuthCookie(impersonationCookieKey, imp.Token.String())
+	cookie.MaxAge = int(imp.MaxExpiresAt.Sub(imp.CreatedAt) / time.Second)
+	http.SetCookie(w, cookie)
+
+	svr.logger.
+		WithField("impersonator_id", s.Person.ID).
+		WithField("person_id", target.ID).
+		WithField("session_id", imp.ID).
+		Info("started impersonation")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (svr *Server) handleStopImpersonation(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	s := getSessionFromContext(r.Context())
+	if s == nil || !s.IsImpersonation() {
+		svr.sendErrorResponse(w, errors.New("not impersonating"),
+			http.StatusBadRequest, "You are not impersonating anyone.")
+		return
+	}
+
+	if err := svr.db.RevokeSession(r.Context(), s.ID); err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to revoke impersonation session"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	svr.destroyImpersonationCookie(w)
+
+	svr.logger.
+		WithField("impersonator_id", s.ImpersonatorID.Int64).
+		WithField("person_id", s.Person.ID).
+		WithField("session_id", s.ID).
+		Info("stopped impersonation")
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+type impersonationMockDB struct {
+	*authMockDB
+
+	people  map[int]app.Person
+	created []app.Session
+	revoked []int
+}
+
+func (db *impersonationMockDB) GetPersonByID(
+	_ context.Context,
+	personID int,
+) (app.Person, error) {
+
+	p, ok := db.people[personID]
+	if !ok {
+		return p, app.ErrNotFound
+	}
+	return p, nil
+}
+
+func (db *impersonationMockDB) CreateSession(
+	_ context.Context,
+	s app.Session,
+) (int, error) {
+
+	s.ID = 100 + len(db.created)
+	db.created = append(db.created, s)
+	db.sessions[s.Token] = s
+	return s.ID, nil
+}
+
+func (db *impersonationMockDB) RevokeSession(
+	_ context.Context,
+	sessionID int,
+) error {
+
+	db.revoked = append(db.revoked, sessionID)
+	return nil
+}
+
+// nolint: gocyclo // many steps are needed to cover the whole flow.
+func TestImpersonation(t *testing.T) {
+	admin := app.Person{ID: 1, Role: app.RoleAdmin}
+	otherAdmin := app.Person{ID: 2, Role: app.RoleAdmin}
+	driver := app.Person{ID: 3, Role: app.RoleDriver}
+
+	newSession := func(p app.Person, id int) app.Session {
+		s, err := app.NewSession(p, app.DefaultSessionPolicy(), false)
+		require.NoError(t, err)
+
+		s.ID = id
+		return *s
+	}
+
+	adminSession := newSession(admin, 1)
+	otherAdminSession := newSession(otherAdmin, 2)
+
+	db := &impersonationMockDB{
+		authMockDB: &authMockDB{
+			DB: &mock.DB{},
+			sessions: map[app.SecureToken]app.Session{
+				adminSession.Token:      adminSession,
+				otherAdminSession.Token: otherAdminSession,
+			},
+			permissions: testRolePermissions,
+		},
+		people: map[int]app.Person{
+			admin.ID:      admin,
+			otherAdmin.ID: otherAdmin,
+			driver.ID:     driver,
+		},
+	}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	serve := func(
+		method, path string,
+		cookies ...*http.Cookie,
+	) *httptest.ResponseRecorder {
+
+		r := httptest.NewRequest(method, path, strings.NewReader(`{}`))
+		for _, c := range cookies {
+			r.AddCookie(c)
+
+			if c.Name == sessionCookieKey {
+				token, err := app.ParseSecureToken(c.Value)
+				require.NoError(t, err)
+				r.Header.Set(csrfHeaderKey, csrfToken(token))
+			}
+		}
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+		return w
+	}
+
+	findCookie := func(
+		w *httptest.ResponseRecorder,
+		name string,
+	) *http.Cookie {
+
+		for _, c := range w.Result().Cookies() {
+			if c.Name == name {
+				return c
+			}
+		}
+		return nil
+	}
+
+	sessionCookie := func(s app.Session) *http.Cookie {
+		return &http.Cookie{Name: sessionCookieKey, Value: s.Token.String()}
+	}
+
+	t.Run("Refused", func(t *testing.T) {
+		testCases := []struct {
+			alias      string
+			userID     string
+			expectCode int
+		}{
+			{alias: "Self", userID: "1", expectCode: http.StatusBadRequest},
+			{alias: "Admin", userID: "2", expectCode: http.StatusForbidden},
+			{alias: "NoSuchUser", userID: "9", expectCode: http.StatusNotFound},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.alias, func(t *testing.T) {
+				w := serve("POST", "/admin/users/"+tc.userID+"/impersonate",
+					sessionCookie(adminSession))
+
+				assert.Equal(t, tc.expectCode, w.Code)
+				assert.Nil(t, findCookie(w, impersonationCookieKey))
+			})
+		}
+
+		assert.Empty(t, db.created)
+	})
+
+	w := serve("POST", "/admin/users/3/impersonate",
+		sessionCookie(adminSession))
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Len(t, db.created, 1)
+
+	imp := db.created[0]
+	assert.Equal(t, driver.ID, imp.Person.ID)
+	assert.Equal(t, int64(admin.ID), imp.ImpersonatorID.Int64)
+	assert.Equal(t, int64(adminSession.ID), imp.ImpersonatorSessionID.Int64)
+
+	impCookie := findCookie(w, impersonationCookieKey)
+	require.NotNil(t, impCookie)
+	assert.Equal(t, imp.Token.String(), impCookie.Value)
+	assert.Nil(t, findCookie(w, sessionCookieKey),
+		"the admin's own session cookie must be left alone")
+
+	whoAmI := func(cookies ...*http.Cookie) (whoAmIResponse, bool) {
+		w := serve("GET", "/whoami", cookies...)
+		if w.Code == http.StatusNoContent {
+			return whoAmIResponse{}, false
+		}
+
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res whoAmIResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+		return res, true
+	}
+
+	t.Run("ActsAsTarget", func(t *testing.T) {
+		res, ok := whoAmI(sessionCookie(adminSession), impCookie)
+		require.True(t, ok)
+
+		assert.Equal(t, driver.ID, res.ID)
+		assert.Equal(t, int64(admin.ID), res.ImpersonatorID.Int64)
+		assert.Equal(t, testRolePermissions[app.RoleDriver], res.Permissions)
+	})
+
+	t.Run("BlocksCredentialChanges", func(t *testing.T) {
+		for _, path := range []string{
+			"/my/profile/password",
+			"/my/profile/email",
+			"/my/tokens/create",
+		} {
+			w := serve("POST", path, sessionCookie(adminSession), impCookie)
+			assert.Equal(t, http.StatusForbidden, w.Code, path)
+		}
+	})
+
+	t.Run("RequiresImpersonatorSession", func(t *testing.T) {
+		res, ok := whoAmI(sessionCookie(otherAdminSession), impCookie)
+		require.True(t, ok)
+		assert.Equal(t, otherAdmin.ID, res.ID)
+		assert.False(t, res.ImpersonatorID.Valid)
+
+		_, ok = whoAmI(impCookie)
+		assert.False(t, ok)
+	})
+
+	t.Run("RefusedAsPrimarySession", func(t *testing.T) {
+		w := serve("GET", "/whoami", sessionCookie(imp))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		c := findCookie(w, sessionCookieKey)
+		require.NotNil(t, c)
+		assert.True(t, c.MaxAge < 0)
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		w := serve("POST", "/impersonation/stop",
+			sessionCookie(adminSession), impCookie)
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		assert.Equal(t, []int{imp.ID}, db.revoked)
+
+		c := findCookie(w, impersonationCookieKey)
+		require.NotNil(t, c)
+		assert.True(t, c.MaxAge < 0)
+		assert.Nil(t, findCookie(w, sessionCookieKey))
+
+		w = serve("POST", "/impersonation/stop", sessionCookie(adminSession))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,427 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// invalidInvitationMessage is shown for invitations that cannot be accepted,
+// without telling whether they ever existed.
+const invalidInvitationMessage = "This invitation is invalid or has " +
+	"expired. Please ask your sponsor to send you a new one."
+
+type invitationCreateRequest struct {
+	Email string   `json:"email"`
+	Role  app.Role `json:"role_id"`
+}
+
+// sendInvitation emails an invitation link to join an organization to its
+// invitee.
+func (svr *Server) sendInvitation(
+	ctx context.Context,
+	sponsor app.Person,
+	org app.Organization,
+	inv app.Invitation,
+) error {
+
+	link := svr.baseURL() + "/account/organization-invitation?token=" +
+		url.QueryEscape(inv.Token.String())
+
+	err := svr.mailer.SendMail(ctx, app.Mail{
+		To:      inv.Email,
+		Subject: "You're invited to join " + org.Name,
+		Body: fmt.Sprintf("Hello,\n\n"+
+			"%s %s has invited you to join %s as a %s in the Driver "+
+			"Incentive Program. To accept, visit this link:\n\n%s\n\n"+
+			"If you do not have an account yet, you will be asked to "+
+			"create one. This link can only be used once, and expires "+
+			"on %s.\n",
+			sponsor.FirstName, sponsor.LastName, org.Name, inv.Role, link,
+			inv.ExpiresAt.Format("January 2, 2006 at 3:04 PM MST")),
+	})
+
+	return errors.Wrap(err, "failed to send invitation")
+}
+
+func (svr *Server) handleSponsorGetInvitations(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
+		return
+	}
+
+	invitations, err := svr.db.GetInvitationsForOrganization(r.Context(),
+		orgID)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to retrieve invitations"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	if invitations == nil {
+		invitations = make([]app.Invitation, 0)
+	}
+
+	svr.sendJSONResponse(w, invitations)
+}
+
+func (svr *Server) handleSponsorCreateInvitation(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	s := getSessionFromContext(r.Context())
+	if s == nil {
+		svr.sendErrorResponse(w, errors.New("missing session for sponsor"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
+		return
+	}
+
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var data invitationCreateRequest
+	if err := d.Decode(&data); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "received bad json data"),
+			http.StatusBadRequest, "Bad JSON data.")
+		return
+	} else if !validateEmail.MatchString(data.Email) {
+		svr.sendErrorResponse(w, errors.New("invalid email address"),
+			http.StatusBadRequest, "Invalid email address.")
+		return
+	}
+
+	switch data.Role {
+	case app.RoleDriver:
+	case app.RoleSponsor:
+		// Choosing who else runs the organization is part of managing it.
+		if !s.HasPermission(app.PermissionOrganizationManage) {
+			svr.sendErrorResponse(w,
+				errors.Errorf("person %d may not invite sponsors",
+					s.Person.ID),
+				http.StatusForbidden,
+				"You do not have permission to invite sponsors.")
+			return
+		}
+	default:
+		svr.sendErrorResponse(w,
+			errors.Errorf("cannot invite role %d", data.Role),
+			http.StatusBadRequest,
+			"Only drivers and sponsors may be invited.")
+		return
+	}
+
+	org, err := svr.db.GetOrganizationByID(r.Context(), orgID)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to get organization"),
+			http.StatusInternalServerError, "")
+		return
+	} else if org.ArchivedAt.Valid {
+		svr.sendErrorResponse(w,
+			errors.Wrapf(app.ErrOrganizationArchived, "organization %d",
+				orgID),
+			http.StatusConflict,
+			"Archived organizations cannot invite anyone.")
+		return
+	}
+
+	p, err := svr.db.GetPersonByEmail(r.Context(), data.Email)
+	if err != nil && !errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to check email"),
+			http.StatusInternalServerError, "")
+		return
+	} else if err == nil {
+		for _, a := range p.Affiliations {
+			if a == orgID {
+				svr.sendErrorResponse(w,
+					errors.Errorf("person %d is already in org %d",
+						p.ID, orgID),
+					http.StatusConflict,
+					"This person already belongs to your organization.")
+				return
+			}
+		}
+	}
+
+	inv, err := app.NewInvitation(orgID, data.Email, data.Role,
+		null.IntFrom(int64(s.Person.ID)), svr.config.OrganizationInvitations)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to create invitation"),
+			http.StatusInternalServerError, "")
+		return
+	}
+	inv.OrganizationName = org.Name
+
+	inv.ID, err = svr.db.CreateInvitation(r.Context(), *inv)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to create invitation"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	err = svr.sendInvitation(r.Context(), s.Person, org, *inv)
+	if err != nil {
+		svr.sendErrorResponse(w, err, http.StatusInternalServerError,
+			"The invitation was created, but could not be sent. Please "+
+				"try inviting this person again.")
+		return
+	}
+
+	svr.sendJSONResponse(w, inv)
+}
+
+func (svr *Server) handleSponsorRevokeInvitation(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
+		return
+	}
+
+	pathParams := mux.Vars(r)
+
+	invitationID, err := strconv.Atoi(pathParams["invitationID"])
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "invitationID must be an integer"),
+			http.StatusBadRequest, "Invitation ID must be an integer.")
+		return
+	}
+
+	err = svr.db.RevokeInvitation(r.Context(), orgID, invitationID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound,
+			"No such pending invitation.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to revoke invitation"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getValidInvitation finds the invitation with the given token, which must
+// still be valid.
+//
+// Upon failure, writes an error to the ResponseWriter and returns false.
+func (svr *Server) getValidInvitation(
+	w http.ResponseWriter,
+	r *http.Request,
+	rawToken string,
+) (app.Invitation, bool) {
+
+	token, err := app.ParseSecureToken(rawToken)
+	if err != nil {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest,
+			invalidInvitationMessage)
+		return app.Invitation{}, false
+	}
+
+	inv, err := svr.db.GetInvitationByToken(r.Context(), token)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest,
+			invalidInvitationMessage)
+		return inv, false
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to get invitation"),
+			http.StatusInternalServerError, "")
+		return inv, false
+	} else if !inv.IsValid() {
+		svr.sendErrorResponse(w,
+			errors.Errorf("invitation %d is %s", inv.ID, inv.Status),
+			http.StatusBadRequest, invalidInvitationMessage)
+		return inv, false
+	}
+
+	return inv, true
+}
+
+// getInvitee finds the person who already has the email address of an
+// invitation. Will be nil if there is none.
+//
+// Upon failure, writes an error to the ResponseWriter and returns false.
+func (svr *Server) getInvitee(
+	w http.ResponseWriter,
+	r *http.Request,
+	inv app.Invitation,
+) (*app.Person, bool) {
+
+	p, err := svr.db.GetPersonByEmail(r.Context(), inv.Email)
+	if errors.Is(err, app.ErrNotFound) {
+		return nil, true
+	} else if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to get invitee"),
+			http.StatusInternalServerError, "")
+		return nil, false
+	}
+
+	return &p, true
+}
+
+type invitationTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// An invitationPreview tells an invitee what they were invited to, and
+// whether they will need to create an account to accept.
+type invitationPreview struct {
+	OrganizationName string    `json:"organization_name"`
+	Email            string    `json:"email"`
+	Role             app.Role  `json:"role_id"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	HasAccount       bool      `json:"has_account"`
+}
+
+// handleGetInvitation describes a valid invitation to its invitee. The token
+// is sent in the body rather than the URL, so that it is not logged.
+func (svr *Server) handleGetInvitation(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var data invitationTokenRequest
+	if err := d.Decode(&data); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "received bad json data"),
+			http.StatusBadRequest, "Bad JSON data.")
+		return
+	}
+
+	inv, ok := svr.getValidInvitation(w, r, data.Token)
+	if !ok {
+		return
+	}
+
+	invitee, ok := svr.getInvitee(w, r, inv)
+	if !ok {
+		return
+	}
+
+	svr.sendJSONResponse(w, invitationPreview{
+		OrganizationName: inv.OrganizationName,
+		Email:            inv.Email,
+		Role:             inv.Role,
+		ExpiresAt:        inv.ExpiresAt,
+		HasAccount:       invitee != nil,
+	})
+}
+
+// An invitationAcceptRequest accepts an invitation. The name and password are
+// only used to create an account for invitees who do not have one.
+type invitationAcceptRequest struct {
+	Token     string `json:"token"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Password  string `json:"password"`
+}
+
+func (svr *Server) handleAcceptInvitation(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var data invitationAcceptRequest
+	if err := d.Decode(&data); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "received bad json data"),
+			http.StatusBadRequest, "Bad JSON data.")
+		return
+	}
+
+	inv, ok := svr.getValidInvitation(w, r, data.Token)
+	if !ok {
+		return
+	}
+
+	invitee, ok := svr.getInvitee(w, r, inv)
+	if !ok {
+		return
+	}
+
+	var newcomer app.Person
+	if invitee == nil {
+		newcomer = app.Person{
+			FirstName: data.FirstName,
+			LastName:  data.LastName,
+			Email:     inv.Email,
+			Role:      inv.Role,
+		}
+
+		if len(newcomer.FirstName) < 1 {
+			svr.sendErrorResponse(w, errors.New("first name cannot be blank"),
+				http.StatusBadRequest, "First Name cannot be blank.")
+			return
+		} else if len(newcomer.LastName) < 1 {
+			svr.sendErrorResponse(w, errors.New("last name cannot be blank"),
+				http.StatusBadRequest, "Last Name cannot be blank.")
+			return
+		} else if !svr.validateNewPassword(w, r, data.Password, newcomer) {
+			return
+		}
+
+		var err error
+		newcomer.Password, err = app.NewPassword(data.Password)
+		if err != nil {
+			svr.sendErrorResponse(w,
+				errors.Wrap(err, "failed to hash password"),
+				http.StatusInternalServerError, "")
+			return
+		}
+	}
+
+	_, err := svr.db.AcceptInvitation(r.Context(), inv.ID, newcomer)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest,
+			invalidInvitationMessage)
+		return
+	} else if errors.Is(err, app.ErrOrganizationArchived) {
+		svr.sendErrorResponse(w, err, http.StatusConflict,
+			"This organization has been archived.")
+		return
+	} else if errors.Is(err, app.ErrConflict) {
+		svr.sendErrorResponse(w, err, http.StatusConflict,
+			"Your account cannot join this organization as a %s. Please "+
+				"contact the sponsor who invited you.", inv.Role)
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to accept invitation"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
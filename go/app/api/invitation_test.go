@@ -0,0 +1,488 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+type orgInvitationMockDB struct {
+	*authMockDB
+
+	people      map[int]app.Person
+	invitations map[app.SecureToken]app.Invitation
+
+	created  *app.Invitation
+	revoked  int
+	accepted *app.Person
+}
+
+func (db *orgInvitationMockDB) GetOrganizationByID(
+	_ context.Context,
+	orgID int,
+) (app.Organization, error) {
+
+	return app.Organization{ID: orgID, Name: "Trucking Co."}, nil
+}
+
+func (db *orgInvitationMockDB) GetPersonByEmail(
+	_ context.Context,
+	email string,
+) (app.Person, error) {
+
+	for _, p := range db.people {
+		if p.Email == email {
+			return p, nil
+		}
+	}
+	return app.Person{}, app.ErrNotFound
+}
+
+func (db *orgInvitationMockDB) CreateInvitation(
+	_ context.Context,
+	inv app.Invitation,
+) (int, error) {
+
+	db.created = &inv
+	return 3, nil
+}
+
+func (db *orgInvitationMockDB) RevokeInvitation(
+	_ context.Context,
+	orgID, invitationID int,
+) error {
+
+	if orgID != 1 || invitationID != 3 {
+		return app.ErrNotFound
+	}
+
+	db.revoked = invitationID
+	return nil
+}
+
+func (db *orgInvitationMockDB) GetInvitationByToken(
+	_ context.Context,
+	token app.SecureToken,
+) (app.Invitation, error) {
+
+	inv, ok := db.invitations[token]
+	if !ok {
+		return inv, app.ErrNotFound
+	}
+	return inv, nil
+}
+
+func (db *orgInvitationMockDB) AcceptInvitation(
+	_ context.Context,
+	_ int,
+	newcomer app.Person,
+) (int, error) {
+
+	for _, p := range db.people {
+		if p.Role == app.RoleAdmin && newcomer.Password == "" {
+			return 0, app.ErrConflict
+		}
+	}
+
+	db.accepted = &newcomer
+	return 9, nil
+}
+
+func newOrgInvitationTestAPI(
+	t *testing.T,
+	sponsor app.Session,
+	permissions map[app.Role][]app.Permission,
+) (*Server, *orgInvitationMockDB) {
+
+	db := &orgInvitationMockDB{
+		authMockDB: &authMockDB{
+			DB: &mock.DB{},
+			sessions: map[app.SecureToken]app.Session{
+				sponsor.Token: sponsor,
+			},
+			permissions: permissions,
+		},
+		people: map[int]app.Person{
+			5: {
+				ID:           5,
+				FirstName:    "Ben",
+				LastName:     "Godfrey",
+				Email:        "bfgodfr@clemson.edu",
+				Role:         app.RoleDriver,
+				Affiliations: []int{1},
+			},
+		},
+		invitations: make(map[app.SecureToken]app.Invitation),
+	}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	return api, db
+}
+
+func TestSponsorCreateInvitation(t *testing.T) {
+	sponsor, err := app.NewSession(app.Person{
+		ID:           1,
+		FirstName:    "Roger",
+		LastName:     "Van Scoy",
+		Role:         app.RoleSponsor,
+		Affiliations: []int{1},
+	}, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	// These sponsors may manage drivers, but not their organization.
+	limited := map[app.Role][]app.Permission{
+		app.RoleSponsor: {app.PermissionDriversManage},
+	}
+
+	testCases := []struct {
+		alias       string
+		body        string
+		permissions map[app.Role][]app.Permission
+		expectCode  int
+		expectRole  app.Role
+	}{
+		{
+			alias:      "Driver",
+			body:       `{"email": "jdoe@clemson.edu", "role_id": 4}`,
+			expectCode: http.StatusOK,
+			expectRole: app.RoleDriver,
+		},
+		{
+			alias:      "Sponsor",
+			body:       `{"email": "jdoe@clemson.edu", "role_id": 2}`,
+			expectCode: http.StatusOK,
+			expectRole: app.RoleSponsor,
+		},
+		{
+			alias:       "DriverWithoutOrganizationManage",
+			body:        `{"email": "jdoe@clemson.edu", "role_id": 4}`,
+			permissions: limited,
+			expectCode:  http.StatusOK,
+			expectRole:  app.RoleDriver,
+		},
+		{
+			alias:       "SponsorWithoutOrganizationManage",
+			body:        `{"email": "jdoe@clemson.edu", "role_id": 2}`,
+			permissions: limited,
+			expectCode:  http.StatusForbidden,
+		},
+		{
+			alias:      "Admin",
+			body:       `{"email": "jdoe@clemson.edu", "role_id": 1}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "BadEmail",
+			body:       `{"email": "jdoe", "role_id": 4}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "AlreadyAffiliated",
+			body:       `{"email": "bfgodfr@clemson.edu", "role_id": 4}`,
+			expectCode: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			permissions := tc.permissions
+			if permissions == nil {
+				permissions = testRolePermissions
+			}
+			api, db := newOrgInvitationTestAPI(t, *sponsor, permissions)
+
+			r := httptest.NewRequest("POST", "/sponsor/invitations/create",
+				strings.NewReader(tc.body))
+			testSessionTokenInject(t, r, sponsor.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+			require.Equal(t, tc.expectCode, w.Code)
+
+			mailer := api.mailer.(*mock.Mailer)
+			if tc.expectCode != http.StatusOK {
+				assert.Nil(t, db.created)
+				assert.Empty(t, mailer.Sent)
+				return
+			}
+
+			require.NotNil(t, db.created)
+			assert.Equal(t, 1, db.created.OrganizationID)
+			assert.Equal(t, "jdoe@clemson.edu", db.created.Email)
+			assert.Equal(t, tc.expectRole, db.created.Role)
+			assert.Equal(t, null.IntFrom(1), db.created.InvitedBy)
+			assert.Equal(t, app.InvitationPending, db.created.Status)
+
+			require.Len(t, mailer.Sent, 1)
+			assert.Equal(t, "jdoe@clemson.edu", mailer.Sent[0].To)
+			assert.Contains(t, mailer.Sent[0].Body,
+				api.baseURL()+"/account/organization-invitation?token="+
+					db.created.Token.String())
+		})
+	}
+}
+
+func TestSponsorRevokeInvitation(t *testing.T) {
+	sponsor, err := app.NewSession(app.Person{
+		ID:           1,
+		Role:         app.RoleSponsor,
+		Affiliations: []int{1},
+	}, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		alias      string
+		path       string
+		expectCode int
+	}{
+		{
+			alias:      "Pending",
+			path:       "/sponsor/invitations/3/revoke",
+			expectCode: http.StatusNoContent,
+		},
+		{
+			alias:      "NotFound",
+			path:       "/sponsor/invitations/4/revoke",
+			expectCode: http.StatusNotFound,
+		},
+		{
+			alias:      "BadID",
+			path:       "/sponsor/invitations/three/revoke",
+			expectCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			api, db := newOrgInvitationTestAPI(t, *sponsor,
+				testRolePermissions)
+
+			r := httptest.NewRequest("POST", tc.path, nil)
+			testSessionTokenInject(t, r, sponsor.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+			require.Equal(t, tc.expectCode, w.Code)
+
+			if tc.expectCode == http.StatusNoContent {
+				assert.Equal(t, 3, db.revoked)
+			} else {
+				assert.Zero(t, db.revoked)
+			}
+		})
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	sponsor, err := app.NewSession(app.Person{
+		ID:           1,
+		Role:         app.RoleSponsor,
+		Affiliations: []int{1},
+	}, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	newInvitation := func(
+		email string,
+		lifetime time.Duration,
+	) *app.Invitation {
+
+		inv, err := app.NewInvitation(1, email, app.RoleDriver,
+			null.IntFrom(1), lifetime)
+		require.NoError(t, err)
+		return inv
+	}
+
+	fresh := newInvitation("jdoe@clemson.edu", time.Hour)
+	existing := newInvitation("bfgodfr@clemson.edu", time.Hour)
+	expired := newInvitation("jdoe@clemson.edu", -time.Hour)
+
+	revoked := newInvitation("jdoe@clemson.edu", time.Hour)
+	revoked.Status = app.InvitationRevoked
+
+	unknown, err := app.NewSecureToken()
+	require.NoError(t, err)
+
+	const password = "long-enough-phrase-2021"
+
+	testCases := []struct {
+		alias          string
+		token          string
+		body           string
+		adminInvitee   bool
+		expectCode     int
+		expectNewcomer *app.Person
+	}{
+		{
+			alias: "NewAccount",
+			token: fresh.Token.String(),
+			body: `"first_name": "Jane", "last_name": "Doe",
+				"password": "` + password + `"`,
+			expectCode: http.StatusNoContent,
+			expectNewcomer: &app.Person{
+				FirstName: "Jane",
+				LastName:  "Doe",
+				Email:     "jdoe@clemson.edu",
+				Role:      app.RoleDriver,
+			},
+		},
+		{
+			alias:          "ExistingAccount",
+			token:          existing.Token.String(),
+			expectCode:     http.StatusNoContent,
+			expectNewcomer: &app.Person{},
+		},
+		{
+			alias:        "Conflict",
+			token:        existing.Token.String(),
+			adminInvitee: true,
+			expectCode:   http.StatusConflict,
+		},
+		{
+			alias: "MissingName",
+			token: fresh.Token.String(),
+			body: `"first_name": "Jane",
+				"password": "` + password + `"`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias: "WeakPassword",
+			token: fresh.Token.String(),
+			body: `"first_name": "Jane", "last_name": "Doe",
+				"password": "short"`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "Expired",
+			token:      expired.Token.String(),
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "Revoked",
+			token:      revoked.Token.String(),
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "Unknown",
+			token:      unknown.String(),
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "Malformed",
+			token:      "aaaaaack",
+			expectCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			api, db := newOrgInvitationTestAPI(t, *sponsor,
+				testRolePermissions)
+			for _, inv := range []*app.Invitation{
+				fresh, existing, expired, revoked,
+			} {
+				db.invitations[inv.Token] = *inv
+			}
+			if tc.adminInvitee {
+				db.people[5] = app.Person{
+					ID:    5,
+					Email: "bfgodfr@clemson.edu",
+					Role:  app.RoleAdmin,
+				}
+			}
+
+			body := `{"token": "` + tc.token + `"`
+			if len(tc.body) > 0 {
+				body += ", " + tc.body
+			}
+			body += "}"
+
+			r := httptest.NewRequest("POST",
+				"/account/organization-invitation/accept",
+				strings.NewReader(body))
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+			require.Equal(t, tc.expectCode, w.Code)
+
+			if tc.expectNewcomer == nil {
+				assert.Nil(t, db.accepted)
+				return
+			}
+
+			require.NotNil(t, db.accepted)
+			if tc.expectNewcomer.Email == "" {
+				assert.Equal(t, tc.expectNewcomer, db.accepted)
+				return
+			}
+
+			ok, _ := db.accepted.Password.Verify(password)
+			assert.True(t, ok)
+
+			db.accepted.Password = ""
+			assert.Equal(t, tc.expectNewcomer, db.accepted)
+		})
+	}
+}
+
+func TestGetInvitation(t *testing.T) {
+	sponsor, err := app.NewSession(app.Person{
+		ID:           1,
+		Role:         app.RoleSponsor,
+		Affiliations: []int{1},
+	}, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		alias            string
+		email            string
+		expectHasAccount bool
+	}{
+		{alias: "NewAccount", email: "jdoe@clemson.edu"},
+		{
+			alias:            "ExistingAccount",
+			email:            "bfgodfr@clemson.edu",
+			expectHasAccount: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			api, db := newOrgInvitationTestAPI(t, *sponsor,
+				testRolePermissions)
+
+			inv, err := app.NewInvitation(1, tc.email, app.RoleSponsor,
+				null.IntFrom(1), time.Hour)
+			require.NoError(t, err)
+			inv.OrganizationName = "Trucking Co."
+			db.invitations[inv.Token] = *inv
+
+			r := httptest.NewRequest("POST", "/account/organization-invitation",
+				strings.NewReader(`{"token": "`+inv.Token.String()+`"}`))
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var preview invitationPreview
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&preview))
+			assert.Equal(t, invitationPreview{
+				OrganizationName: "Trucking Co.",
+				Email:            tc.email,
+				Role:             app.RoleSponsor,
+				ExpiresAt:        inv.ExpiresAt,
+				HasAccount:       tc.expectHasAccount,
+			}, preview)
+		})
+	}
+}
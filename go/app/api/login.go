@@ -2,19 +2,31 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
 
 	"github.com/BenJetson/CPSC491-project/go/app"
 )
 
 const sessionCookieKey = "SESSION_TOKEN"
 
+// timingPassword is a hash that no password will match, checked against when
+// a login names an unknown account. It must use the DefaultPasswordHashParams,
+// so that checking it takes as long as checking a real password.
+const timingPassword app.Password = "$argon2id$v=19$m=19456,t=2,p=1$" +
+	"rKGDNfNOODsXjQH6TvvLkQ$kk3pFC51DrPRs99Oq9kRzMJHxOs8oCqe4OxEry5kseQ"
+
 type loginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// RememberMe asks for a longer-lived session on this device.
+	RememberMe bool `json:"remember_me"`
 }
 
 func (req *loginRequest) validateFields() error {
@@ -27,6 +39,24 @@ func (req *loginRequest) validateFields() error {
 	return nil
 }
 
+// maxUserAgentLength is the maximum number of bytes of a User-Agent header
+// that will be stored with a session.
+const maxUserAgentLength = 512
+
+// truncateUserAgent shortens an overly long User-Agent header for storage.
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > maxUserAgentLength {
+		return userAgent[:maxUserAgentLength]
+	}
+	return userAgent
+}
+
+// loginAttemptKey normalizes an email address so that failed login attempts
+// are counted together regardless of case or surrounding whitespace.
+func loginAttemptKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 func (svr *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	d := json.NewDecoder(r.Body)
 	d.DisallowUnknownFields()
@@ -47,79 +77,220 @@ func (svr *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	attempt := app.LoginAttempt{
+		Email:       loginAttemptKey(credentials.Email),
+		IPAddress:   clientIP(r),
+		AttemptedAt: time.Now().UTC().Round(time.Second),
+	}
+
+	attemptID, ok := svr.startLoginAttempt(w, r, attempt)
+	if !ok {
+		return
+	}
+
 	p, err := svr.db.GetPersonByEmail(r.Context(), credentials.Email)
 	if errors.Is(err, app.ErrNotFound) {
+		// Spend the same effort hashing as we would for a real account, so
+		// that response timing does not reveal which accounts exist.
+		timingPassword.Verify(credentials.Password)
+
+		svr.failLogin(w,
+			errors.Wrapf(err, "no person by email %s", credentials.Email))
+		return
+	} else if err != nil {
 		svr.sendErrorResponse(
 			w,
-			errors.Wrapf(err, "no person by email %s", credentials.Email),
-			http.StatusUnauthorized,
-			"Email address or password was incorrect.",
+			errors.Wrap(err, "failed to retrieve person"),
+			http.StatusInternalServerError,
+			"",
 		)
 		return
-	} else if err != nil {
+	}
+
+	ok, needsRehash := p.Password.Verify(credentials.Password)
+	if !ok {
+		svr.failLogin(w, errors.New("password did not match"))
+		return
+	} else if needsRehash {
+		svr.rehashPassword(r, p, credentials.Password)
+	}
+
+	if err = svr.db.SucceedLoginAttempt(r.Context(), attemptID); err != nil {
 		svr.sendErrorResponse(
 			w,
-			errors.Wrap(err, "failed to retrieve person"),
+			errors.Wrap(err, "failed to record login attempt"),
 			http.StatusInternalServerError,
 			"",
 		)
 		return
 	}
 
-	if !p.Password.Verify(credentials.Password) {
+	err = svr.db.ClearLoginFailuresForEmail(r.Context(), attempt.Email)
+	if err != nil {
 		svr.sendErrorResponse(
 			w,
-			errors.Wrap(err, "password did not match"),
-			http.StatusUnauthorized,
-			"Email address or password was incorrect.",
+			errors.Wrap(err, "failed to clear login failures"),
+			http.StatusInternalServerError,
+			"",
 		)
 		return
 	}
 
-	var s *app.Session
-	if s, err = app.NewSession(p); err != nil {
+	if !svr.startSession(w, r, p, credentials.RememberMe) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// rehashPassword replaces the password hash of a person who has just logged
+// in with a hash using the current parameters, so that old hashes are upgraded
+// without forcing a reset. The password itself is unchanged, so the old hash
+// is not kept in their password history.
+//
+// Failures are logged but otherwise ignored, since the old hash still works.
+func (svr *Server) rehashPassword(
+	r *http.Request,
+	p app.Person,
+	plaintext string,
+) {
+
+	logger := svr.logger.WithField("person_id", p.ID)
+
+	hashedPass, err := app.NewPassword(plaintext)
+	if err != nil {
+		logger.WithError(err).Warn("failed to rehash password")
+		return
+	}
+
+	err = svr.db.RehashPersonPassword(r.Context(), p.ID, p.Password,
+		hashedPass)
+	if err != nil {
+		logger.WithError(err).Warn("failed to store rehashed password")
+		return
+	}
+
+	logger.Info("upgraded password hash")
+}
+
+// startSession creates a new login session for a person who has been
+// authenticated, and sets the session cookie on the client. Writes an error
+// response and returns false upon failure.
+func (svr *Server) startSession(
+	w http.ResponseWriter,
+	r *http.Request,
+	p app.Person,
+	rememberMe bool,
+) bool {
+
+	s, err := app.NewSession(p, svr.config.Sessions, rememberMe)
+	if err != nil {
 		svr.sendErrorResponse(
 			w,
 			errors.Wrap(err, "failed to create new login session"),
 			http.StatusInternalServerError,
 			"",
 		)
-		return
-	} else if _, err = svr.db.CreateSession(r.Context(), *s); err != nil {
+		return false
+	}
+
+	s.UserAgent = truncateUserAgent(r.UserAgent())
+	s.IPAddress = clientIP(r)
+
+	if _, err = svr.db.CreateSession(r.Context(), *s); err != nil {
 		svr.sendErrorResponse(
 			w,
 			errors.Wrap(err, "failed to store login session"),
 			http.StatusInternalServerError,
 			"",
 		)
-		return
+		return false
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:  sessionCookieKey,
-		Value: s.Token.String(),
+	cookie := svr.newAuthCookie(sessionCookieKey, s.Token.String())
 
-		// Ensure that this cookie is only used on the same domain with the
-		// same protocol.
-		Domain:   svr.hostname(),
-		SameSite: http.SameSiteStrictMode,
-		Secure:   svr.useHTTPS(),
-		Path:     "/",
+	// Sessions expire on our app server-side, but let's ask the client to
+	// ditch the cookie automatically as well. Unless the person asked to be
+	// remembered, the cookie only lasts until the browser is closed.
+	if s.IsRememberMe {
+		cookie.MaxAge = int(s.MaxExpiresAt.Sub(s.CreatedAt) / time.Second)
+	}
 
-		// HttpOnly hides this cookie from JavaScript in browsers for security.
-		HttpOnly: true,
+	http.SetCookie(w, cookie)
 
-		// Sessions expire on our app server-side, but let's ask the client to
-		// ditch the cookie automatically as well.
-		MaxAge: int(app.SessionLength / time.Second),
-	})
+	// The web app must echo this in all further requests that change state.
+	w.Header().Set(csrfHeaderKey, csrfToken(s.Token))
 
-	w.WriteHeader(http.StatusNoContent)
+	return true
 }
 
-func (svr *Server) destroySessionCookie(w http.ResponseWriter) {
-	http.SetCookie(w, &http.Cookie{
-		Name: sessionCookieKey,
+// startLoginAttempt records a login attempt, which counts as failed until it
+// is known to have succeeded, unless the recent failed attempts for either
+// its account or client IP address call for it to be delayed or locked out.
+// The attempt is recorded before the password is checked, so that concurrent
+// attempts cannot slip past the limits together.
+//
+// Returns the ID of the recorded attempt, or false when the attempt was
+// refused and a response has already been written.
+func (svr *Server) startLoginAttempt(
+	w http.ResponseWriter,
+	r *http.Request,
+	a app.LoginAttempt,
+) (int, bool) {
+
+	attemptID, wait, err := svr.db.StartLoginAttempt(r.Context(), a,
+		svr.config.AccountLockout, svr.config.IPLockout)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to record login attempt"),
+			http.StatusInternalServerError, "")
+		return 0, false
+	} else if wait <= 0 {
+		return attemptID, true
+	}
+
+	// Round up, so that clients honoring Retry-After never retry too early.
+	seconds := int((wait + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+
+	svr.sendErrorResponse(w,
+		errors.Errorf("login for %s from %s throttled for %s",
+			a.Email, a.IPAddress, wait),
+		http.StatusTooManyRequests,
+		"Too many failed login attempts. Please try again in %s.",
+		describeWait(seconds),
+	)
+	return 0, false
+}
+
+// describeWait describes a wait of the given number of seconds for users.
+func describeWait(seconds int) string {
+	if seconds <= 60 {
+		return fmt.Sprintf("%d seconds", seconds)
+	}
+
+	minutes := (seconds + 59) / 60
+	return fmt.Sprintf("%d minutes", minutes)
+}
+
+// failLogin sends the generic response for bad credentials. The response is
+// identical whether or not the account exists. The attempt itself has already
+// been recorded as failed by startLoginAttempt.
+func (svr *Server) failLogin(w http.ResponseWriter, cause error) {
+	svr.sendErrorResponse(
+		w,
+		cause,
+		http.StatusUnauthorized,
+		"Email address or password was incorrect.",
+	)
+}
+
+// newAuthCookie creates a cookie that holds a secret token authenticating the
+// client.
+func (svr *Server) newAuthCookie(name, value string) *http.Cookie {
+	return &http.Cookie{
+		Name:  name,
+		Value: value,
 
 		// Ensure that this cookie is only used on the same domain with the
 		// same protocol.
@@ -130,15 +301,22 @@ func (svr *Server) destroySessionCookie(w http.ResponseWriter) {
 
 		// HttpOnly hides this cookie from JavaScript in browsers for security.
 		HttpOnly: true,
+	}
+}
+
+func (svr *Server) destroySessionCookie(w http.ResponseWriter) {
+	cookie := svr.newAuthCookie(sessionCookieKey, "")
+
+	// A MaxAge less than zero will cause clients to destroy this cookie.
+	cookie.MaxAge = -1
 
-		// A MaxAge less than zero will cause clients to destroy this cookie.
-		MaxAge: -1,
-	})
+	http.SetCookie(w, cookie)
 }
 
 func (svr *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	// Requests using an API token have no login session to revoke.
 	s := getSessionFromContext(r.Context())
-	if s != nil {
+	if s != nil && getAPITokenFromContext(r.Context()) == nil {
 		if err := svr.db.RevokeSession(r.Context(), s.ID); err != nil {
 			svr.sendErrorResponse(
 				w,
@@ -148,19 +326,63 @@ func (svr *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 			)
 			return
 		}
+
+		// Logging out while impersonating logs the administrator out too,
+		// since they share the same browser.
+		if s.IsImpersonation() {
+			err := svr.db.RevokeSession(r.Context(),
+				int(s.ImpersonatorSessionID.Int64))
+			if err != nil {
+				svr.sendErrorResponse(
+					w,
+					errors.Wrap(err, "failed to revoke impersonator session"),
+					http.StatusInternalServerError,
+					"",
+				)
+				return
+			}
+		}
 	}
 
-	// Destroy the session cookie on the client.
+	// Destroy the session cookies on the client.
 	svr.destroySessionCookie(w)
+	svr.destroyImpersonationCookie(w)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// A whoAmIResponse describes the current user and what they may do, so that
+// the web app can hide controls they cannot use.
+type whoAmIResponse struct {
+	app.Person
+	Permissions []app.Permission `json:"permissions"`
+	// ImpersonatorID is the person ID of the administrator viewing the app as
+	// the current user, if any.
+	ImpersonatorID null.Int `json:"impersonator_id"`
+	// CSRFToken must be sent in the X-CSRF-Token header of any request that
+	// changes state. It is blank when not using a login session.
+	CSRFToken string `json:"csrf_token,omitempty"`
+}
+
 func (svr *Server) handleWhoAmI(w http.ResponseWriter, r *http.Request) {
 	s := getSessionFromContext(r.Context())
 	if s == nil {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
-	svr.sendJSONResponse(w, s.Person)
+
+	res := whoAmIResponse{
+		Person:      s.Person,
+		Permissions: s.Permissions,
+
+		ImpersonatorID: s.ImpersonatorID,
+	}
+	if res.Permissions == nil {
+		res.Permissions = []app.Permission{}
+	}
+	if getAPITokenFromContext(r.Context()) == nil {
+		res.CSRFToken, _ = getCSRFToken(r)
+	}
+
+	svr.sendJSONResponse(w, res)
 }
@@ -2,16 +2,18 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
-	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
 
 	"github.com/BenJetson/CPSC491-project/go/app"
 	"github.com/BenJetson/CPSC491-project/go/app/mock"
@@ -29,6 +31,67 @@ type loginMockDB struct {
 	createSessionErr error
 
 	revokeSessionErr error
+
+	accountFailures app.LoginFailures
+	ipFailures      app.LoginFailures
+	attempts        []app.LoginAttempt
+	attemptErr      error
+
+	updatedPasswords  []app.Password
+	rehashedPasswords []app.Password
+}
+
+func (db *loginMockDB) UpdatePersonPassword(
+	_ context.Context,
+	_ int,
+	p app.Password,
+) error {
+
+	db.updatedPasswords = append(db.updatedPasswords, p)
+	return nil
+}
+
+func (db *loginMockDB) RehashPersonPassword(
+	_ context.Context,
+	_ int,
+	_, newPass app.Password,
+) error {
+
+	db.rehashedPasswords = append(db.rehashedPasswords, newPass)
+	return nil
+}
+
+func (db *loginMockDB) StartLoginAttempt(
+	_ context.Context,
+	a app.LoginAttempt,
+	accountPolicy, ipPolicy app.LockoutPolicy,
+) (int, time.Duration, error) {
+
+	if db.attemptErr != nil {
+		return 0, 0, db.attemptErr
+	}
+
+	wait := accountPolicy.RetryAfter(db.accountFailures, a.AttemptedAt)
+	ipWait := ipPolicy.RetryAfter(db.ipFailures, a.AttemptedAt)
+	if ipWait > wait {
+		wait = ipWait
+	}
+
+	if wait > 0 {
+		return 0, wait, nil
+	}
+
+	db.attempts = append(db.attempts, a)
+	return len(db.attempts), 0, nil
+}
+
+func (db *loginMockDB) SucceedLoginAttempt(
+	_ context.Context,
+	attemptID int,
+) error {
+
+	db.attempts[attemptID-1].Succeeded = true
+	return nil
 }
 
 func (db *loginMockDB) GetPersonByEmail(
@@ -41,7 +104,7 @@ func (db *loginMockDB) GetPersonByEmail(
 
 func (db *loginMockDB) GetSessionByToken(
 	_ context.Context,
-	_ uuid.UUID,
+	_ app.SecureToken,
 ) (app.Session, error) {
 
 	return db.sessionByToken, db.sessionByTokenErr
@@ -59,8 +122,13 @@ func (db *loginMockDB) RevokeSession(_ context.Context, _ int) error {
 	return db.revokeSessionErr
 }
 
-func testSessionTokenInject(_ *testing.T, r *http.Request, token uuid.UUID) {
-	if token == uuid.Nil {
+func testSessionTokenInject(
+	_ *testing.T,
+	r *http.Request,
+	token app.SecureToken,
+) {
+
+	if token.IsZero() {
 		return
 	}
 
@@ -70,12 +138,20 @@ func testSessionTokenInject(_ *testing.T, r *http.Request, token uuid.UUID) {
 	}
 
 	r.Header.Add("Cookie", c.String())
+
+	// Act as the web app does, so that requests which change state pass.
+	r.Header.Set(csrfHeaderKey, csrfToken(token))
 }
 
 func TestHandleLogin(t *testing.T) {
 	db := &loginMockDB{}
 	api, _, _ := newTestAPI(t, db, nil)
 
+	timingParams, err := timingPassword.Params()
+	require.NoError(t, err)
+	require.Equal(t, app.DefaultPasswordHashParams, timingParams,
+		"timingPassword must cost as much to check as a real password")
+
 	pass, err := app.NewPassword("zxcvbnJKL")
 	require.NoError(t, err)
 
@@ -86,6 +162,15 @@ func TestHandleLogin(t *testing.T) {
 		Password:  pass,
 	}
 
+	legacyPass, err := app.HashPassword("zxcvbnJKL", app.PasswordHashParams{
+		Algorithm:  app.PasswordAlgorithmBcrypt,
+		BcryptCost: 4,
+	})
+	require.NoError(t, err)
+
+	legacy := p
+	legacy.Password = legacyPass
+
 	testCases := []struct {
 		alias               string
 		body                string
@@ -96,6 +181,7 @@ func TestHandleLogin(t *testing.T) {
 		expectCode          int
 		expectCookie        bool
 		expectDestroyCookie bool
+		expectRehash        bool
 	}{
 		{
 			alias:      "NilBody",
@@ -201,6 +287,30 @@ func TestHandleLogin(t *testing.T) {
 			expectCode:      http.StatusNoContent,
 			expectCookie:    true,
 		},
+		{
+			alias: "SuccessRehash",
+			body: `
+				{
+					"email": "jack@box.net",
+					"password": "zxcvbnJKL"
+				}
+			`,
+			dbPersonByEmail: legacy,
+			expectCode:      http.StatusNoContent,
+			expectCookie:    true,
+			expectRehash:    true,
+		},
+		{
+			alias: "WrongPasswordNoRehash",
+			body: `
+				{
+					"email": "jack@box.net",
+					"password": "p@$$w0rd=ye$"
+				}
+			`,
+			dbPersonByEmail: legacy,
+			expectCode:      http.StatusUnauthorized,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -208,6 +318,8 @@ func TestHandleLogin(t *testing.T) {
 			db.personByEmail = tc.dbPersonByEmail
 			db.personByEmailErr = tc.dbPersonByEmailErr
 			db.createSessionErr = tc.dbCreateSessionErr
+			db.updatedPasswords = nil
+			db.rehashedPasswords = nil
 
 			var body io.Reader
 			if !tc.nilBody {
@@ -247,6 +359,181 @@ func TestHandleLogin(t *testing.T) {
 			)
 			assert.Equal(t, tc.expectDestroyCookie, willDestroyLoginCookie,
 				"cookie destruction expectation mismatch")
+			assert.Equal(t, tc.expectCookie,
+				len(w.Header().Get(csrfHeaderKey)) > 0,
+				"csrf token presence expectation mismatch")
+
+			// Rehashing must not record the old hash in password history.
+			assert.Empty(t, db.updatedPasswords)
+			if !tc.expectRehash {
+				assert.Empty(t, db.rehashedPasswords)
+				return
+			}
+
+			require.Len(t, db.rehashedPasswords, 1)
+			rehashed := db.rehashedPasswords[0]
+
+			ok, needsRehash := rehashed.Verify("zxcvbnJKL")
+			assert.True(t, ok)
+			assert.False(t, needsRehash)
+		})
+	}
+}
+
+func TestHandleLoginLockout(t *testing.T) {
+	db := &loginMockDB{}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	api.config.AccountLockout = app.DefaultAccountLockoutPolicy
+	api.config.IPLockout = app.DefaultIPLockoutPolicy
+
+	pass, err := app.NewPassword("zxcvbnJKL")
+	require.NoError(t, err)
+
+	p := app.Person{
+		FirstName: "Billy Joe",
+		LastName:  "Bob",
+		Email:     "jack@box.net",
+		Password:  pass,
+	}
+
+	recent := func(count int) app.LoginFailures {
+		return app.LoginFailures{
+			Count:        count,
+			LastFailedAt: null.TimeFrom(time.Now().UTC()),
+		}
+	}
+
+	testCases := []struct {
+		alias              string
+		password           string
+		dbPersonByEmail    app.Person
+		dbPersonByEmailErr error
+		accountFailures    app.LoginFailures
+		ipFailures         app.LoginFailures
+		attemptErr         error
+		expectCode         int
+		expectAttempt      bool
+		expectSucceeded    bool
+	}{
+		{
+			alias:           "NoFailures",
+			password:        "zxcvbnJKL",
+			dbPersonByEmail: p,
+			expectCode:      http.StatusNoContent,
+			expectAttempt:   true,
+			expectSucceeded: true,
+		},
+		{
+			alias:           "FailuresBelowDelay",
+			password:        "zxcvbnJKL",
+			dbPersonByEmail: p,
+			accountFailures: recent(2),
+			expectCode:      http.StatusNoContent,
+			expectAttempt:   true,
+			expectSucceeded: true,
+		},
+		{
+			alias:           "WrongPasswordRecorded",
+			password:        "p@$$w0rd=ye$",
+			dbPersonByEmail: p,
+			expectCode:      http.StatusUnauthorized,
+			expectAttempt:   true,
+		},
+		{
+			alias:              "UnknownAccountRecorded",
+			password:           "p@$$w0rd=ye$",
+			dbPersonByEmailErr: errors.Wrap(app.ErrNotFound, "nobody"),
+			expectCode:         http.StatusUnauthorized,
+			expectAttempt:      true,
+		},
+		{
+			alias:           "AccountDelayed",
+			password:        "zxcvbnJKL",
+			dbPersonByEmail: p,
+			accountFailures: recent(4),
+			expectCode:      http.StatusTooManyRequests,
+		},
+		{
+			alias:           "AccountLockedOut",
+			password:        "zxcvbnJKL",
+			dbPersonByEmail: p,
+			accountFailures: recent(10),
+			expectCode:      http.StatusTooManyRequests,
+		},
+		{
+			alias:              "UnknownAccountLockedOut",
+			password:           "zxcvbnJKL",
+			dbPersonByEmailErr: errors.Wrap(app.ErrNotFound, "nobody"),
+			accountFailures:    recent(10),
+			expectCode:         http.StatusTooManyRequests,
+		},
+		{
+			alias:           "IPLockedOut",
+			password:        "zxcvbnJKL",
+			dbPersonByEmail: p,
+			ipFailures:      recent(50),
+			expectCode:      http.StatusTooManyRequests,
+		},
+		{
+			alias:           "LockoutExpired",
+			password:        "zxcvbnJKL",
+			dbPersonByEmail: p,
+			accountFailures: app.LoginFailures{
+				Count:        10,
+				LastFailedAt: null.TimeFrom(time.Now().Add(-time.Hour)),
+			},
+			expectCode:      http.StatusNoContent,
+			expectAttempt:   true,
+			expectSucceeded: true,
+		},
+		{
+			// Without a record of the attempt, the limits cannot hold.
+			alias:           "RecordFailed",
+			password:        "p@$$w0rd=ye$",
+			dbPersonByEmail: p,
+			attemptErr:      errors.New("the database is down"),
+			expectCode:      http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			db.personByEmail = tc.dbPersonByEmail
+			db.personByEmailErr = tc.dbPersonByEmailErr
+			db.accountFailures = tc.accountFailures
+			db.ipFailures = tc.ipFailures
+			db.attemptErr = tc.attemptErr
+			db.attempts = nil
+
+			body := strings.NewReader(fmt.Sprintf(`
+				{
+					"email": "Jack@Box.net ",
+					"password": "%s"
+				}
+			`, tc.password))
+
+			r := httptest.NewRequest("POST", "/login", body)
+			r.Header.Set("X-Forwarded-For", "10.0.0.1, 192.0.2.44")
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+
+			if tc.expectCode == http.StatusTooManyRequests {
+				assert.NotEmpty(t, w.Header().Get("Retry-After"))
+			}
+
+			if !tc.expectAttempt {
+				assert.Empty(t, db.attempts)
+				return
+			}
+
+			require.Len(t, db.attempts, 1)
+			assert.Equal(t, "jack@box.net", db.attempts[0].Email)
+			assert.Equal(t, "192.0.2.44", db.attempts[0].IPAddress)
+			assert.Equal(t, tc.expectSucceeded, db.attempts[0].Succeeded)
 		})
 	}
 }
@@ -265,14 +552,14 @@ func TestHandleLogout(t *testing.T) {
 		Password:  pass,
 	}
 
-	s, err := app.NewSession(p)
+	s, err := app.NewSession(p, app.DefaultSessionPolicy(), false)
 	require.NoError(t, err)
 	require.NotNil(t, s)
-	require.NotEqual(t, uuid.Nil, s.Token)
+	require.False(t, s.Token.IsZero())
 
 	testCases := []struct {
 		alias               string
-		sessionToken        uuid.UUID
+		sessionToken        app.SecureToken
 		dbSessionByToken    app.Session
 		dbSessionByTokenErr error
 		dbRevokeSessionErr  error
@@ -361,3 +648,35 @@ func TestHandleLogout(t *testing.T) {
 		})
 	}
 }
+
+func TestLegacySessionToken(t *testing.T) {
+	db := &loginMockDB{
+		sessionByTokenErr: errors.New("should not be called"),
+	}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	r := httptest.NewRequest("POST", "/logout", nil)
+	w := httptest.NewRecorder()
+
+	// Cookies from before tokens were hashed hold a UUID.
+	c := http.Cookie{
+		Name:  sessionCookieKey,
+		Value: "8b6f1c1e-5d2a-4b8e-9a43-6f0f2d8e4c11",
+	}
+	r.Header.Add("Cookie", c.String())
+
+	api.router.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	var destroyed bool
+	for _, c := range res.Cookies() {
+		if c.Name == sessionCookieKey && c.MaxAge < 0 {
+			destroyed = true
+		}
+	}
+	assert.True(t, destroyed, "legacy session cookie should be destroyed")
+}
@@ -4,9 +4,36 @@ import (
 	"net/http"
 	"runtime/debug"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
 )
 
+// requestIDHeaderKey is the header that identifies each request, so that its
+// audit events may be found in the logs.
+const requestIDHeaderKey = "X-Request-ID"
+
+// requestIDMiddleware identifies each request, reusing the identifier given by
+// the proxy when it is a UUID, and attaches it to the request context for use
+// in the audit log.
+func (svr *Server) requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(r.Header.Get(requestIDHeaderKey))
+		if err != nil {
+			id = uuid.New()
+		}
+
+		w.Header().Set(requestIDHeaderKey, id.String())
+
+		ctx := app.ContextWithAuditActor(r.Context(), app.AuditActor{
+			RequestID: null.StringFrom(id.String()),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func (svr *Server) panicRecoveryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func (svr *Server) handleMyGetNotifications(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	_, userID, ok := svr.getMyProfileUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var unreadOnly bool
+	if value := r.URL.Query().Get("unread"); len(value) > 0 {
+		var err error
+		if unreadOnly, err = strconv.ParseBool(value); err != nil {
+			svr.sendErrorResponse(w, errors.Wrap(err, "unread must be a bool"),
+				http.StatusBadRequest, "Unread must be true or false.")
+			return
+		}
+	}
+
+	notifications, err := svr.db.GetNotificationsForPerson(r.Context(),
+		userID, unreadOnly)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to get notifications"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	svr.sendJSONResponse(w, notifications)
+}
+
+func (svr *Server) handleMyMarkNotificationRead(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	_, userID, ok := svr.getMyProfileUserID(w, r)
+	if !ok {
+		return
+	}
+
+	pathParams := mux.Vars(r)
+
+	notificationID, err := strconv.Atoi(pathParams["notificationID"])
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "notificationID must be an integer"),
+			http.StatusBadRequest, "Notification ID must be an integer.")
+		return
+	}
+
+	err = svr.db.MarkNotificationRead(r.Context(), notificationID, userID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound,
+			"No such notification.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to mark notification read"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
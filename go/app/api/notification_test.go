@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+type notificationMockDB struct {
+	*authMockDB
+
+	notifications []app.Notification
+	unreadOnly    bool
+}
+
+func (db *notificationMockDB) GetNotificationsForPerson(
+	_ context.Context,
+	personID int,
+	unreadOnly bool,
+) ([]app.Notification, error) {
+
+	db.unreadOnly = unreadOnly
+
+	var ns []app.Notification
+	for _, n := range db.notifications {
+		if n.PersonID == personID {
+			ns = append(ns, n)
+		}
+	}
+	return ns, nil
+}
+
+func (db *notificationMockDB) MarkNotificationRead(
+	_ context.Context,
+	notificationID, personID int,
+) error {
+
+	for _, n := range db.notifications {
+		if n.ID == notificationID && n.PersonID == personID {
+			return nil
+		}
+	}
+	return app.ErrNotFound
+}
+
+func TestMyNotifications(t *testing.T) {
+	s, err := app.NewSession(app.Person{ID: 2, Role: app.RoleSponsor},
+		app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	db := &notificationMockDB{
+		authMockDB: &authMockDB{
+			DB:          &mock.DB{},
+			sessions:    map[app.SecureToken]app.Session{s.Token: *s},
+			permissions: testRolePermissions,
+		},
+		notifications: []app.Notification{
+			{
+				ID:       1,
+				PersonID: 2,
+				Kind:     app.NotificationPersonDeactivated,
+				Message:  "The account of Ben Godfrey has been deactivated.",
+			},
+			{
+				ID:       2,
+				PersonID: 3,
+				Kind:     app.NotificationPersonDeactivated,
+				Message:  "The account of Ben Godfrey has been deactivated.",
+			},
+		},
+	}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	testCases := []struct {
+		alias        string
+		method       string
+		path         string
+		expectCode   int
+		expectUnread bool
+	}{
+		{
+			alias:      "List",
+			method:     "GET",
+			path:       "/my/notifications",
+			expectCode: http.StatusOK,
+		},
+		{
+			alias:        "ListUnread",
+			method:       "GET",
+			path:         "/my/notifications?unread=true",
+			expectCode:   http.StatusOK,
+			expectUnread: true,
+		},
+		{
+			alias:      "ListBadUnread",
+			method:     "GET",
+			path:       "/my/notifications?unread=maybe",
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias:      "MarkRead",
+			method:     "POST",
+			path:       "/my/notifications/1/read",
+			expectCode: http.StatusNoContent,
+		},
+		{
+			alias:      "MarkOthersRead",
+			method:     "POST",
+			path:       "/my/notifications/2/read",
+			expectCode: http.StatusNotFound,
+		},
+		{
+			alias:      "MarkBadID",
+			method:     "POST",
+			path:       "/my/notifications/abc/read",
+			expectCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			db.unreadOnly = false
+
+			r := httptest.NewRequest(tc.method, tc.path, nil)
+			testSessionTokenInject(t, r, s.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+			require.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, tc.expectUnread, db.unreadOnly)
+
+			if tc.method == "GET" && w.Code == http.StatusOK {
+				var ns []app.Notification
+				err := json.NewDecoder(w.Body).Decode(&ns)
+				require.NoError(t, err)
+				require.Len(t, ns, 1)
+				assert.Equal(t, 1, ns[0].ID)
+			}
+		})
+	}
+}
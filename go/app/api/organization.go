@@ -1,11 +1,56 @@
 package api
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 
+	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
 )
 
+// logoURL produces a link to the logo of an organization, or nothing if it has
+// no logo. The link changes whenever the logo does, so that it may be cached.
+func (svr *Server) logoURL(org app.Organization) string {
+	if !org.LogoKey.Valid {
+		return ""
+	}
+
+	version := sha256.Sum256([]byte(org.LogoKey.String))
+	return fmt.Sprintf("%s/api/organizations/%d/logo?v=%s",
+		svr.baseURL(), org.ID, hex.EncodeToString(version[:8]))
+}
+
+// withLogoURLs fills in the links to the logos of organizations.
+func (svr *Server) withLogoURLs(orgs []app.Organization) []app.Organization {
+	if orgs == nil {
+		return make([]app.Organization, 0)
+	}
+
+	for i := range orgs {
+		orgs[i].LogoURL = svr.logoURL(orgs[i])
+	}
+	return orgs
+}
+
+// isLogoContentType determines whether images of a MIME type may be uploaded
+// as logos.
+func isLogoContentType(contentType string) bool {
+	for _, allowed := range app.LogoContentTypes {
+		if contentType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
 func (svr *Server) handleGetAllOrganizations(
 	w http.ResponseWriter,
 	r *http.Request,
@@ -19,5 +64,201 @@ func (svr *Server) handleGetAllOrganizations(
 		return
 	}
 
-	svr.sendJSONResponse(w, orgs)
+	svr.sendJSONResponse(w, svr.withLogoURLs(orgs))
+}
+
+// handleDriverGetOrganizations sends the organizations that drivers may apply
+// to join.
+func (svr *Server) handleDriverGetOrganizations(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	orgs, err := svr.db.GetOrganizationsAcceptingApplications(r.Context())
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to get organizations"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	svr.sendJSONResponse(w, svr.withLogoURLs(orgs))
+}
+
+// handleGetOrganizationLogo sends the logo of an organization. No session is
+// required, since logos are shown to anyone who may see the organization.
+func (svr *Server) handleGetOrganizationLogo(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	orgID, err := strconv.Atoi(pathParams["orgID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "orgID must be an integer"),
+			http.StatusBadRequest, "Organization ID must be an integer.")
+		return
+	}
+
+	org, err := svr.db.GetOrganizationByID(r.Context(), orgID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound,
+			"No such organization.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to get organization"),
+			http.StatusInternalServerError, "")
+		return
+	} else if !org.LogoKey.Valid {
+		svr.sendErrorResponse(w,
+			errors.Errorf("organization %d has no logo", orgID),
+			http.StatusNotFound, "This organization has no logo.")
+		return
+	}
+
+	f, err := svr.files.OpenFile(r.Context(), org.LogoKey.String)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrapf(err, "failed to open logo of organization %d",
+				orgID),
+			http.StatusInternalServerError, "")
+		return
+	}
+	defer f.Close()
+
+	h := w.Header()
+	h.Set("Content-Type", org.LogoContentType.String)
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Cache-Control", "public, max-age=86400")
+
+	if _, err = io.Copy(w, f); err != nil {
+		svr.logger.WithError(err).
+			Errorf("failed to send logo of organization %d", orgID)
+	}
+}
+
+// setOrganizationLogo replaces the logo of an organization with the image
+// uploaded in the request, or removes its logo when remove is true. The file
+// of the logo that was replaced is deleted.
+func (svr *Server) setOrganizationLogo(
+	w http.ResponseWriter,
+	r *http.Request,
+	orgID int,
+	remove bool,
+) {
+
+	var key, contentType null.String
+	if !remove {
+		u, ok := svr.receiveUpload(w, r, app.MaxLogoSize, isLogoContentType,
+			"Only JPEG and PNG images may be used as logos.")
+		if !ok {
+			return
+		}
+
+		k, err := svr.files.PutFile(r.Context(), bytes.NewReader(u.content))
+		if err != nil {
+			svr.sendErrorResponse(w,
+				errors.Wrap(err, "failed to store uploaded logo"),
+				http.StatusInternalServerError, "")
+			return
+		}
+
+		key, contentType = null.StringFrom(k), null.StringFrom(u.contentType)
+	}
+
+	previous, err := svr.db.SetOrganizationLogo(r.Context(), orgID, key,
+		contentType)
+	if err != nil {
+		// Do not leave behind a file that nothing refers to.
+		svr.deleteLogoFile(r, key)
+
+		if errors.Is(err, app.ErrNotFound) {
+			svr.sendErrorResponse(w, err, http.StatusNotFound,
+				"No such organization.")
+			return
+		}
+
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to set organization logo"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	svr.deleteLogoFile(r, previous)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteLogoFile deletes the file of a logo that is no longer used, if there
+// is one. Failures are only logged, since the logo has already been replaced.
+func (svr *Server) deleteLogoFile(r *http.Request, key null.String) {
+	if !key.Valid {
+		return
+	}
+
+	if err := svr.files.DeleteFile(r.Context(), key.String); err != nil {
+		svr.logger.WithError(err).
+			Errorf("failed to delete unused logo file %s", key.String)
+	}
+}
+
+func (svr *Server) handleSponsorSetOrganizationLogo(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
+		return
+	}
+
+	svr.setOrganizationLogo(w, r, orgID, false)
+}
+
+func (svr *Server) handleSponsorDeleteOrganizationLogo(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
+		return
+	}
+
+	svr.setOrganizationLogo(w, r, orgID, true)
+}
+
+func (svr *Server) handleAdminSetOrganizationLogo(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	orgID, err := strconv.Atoi(pathParams["orgID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "orgID must be an integer"),
+			http.StatusBadRequest, "Organization ID must be an integer.")
+		return
+	}
+
+	svr.setOrganizationLogo(w, r, orgID, false)
+}
+
+func (svr *Server) handleAdminDeleteOrganizationLogo(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	orgID, err := strconv.Atoi(pathParams["orgID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "orgID must be an integer"),
+			http.StatusBadRequest, "Organization ID must be an integer.")
+		return
+	}
+
+	svr.setOrganizationLogo(w, r, orgID, true)
 }
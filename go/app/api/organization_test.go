@@ -0,0 +1,381 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+// testPNG is the signature of a PNG image, which is enough for its type to be
+// detected.
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+type orgProfileMockDB struct {
+	*authMockDB
+
+	org app.Organization
+}
+
+func (db *orgProfileMockDB) GetOrganizationByID(
+	_ context.Context,
+	orgID int,
+) (app.Organization, error) {
+
+	if orgID != db.org.ID {
+		return app.Organization{}, app.ErrNotFound
+	}
+	return db.org, nil
+}
+
+func (db *orgProfileMockDB) GetOrganizationsAcceptingApplications(
+	_ context.Context,
+) ([]app.Organization, error) {
+
+	if db.org.IsClosed {
+		return nil, nil
+	}
+	return []app.Organization{db.org}, nil
+}
+
+func (db *orgProfileMockDB) UpdateOrganization(
+	_ context.Context,
+	org app.Organization,
+) error {
+
+	if org.ID != db.org.ID {
+		return app.ErrNotFound
+	}
+
+	org.LogoKey, org.LogoContentType = db.org.LogoKey, db.org.LogoContentType
+	db.org = org
+	return nil
+}
+
+func (db *orgProfileMockDB) SetOrganizationLogo(
+	_ context.Context,
+	orgID int,
+	key, contentType null.String,
+) (null.String, error) {
+
+	if orgID != db.org.ID {
+		return null.String{}, app.ErrNotFound
+	}
+
+	previous := db.org.LogoKey
+	db.org.LogoKey, db.org.LogoContentType = key, contentType
+	return previous, nil
+}
+
+func newOrgProfileMockDB(sessions ...*app.Session) *orgProfileMockDB {
+	byToken := make(map[app.SecureToken]app.Session)
+	for _, s := range sessions {
+		byToken[s.Token] = *s
+	}
+
+	return &orgProfileMockDB{
+		authMockDB: &authMockDB{
+			DB:          &mock.DB{},
+			sessions:    byToken,
+			permissions: testRolePermissions,
+		},
+		org: app.Organization{
+			ID:         1,
+			Name:       "Trucking Co.",
+			PointValue: 1,
+			PointsName: app.DefaultPointsName,
+		},
+	}
+}
+
+func TestSponsorUpdateOrganizationProfile(t *testing.T) {
+	sponsor, err := app.NewSession(app.Person{
+		ID:           2,
+		Role:         app.RoleSponsor,
+		Affiliations: []int{1},
+	}, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		alias      string
+		body       string
+		expectCode int
+		expectOrg  app.Organization
+	}{
+		{
+			alias: "Profile",
+			body: `{
+				"name": "Trucking Co.",
+				"point_value": 1,
+				"description": "  We haul things.  ",
+				"contact_email": "fleet@trucking.example",
+				"website": "https://trucking.example",
+				"applications_closed": true,
+				"points_name": " Safety Bucks "
+			}`,
+			expectCode: http.StatusNoContent,
+			expectOrg: app.Organization{
+				ID:           1,
+				Name:         "Trucking Co.",
+				PointValue:   1,
+				Description:  "We haul things.",
+				ContactEmail: "fleet@trucking.example",
+				Website:      "https://trucking.example",
+				IsClosed:     true,
+				PointsName:   "Safety Bucks",
+			},
+		},
+		{
+			alias:      "OnlyRequiredFields",
+			body:       `{"name": "Trucking Inc.", "point_value": 2}`,
+			expectCode: http.StatusNoContent,
+			expectOrg: app.Organization{
+				ID:         1,
+				Name:       "Trucking Inc.",
+				PointValue: 2,
+			},
+		},
+		{
+			alias: "BadContactEmail",
+			body: `{
+				"name": "Trucking Co.",
+				"point_value": 1,
+				"contact_email": "fleet"
+			}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias: "BadWebsite",
+			body: `{
+				"name": "Trucking Co.",
+				"point_value": 1,
+				"website": "javascript:alert(1)"
+			}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias: "LongPointsName",
+			body: `{
+				"name": "Trucking Co.",
+				"point_value": 1,
+				"points_name": "` + strings.Repeat("x", app.MaxPointsName+1) +
+				`"
+			}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			alias: "LongDescription",
+			body: `{
+				"name": "Trucking Co.",
+				"point_value": 1,
+				"description": "` +
+				strings.Repeat("x", app.MaxOrganizationDescription+1) + `"
+			}`,
+			expectCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			db := newOrgProfileMockDB(sponsor)
+			before := db.org
+			api, _, _ := newTestAPI(t, db, nil)
+
+			r := httptest.NewRequest("POST", "/sponsor/organization/update",
+				strings.NewReader(tc.body))
+			testSessionTokenInject(t, r, sponsor.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			if tc.expectCode != http.StatusNoContent {
+				assert.Equal(t, before, db.org)
+				return
+			}
+			assert.Equal(t, tc.expectOrg, db.org)
+		})
+	}
+}
+
+func TestOrganizationLogo(t *testing.T) {
+	sponsor, err := app.NewSession(app.Person{
+		ID:           2,
+		Role:         app.RoleSponsor,
+		Affiliations: []int{1},
+	}, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	admin, err := app.NewSession(app.Person{ID: 1, Role: app.RoleAdmin},
+		app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	driver, err := app.NewSession(app.Person{ID: 3, Role: app.RoleDriver},
+		app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	db := newOrgProfileMockDB(sponsor, admin, driver)
+	api, _, _ := newTestAPI(t, db, nil)
+	files := api.files.(*mock.FileStore)
+
+	getLogo := func(t *testing.T) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/organizations/1/logo", nil)
+		w := httptest.NewRecorder()
+		api.router.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("NoLogo", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, getLogo(t).Code)
+	})
+
+	t.Run("Upload", func(t *testing.T) {
+		r := newAttachmentUpload(t, "/sponsor/organization/logo",
+			"logo.png", testPNG)
+		testSessionTokenInject(t, r, sponsor.Token)
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, null.StringFrom("image/png"), db.org.LogoContentType)
+		assert.Len(t, files.Files, 1)
+
+		w = getLogo(t)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		body, err := ioutil.ReadAll(w.Body)
+		require.NoError(t, err)
+		assert.Equal(t, testPNG, body)
+	})
+
+	t.Run("TypeNotAllowed", func(t *testing.T) {
+		r := newAttachmentUpload(t, "/sponsor/organization/logo",
+			"logo.pdf", testPDF)
+		testSessionTokenInject(t, r, sponsor.Token)
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+		assert.Len(t, files.Files, 1)
+	})
+
+	t.Run("AdminReplaces", func(t *testing.T) {
+		previous := db.org.LogoKey
+
+		r := newAttachmentUpload(t, "/admin/organizations/1/logo",
+			"logo.png", testPNG)
+		testSessionTokenInject(t, r, admin.Token)
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusNoContent, w.Code)
+		assert.NotEqual(t, previous, db.org.LogoKey)
+		assert.Len(t, files.Files, 1)
+		assert.NotContains(t, files.Files, previous.String)
+	})
+
+	t.Run("DriverListing", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/driver/organizations/all", nil)
+		testSessionTokenInject(t, r, driver.Token)
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var orgs []app.Organization
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&orgs))
+		require.Len(t, orgs, 1)
+		assert.Contains(t, orgs[0].LogoURL, "/api/organizations/1/logo?v=")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/sponsor/organization/logo/delete",
+			nil)
+		testSessionTokenInject(t, r, sponsor.Token)
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusNoContent, w.Code)
+		assert.False(t, db.org.LogoKey.Valid)
+		assert.Empty(t, files.Files)
+		assert.Equal(t, http.StatusNotFound, getLogo(t).Code)
+	})
+}
+
+func TestSponsorArchivedOrganization(t *testing.T) {
+	sponsor, err := app.NewSession(app.Person{
+		ID:           2,
+		Role:         app.RoleSponsor,
+		Affiliations: []int{1},
+	}, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	db := newOrgProfileMockDB(sponsor)
+	db.org.ArchivedAt = null.TimeFrom(time.Now())
+	before := db.org
+	api, _, _ := newTestAPI(t, db, nil)
+
+	testCases := []struct {
+		alias      string
+		method     string
+		path       string
+		body       string
+		expectCode int
+	}{
+		{
+			alias:      "View",
+			method:     "GET",
+			path:       "/sponsor/organization",
+			expectCode: http.StatusOK,
+		},
+		{
+			alias:      "Update",
+			method:     "POST",
+			path:       "/sponsor/organization/update",
+			body:       `{"name": "Trucking Inc.", "point_value": 2}`,
+			expectCode: http.StatusConflict,
+		},
+		{
+			alias:      "DeleteLogo",
+			method:     "POST",
+			path:       "/sponsor/organization/logo/delete",
+			expectCode: http.StatusConflict,
+		},
+		{
+			alias:      "UpdateApplicationForm",
+			method:     "POST",
+			path:       "/sponsor/organization/application-form/update",
+			body:       `{"questions": []}`,
+			expectCode: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.path,
+				strings.NewReader(tc.body))
+			testSessionTokenInject(t, r, sponsor.Token)
+			w := httptest.NewRecorder()
+
+			api.router.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectCode, w.Code)
+			assert.Equal(t, before, db.org)
+		})
+	}
+}
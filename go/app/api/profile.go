@@ -167,10 +167,6 @@ func (r *passwordChangeRequest) validateFields(
 		return
 	}
 
-	if message, err = validatePassword(r.NewPassword); err != nil {
-		return
-	}
-
 	return
 }
 
@@ -198,7 +194,7 @@ func (svr *Server) handleMyProfileUpdatePassword(
 		return
 	}
 
-	if !s.Person.Password.Verify(data.CurrentPassword) {
+	if ok, _ := s.Person.Password.Verify(data.CurrentPassword); !ok {
 		svr.sendErrorResponse(
 			w,
 			errors.New("password did not match"),
@@ -208,6 +204,10 @@ func (svr *Server) handleMyProfileUpdatePassword(
 		return
 	}
 
+	if !svr.validateNewPassword(w, r, data.NewPassword, s.Person) {
+		return
+	}
+
 	hashedPass, err := app.NewPassword(data.NewPassword)
 	if err != nil {
 		svr.sendErrorResponse(w,
@@ -243,7 +243,7 @@ func (svr *Server) handleMyProfileDeactivate(
 		return
 	}
 
-	err := svr.db.DeactivatePerson(r.Context(), userID)
+	err := svr.db.DeactivatePerson(r.Context(), userID, "")
 	if err != nil {
 		svr.sendErrorResponse(w, errors.Wrap(err, "failed to deactivate user"),
 			http.StatusInternalServerError, "")
@@ -36,7 +36,9 @@ func (reg *registrationRequest) validateFields() (message string, err error) {
 		return
 	}
 
-	if message, err = validatePassword(reg.Password); err != nil {
+	if len(reg.Password) < 1 {
+		err = errors.New("password cannot be blank")
+		message = "Password cannot be blank."
 		return
 	}
 
@@ -66,6 +68,17 @@ func (svr *Server) handleRegistration(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	p := app.Person{
+		FirstName: reg.FirstName,
+		LastName:  reg.LastName,
+		Email:     reg.Email,
+		Role:      app.RoleDriver,
+	}
+
+	if !svr.validateNewPassword(w, r, reg.Password, p) {
+		return
+	}
+
 	hashedPass, err := app.NewPassword(reg.Password)
 	if err != nil {
 		svr.sendErrorResponse(w,
@@ -74,13 +87,7 @@ func (svr *Server) handleRegistration(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	p := app.Person{
-		FirstName: reg.FirstName,
-		LastName:  reg.LastName,
-		Email:     reg.Email,
-		Password:  hashedPass,
-		Role:      app.RoleDriver,
-	}
+	p.Password = hashedPass
 
 	if _, err := svr.db.CreatePerson(r.Context(), p); err != nil {
 		svr.sendErrorResponse(w,
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// A sessionInfo describes a login session to its owner or an administrator,
+// without revealing its secret token.
+type sessionInfo struct {
+	ID         int       `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	// IsCurrent is true when this is the session making the request.
+	IsCurrent bool `json:"is_current"`
+}
+
+// getActiveSessionInfo fetches the valid sessions for a person, other than
+// impersonations of them, and describes them relative to the current session.
+// Writes an error response and returns false upon failure.
+func (svr *Server) getActiveSessionInfo(
+	w http.ResponseWriter,
+	r *http.Request,
+	personID int,
+) ([]sessionInfo, bool) {
+
+	ss, err := svr.db.GetSessionsForPerson(r.Context(), personID, false)
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to get sessions"),
+			http.StatusInternalServerError, "")
+		return nil, false
+	}
+
+	var currentID int
+	if current := getSessionFromContext(r.Context()); current != nil {
+		currentID = current.ID
+	}
+
+	infos := make([]sessionInfo, 0, len(ss))
+	for _, s := range ss {
+		// Administrators impersonating the person are not their sessions to
+		// see or manage.
+		if s.IsImpersonation() {
+			continue
+		}
+
+		infos = append(infos, sessionInfo{
+			ID:         s.ID,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+			UserAgent:  s.UserAgent,
+			IPAddress:  s.IPAddress,
+			IsCurrent:  s.ID == currentID,
+		})
+	}
+
+	return infos, true
+}
+
+// revokeSessionOfPerson revokes the active session named by the request path
+// if it belongs to a person, returning its ID. Writes an error response and
+// returns false upon failure, including when the session does not belong to
+// that person.
+func (svr *Server) revokeSessionOfPerson(
+	w http.ResponseWriter,
+	r *http.Request,
+	personID int,
+) (sessionID int, ok bool) {
+
+	pathParams := mux.Vars(r)
+
+	sessionID, err := strconv.Atoi(pathParams["sessionID"])
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "sessionID must be an integer"),
+			http.StatusBadRequest, "Session ID must be an integer.")
+		return
+	}
+
+	infos, fetched := svr.getActiveSessionInfo(w, r, personID)
+	if !fetched {
+		return
+	}
+
+	owned := false
+	for _, info := range infos {
+		if info.ID == sessionID {
+			owned = true
+			break
+		}
+	}
+
+	if !owned {
+		svr.sendErrorResponse(w,
+			errors.Errorf("person %d has no active session %d",
+				personID, sessionID),
+			http.StatusNotFound, "No such session.")
+		return
+	}
+
+	err = svr.db.RevokeSession(r.Context(), sessionID)
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to revoke session"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	ok = true
+	return
+}
+
+func (svr *Server) handleMyGetSessions(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	_, userID, ok := svr.getMyProfileUserID(w, r)
+	if !ok {
+		return
+	}
+
+	infos, ok := svr.getActiveSessionInfo(w, r, userID)
+	if !ok {
+		return
+	}
+
+	svr.sendJSONResponse(w, infos)
+}
+
+func (svr *Server) handleMyRevokeSession(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	s, userID, ok := svr.getMyProfileUserID(w, r)
+	if !ok {
+		return
+	}
+
+	sessionID, ok := svr.revokeSessionOfPerson(w, r, userID)
+	if !ok {
+		return
+	}
+
+	// When revoking the current session, the client is now logged out.
+	if sessionID == s.ID {
+		svr.destroySessionCookie(w)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (svr *Server) handleMyRevokeOtherSessions(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	s, userID, ok := svr.getMyProfileUserID(w, r)
+	if !ok {
+		return
+	}
+
+	err := svr.db.RevokeSessionsForPersonExcept(r.Context(), userID, s.ID)
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to revoke sessions"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (svr *Server) handleAdminGetUserSessions(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	userID, err := strconv.Atoi(pathParams["userID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "userID must be an integer"),
+			http.StatusBadRequest, "User ID must be an integer.")
+		return
+	}
+
+	infos, ok := svr.getActiveSessionInfo(w, r, userID)
+	if !ok {
+		return
+	}
+
+	svr.sendJSONResponse(w, infos)
+}
+
+func (svr *Server) handleAdminRevokeUserSession(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	userID, err := strconv.Atoi(pathParams["userID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "userID must be an integer"),
+			http.StatusBadRequest, "User ID must be an integer.")
+		return
+	}
+
+	if _, ok := svr.revokeSessionOfPerson(w, r, userID); !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (svr *Server) handleAdminRevokeUserSessions(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	userID, err := strconv.Atoi(pathParams["userID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "userID must be an integer"),
+			http.StatusBadRequest, "User ID must be an integer.")
+		return
+	}
+
+	// The current session is spared, in case an admin targets themselves.
+	var currentID int
+	if s := getSessionFromContext(r.Context()); s != nil {
+		currentID = s.ID
+	}
+
+	err = svr.db.RevokeSessionsForPersonExcept(r.Context(), userID, currentID)
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to revoke sessions"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
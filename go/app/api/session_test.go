@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+type sessionMockDB struct {
+	*mock.DB
+
+	current  app.Session
+	sessions []app.Session
+
+	revokedIDs     []int
+	exceptPersonID int
+	exceptID       int
+}
+
+func (db *sessionMockDB) GetSessionByToken(
+	_ context.Context,
+	_ app.SecureToken,
+) (app.Session, error) {
+
+	return db.current, nil
+}
+
+func (db *sessionMockDB) GetSessionsForPerson(
+	_ context.Context,
+	personID int,
+	_ bool,
+) ([]app.Session, error) {
+
+	var ss []app.Session
+	for _, s := range db.sessions {
+		if s.Person.ID == personID {
+			ss = append(ss, s)
+		}
+	}
+	return ss, nil
+}
+
+func (db *sessionMockDB) RevokeSession(_ context.Context, id int) error {
+	db.revokedIDs = append(db.revokedIDs, id)
+	return nil
+}
+
+func (db *sessionMockDB) RevokeSessionsForPersonExcept(
+	_ context.Context,
+	personID, sessionID int,
+) error {
+
+	db.exceptPersonID = personID
+	db.exceptID = sessionID
+	return nil
+}
+
+func (db *sessionMockDB) GetPermissionsForRole(
+	_ context.Context,
+	role app.Role,
+) ([]app.Permission, error) {
+
+	return testRolePermissions[role], nil
+}
+
+func TestMySessions(t *testing.T) {
+	me := app.Person{ID: 1, Role: app.RoleDriver}
+	them := app.Person{ID: 2, Role: app.RoleDriver}
+
+	newSession := func(p app.Person, id int, userAgent string) app.Session {
+		s, err := app.NewSession(p, app.DefaultSessionPolicy(), false)
+		require.NoError(t, err)
+
+		s.ID = id
+		s.UserAgent = userAgent
+		return *s
+	}
+
+	current := newSession(me, 1, "Firefox")
+	impersonation := newSession(me, 4, "Edge")
+	impersonation.ImpersonatorID = null.IntFrom(3)
+	impersonation.ImpersonatorSessionID = null.IntFrom(5)
+
+	db := &sessionMockDB{
+		current: current,
+		sessions: []app.Session{
+			current,
+			newSession(me, 2, "Safari"),
+			newSession(them, 3, "Chrome"),
+			impersonation,
+		},
+	}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		testSessionTokenInject(t, r, current.Token)
+
+		api.router.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("List", func(t *testing.T) {
+		w := serve("GET", "/my/sessions")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var infos []sessionInfo
+		err := json.NewDecoder(w.Body).Decode(&infos)
+		require.NoError(t, err)
+
+		require.Len(t, infos, 2)
+		assert.Equal(t, 1, infos[0].ID)
+		assert.Equal(t, "Firefox", infos[0].UserAgent)
+		assert.True(t, infos[0].IsCurrent)
+		assert.Equal(t, 2, infos[1].ID)
+		assert.Equal(t, "Safari", infos[1].UserAgent)
+		assert.False(t, infos[1].IsCurrent)
+
+		assert.NotContains(t, w.Body.String(), current.Token.String())
+	})
+
+	t.Run("RevokeOwn", func(t *testing.T) {
+		db.revokedIDs = nil
+
+		w := serve("POST", "/my/sessions/2/revoke")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, []int{2}, db.revokedIDs)
+	})
+
+	t.Run("RevokeSomeoneElses", func(t *testing.T) {
+		db.revokedIDs = nil
+
+		w := serve("POST", "/my/sessions/3/revoke")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Empty(t, db.revokedIDs)
+	})
+
+	t.Run("RevokeBadID", func(t *testing.T) {
+		w := serve("POST", "/my/sessions/xyz/revoke")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("RevokeOthers", func(t *testing.T) {
+		w := serve("POST", "/my/sessions/others/revoke")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, me.ID, db.exceptPersonID)
+		assert.Equal(t, current.ID, db.exceptID)
+	})
+}
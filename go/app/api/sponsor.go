@@ -2,26 +2,246 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
 
 	"github.com/BenJetson/CPSC491-project/go/app"
 )
 
-func (svr *Server) handleSponsorGetOwnOrganization(
+// organizationHeaderKey names the header that selects which organization a
+// request from a sponsor is for, overriding the active organization of their
+// session. API tokens have no session, so they must use this header whenever
+// their owner is affiliated with several organizations.
+const organizationHeaderKey = "X-Organization-ID"
+
+// getSponsorOrganizationID determines which organization a request from a
+// sponsor is for, as chooseSponsorOrganizationID does. Archived organizations
+// may still be looked at, but requests that would change anything in them are
+// refused.
+//
+// Upon failure, writes an error to the ResponseWriter and returns false.
+func (svr *Server) getSponsorOrganizationID(
+	w http.ResponseWriter,
+	r *http.Request,
+) (int, bool) {
+
+	orgID, ok := svr.chooseSponsorOrganizationID(w, r)
+	if !ok || r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return orgID, ok
+	}
+
+	org, err := svr.db.GetOrganizationByID(r.Context(), orgID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound,
+			"No such organization.")
+		return 0, false
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to get organization"),
+			http.StatusInternalServerError, "")
+		return 0, false
+	} else if org.ArchivedAt.Valid {
+		svr.sendErrorResponse(w,
+			errors.Wrapf(app.ErrOrganizationArchived, "organization %d",
+				orgID),
+			http.StatusConflict,
+			"This organization is archived, so it can no longer be changed.")
+		return 0, false
+	}
+
+	return orgID, true
+}
+
+// chooseSponsorOrganizationID determines which organization a request from a
+// sponsor is for. This is the organization given by the request header, else
+// the active organization of the session, else the only organization that the
+// sponsor is affiliated with.
+//
+// Upon failure, writes an error to the ResponseWriter and returns false.
+func (svr *Server) chooseSponsorOrganizationID(
+	w http.ResponseWriter,
+	r *http.Request,
+) (int, bool) {
+
+	s := getSessionFromContext(r.Context())
+	if s == nil {
+		svr.sendErrorResponse(w, errors.New("no session"),
+			http.StatusUnauthorized, "")
+		return 0, false
+	}
+
+	isAffiliated := func(orgID int) bool {
+		for _, a := range s.Person.Affiliations {
+			if a == orgID {
+				return true
+			}
+		}
+		return false
+	}
+
+	if header := r.Header.Get(organizationHeaderKey); len(header) > 0 {
+		orgID, err := strconv.Atoi(header)
+		if err != nil {
+			svr.sendErrorResponse(w,
+				errors.Wrap(err, "organization header must be an integer"),
+				http.StatusBadRequest,
+				"The %s header must be an integer.", organizationHeaderKey)
+			return 0, false
+		} else if !isAffiliated(orgID) {
+			svr.sendErrorResponse(w,
+				errors.Errorf("person %d is not affiliated with org %d",
+					s.Person.ID, orgID),
+				http.StatusForbidden,
+				"You are not affiliated with that organization.")
+			return 0, false
+		}
+		return orgID, true
+	}
+
+	// An affiliation may have been removed since the organization was chosen,
+	// in which case the choice no longer applies.
+	active := s.ActiveOrganizationID
+	if active.Valid && isAffiliated(int(active.Int64)) {
+		return int(active.Int64), true
+	}
+
+	switch len(s.Person.Affiliations) {
+	case 0:
+		svr.sendErrorResponse(w,
+			errors.Errorf("person %d has no affiliations", s.Person.ID),
+			http.StatusForbidden,
+			"You are not affiliated with any organization.")
+		return 0, false
+	case 1:
+		return s.Person.Affiliations[0], true
+	}
+
+	svr.sendErrorResponse(w,
+		errors.Errorf("person %d has %d affiliations but none is active",
+			s.Person.ID, len(s.Person.Affiliations)),
+		http.StatusPreconditionRequired,
+		"Please choose an organization to work on.")
+	return 0, false
+}
+
+// A sponsorOrganization is an organization that a sponsor is affiliated with.
+// Archived organizations are listed too, so that their history may still be
+// looked at, but nothing in them may be changed.
+type sponsorOrganization struct {
+	app.Organization
+	// IsActive is true when requests without an organization header will be
+	// for this organization.
+	IsActive bool `json:"is_active"`
+}
+
+func (svr *Server) handleSponsorGetMyOrganizations(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	s := getSessionFromContext(r.Context())
+	if s == nil {
+		svr.sendErrorResponse(w, errors.New("no session"),
+			http.StatusUnauthorized, "")
+		return
+	}
+
+	orgs, err := svr.db.GetOrganizationsForPerson(r.Context(), s.Person.ID)
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to fetch organizations"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	// The active organization is only known when one can be determined
+	// without the sponsor choosing.
+	active := s.ActiveOrganizationID
+	if len(s.Person.Affiliations) == 1 {
+		active = null.IntFrom(int64(s.Person.Affiliations[0]))
+	}
+
+	mine := make([]sponsorOrganization, 0, len(orgs))
+	for _, org := range orgs {
+		org.LogoURL = svr.logoURL(org)
+		mine = append(mine, sponsorOrganization{
+			Organization: org,
+			IsActive:     active.Valid && active.Int64 == int64(org.ID),
+		})
+	}
+
+	svr.sendJSONResponse(w, mine)
+}
+
+type switchOrganizationRequest struct {
+	OrganizationID int `json:"organization_id"`
+}
+
+func (svr *Server) handleSponsorSwitchOrganization(
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
 
-	orgID, err := getOrganizationIDOfSponsor(r)
+	s := getSessionFromContext(r.Context())
+	if s == nil {
+		svr.sendErrorResponse(w, errors.New("no session"),
+			http.StatusUnauthorized, "")
+		return
+	}
+
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var data switchOrganizationRequest
+	if err := d.Decode(&data); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "received bad json data"),
+			http.StatusBadRequest, "Bad JSON data.")
+		return
+	}
+
+	isAffiliated := false
+	for _, orgID := range s.Person.Affiliations {
+		isAffiliated = isAffiliated || orgID == data.OrganizationID
+	}
+
+	if !isAffiliated {
+		svr.sendErrorResponse(w,
+			errors.Errorf("person %d is not affiliated with org %d",
+				s.Person.ID, data.OrganizationID),
+			http.StatusForbidden,
+			"You are not affiliated with that organization.")
+		return
+	}
+
+	err := svr.db.SetSessionActiveOrganization(r.Context(), s.ID,
+		null.IntFrom(int64(data.OrganizationID)))
 	if err != nil {
 		svr.sendErrorResponse(w,
-			errors.Wrap(err, "cannot determine sponsor organization identity"),
+			errors.Wrap(err, "failed to switch organization"),
 			http.StatusInternalServerError, "")
 		return
 	}
 
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (svr *Server) handleSponsorGetOwnOrganization(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
+		return
+	}
+
 	org, err := svr.db.GetOrganizationByID(r.Context(), orgID)
 	if err != nil {
 		svr.sendErrorResponse(w,
@@ -30,12 +250,35 @@ func (svr *Server) handleSponsorGetOwnOrganization(
 		return
 	}
 
+	org.LogoURL = svr.logoURL(org)
 	svr.sendJSONResponse(w, org)
 }
 
 type organizationRequest struct {
-	Name       string    `json:"name"`
-	PointValue app.Money `json:"point_value"`
+	Name         string    `json:"name"`
+	PointValue   app.Money `json:"point_value"`
+	CooldownDays int       `json:"reapply_cooldown_days"`
+	Description  string    `json:"description"`
+	ContactEmail string    `json:"contact_email"`
+	Website      string    `json:"website"`
+	IsClosed     bool      `json:"applications_closed"`
+	PointsName   string    `json:"points_name"`
+}
+
+// organization produces the organization described by this request. Its logo
+// is not part of the request, and is left alone.
+func (r *organizationRequest) organization(orgID int) app.Organization {
+	return app.Organization{
+		ID:           orgID,
+		Name:         r.Name,
+		PointValue:   r.PointValue,
+		CooldownDays: r.CooldownDays,
+		Description:  r.Description,
+		ContactEmail: r.ContactEmail,
+		Website:      r.Website,
+		IsClosed:     r.IsClosed,
+		PointsName:   r.PointsName,
+	}
 }
 
 func (r *organizationRequest) validateFields() (message string, err error) {
@@ -55,6 +298,44 @@ func (r *organizationRequest) validateFields() (message string, err error) {
 		return
 	}
 
+	if r.CooldownDays < 0 || r.CooldownDays > app.MaxCooldownDays {
+		message = fmt.Sprintf("Reapplication cooldown must be between 0 "+
+			"and %d days.", app.MaxCooldownDays)
+		return
+	}
+
+	r.Description = strings.TrimSpace(r.Description)
+	if utf8.RuneCountInString(r.Description) > app.MaxOrganizationDescription {
+		message = fmt.Sprintf("Description may be at most %d characters.",
+			app.MaxOrganizationDescription)
+		return
+	}
+
+	r.ContactEmail = strings.TrimSpace(r.ContactEmail)
+	if r.ContactEmail != "" && !validateEmail.MatchString(r.ContactEmail) {
+		message = "Invalid contact email address."
+		return
+	}
+
+	r.Website = strings.TrimSpace(r.Website)
+	if r.Website != "" {
+		site, parseErr := url.Parse(r.Website)
+		if parseErr != nil ||
+			(site.Scheme != "http" && site.Scheme != "https") ||
+			site.Host == "" {
+
+			message = "Website must be a valid HTTP or HTTPS URL."
+			return
+		}
+	}
+
+	r.PointsName = strings.TrimSpace(r.PointsName)
+	if utf8.RuneCountInString(r.PointsName) > app.MaxPointsName {
+		message = fmt.Sprintf("Points name may be at most %d characters.",
+			app.MaxPointsName)
+		return
+	}
+
 	return
 }
 
@@ -63,11 +344,8 @@ func (svr *Server) handleSponsorUpdateOwnOrganization(
 	r *http.Request,
 ) {
 
-	orgID, err := getOrganizationIDOfSponsor(r)
-	if err != nil {
-		svr.sendErrorResponse(w,
-			errors.Wrap(err, "cannot determine sponsor organization identity"),
-			http.StatusInternalServerError, "")
+	orgID, ok := svr.getSponsorOrganizationID(w, r)
+	if !ok {
 		return
 	}
 
@@ -76,6 +354,7 @@ func (svr *Server) handleSponsorUpdateOwnOrganization(
 
 	var data organizationRequest
 	var message string
+	var err error
 	if err = d.Decode(&data); err != nil {
 		svr.sendErrorResponse(w, errors.Wrap(err, "received bad json data"),
 			http.StatusBadRequest, "Bad JSON data.")
@@ -85,11 +364,7 @@ func (svr *Server) handleSponsorUpdateOwnOrganization(
 		return
 	}
 
-	err = svr.db.UpdateOrganization(r.Context(), app.Organization{
-		ID:         orgID,
-		Name:       data.Name,
-		PointValue: data.PointValue,
-	})
+	err = svr.db.UpdateOrganization(r.Context(), data.organization(orgID))
 	if err != nil {
 		svr.sendErrorResponse(w,
 			errors.Wrap(err, "failed to update sponsor organization"),
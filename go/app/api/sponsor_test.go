@@ -0,0 +1,281 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+)
+
+type sponsorMockDB struct {
+	*authMockDB
+
+	activeSessionID int
+	activeOrgID     null.Int
+}
+
+func (db *sponsorMockDB) GetOrganizationByID(
+	_ context.Context,
+	orgID int,
+) (app.Organization, error) {
+
+	return app.Organization{ID: orgID}, nil
+}
+
+func (db *sponsorMockDB) GetOrganizationsForPerson(
+	_ context.Context,
+	personID int,
+) ([]app.Organization, error) {
+
+	if personID != 3 {
+		return nil, nil
+	}
+
+	archived := app.Organization{
+		ID:         2,
+		ArchivedAt: null.TimeFrom(time.Now()),
+	}
+	return []app.Organization{{ID: 1}, archived}, nil
+}
+
+func (db *sponsorMockDB) SetSessionActiveOrganization(
+	_ context.Context,
+	sessionID int,
+	orgID null.Int,
+) error {
+
+	db.activeSessionID = sessionID
+	db.activeOrgID = orgID
+	return nil
+}
+
+func TestSponsorOrganization(t *testing.T) {
+	newSession := func(
+		id int,
+		active null.Int,
+		affiliations ...int,
+	) app.Session {
+
+		s, err := app.NewSession(app.Person{
+			ID:           id,
+			Role:         app.RoleSponsor,
+			Affiliations: affiliations,
+		}, app.DefaultSessionPolicy(), false)
+		require.NoError(t, err)
+
+		s.ID = id
+		s.ActiveOrganizationID = active
+		return *s
+	}
+
+	single := newSession(1, null.Int{}, 1)
+	multi := newSession(2, null.Int{}, 1, 2)
+	chosen := newSession(3, null.IntFrom(2), 1, 2)
+	stale := newSession(4, null.IntFrom(3), 1, 2)
+	none := newSession(5, null.Int{})
+
+	driver, err := app.NewSession(app.Person{
+		ID:           6,
+		Role:         app.RoleDriver,
+		Affiliations: []int{1, 2},
+	}, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	db := &sponsorMockDB{
+		authMockDB: &authMockDB{
+			DB: &mock.DB{},
+			sessions: map[app.SecureToken]app.Session{
+				single.Token: single,
+				multi.Token:  multi,
+				chosen.Token: chosen,
+				stale.Token:  stale,
+				none.Token:   none,
+				driver.Token: *driver,
+			},
+			permissions: testRolePermissions,
+		},
+	}
+	api, _, _ := newTestAPI(t, db, nil)
+
+	t.Run("Resolve", func(t *testing.T) {
+		testCases := []struct {
+			alias       string
+			session     app.Session
+			header      string
+			expectCode  int
+			expectOrgID int
+		}{
+			{
+				alias:       "SingleAffiliation",
+				session:     single,
+				expectCode:  http.StatusOK,
+				expectOrgID: 1,
+			},
+			{
+				alias:      "MultipleUnchosen",
+				session:    multi,
+				expectCode: http.StatusPreconditionRequired,
+			},
+			{
+				alias:       "MultipleChosen",
+				session:     chosen,
+				expectCode:  http.StatusOK,
+				expectOrgID: 2,
+			},
+			{
+				alias:      "ChosenNoLongerAffiliated",
+				session:    stale,
+				expectCode: http.StatusPreconditionRequired,
+			},
+			{
+				alias:       "Header",
+				session:     multi,
+				header:      "2",
+				expectCode:  http.StatusOK,
+				expectOrgID: 2,
+			},
+			{
+				alias:       "HeaderOverridesChosen",
+				session:     chosen,
+				header:      "1",
+				expectCode:  http.StatusOK,
+				expectOrgID: 1,
+			},
+			{
+				alias:      "HeaderNotAffiliated",
+				session:    single,
+				header:     "2",
+				expectCode: http.StatusForbidden,
+			},
+			{
+				alias:      "HeaderMalformed",
+				session:    single,
+				header:     "one",
+				expectCode: http.StatusBadRequest,
+			},
+			{
+				alias:      "NoAffiliations",
+				session:    none,
+				expectCode: http.StatusForbidden,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.alias, func(t *testing.T) {
+				r := httptest.NewRequest("GET", "/sponsor/organization", nil)
+				testSessionTokenInject(t, r, tc.session.Token)
+				if len(tc.header) > 0 {
+					r.Header.Set(organizationHeaderKey, tc.header)
+				}
+				w := httptest.NewRecorder()
+
+				api.router.ServeHTTP(w, r)
+
+				require.Equal(t, tc.expectCode, w.Code)
+				if tc.expectCode != http.StatusOK {
+					return
+				}
+
+				var org app.Organization
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&org))
+				assert.Equal(t, tc.expectOrgID, org.ID)
+			})
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/sponsor/organizations", nil)
+		testSessionTokenInject(t, r, chosen.Token)
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var orgs []sponsorOrganization
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&orgs))
+		require.Len(t, orgs, 2)
+		assert.Equal(t, 1, orgs[0].ID)
+		assert.False(t, orgs[0].IsActive)
+		assert.False(t, orgs[0].ArchivedAt.Valid)
+		assert.Equal(t, 2, orgs[1].ID)
+		assert.True(t, orgs[1].IsActive)
+		// Archived organizations are listed, but marked as such.
+		assert.True(t, orgs[1].ArchivedAt.Valid)
+	})
+
+	t.Run("DriverForbidden", func(t *testing.T) {
+		db.activeSessionID = 0
+
+		r := httptest.NewRequest("GET", "/sponsor/organizations", nil)
+		testSessionTokenInject(t, r, driver.Token)
+		w := httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		r = httptest.NewRequest("POST", "/sponsor/organization/switch",
+			strings.NewReader(`{"organization_id": 2}`))
+		testSessionTokenInject(t, r, driver.Token)
+		w = httptest.NewRecorder()
+
+		api.router.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Zero(t, db.activeSessionID)
+	})
+
+	t.Run("Switch", func(t *testing.T) {
+		testCases := []struct {
+			alias      string
+			body       string
+			expectCode int
+		}{
+			{
+				alias:      "Affiliated",
+				body:       `{"organization_id": 2}`,
+				expectCode: http.StatusNoContent,
+			},
+			{
+				alias:      "NotAffiliated",
+				body:       `{"organization_id": 3}`,
+				expectCode: http.StatusForbidden,
+			},
+			{
+				alias:      "BadBody",
+				body:       `{"org": 2}`,
+				expectCode: http.StatusBadRequest,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.alias, func(t *testing.T) {
+				db.activeSessionID = 0
+				db.activeOrgID = null.Int{}
+
+				r := httptest.NewRequest("POST", "/sponsor/organization/switch",
+					strings.NewReader(tc.body))
+				testSessionTokenInject(t, r, multi.Token)
+				w := httptest.NewRecorder()
+
+				api.router.ServeHTTP(w, r)
+
+				assert.Equal(t, tc.expectCode, w.Code)
+				if tc.expectCode == http.StatusNoContent {
+					assert.Equal(t, multi.ID, db.activeSessionID)
+					assert.Equal(t, null.IntFrom(2), db.activeOrgID)
+				} else {
+					assert.Zero(t, db.activeSessionID)
+				}
+			})
+		}
+	})
+}
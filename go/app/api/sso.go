@@ -0,0 +1,531 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/oidc"
+)
+
+// ssoStateCookieKey names the cookie that ties a sign on attempt to the
+// browser that started it, so that an attacker cannot complete their own
+// attempt in somebody else's browser.
+const ssoStateCookieKey = "SSO_STATE"
+
+// ssoRedirectURL is where identity providers send people back to, which must
+// be registered with each provider.
+func (svr *Server) ssoRedirectURL() string {
+	return svr.baseURL() + "/api/sso/callback"
+}
+
+// failSSO logs a failed sign on and sends the person back to the login page
+// of the web app with a message, since sign on happens in the browser rather
+// than through API requests.
+func (svr *Server) failSSO(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+	userMessage string,
+) {
+
+	svr.logger.
+		WithError(err).
+		WithField("message", userMessage).
+		Warn("single sign-on failed")
+
+	params := url.Values{}
+	params.Set("error", userMessage)
+
+	http.Redirect(w, r, svr.baseURL()+"/login?"+params.Encode(),
+		http.StatusFound)
+}
+
+// getEnabledOrganizationSSO fetches the sign on configuration of an
+// organization, failing the sign on when there is none, it is disabled, or the
+// organization is archived.
+func (svr *Server) getEnabledOrganizationSSO(
+	w http.ResponseWriter,
+	r *http.Request,
+	orgID int,
+) (app.OrganizationSSO, bool) {
+
+	org, err := svr.db.GetOrganizationByID(r.Context(), orgID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.failSSO(w, r, err,
+			"Single sign-on is not available for this organization.")
+		return app.OrganizationSSO{}, false
+	} else if err != nil {
+		svr.failSSO(w, r, errors.Wrap(err, "failed to get organization"),
+			"Single sign-on failed. Please try again.")
+		return app.OrganizationSSO{}, false
+	} else if org.ArchivedAt.Valid {
+		svr.failSSO(w, r,
+			errors.Wrapf(app.ErrOrganizationArchived, "organization %d",
+				orgID),
+			"This organization has been archived.")
+		return app.OrganizationSSO{}, false
+	}
+
+	c, err := svr.db.GetOrganizationSSO(r.Context(), orgID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.failSSO(w, r, err,
+			"Single sign-on is not available for this organization.")
+		return c, false
+	} else if err != nil {
+		svr.failSSO(w, r, errors.Wrap(err, "failed to get sso config"),
+			"Single sign-on failed. Please try again.")
+		return c, false
+	} else if !c.IsEnabled {
+		svr.failSSO(w, r, errors.Errorf("sso disabled for org %d", orgID),
+			"Single sign-on is not available for this organization.")
+		return c, false
+	}
+
+	return c, true
+}
+
+func (svr *Server) handleSSOLogin(w http.ResponseWriter, r *http.Request) {
+	pathParams := mux.Vars(r)
+
+	orgID, err := strconv.Atoi(pathParams["orgID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "orgID must be an integer"),
+			http.StatusBadRequest, "Organization ID must be an integer.")
+		return
+	}
+
+	c, ok := svr.getEnabledOrganizationSSO(w, r, orgID)
+	if !ok {
+		return
+	}
+
+	p, err := oidc.Discover(r.Context(), nil, c.IssuerURL)
+	if err != nil {
+		svr.failSSO(w, r, errors.Wrap(err, "failed to discover provider"),
+			"Could not reach your identity provider. Please try again.")
+		return
+	}
+
+	rememberMe := r.URL.Query().Get("remember_me") == "true"
+
+	l, err := app.NewOIDCLogin(orgID, rememberMe)
+	if err != nil {
+		svr.failSSO(w, r, errors.Wrap(err, "failed to start oidc login"),
+			"Single sign-on failed. Please try again.")
+		return
+	}
+
+	if err = svr.db.CreateOIDCLogin(r.Context(), *l); err != nil {
+		svr.failSSO(w, r, errors.Wrap(err, "failed to store oidc login"),
+			"Single sign-on failed. Please try again.")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:  ssoStateCookieKey,
+		Value: l.State.String(),
+
+		Domain: svr.hostname(),
+		// The cookie must be sent when the identity provider redirects back
+		// to us, which a strict cookie would not be.
+		SameSite: http.SameSiteLaxMode,
+		Secure:   svr.useHTTPS(),
+		Path:     "/",
+		HttpOnly: true,
+		MaxAge:   int(app.OIDCLoginLifetime.Seconds()),
+	})
+
+	cfg := oidc.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		RedirectURL:  svr.ssoRedirectURL(),
+	}
+
+	authURL := p.AuthCodeURL(cfg, l.State.String(), l.Nonce, l.CodeVerifier)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// nolint: gocyclo // sign on has many distinct ways to fail.
+func (svr *Server) handleSSOCallback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	// The state cookie is only needed once, so ditch it right away.
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoStateCookieKey,
+		Domain:   svr.hostname(),
+		Path:     "/",
+		HttpOnly: true,
+		MaxAge:   -1,
+	})
+
+	if idpErr := q.Get("error"); idpErr != "" {
+		svr.failSSO(w, r,
+			errors.Errorf("provider returned %s: %s",
+				idpErr, q.Get("error_description")),
+			"Your identity provider did not sign you in.")
+		return
+	}
+
+	c, err := r.Cookie(ssoStateCookieKey)
+	if err != nil || c.Value != q.Get("state") {
+		svr.failSSO(w, r, errors.New("sso state does not match cookie"),
+			"Single sign-on expired. Please try again.")
+		return
+	}
+
+	state, err := app.ParseSecureToken(q.Get("state"))
+	if err != nil {
+		svr.failSSO(w, r, errors.Wrap(err, "malformed sso state"),
+			"Single sign-on expired. Please try again.")
+		return
+	}
+
+	l, err := svr.db.ConsumeOIDCLogin(r.Context(), state)
+	if err != nil || !l.IsValid() {
+		if err == nil {
+			err = errors.New("oidc login is expired")
+		}
+		svr.failSSO(w, r, errors.Wrap(err, "failed to get oidc login"),
+			"Single sign-on expired. Please try again.")
+		return
+	}
+
+	sso, ok := svr.getEnabledOrganizationSSO(w, r, l.OrganizationID)
+	if !ok {
+		return
+	}
+
+	provider, err := oidc.Discover(r.Context(), nil, sso.IssuerURL)
+	if err != nil {
+		svr.failSSO(w, r, errors.Wrap(err, "failed to discover provider"),
+			"Could not reach your identity provider. Please try again.")
+		return
+	}
+
+	cfg := oidc.Config{
+		ClientID:     sso.ClientID,
+		ClientSecret: sso.ClientSecret,
+		RedirectURL:  svr.ssoRedirectURL(),
+	}
+
+	claims, err := provider.Exchange(r.Context(), cfg, q.Get("code"),
+		l.CodeVerifier, l.Nonce)
+	if err != nil {
+		svr.failSSO(w, r, errors.Wrap(err, "failed to exchange code"),
+			"Your identity provider did not sign you in.")
+		return
+	}
+
+	if !claims.EmailVerified || !sso.AllowsEmail(claims.Email) {
+		svr.failSSO(w, r,
+			errors.Errorf("email '%s' (verified: %t) not allowed for org %d",
+				claims.Email, claims.EmailVerified, sso.OrganizationID),
+			"Your email address may not sign on to this organization.")
+		return
+	}
+
+	p, ok := svr.getOrProvisionSSOPerson(w, r, sso, claims)
+	if !ok {
+		return
+	}
+
+	if p.IsDeactivated {
+		svr.failSSO(w, r, errors.Errorf("person %d is deactivated", p.ID),
+			"Your account has been deactivated.")
+		return
+	}
+
+	if !svr.startSession(w, r, p, l.RememberMe) {
+		return
+	}
+
+	http.Redirect(w, r, svr.baseURL()+"/", http.StatusFound)
+}
+
+// getOrProvisionSSOPerson finds the person who signed on with the identity in
+// the given claims. People signing on for the first time are linked to an
+// existing account of the organization with the same email, or else have a
+// new account created for them just in time.
+//
+// Fails the sign on and returns false upon failure.
+func (svr *Server) getOrProvisionSSOPerson(
+	w http.ResponseWriter,
+	r *http.Request,
+	sso app.OrganizationSSO,
+	claims oidc.Claims,
+) (app.Person, bool) {
+
+	issuer, subject := sso.IssuerURL, claims.Subject
+
+	p, err := svr.db.GetPersonByIdentity(r.Context(), issuer, subject)
+	if err == nil && !isAffiliatedWith(p, sso.OrganizationID) {
+		// People removed from the organization may no longer sign on
+		// through it, even though their identity remains linked.
+		svr.failSSO(w, r,
+			errors.Errorf("person %d is no longer affiliated with org %d",
+				p.ID, sso.OrganizationID),
+			"Your account no longer belongs to this organization.")
+		return p, false
+	} else if err == nil {
+		return p, true
+	} else if !errors.Is(err, app.ErrNotFound) {
+		svr.failSSO(w, r, errors.Wrap(err, "failed to get person by identity"),
+			"Single sign-on failed. Please try again.")
+		return p, false
+	}
+
+	p, err = svr.db.GetPersonByEmail(r.Context(), claims.Email)
+	if err == nil {
+		// Only accounts that already belong to the organization may be
+		// linked, so that an organization's provider cannot take over the
+		// accounts of people outside of it.
+		if !isAffiliatedWith(p, sso.OrganizationID) {
+			svr.failSSO(w, r,
+				errors.Errorf("person %d is not affiliated with org %d",
+					p.ID, sso.OrganizationID),
+				"An account with your email address already exists. "+
+					"Please log in with your password.")
+			return p, false
+		}
+
+		err = svr.db.LinkPersonIdentity(r.Context(), p.ID, issuer, subject)
+		if err != nil {
+			svr.failSSO(w, r, errors.Wrap(err, "failed to link identity"),
+				"Single sign-on failed. Please try again.")
+			return p, false
+		}
+
+		return p, true
+	} else if !errors.Is(err, app.ErrNotFound) {
+		svr.failSSO(w, r, errors.Wrap(err, "failed to get person by email"),
+			"Single sign-on failed. Please try again.")
+		return p, false
+	}
+
+	p = app.Person{
+		FirstName: claims.GivenName,
+		LastName:  claims.FamilyName,
+		Email:     claims.Email,
+		Role:      app.RoleSponsor,
+		// People created by sign on have no password, so they may only log
+		// in through their identity provider.
+		Password:     "",
+		Affiliations: []int{sso.OrganizationID},
+	}
+
+	if p.FirstName == "" && p.LastName == "" {
+		p.FirstName, p.LastName = splitName(claims.Name)
+	}
+
+	p.ID, err = svr.db.ProvisionSSOPerson(r.Context(), p,
+		sso.OrganizationID, issuer, subject)
+	if err != nil {
+		svr.failSSO(w, r, errors.Wrap(err, "failed to provision person"),
+			"Single sign-on failed. Please try again.")
+		return p, false
+	}
+
+	return p, true
+}
+
+// isAffiliatedWith determines whether a person belongs to an organization.
+func isAffiliatedWith(p app.Person, orgID int) bool {
+	for _, a := range p.Affiliations {
+		if a == orgID {
+			return true
+		}
+	}
+	return false
+}
+
+// splitName divides a full name into first and last names.
+func splitName(name string) (first, last string) {
+	fields := strings.Fields(name)
+	if len(fields) < 1 {
+		return "", ""
+	} else if len(fields) == 1 {
+		return fields[0], ""
+	}
+	return strings.Join(fields[:len(fields)-1], " "), fields[len(fields)-1]
+}
+
+type ssoConfigRequest struct {
+	IssuerURL string `json:"issuer_url"`
+	ClientID  string `json:"client_id"`
+	// ClientSecret may be left blank to keep the existing secret.
+	ClientSecret string   `json:"client_secret"`
+	EmailDomains []string `json:"email_domains"`
+	IsEnabled    bool     `json:"is_enabled"`
+}
+
+func (svr *Server) validateSSOConfig(
+	req *ssoConfigRequest,
+) (message string, err error) {
+
+	defer func() {
+		if message != "" {
+			err = errors.New(message)
+		}
+	}()
+
+	issuer, parseErr := url.Parse(req.IssuerURL)
+
+	// Plain HTTP is allowed locally, for testing with a mock provider.
+	schemeOK := issuer != nil && (issuer.Scheme == "https" ||
+		(issuer.Scheme == "http" && svr.config.Tier == TierLocal))
+
+	if parseErr != nil || !schemeOK || issuer.Host == "" {
+		message = "Issuer URL must be a valid HTTPS URL."
+	} else if len(strings.TrimSpace(req.ClientID)) < 1 {
+		message = "Client ID cannot be blank."
+	} else if len(req.EmailDomains) < 1 {
+		message = "At least one email domain is required."
+	}
+
+	for idx, domain := range req.EmailDomains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if message == "" && (domain == "" || strings.Contains(domain, "@")) {
+			message = "Email domains must be like 'example.com'."
+		}
+		req.EmailDomains[idx] = domain
+	}
+
+	return
+}
+
+func (svr *Server) handleAdminGetOrganizationSSO(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	orgID, err := strconv.Atoi(pathParams["orgID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "orgID must be an integer"),
+			http.StatusBadRequest, "Organization ID must be an integer.")
+		return
+	}
+
+	c, err := svr.db.GetOrganizationSSO(r.Context(), orgID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound,
+			"Single sign-on is not configured for this organization.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to get sso config"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	svr.sendJSONResponse(w, c)
+}
+
+func (svr *Server) handleAdminSetOrganizationSSO(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	orgID, err := strconv.Atoi(pathParams["orgID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "orgID must be an integer"),
+			http.StatusBadRequest, "Organization ID must be an integer.")
+		return
+	}
+
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+
+	var req ssoConfigRequest
+	if err = d.Decode(&req); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to decode body"),
+			http.StatusBadRequest, "Malformed request body.")
+		return
+	}
+
+	if msg, err := svr.validateSSOConfig(&req); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "invalid sso config"),
+			http.StatusBadRequest, msg)
+		return
+	}
+
+	if _, err = svr.db.GetOrganizationByID(r.Context(), orgID); err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, app.ErrNotFound) {
+			code = http.StatusNotFound
+		}
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to get org"),
+			code, "")
+		return
+	}
+
+	c := app.OrganizationSSO{
+		OrganizationID: orgID,
+		IssuerURL:      strings.TrimSuffix(req.IssuerURL, "/"),
+		ClientID:       strings.TrimSpace(req.ClientID),
+		ClientSecret:   req.ClientSecret,
+		EmailDomains:   req.EmailDomains,
+		IsEnabled:      req.IsEnabled,
+	}
+
+	if c.ClientSecret == "" {
+		existing, err := svr.db.GetOrganizationSSO(r.Context(), orgID)
+		if errors.Is(err, app.ErrNotFound) {
+			svr.sendErrorResponse(w, err, http.StatusBadRequest,
+				"Client secret cannot be blank.")
+			return
+		} else if err != nil {
+			svr.sendErrorResponse(w,
+				errors.Wrap(err, "failed to get sso config"),
+				http.StatusInternalServerError, "")
+			return
+		}
+		c.ClientSecret = existing.ClientSecret
+	}
+
+	if err = svr.db.SetOrganizationSSO(r.Context(), c); err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "failed to set sso config"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (svr *Server) handleAdminDeleteOrganizationSSO(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	pathParams := mux.Vars(r)
+
+	orgID, err := strconv.Atoi(pathParams["orgID"])
+	if err != nil {
+		svr.sendErrorResponse(w, errors.Wrap(err, "orgID must be an integer"),
+			http.StatusBadRequest, "Organization ID must be an integer.")
+		return
+	}
+
+	err = svr.db.DeleteOrganizationSSO(r.Context(), orgID)
+	if errors.Is(err, app.ErrNotFound) {
+		svr.sendErrorResponse(w, err, http.StatusNotFound,
+			"Single sign-on is not configured for this organization.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to delete sso config"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
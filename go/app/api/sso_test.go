@@ -0,0 +1,414 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+	"github.com/BenJetson/CPSC491-project/go/app/mock"
+	"github.com/BenJetson/CPSC491-project/go/app/oidc/oidctest"
+)
+
+type ssoMockDB struct {
+	*mock.DB
+
+	org    app.Organization
+	sso    app.OrganizationSSO
+	ssoErr error
+
+	logins map[string]app.OIDCLogin
+
+	personByIdentity    app.Person
+	personByIdentityErr error
+
+	personByEmail    app.Person
+	personByEmailErr error
+
+	linkedPersonID int
+	provisioned    *app.Person
+	sessions       []app.Session
+}
+
+func (db *ssoMockDB) GetOrganizationByID(
+	_ context.Context,
+	_ int,
+) (app.Organization, error) {
+
+	return db.org, nil
+}
+
+func (db *ssoMockDB) GetOrganizationSSO(
+	_ context.Context,
+	_ int,
+) (app.OrganizationSSO, error) {
+
+	return db.sso, db.ssoErr
+}
+
+func (db *ssoMockDB) CreateOIDCLogin(
+	_ context.Context,
+	l app.OIDCLogin,
+) error {
+
+	db.logins[l.State.Hash()] = l
+	return nil
+}
+
+func (db *ssoMockDB) ConsumeOIDCLogin(
+	_ context.Context,
+	state app.SecureToken,
+) (app.OIDCLogin, error) {
+
+	l, ok := db.logins[state.Hash()]
+	if !ok {
+		return l, app.ErrNotFound
+	}
+	delete(db.logins, state.Hash())
+	return l, nil
+}
+
+func (db *ssoMockDB) GetPersonByIdentity(
+	_ context.Context,
+	_, _ string,
+) (app.Person, error) {
+
+	return db.personByIdentity, db.personByIdentityErr
+}
+
+func (db *ssoMockDB) GetPersonByEmail(
+	_ context.Context,
+	_ string,
+) (app.Person, error) {
+
+	return db.personByEmail, db.personByEmailErr
+}
+
+func (db *ssoMockDB) LinkPersonIdentity(
+	_ context.Context,
+	personID int,
+	_, _ string,
+) error {
+
+	db.linkedPersonID = personID
+	return nil
+}
+
+func (db *ssoMockDB) ProvisionSSOPerson(
+	_ context.Context,
+	p app.Person,
+	_ int,
+	_, _ string,
+) (int, error) {
+
+	db.provisioned = &p
+	return 42, nil
+}
+
+func (db *ssoMockDB) CreateSession(
+	_ context.Context,
+	s app.Session,
+) (int, error) {
+
+	db.sessions = append(db.sessions, s)
+	return len(db.sessions), nil
+}
+
+// nolint: gocyclo // many cases are needed for complete coverage.
+func TestSSOLogin(t *testing.T) {
+	idp, err := oidctest.NewProvider("dispatch", "s3cret")
+	require.NoError(t, err)
+	defer idp.Close()
+
+	noRedirectClient := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	sso := app.OrganizationSSO{
+		OrganizationID: 7,
+		IssuerURL:      idp.Issuer(),
+		ClientID:       idp.ClientID,
+		ClientSecret:   idp.ClientSecret,
+		EmailDomains:   []string{"acme.example"},
+		IsEnabled:      true,
+	}
+
+	user := oidctest.User{
+		Subject:       "abc123",
+		Email:         "dana@acme.example",
+		EmailVerified: true,
+		GivenName:     "Dana",
+		FamilyName:    "Dispatcher",
+	}
+
+	existing := app.Person{
+		ID:        3,
+		FirstName: "Dana",
+		LastName:  "Dispatcher",
+		Email:     "dana@acme.example",
+		Role:      app.RoleSponsor,
+	}
+
+	affiliated := existing
+	affiliated.Affiliations = []int{7}
+
+	deactivated := affiliated
+	deactivated.IsDeactivated = true
+
+	notFound := errors.Wrap(app.ErrNotFound, "nobody")
+
+	testCases := []struct {
+		alias               string
+		mutateSSO           func(c *app.OrganizationSSO)
+		mutateUser          func(u *oidctest.User)
+		personByIdentity    app.Person
+		personByIdentityErr error
+		personByEmail       app.Person
+		personByEmailErr    error
+		badState            bool
+		archived            bool
+		expectSession       bool
+		expectLinked        int
+		expectProvisioned   bool
+	}{
+		{
+			alias:               "NewPerson",
+			personByIdentityErr: notFound,
+			personByEmailErr:    notFound,
+			expectSession:       true,
+			expectProvisioned:   true,
+		},
+		{
+			alias:            "KnownIdentity",
+			personByIdentity: affiliated,
+			expectSession:    true,
+		},
+		{
+			alias:            "KnownIdentityRemoved",
+			personByIdentity: existing,
+		},
+		{
+			alias:               "LinkAffiliatedEmail",
+			personByIdentityErr: notFound,
+			personByEmail:       affiliated,
+			expectSession:       true,
+			expectLinked:        3,
+		},
+		{
+			alias:               "UnaffiliatedEmail",
+			personByIdentityErr: notFound,
+			personByEmail:       existing,
+		},
+		{
+			alias:            "Deactivated",
+			personByIdentity: deactivated,
+		},
+		{
+			alias: "DisallowedDomain",
+			mutateUser: func(u *oidctest.User) {
+				u.Email = "x@evil.test"
+			},
+			personByIdentityErr: notFound,
+			personByEmailErr:    notFound,
+		},
+		{
+			alias: "UnverifiedEmail",
+			mutateUser: func(u *oidctest.User) {
+				u.EmailVerified = false
+			},
+			personByIdentityErr: notFound,
+			personByEmailErr:    notFound,
+		},
+		{
+			alias:               "StateMismatch",
+			personByIdentityErr: notFound,
+			personByEmailErr:    notFound,
+			badState:            true,
+		},
+		{
+			alias:     "Disabled",
+			mutateSSO: func(c *app.OrganizationSSO) { c.IsEnabled = false },
+		},
+		{
+			alias:    "Archived",
+			archived: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			db := &ssoMockDB{
+				org:                 app.Organization{ID: 7},
+				sso:                 sso,
+				logins:              make(map[string]app.OIDCLogin),
+				personByIdentity:    tc.personByIdentity,
+				personByIdentityErr: tc.personByIdentityErr,
+				personByEmail:       tc.personByEmail,
+				personByEmailErr:    tc.personByEmailErr,
+			}
+			if tc.mutateSSO != nil {
+				tc.mutateSSO(&db.sso)
+			}
+			if tc.archived {
+				db.org.ArchivedAt = null.TimeFrom(time.Now())
+			}
+
+			u := user
+			if tc.mutateUser != nil {
+				tc.mutateUser(&u)
+			}
+			idp.SetUser(u)
+
+			api, _, _ := newTestAPI(t, db, nil)
+
+			// Start the sign on, which should redirect to the provider.
+			r := httptest.NewRequest("GET", "/sso/7/login?remember_me=true",
+				nil)
+			w := httptest.NewRecorder()
+			api.router.ServeHTTP(w, r)
+
+			require.Equal(t, http.StatusFound, w.Code)
+			location := w.Header().Get("Location")
+
+			if tc.mutateSSO != nil || tc.archived {
+				assert.Contains(t, location, "/login?error=")
+				assert.Empty(t, db.logins)
+				return
+			}
+			require.True(t, strings.HasPrefix(location, idp.Issuer()))
+
+			var stateCookie *http.Cookie
+			for _, c := range w.Result().Cookies() {
+				if c.Name == ssoStateCookieKey {
+					stateCookie = c
+				}
+			}
+			require.NotNil(t, stateCookie)
+
+			// Let the provider authenticate, which should redirect back.
+			res, err := noRedirectClient.Get(location)
+			require.NoError(t, err)
+			res.Body.Close()
+			require.Equal(t, http.StatusFound, res.StatusCode)
+
+			callback, err := url.Parse(res.Header.Get("Location"))
+			require.NoError(t, err)
+			assert.Equal(t, api.ssoRedirectURL(),
+				strings.Split(callback.String(), "?")[0])
+
+			if tc.badState {
+				other, err := app.NewSecureToken()
+				require.NoError(t, err)
+				stateCookie.Value = other.String()
+			}
+
+			r = httptest.NewRequest("GET",
+				"/sso/callback?"+callback.RawQuery, nil)
+			r.AddCookie(stateCookie)
+			w = httptest.NewRecorder()
+			api.router.ServeHTTP(w, r)
+
+			require.Equal(t, http.StatusFound, w.Code)
+			location = w.Header().Get("Location")
+
+			var sessionCookie *http.Cookie
+			for _, c := range w.Result().Cookies() {
+				if c.Name == sessionCookieKey {
+					sessionCookie = c
+				}
+			}
+
+			if tc.expectSession {
+				assert.Equal(t, api.baseURL()+"/", location)
+				require.NotNil(t, sessionCookie)
+				require.Len(t, db.sessions, 1)
+				assert.True(t, db.sessions[0].IsRememberMe)
+			} else {
+				assert.Contains(t, location, "/login?error=")
+				assert.Nil(t, sessionCookie)
+				assert.Empty(t, db.sessions)
+			}
+
+			assert.Equal(t, tc.expectLinked, db.linkedPersonID)
+			if tc.expectProvisioned {
+				require.NotNil(t, db.provisioned)
+				assert.Equal(t, u.Email, db.provisioned.Email)
+				assert.Equal(t, u.GivenName, db.provisioned.FirstName)
+				assert.Equal(t, app.RoleSponsor, db.provisioned.Role)
+			} else {
+				assert.Nil(t, db.provisioned)
+			}
+		})
+	}
+}
+
+func TestValidateSSOConfig(t *testing.T) {
+	api, _, _ := newTestAPI(t, &mock.DB{}, nil)
+
+	testCases := []struct {
+		alias        string
+		req          ssoConfigRequest
+		expectDomain []string
+		expectError  bool
+	}{
+		{
+			alias: "Valid",
+			req: ssoConfigRequest{
+				IssuerURL:    "https://sso.acme.example",
+				ClientID:     "dispatch",
+				EmailDomains: []string{" ACME.example "},
+			},
+			expectDomain: []string{"acme.example"},
+		},
+		{
+			alias: "NoDomains",
+			req: ssoConfigRequest{
+				IssuerURL: "https://sso.acme.example",
+				ClientID:  "dispatch",
+			},
+			expectError: true,
+		},
+		{
+			alias: "EmailForDomain",
+			req: ssoConfigRequest{
+				IssuerURL:    "https://sso.acme.example",
+				ClientID:     "dispatch",
+				EmailDomains: []string{"dana@acme.example"},
+			},
+			expectError: true,
+		},
+		{
+			alias: "NoClientID",
+			req: ssoConfigRequest{
+				IssuerURL:    "https://sso.acme.example",
+				EmailDomains: []string{"acme.example"},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			message, err := api.validateSSOConfig(&tc.req)
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.NotEmpty(t, message)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectDomain, tc.req.EmailDomains)
+		})
+	}
+}
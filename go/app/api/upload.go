@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// uploadFormField is the name of the multipart form field that carries an
+// uploaded file.
+const uploadFormField = "file"
+
+// An upload is a file received from a multipart form.
+type upload struct {
+	fileName string
+	// contentType is the MIME type of the file, as detected from its contents.
+	contentType string
+	content     []byte
+}
+
+// receiveUpload reads the file from the multipart form of a request, and scans
+// it for malware. The file may be at most maxSize bytes, and the type detected
+// from its contents must be allowed; typesMessage tells the client which types
+// are. When the file cannot be accepted, an error response is sent and ok is
+// false.
+func (svr *Server) receiveUpload(
+	w http.ResponseWriter,
+	r *http.Request,
+	maxSize int64,
+	allowed func(contentType string) bool,
+	typesMessage string,
+) (u upload, ok bool) {
+
+	// Leave some room for the multipart headers around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+(64<<10))
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "request is not multipart"),
+			http.StatusBadRequest, "Files must be uploaded as a form.")
+		return
+	}
+
+	var part io.Reader
+	for part == nil {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			svr.sendErrorResponse(w, errors.New("no file in upload"),
+				http.StatusBadRequest, "No file was uploaded.")
+			return
+		} else if err != nil {
+			svr.sendErrorResponse(w,
+				errors.Wrap(err, "failed to read multipart form"),
+				http.StatusBadRequest, "The upload could not be read.")
+			return
+		}
+
+		if p.FormName() == uploadFormField {
+			part = p
+			u.fileName = p.FileName()
+		}
+	}
+
+	// Read one byte more than allowed, to tell whether the file is too big.
+	u.content, err = ioutil.ReadAll(io.LimitReader(part, maxSize+1))
+	if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to read uploaded file"),
+			http.StatusRequestEntityTooLarge,
+			"Files may be at most %d MB.", maxSize>>20)
+		return
+	} else if int64(len(u.content)) > maxSize {
+		svr.sendErrorResponse(w,
+			errors.Errorf("uploaded file exceeds %d bytes", maxSize),
+			http.StatusRequestEntityTooLarge,
+			"Files may be at most %d MB.", maxSize>>20)
+		return
+	} else if len(u.content) < 1 {
+		svr.sendErrorResponse(w, errors.New("uploaded file is empty"),
+			http.StatusBadRequest, "The uploaded file is empty.")
+		return
+	}
+
+	// The type claimed by the client cannot be trusted, so detect it from the
+	// contents instead.
+	u.contentType, _, err = mime.ParseMediaType(
+		http.DetectContentType(u.content))
+	if err != nil || !allowed(u.contentType) {
+		svr.sendErrorResponse(w,
+			errors.Errorf("uploaded file has type '%s'", u.contentType),
+			http.StatusUnsupportedMediaType, typesMessage)
+		return
+	}
+
+	err = svr.scanner.ScanFile(r.Context(), bytes.NewReader(u.content))
+	if errors.Is(err, app.ErrFileInfected) {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "uploaded file is infected"),
+			http.StatusUnprocessableEntity,
+			"This file appears to contain malware and was not saved.")
+		return
+	} else if err != nil {
+		svr.sendErrorResponse(w,
+			errors.Wrap(err, "failed to scan uploaded file"),
+			http.StatusInternalServerError, "")
+		return
+	}
+
+	return u, true
+}
@@ -1,10 +1,13 @@
 package api
 
 import (
-	"fmt"
+	"context"
+	"net/http"
 	"regexp"
 
 	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
 )
 
 // validateEmail is a regular expression validator for email addresses.
@@ -17,15 +20,49 @@ var validateEmail = regexp.MustCompile(
 		`[a-zA-Z0-9])?)*$`,
 )
 
-func validatePassword(pass string) (message string, err error) {
-	const minPassLength = 8
+// getRecentPasswords fetches the most recent password hashes of a person that
+// the password policy forbids reusing, newest first. People who do not exist
+// yet have none.
+func (svr *Server) getRecentPasswords(
+	ctx context.Context,
+	p app.Person,
+) ([]app.Password, error) {
+
+	size := svr.config.Passwords.HistorySize
+	if p.ID == 0 || size < 1 {
+		return nil, nil
+	}
+
+	previous, err := svr.db.GetPreviousPasswords(ctx, p.ID, size-1)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get password history")
+	}
+
+	return append([]app.Password{p.Password}, previous...), nil
+}
+
+// validateNewPassword checks a new password for a person against the password
+// policy, including their password history.
+//
+// Upon failure, writes an error to the ResponseWriter and returns false.
+func (svr *Server) validateNewPassword(
+	w http.ResponseWriter,
+	r *http.Request,
+	plaintext string,
+	p app.Person,
+) bool {
+
+	recent, err := svr.getRecentPasswords(r.Context(), p)
+	if err != nil {
+		svr.sendErrorResponse(w, err, http.StatusInternalServerError, "")
+		return false
+	}
 
-	if len(pass) < minPassLength {
-		err = errors.New("password does not meet length requirement")
-		message = fmt.Sprintf("Password must be at least %d characters long.",
-			minPassLength)
-		return
+	message, err := svr.config.Passwords.Validate(plaintext, p, recent)
+	if err != nil {
+		svr.sendErrorResponse(w, err, http.StatusBadRequest, message)
+		return false
 	}
 
-	return
+	return true
 }
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+)
+
+// An APITokenScope grants an API token access to one area of the API, either
+// for reading or for writing.
+//
+// Scopes are named as "area:access". Write access implies read access.
+type APITokenScope string
+
+// These are the scopes that may be granted to an API token.
+const (
+	ScopeMyRead       APITokenScope = "my:read"
+	ScopeMyWrite      APITokenScope = "my:write"
+	ScopeAdminRead    APITokenScope = "admin:read"
+	ScopeAdminWrite   APITokenScope = "admin:write"
+	ScopeSponsorRead  APITokenScope = "sponsor:read"
+	ScopeSponsorWrite APITokenScope = "sponsor:write"
+	ScopeDriverRead   APITokenScope = "driver:read"
+	ScopeDriverWrite  APITokenScope = "driver:write"
+)
+
+// AllAPITokenScopes lists every valid APITokenScope.
+var AllAPITokenScopes = []APITokenScope{
+	ScopeMyRead,
+	ScopeMyWrite,
+	ScopeAdminRead,
+	ScopeAdminWrite,
+	ScopeSponsorRead,
+	ScopeSponsorWrite,
+	ScopeDriverRead,
+	ScopeDriverWrite,
+}
+
+// ScopeFor determines the scope required to access an area of the API, for
+// writing or for reading.
+func ScopeFor(area string, write bool) APITokenScope {
+	if write {
+		return APITokenScope(area + ":write")
+	}
+	return APITokenScope(area + ":read")
+}
+
+// IsValid determines whether this scope is one that may be granted.
+func (s APITokenScope) IsValid() bool {
+	for _, valid := range AllAPITokenScopes {
+		if s == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Implies determines whether a token granted this scope may also be used for
+// another scope. Each scope implies itself, and write scopes imply the read
+// scope of their area.
+func (s APITokenScope) Implies(other APITokenScope) bool {
+	if s == other {
+		return true
+	}
+
+	area := strings.TrimSuffix(string(s), ":write")
+	return area != string(s) && other == ScopeFor(area, false)
+}
+
+// MaxAPITokenLifetime is the longest an API token may be valid for.
+const MaxAPITokenLifetime = 365 * 24 * time.Hour
+
+// An APIToken is a long-lived credential that a person may create for
+// scripts and integrations to access the API on their behalf.
+type APIToken struct {
+	// ID is the identifying number of this token. It is not secret.
+	ID int `db:"api_token_id" json:"id"`
+	// PersonID is the identifier of the person who owns this token.
+	PersonID int `db:"person_id" json:"person_id"`
+	// Token is the secret value presented by the client as a bearer token.
+	//
+	// Only the hash of the token is stored, so this will be the zero value
+	// for tokens retrieved from a DataStore.
+	Token SecureToken `db:"-" json:"-"`
+	// Name describes what this token is used for, as chosen by its owner.
+	Name string `db:"name" json:"name"`
+	// Scopes lists the areas of the API that this token may access.
+	Scopes []APITokenScope `db:"scopes" json:"scopes"`
+	// CreatedAt is the timestamp this token was created at.
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	// ExpiresAt is the timestamp this token will expire at.
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+	// LastUsedAt is the approximate timestamp this token was last used at.
+	// Will be null if the token has never been used.
+	LastUsedAt null.Time `db:"last_used_at" json:"last_used_at"`
+	// IsRevoked is true when the token was manually revoked.
+	IsRevoked bool `db:"is_revoked" json:"is_revoked"`
+}
+
+// NewAPIToken creates a new API token with a secure random value for a given
+// person. The scopes must all be valid and the expiration must be within
+// MaxAPITokenLifetime.
+func NewAPIToken(
+	personID int,
+	name string,
+	scopes []APITokenScope,
+	expiresAt time.Time,
+) (*APIToken, error) {
+
+	now := time.Now().UTC().Round(time.Second)
+
+	if len(scopes) < 1 {
+		return nil, errors.New("token must have at least one scope")
+	}
+
+	for _, scope := range scopes {
+		if !scope.IsValid() {
+			return nil, errors.Errorf("unknown scope '%s'", scope)
+		}
+	}
+
+	if !expiresAt.After(now) {
+		return nil, errors.New("expiration must be in the future")
+	} else if expiresAt.Sub(now) > MaxAPITokenLifetime {
+		return nil, errors.Errorf("expiration must be within %v",
+			MaxAPITokenLifetime)
+	}
+
+	token, err := NewSecureToken()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create api token")
+	}
+
+	return &APIToken{
+		PersonID:  personID,
+		Token:     token,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: now,
+		ExpiresAt: expiresAt.UTC().Round(time.Second),
+	}, nil
+}
+
+// HasScope determines whether this token may be used for a given scope.
+func (t *APIToken) HasScope(scope APITokenScope) bool {
+	for _, granted := range t.Scopes {
+		if granted.Implies(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValid determines whether or not this token may still be used.
+func (t *APIToken) IsValid() bool {
+	now := time.Now().UTC().Round(time.Second)
+
+	// WARNING: if you change the logic here, make sure it matches the SQL
+	// query logic of the GetAPITokensForPerson method in the db package!
+
+	return !t.IsRevoked && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,29 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPITokenHasScope(t *testing.T) {
+	tok := APIToken{Scopes: []APITokenScope{ScopeDriverWrite, ScopeMyRead}}
+
+	testCases := []struct {
+		scope  APITokenScope
+		expect bool
+	}{
+		{scope: ScopeDriverWrite, expect: true},
+		{scope: ScopeDriverRead, expect: true},
+		{scope: ScopeMyRead, expect: true},
+		{scope: ScopeMyWrite, expect: false},
+		{scope: ScopeAdminRead, expect: false},
+		{scope: ScopeFor("", false), expect: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.scope), func(t *testing.T) {
+			assert.Equal(t, tc.expect, tok.HasScope(tc.scope))
+		})
+	}
+}
@@ -1,11 +1,85 @@
 package app
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/pkg/errors"
 	"gopkg.in/guregu/null.v4"
 )
 
+// An ApplicationStatus is a state in the lifecycle of an Application.
+type ApplicationStatus string
+
+// These are the states that an application may be in.
+const (
+	// ApplicationSubmitted applications await review by the organization.
+	ApplicationSubmitted ApplicationStatus = "submitted"
+	// ApplicationOnHold applications have been set aside by the organization.
+	ApplicationOnHold ApplicationStatus = "on_hold"
+	// ApplicationInfoRequested applications await an answer from the
+	// applicant to a question from the organization.
+	ApplicationInfoRequested ApplicationStatus = "info_requested"
+	// ApplicationApproved applications have been accepted, making the
+	// applicant a driver for the organization.
+	ApplicationApproved ApplicationStatus = "approved"
+	// ApplicationRejected applications have been turned down.
+	ApplicationRejected ApplicationStatus = "rejected"
+	// ApplicationWithdrawn applications have been withdrawn by the applicant.
+	ApplicationWithdrawn ApplicationStatus = "withdrawn"
+)
+
+// applicationTransitions lists the states that each state may move to.
+// States that are missing are final.
+var applicationTransitions = map[ApplicationStatus][]ApplicationStatus{
+	ApplicationSubmitted: {
+		ApplicationOnHold,
+		ApplicationInfoRequested,
+		ApplicationApproved,
+		ApplicationRejected,
+		ApplicationWithdrawn,
+	},
+	ApplicationOnHold: {
+		ApplicationSubmitted,
+		ApplicationInfoRequested,
+		ApplicationApproved,
+		ApplicationRejected,
+		ApplicationWithdrawn,
+	},
+	ApplicationInfoRequested: {
+		ApplicationSubmitted,
+		ApplicationRejected,
+		ApplicationWithdrawn,
+	},
+}
+
+// CanTransitionTo determines whether an application in this state may move
+// to the given state.
+func (s ApplicationStatus) CanTransitionTo(to ApplicationStatus) bool {
+	for _, allowed := range applicationTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinal determines whether an application in this state can never change.
+func (s ApplicationStatus) IsFinal() bool {
+	return len(applicationTransitions[s]) < 1
+}
+
+// IsKnown determines whether this is one of the states an application may be
+// in.
+func (s ApplicationStatus) IsKnown() bool {
+	switch s {
+	case ApplicationSubmitted, ApplicationOnHold, ApplicationInfoRequested,
+		ApplicationApproved, ApplicationRejected, ApplicationWithdrawn:
+		return true
+	}
+	return false
+}
+
 // Application represents a driver application to be sponsored by an
 // organization.
 type Application struct {
@@ -20,6 +94,11 @@ type Application struct {
 	OrganizationTitle string `db:"name" json:"organization_name"`
 	// Comment is a driver-supplied comment to go with their application.
 	Comment string `db:"comment" json:"comment"`
+	// Answers are the driver's answers to the application form of the
+	// organization. Only filled in when fetching a single application.
+	Answers []ApplicationAnswer `db:"-" json:"answers,omitempty"`
+	// Status is the current state of this application.
+	Status ApplicationStatus `db:"status" json:"status"`
 	// Approved specifies whether or not the organization has approved this
 	// application or not. Will be null if no decision has been made.
 	Approved null.Bool `db:"approved" json:"approved"`
@@ -31,3 +110,75 @@ type Application struct {
 	// this application.
 	ApprovedAt null.Time `db:"approved_at" json:"approved_at"`
 }
+
+// An ApplicationEvent records a change in the status of an Application.
+type ApplicationEvent struct {
+	ID            int `db:"application_event_id" json:"id"`
+	ApplicationID int `db:"application_id" json:"application_id"`
+	// FromStatus is the status before the change. Will be blank for the
+	// submission of the application.
+	FromStatus ApplicationStatus `db:"from_status" json:"from_status"`
+	ToStatus   ApplicationStatus `db:"to_status" json:"to_status"`
+	// ActorID is the person who made the change, if known.
+	ActorID null.Int `db:"actor_id" json:"actor_id"`
+	// Message is the note that went with the change, such as the question
+	// asked by the organization or the answer given by the applicant.
+	Message   null.String `db:"message" json:"message"`
+	CreatedAt time.Time   `db:"created_at" json:"created_at"`
+}
+
+// An ApplicationSort is an order in which applications may be listed.
+type ApplicationSort string
+
+// These are the orders in which applications may be listed.
+const (
+	// ApplicationSortNewest lists the most recently submitted first.
+	ApplicationSortNewest ApplicationSort = "newest"
+	// ApplicationSortOldest lists the least recently submitted first.
+	ApplicationSortOldest ApplicationSort = "oldest"
+	// ApplicationSortApplicant lists by the name of the applicant.
+	ApplicationSortApplicant ApplicationSort = "applicant"
+)
+
+// An ApplicationFilter narrows down which applications to fetch, and in what
+// order. Zero-valued fields do not filter anything.
+type ApplicationFilter struct {
+	// Statuses includes only applications in one of these states.
+	Statuses []ApplicationStatus
+	// Since and Until bound when the applications were submitted.
+	Since null.Time
+	Until null.Time
+	// Sort orders the applications, newest first when blank.
+	Sort ApplicationSort
+}
+
+// These errors may be returned by CreateApplication when a person may not
+// apply to an organization. Each is also an ErrConflict.
+var (
+	// ErrApplicationOpen means that the person already has an open
+	// application to the organization.
+	ErrApplicationOpen = errors.WithMessage(ErrConflict,
+		"application already open")
+	// ErrAlreadyAffiliated means that the person already belongs to the
+	// organization.
+	ErrAlreadyAffiliated = errors.WithMessage(ErrConflict,
+		"already affiliated with organization")
+)
+
+// A ReapplyCooldownError may be returned by CreateApplication when the person
+// was rejected by the organization too recently to apply again. It is also an
+// ErrConflict.
+type ReapplyCooldownError struct {
+	// Until is when the person may apply again.
+	Until time.Time
+}
+
+func (e *ReapplyCooldownError) Error() string {
+	return fmt.Sprintf("may not reapply until %s",
+		e.Until.Format(time.RFC3339))
+}
+
+// Unwrap allows errors.Is to match a ReapplyCooldownError to ErrConflict.
+func (e *ReapplyCooldownError) Unwrap() error {
+	return ErrConflict
+}
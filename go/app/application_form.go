@@ -0,0 +1,289 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+)
+
+// A QuestionType describes the kind of answer an ApplicationQuestion expects.
+type QuestionType string
+
+// These are the types of questions that may appear on an application form.
+const (
+	// QuestionText answers are JSON strings.
+	QuestionText QuestionType = "text"
+	// QuestionNumber answers are JSON numbers.
+	QuestionNumber QuestionType = "number"
+	// QuestionChoice answers are JSON strings, which must be one of the
+	// choices of the question.
+	QuestionChoice QuestionType = "choice"
+	// QuestionYesNo answers are JSON booleans.
+	QuestionYesNo QuestionType = "yes_no"
+	// QuestionDate answers are JSON strings in the form YYYY-MM-DD.
+	QuestionDate QuestionType = "date"
+)
+
+// questionDateLayout is the layout of answers to QuestionDate questions.
+const questionDateLayout = "2006-01-02"
+
+// An ApplicationQuestion is a question on the application form of an
+// organization, which drivers answer when they apply.
+type ApplicationQuestion struct {
+	ID             int          `db:"application_question_id" json:"id"`
+	OrganizationID int          `db:"organization_id" json:"organization_id"`
+	Prompt         string       `db:"prompt" json:"prompt"`
+	Type           QuestionType `db:"question_type" json:"type"`
+	IsRequired     bool         `db:"is_required" json:"is_required"`
+	// Choices are the allowed answers to a QuestionChoice question.
+	Choices []string `db:"choices" json:"choices"`
+	// Min and Max bound the answers to a QuestionNumber question, or the
+	// number of characters in the answers to a QuestionText question.
+	Min null.Float `db:"min_value" json:"min"`
+	Max null.Float `db:"max_value" json:"max"`
+}
+
+// Validate checks that a question is well formed, as when a sponsor changes
+// the application form of their organization.
+//
+// Upon failure, returns an error and a message for the user.
+func (q ApplicationQuestion) Validate() (message string, err error) {
+	if len(strings.TrimSpace(q.Prompt)) < 1 {
+		err = errors.New("question prompt is blank")
+		message = "Every question must have a prompt."
+		return
+	}
+
+	switch q.Type {
+	case QuestionText, QuestionNumber, QuestionChoice, QuestionYesNo,
+		QuestionDate:
+	default:
+		err = errors.Errorf("unknown question type '%s'", q.Type)
+		message = fmt.Sprintf("Question \"%s\" has an unknown type.",
+			q.Prompt)
+		return
+	}
+
+	if q.Type == QuestionChoice {
+		seen := make(map[string]bool)
+		for _, c := range q.Choices {
+			if len(strings.TrimSpace(c)) < 1 || seen[c] {
+				err = errors.New("question has blank or repeated choice")
+				message = fmt.Sprintf("The choices for question \"%s\" "+
+					"must not be blank or repeated.", q.Prompt)
+				return
+			}
+			seen[c] = true
+		}
+
+		if len(q.Choices) < 1 {
+			err = errors.New("choice question has no choices")
+			message = fmt.Sprintf("Question \"%s\" must have choices.",
+				q.Prompt)
+			return
+		}
+	} else if len(q.Choices) > 0 {
+		err = errors.Errorf("%s question has choices", q.Type)
+		message = fmt.Sprintf("Only choice questions may have choices, "+
+			"unlike question \"%s\".", q.Prompt)
+		return
+	}
+
+	if (q.Min.Valid || q.Max.Valid) &&
+		q.Type != QuestionNumber && q.Type != QuestionText {
+
+		err = errors.Errorf("%s question has bounds", q.Type)
+		message = fmt.Sprintf("Only number and text questions may have a "+
+			"minimum or maximum, unlike question \"%s\".", q.Prompt)
+		return
+	}
+
+	if q.Min.Valid && q.Max.Valid && q.Min.Float64 > q.Max.Float64 {
+		err = errors.New("question minimum exceeds maximum")
+		message = fmt.Sprintf("The minimum of question \"%s\" must not "+
+			"exceed its maximum.", q.Prompt)
+		return
+	}
+
+	return
+}
+
+// isBlankAnswer determines whether an answer was left out.
+func isBlankAnswer(value json.RawMessage) bool {
+	value = bytes.TrimSpace(value)
+	return len(value) < 1 || bytes.Equal(value, []byte("null")) ||
+		bytes.Equal(value, []byte(`""`))
+}
+
+// ValidateAnswer checks that a value is a valid answer to this question, which
+// must not be blank. The answer is returned in canonical form.
+//
+// Upon failure, returns an error and a message for the user.
+func (q ApplicationQuestion) ValidateAnswer(
+	value json.RawMessage,
+) (answer json.RawMessage, message string, err error) {
+
+	fail := func(
+		cause error,
+		requirement string,
+	) (json.RawMessage, string, error) {
+
+		return nil,
+			fmt.Sprintf("The answer to \"%s\" %s", q.Prompt, requirement),
+			errors.Wrapf(cause, "invalid answer to question %d", q.ID)
+	}
+
+	var v interface{}
+	switch q.Type {
+	case QuestionText:
+		var s string
+		if err = json.Unmarshal(value, &s); err != nil {
+			return fail(err, "must be text.")
+		}
+
+		s = strings.TrimSpace(s)
+		n := float64(len([]rune(s)))
+		if n < 1 {
+			return fail(errors.New("blank"), "must not be blank.")
+		} else if q.Min.Valid && n < q.Min.Float64 {
+			return fail(errors.New("too short"), fmt.Sprintf(
+				"must be at least %v characters long.", q.Min.Float64))
+		} else if q.Max.Valid && n > q.Max.Float64 {
+			return fail(errors.New("too long"), fmt.Sprintf(
+				"must be at most %v characters long.", q.Max.Float64))
+		}
+		v = s
+	case QuestionNumber:
+		var f float64
+		if err = json.Unmarshal(value, &f); err != nil {
+			return fail(err, "must be a number.")
+		}
+
+		if q.Min.Valid && f < q.Min.Float64 {
+			return fail(errors.New("too small"),
+				fmt.Sprintf("must be at least %v.", q.Min.Float64))
+		} else if q.Max.Valid && f > q.Max.Float64 {
+			return fail(errors.New("too large"),
+				fmt.Sprintf("must be at most %v.", q.Max.Float64))
+		}
+		v = f
+	case QuestionChoice:
+		var s string
+		if err = json.Unmarshal(value, &s); err != nil {
+			return fail(err, "must be one of the choices.")
+		}
+
+		found := false
+		for _, c := range q.Choices {
+			found = found || c == s
+		}
+		if !found {
+			return fail(errors.Errorf("'%s' is not a choice", s),
+				"must be one of the choices.")
+		}
+		v = s
+	case QuestionYesNo:
+		var b bool
+		if err = json.Unmarshal(value, &b); err != nil {
+			return fail(err, "must be yes or no.")
+		}
+		v = b
+	case QuestionDate:
+		var s string
+		if err = json.Unmarshal(value, &s); err != nil {
+			return fail(err, "must be a date.")
+		}
+
+		var d time.Time
+		if d, err = time.Parse(questionDateLayout, s); err != nil {
+			return fail(err, "must be a date.")
+		}
+		v = d.Format(questionDateLayout)
+	default:
+		return fail(errors.Errorf("unknown question type '%s'", q.Type),
+			"cannot be checked.")
+	}
+
+	answer, err = json.Marshal(v)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to encode answer")
+	}
+
+	return answer, "", nil
+}
+
+// An ApplicationAnswer is the answer of an applicant to an
+// ApplicationQuestion. The prompt and type of the question are kept with the
+// answer, so that it still makes sense if the form changes later.
+type ApplicationAnswer struct {
+	QuestionID int          `db:"application_question_id" json:"question_id"`
+	Prompt     string       `db:"prompt" json:"prompt"`
+	Type       QuestionType `db:"question_type" json:"type"`
+	// Value is the answer, whose JSON type depends upon the Type.
+	Value json.RawMessage `db:"value" json:"value"`
+}
+
+// ValidateApplicationAnswers checks answers against the application form of an
+// organization. Every required question must be answered, and every answer
+// must be to a question on the form. Blank answers to optional questions are
+// dropped.
+//
+// The valid answers are returned in the order of the form.
+//
+// Upon failure, returns an error and a message for the user.
+func ValidateApplicationAnswers(
+	form []ApplicationQuestion,
+	answers []ApplicationAnswer,
+) (valid []ApplicationAnswer, message string, err error) {
+
+	byQuestion := make(map[int]json.RawMessage)
+	for _, a := range answers {
+		if _, ok := byQuestion[a.QuestionID]; ok {
+			return nil, "Each question may only be answered once.",
+				errors.Errorf("question %d answered twice", a.QuestionID)
+		}
+		byQuestion[a.QuestionID] = a.Value
+	}
+
+	valid = make([]ApplicationAnswer, 0, len(form))
+	for _, q := range form {
+		value, ok := byQuestion[q.ID]
+		delete(byQuestion, q.ID)
+
+		if !ok || isBlankAnswer(value) {
+			if q.IsRequired {
+				message = fmt.Sprintf("Question \"%s\" must be answered.",
+					q.Prompt)
+				err = errors.Errorf("required question %d unanswered", q.ID)
+				return nil, message, err
+			}
+			continue
+		}
+
+		value, message, err = q.ValidateAnswer(value)
+		if err != nil {
+			return nil, message, err
+		}
+
+		valid = append(valid, ApplicationAnswer{
+			QuestionID: q.ID,
+			Prompt:     q.Prompt,
+			Type:       q.Type,
+			Value:      value,
+		})
+	}
+
+	for id := range byQuestion {
+		message = "An answer was given to a question that is not on the " +
+			"application form."
+		err = errors.Errorf("question %d is not on the form", id)
+		return nil, message, err
+	}
+
+	return valid, "", nil
+}
@@ -0,0 +1,256 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestApplicationQuestionValidate(t *testing.T) {
+	testCases := []struct {
+		alias       string
+		q           ApplicationQuestion
+		expectValid bool
+	}{
+		{
+			alias:       "Text",
+			q:           ApplicationQuestion{Prompt: "CDL", Type: QuestionText},
+			expectValid: true,
+		},
+		{
+			alias: "NumberBounds",
+			q: ApplicationQuestion{
+				Prompt: "Years driving",
+				Type:   QuestionNumber,
+				Min:    null.FloatFrom(0),
+				Max:    null.FloatFrom(60),
+			},
+			expectValid: true,
+		},
+		{
+			alias: "Choice",
+			q: ApplicationQuestion{
+				Prompt:  "License class",
+				Type:    QuestionChoice,
+				Choices: []string{"A", "B", "C"},
+			},
+			expectValid: true,
+		},
+		{
+			alias: "BlankPrompt",
+			q:     ApplicationQuestion{Prompt: "  ", Type: QuestionText},
+		},
+		{
+			alias: "UnknownType",
+			q:     ApplicationQuestion{Prompt: "CDL", Type: "essay"},
+		},
+		{
+			alias: "ChoiceWithoutChoices",
+			q: ApplicationQuestion{
+				Prompt: "License class",
+				Type:   QuestionChoice,
+			},
+		},
+		{
+			alias: "RepeatedChoice",
+			q: ApplicationQuestion{
+				Prompt:  "License class",
+				Type:    QuestionChoice,
+				Choices: []string{"A", "A"},
+			},
+		},
+		{
+			alias: "ChoicesOnText",
+			q: ApplicationQuestion{
+				Prompt:  "CDL",
+				Type:    QuestionText,
+				Choices: []string{"A"},
+			},
+		},
+		{
+			alias: "BoundsOnDate",
+			q: ApplicationQuestion{
+				Prompt: "Hire date",
+				Type:   QuestionDate,
+				Min:    null.FloatFrom(0),
+			},
+		},
+		{
+			alias: "MinAboveMax",
+			q: ApplicationQuestion{
+				Prompt: "Years driving",
+				Type:   QuestionNumber,
+				Min:    null.FloatFrom(10),
+				Max:    null.FloatFrom(5),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			message, err := tc.q.Validate()
+			if tc.expectValid {
+				assert.NoError(t, err)
+				assert.Empty(t, message)
+			} else {
+				assert.Error(t, err)
+				assert.NotEmpty(t, message)
+			}
+		})
+	}
+}
+
+func TestValidateApplicationAnswers(t *testing.T) {
+	form := []ApplicationQuestion{
+		{
+			ID:         1,
+			Prompt:     "CDL number",
+			Type:       QuestionText,
+			IsRequired: true,
+			Max:        null.FloatFrom(10),
+		},
+		{
+			ID:     2,
+			Prompt: "Years driving",
+			Type:   QuestionNumber,
+			Min:    null.FloatFrom(0),
+		},
+		{
+			ID:      3,
+			Prompt:  "License class",
+			Type:    QuestionChoice,
+			Choices: []string{"A", "B"},
+		},
+		{ID: 4, Prompt: "Employed?", Type: QuestionYesNo},
+		{ID: 5, Prompt: "Hire date", Type: QuestionDate},
+	}
+
+	answer := func(id int, value string) ApplicationAnswer {
+		return ApplicationAnswer{
+			QuestionID: id,
+			Value:      json.RawMessage(value),
+		}
+	}
+
+	testCases := []struct {
+		alias         string
+		answers       []ApplicationAnswer
+		expectAnswers map[int]string
+	}{
+		{
+			alias: "All",
+			answers: []ApplicationAnswer{
+				answer(5, `"2019-06-01"`),
+				answer(4, `true`),
+				answer(3, `"B"`),
+				answer(2, `12`),
+				answer(1, `" X1234 "`),
+			},
+			expectAnswers: map[int]string{
+				1: `"X1234"`,
+				2: `12`,
+				3: `"B"`,
+				4: `true`,
+				5: `"2019-06-01"`,
+			},
+		},
+		{
+			alias: "OptionalBlank",
+			answers: []ApplicationAnswer{
+				answer(1, `"X1234"`),
+				answer(3, `null`),
+				answer(5, `""`),
+			},
+			expectAnswers: map[int]string{1: `"X1234"`},
+		},
+		{alias: "RequiredMissing", answers: []ApplicationAnswer{}},
+		{
+			alias:   "RequiredBlank",
+			answers: []ApplicationAnswer{answer(1, `"   "`)},
+		},
+		{
+			alias:   "TooLong",
+			answers: []ApplicationAnswer{answer(1, `"X12345678901"`)},
+		},
+		{
+			alias: "NotANumber",
+			answers: []ApplicationAnswer{
+				answer(1, `"X1234"`),
+				answer(2, `"twelve"`),
+			},
+		},
+		{
+			alias: "TooSmall",
+			answers: []ApplicationAnswer{
+				answer(1, `"X1234"`),
+				answer(2, `-1`),
+			},
+		},
+		{
+			alias: "NotAChoice",
+			answers: []ApplicationAnswer{
+				answer(1, `"X1234"`),
+				answer(3, `"C"`),
+			},
+		},
+		{
+			alias: "NotABoolean",
+			answers: []ApplicationAnswer{
+				answer(1, `"X1234"`),
+				answer(4, `"yes"`),
+			},
+		},
+		{
+			alias: "NotADate",
+			answers: []ApplicationAnswer{
+				answer(1, `"X1234"`),
+				answer(5, `"06/01/2019"`),
+			},
+		},
+		{
+			alias: "NotOnForm",
+			answers: []ApplicationAnswer{
+				answer(1, `"X1234"`),
+				answer(9, `"?"`),
+			},
+		},
+		{
+			alias: "Repeated",
+			answers: []ApplicationAnswer{
+				answer(1, `"X1234"`),
+				answer(1, `"X5678"`),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			valid, message, err := ValidateApplicationAnswers(form,
+				tc.answers)
+
+			if tc.expectAnswers == nil {
+				assert.Error(t, err)
+				assert.NotEmpty(t, message)
+				assert.Nil(t, valid)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, valid, len(tc.expectAnswers))
+
+			lastID := 0
+			for _, a := range valid {
+				assert.True(t, a.QuestionID > lastID, "in form order")
+				lastID = a.QuestionID
+
+				assert.Equal(t, form[a.QuestionID-1].Prompt, a.Prompt)
+				assert.Equal(t, form[a.QuestionID-1].Type, a.Type)
+				assert.JSONEq(t, tc.expectAnswers[a.QuestionID],
+					string(a.Value))
+			}
+		})
+	}
+}
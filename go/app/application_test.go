@@ -0,0 +1,126 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplicationStatusTransitions(t *testing.T) {
+	testCases := []struct {
+		alias  string
+		from   ApplicationStatus
+		to     ApplicationStatus
+		expect bool
+	}{
+		{
+			alias:  "Approve",
+			from:   ApplicationSubmitted,
+			to:     ApplicationApproved,
+			expect: true,
+		},
+		{
+			alias:  "Hold",
+			from:   ApplicationSubmitted,
+			to:     ApplicationOnHold,
+			expect: true,
+		},
+		{
+			alias:  "Resume",
+			from:   ApplicationOnHold,
+			to:     ApplicationSubmitted,
+			expect: true,
+		},
+		{
+			alias:  "Answer",
+			from:   ApplicationInfoRequested,
+			to:     ApplicationSubmitted,
+			expect: true,
+		},
+		{
+			alias:  "WithdrawWhileWaiting",
+			from:   ApplicationInfoRequested,
+			to:     ApplicationWithdrawn,
+			expect: true,
+		},
+		{
+			alias: "ApproveWithoutAnswer",
+			from:  ApplicationInfoRequested,
+			to:    ApplicationApproved,
+		},
+		{
+			alias: "HoldWhileWaiting",
+			from:  ApplicationInfoRequested,
+			to:    ApplicationOnHold,
+		},
+		{
+			alias: "Same",
+			from:  ApplicationSubmitted,
+			to:    ApplicationSubmitted,
+		},
+		{
+			alias: "Reconsider",
+			from:  ApplicationRejected,
+			to:    ApplicationApproved,
+		},
+		{
+			alias: "WithdrawApproved",
+			from:  ApplicationApproved,
+			to:    ApplicationWithdrawn,
+		},
+		{
+			alias: "Reopen",
+			from:  ApplicationWithdrawn,
+			to:    ApplicationSubmitted,
+		},
+		{
+			alias: "Unknown",
+			from:  ApplicationStatus("bogus"),
+			to:    ApplicationSubmitted,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.from.CanTransitionTo(tc.to))
+		})
+	}
+}
+
+func TestApplicationStatusIsFinal(t *testing.T) {
+	for _, s := range []ApplicationStatus{
+		ApplicationSubmitted,
+		ApplicationOnHold,
+		ApplicationInfoRequested,
+	} {
+		assert.False(t, s.IsFinal(), s)
+	}
+
+	for _, s := range []ApplicationStatus{
+		ApplicationApproved,
+		ApplicationRejected,
+		ApplicationWithdrawn,
+	} {
+		assert.True(t, s.IsFinal(), s)
+	}
+}
+
+func TestApplicationRefusalsAreConflicts(t *testing.T) {
+	for _, err := range []error{
+		ErrApplicationOpen,
+		ErrAlreadyAffiliated,
+		errors.Wrap(&ReapplyCooldownError{Until: time.Now()}, "test"),
+	} {
+		assert.True(t, errors.Is(err, ErrConflict), err)
+	}
+
+	var cooldown *ReapplyCooldownError
+	err := errors.Wrap(&ReapplyCooldownError{
+		Until: time.Date(2021, 4, 5, 14, 30, 0, 0, time.UTC),
+	}, "test")
+	assert.True(t, errors.As(err, &cooldown))
+	assert.Equal(t, 5, cooldown.Until.Day())
+	assert.False(t, errors.Is(ErrApplicationOpen, ErrAlreadyAffiliated))
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// An Attachment is a document, such as proof of a license, that an applicant
+// uploaded with their application. Its contents are kept in a FileStore.
+type Attachment struct {
+	ID            int    `db:"attachment_id" json:"id"`
+	ApplicationID int    `db:"application_id" json:"application_id"`
+	FileName      string `db:"file_name" json:"file_name"`
+	// ContentType is the MIME type of the file, as detected from its contents.
+	ContentType string    `db:"content_type" json:"content_type"`
+	Size        int64     `db:"size_bytes" json:"size"`
+	StorageKey  string    `db:"storage_key" json:"-"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	// URL is a signed link to download this attachment, which expires.
+	URL string `db:"-" json:"url,omitempty"`
+}
+
+// An AttachmentPolicy limits the files that may be attached to applications.
+type AttachmentPolicy struct {
+	// MaxSize is the largest file that may be uploaded, in bytes.
+	MaxSize int64
+	// MaxPerApplication is the most attachments an application may have.
+	MaxPerApplication int
+	// ContentTypes lists the MIME types of files that may be uploaded.
+	ContentTypes []string
+	// URLLifetime is how long signed download links remain valid.
+	URLLifetime time.Duration
+}
+
+// DefaultAttachmentPolicy is the suggested AttachmentPolicy for our app, which
+// allows documents and photos of them.
+var DefaultAttachmentPolicy = AttachmentPolicy{
+	MaxSize:           10 << 20,
+	MaxPerApplication: 10,
+	ContentTypes: []string{
+		"application/pdf",
+		"image/jpeg",
+		"image/png",
+	},
+	URLLifetime: 15 * time.Minute,
+}
+
+// AllowsContentType determines whether files of a MIME type may be uploaded.
+func (p AttachmentPolicy) AllowsContentType(contentType string) bool {
+	for _, allowed := range p.ContentTypes {
+		if contentType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentSignature computes the signature of a download link for an
+// attachment that expires at the given time.
+func attachmentSignature(key []byte, attachmentID int, expires int64) []byte {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "attachment:%d:%d", attachmentID, expires)
+	return mac.Sum(nil)
+}
+
+// SignAttachment produces the query parameters for a download link to an
+// attachment, which is valid until the given time.
+func SignAttachment(
+	key []byte,
+	attachmentID int,
+	expires time.Time,
+) (expiresParam, signatureParam string) {
+
+	unix := expires.Unix()
+	sig := attachmentSignature(key, attachmentID, unix)
+	return strconv.FormatInt(unix, 10), hex.EncodeToString(sig)
+}
+
+// VerifyAttachment determines whether the query parameters of a download link
+// to an attachment were produced by SignAttachment with the same key, and have
+// not yet expired.
+func VerifyAttachment(
+	key []byte,
+	attachmentID int,
+	expiresParam, signatureParam string,
+	now time.Time,
+) bool {
+
+	unix, err := strconv.ParseInt(expiresParam, 10, 64)
+	if err != nil || now.Unix() > unix {
+		return false
+	}
+
+	sig, err := hex.DecodeString(signatureParam)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(sig, attachmentSignature(key, attachmentID, unix))
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachmentSignature(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	now := time.Now()
+
+	expires, sig := SignAttachment(key, 7, now.Add(time.Minute))
+
+	testCases := []struct {
+		alias   string
+		key     []byte
+		id      int
+		expires string
+		sig     string
+		now     time.Time
+		expect  bool
+	}{
+		{
+			alias:   "Valid",
+			key:     key,
+			id:      7,
+			expires: expires,
+			sig:     sig,
+			now:     now,
+			expect:  true,
+		},
+		{
+			alias:   "Expired",
+			key:     key,
+			id:      7,
+			expires: expires,
+			sig:     sig,
+			now:     now.Add(2 * time.Minute),
+		},
+		{
+			alias:   "OtherAttachment",
+			key:     key,
+			id:      8,
+			expires: expires,
+			sig:     sig,
+			now:     now,
+		},
+		{
+			alias:   "OtherKey",
+			key:     []byte("fedcba9876543210fedcba9876543210"),
+			id:      7,
+			expires: expires,
+			sig:     sig,
+			now:     now,
+		},
+		{
+			alias:   "ExtendedExpiry",
+			key:     key,
+			id:      7,
+			expires: "99999999999",
+			sig:     sig,
+			now:     now,
+		},
+		{
+			alias:   "Malformed",
+			key:     key,
+			id:      7,
+			expires: expires,
+			sig:     "not hex",
+			now:     now,
+		},
+		{
+			alias: "Blank",
+			key:   key,
+			id:    7,
+			now:   now,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			assert.Equal(t, tc.expect,
+				VerifyAttachment(tc.key, tc.id, tc.expires, tc.sig, tc.now))
+		})
+	}
+}
+
+func TestAttachmentPolicyAllowsContentType(t *testing.T) {
+	p := DefaultAttachmentPolicy
+
+	assert.True(t, p.AllowsContentType("application/pdf"))
+	assert.True(t, p.AllowsContentType("image/png"))
+	assert.False(t, p.AllowsContentType("text/html; charset=utf-8"))
+	assert.False(t, p.AllowsContentType("application/octet-stream"))
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// An AuditAction describes the kind of change that an AuditEvent records.
+type AuditAction string
+
+// These are the actions that are recorded in the audit log.
+const (
+	AuditActionPersonCreate       AuditAction = "person.create"
+	AuditActionPersonRoleUpdate   AuditAction = "person.role.update"
+	AuditActionPersonActivate     AuditAction = "person.activate"
+	AuditActionPersonDeactivate   AuditAction = "person.deactivate"
+	AuditActionOrganizationCreate AuditAction = "organization.create"
+	AuditActionOrganizationUpdate AuditAction = "organization.update"
+	AuditActionOrganizationDelete AuditAction = "organization.delete"
+	AuditActionOrgArchive         AuditAction = "organization.archive"
+	AuditActionOrgRestore         AuditAction = "organization.restore"
+	AuditActionProductAdd         AuditAction = "product.add"
+	AuditActionProductRemove      AuditAction = "product.remove"
+	AuditActionApplicationDecide  AuditAction = "application.decide"
+	AuditActionApplicationForm    AuditAction = "application.form.update"
+	AuditActionPointsSet          AuditAction = "points.set"
+	AuditActionAffiliationAdd     AuditAction = "affiliation.add"
+	AuditActionAffiliationRemove  AuditAction = "affiliation.remove"
+	AuditActionInvitationCreate   AuditAction = "invitation.create"
+	AuditActionInvitationRevoke   AuditAction = "invitation.revoke"
+	AuditActionInvitationAccept   AuditAction = "invitation.accept"
+	AuditActionSSOUpdate          AuditAction = "sso.update"
+	AuditActionSSODelete          AuditAction = "sso.delete"
+)
+
+// An AuditTargetType names the kind of object that an AuditEvent changed.
+type AuditTargetType string
+
+// These are the kinds of objects that audited changes are made to.
+const (
+	AuditTargetPerson       AuditTargetType = "person"
+	AuditTargetOrganization AuditTargetType = "organization"
+	AuditTargetProduct      AuditTargetType = "product"
+	AuditTargetApplication  AuditTargetType = "application"
+	AuditTargetInvitation   AuditTargetType = "invitation"
+	// AuditTargetAffiliation events identify an affiliation by its person,
+	// within the organization of the event.
+	AuditTargetAffiliation AuditTargetType = "affiliation"
+	// AuditTargetSSO events identify the single sign-on configuration of an
+	// organization by the ID of the organization.
+	AuditTargetSSO AuditTargetType = "sso"
+)
+
+// An AuditEvent records a privileged change, who made it, and the state of the
+// changed object before and after. Audit events may never be altered.
+type AuditEvent struct {
+	ID        int       `db:"audit_event_id" json:"id"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	// ActorID is the person who made the change. It will be null for changes
+	// made outside of any request, such as by maintenance tasks.
+	ActorID null.Int `db:"actor_id" json:"actor_id"`
+	// ImpersonatorID is the administrator who made the change while
+	// impersonating the actor, if any.
+	ImpersonatorID null.Int `db:"impersonator_id" json:"impersonator_id"`
+	// RequestID identifies the API request that made the change, so that it
+	// may be found in the logs.
+	RequestID null.String `db:"request_id" json:"request_id"`
+
+	Action         AuditAction     `db:"action" json:"action"`
+	TargetType     AuditTargetType `db:"target_type" json:"target_type"`
+	TargetID       int             `db:"target_id" json:"target_id"`
+	OrganizationID null.Int        `db:"organization_id" json:"organization_id"`
+	// Reason explains why the change was made, when one was given.
+	Reason null.String `db:"reason" json:"reason"`
+
+	// Before and After hold the JSON representation of the target. Before is
+	// null for objects being created, and After is null for objects being
+	// deleted.
+	Before json.RawMessage `db:"before" json:"before"`
+	After  json.RawMessage `db:"after" json:"after"`
+}
+
+// An AuditFilter narrows down which audit events to fetch. Zero-valued fields
+// do not filter anything.
+type AuditFilter struct {
+	ActorID        null.Int
+	Action         AuditAction
+	TargetType     AuditTargetType
+	TargetID       null.Int
+	OrganizationID null.Int
+	Since          null.Time
+	Until          null.Time
+
+	// BeforeID only includes events older than the event with this ID, so that
+	// results may be paged through from newest to oldest.
+	BeforeID null.Int
+	// Limit is the maximum number of events to fetch.
+	Limit int
+}
+
+// An AuditActor identifies who is responsible for the changes made while
+// handling a request.
+type AuditActor struct {
+	PersonID       null.Int
+	ImpersonatorID null.Int
+	RequestID      null.String
+}
+
+type auditContextKey struct{}
+
+// ContextWithAuditActor attaches an AuditActor to a context, so that any
+// audited changes made with the context are attributed to them.
+func ContextWithAuditActor(ctx context.Context, a AuditActor) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, a)
+}
+
+// AuditActorFromContext retrieves the AuditActor attached to a context. When
+// there is none, the zero value is returned.
+func AuditActorFromContext(ctx context.Context) AuditActor {
+	a, _ := ctx.Value(auditContextKey{}).(AuditActor)
+	return a
+}
@@ -9,4 +9,5 @@ type Balance struct {
 	OrganizationID   int    `db:"organization_id" json:"organization_id"`
 	OrganizationName string `db:"name" json:"organization_name"`
 	Points           int    `db:"points" json:"balance"`
+	IsFrozen         bool   `db:"is_frozen" json:"is_frozen"`
 }
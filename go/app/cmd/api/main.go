@@ -6,6 +6,8 @@ import (
 	"github.com/BenJetson/CPSC491-project/go/app/api"
 	"github.com/BenJetson/CPSC491-project/go/app/db"
 	"github.com/BenJetson/CPSC491-project/go/app/etsy"
+	"github.com/BenJetson/CPSC491-project/go/app/mail"
+	"github.com/BenJetson/CPSC491-project/go/app/storage"
 )
 
 func main() {
@@ -31,7 +33,22 @@ func main() {
 		logger.Fatalln(err)
 	}
 
-	svr, err := api.NewServer(logger, db, cv, svrCfg)
+	mailer, err := mail.NewMailerFromEnv(logger)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	files, err := storage.NewFileStoreFromEnv()
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	scanner, err := storage.NewScannerFromEnv(logger)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	svr, err := api.NewServer(logger, db, cv, mailer, files, scanner, svrCfg)
 	if err != nil {
 		logger.Fatalln(err)
 	}
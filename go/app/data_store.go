@@ -2,8 +2,8 @@ package app
 
 import (
 	"context"
+	"time"
 
-	"github.com/google/uuid"
 	"gopkg.in/guregu/null.v4"
 	// "gopkg.in/guregu/null.v4"
 )
@@ -13,9 +13,18 @@ type DataStore interface {
 	PersonStore
 	AffiliationStore
 	SessionStore
+	APITokenStore
+	SSOStore
+	PermissionStore
+	LoginAttemptStore
 	ApplicationStore
 	OrganizationStore
 	CatalogStore
+	AuditStore
+	NotificationStore
+	AccountInvitationStore
+	AttachmentStore
+	InvitationStore
 }
 
 // PersonStore defines methods for working with app.Person objects in the
@@ -38,22 +47,37 @@ type PersonStore interface {
 	UpdatePersonEmail(ctx context.Context, personID int, email string) error
 	UpdatePersonRole(ctx context.Context, personID int, roleType Role) error
 	UpdatePersonPassword(ctx context.Context, personID int, p Password) error
-	ActivatePerson(ctx context.Context, personID int) error
-	DeactivatePerson(ctx context.Context, personID int) error
+	RehashPersonPassword(
+		ctx context.Context,
+		personID int,
+		oldPass, newPass Password,
+	) error
+	GetPreviousPasswords(
+		ctx context.Context,
+		personID int,
+		limit int,
+	) ([]Password, error)
+	ActivatePerson(ctx context.Context, personID int, reason string) error
+	DeactivatePerson(ctx context.Context, personID int, reason string) error
 }
 
 // AffiliationStore defines methods for interacting with affiliations between
 // Persons and Organizations, with Points.
 type AffiliationStore interface {
-	AddPersonAffiliation(
+	GetAffiliationsForPerson(
 		ctx context.Context,
-		personID, orgID int,
-		role Role,
-	) error
+		personID int,
+	) ([]Affiliation, error)
+	AddPersonAffiliation(ctx context.Context, personID, orgID int) error
 	RemovePersonAffiliation(
 		ctx context.Context,
 		personID, orgID int,
 	) error
+	ChangePersonAffiliations(
+		ctx context.Context,
+		personID int,
+		add, remove []int,
+	) error
 	SetPointsForAffiliation(
 		ctx context.Context,
 		personID, orgID int,
@@ -70,9 +94,23 @@ type SessionStore interface {
 		personID int,
 		includeInvalid bool,
 	) ([]Session, error)
-	GetSessionByToken(ctx context.Context, token uuid.UUID) (Session, error)
+	GetSessionByToken(
+		ctx context.Context,
+		token SecureToken,
+	) (Session, error)
 
 	CreateSession(ctx context.Context, s Session) (int, error)
+	RenewSession(
+		ctx context.Context,
+		sessionID int,
+		ipAddress string,
+		lastSeenAt, expiresAt time.Time,
+	) error
+	SetSessionActiveOrganization(
+		ctx context.Context,
+		sessionID int,
+		orgID null.Int,
+	) error
 
 	RevokeSession(ctx context.Context, sessionID int) error
 	RevokeSessionsForPersonExcept(
@@ -81,6 +119,73 @@ type SessionStore interface {
 	) error
 }
 
+// APITokenStore defines methods for working with app.APIToken objects in the
+// database.
+type APITokenStore interface {
+	GetAPITokensForPerson(
+		ctx context.Context,
+		personID int,
+		includeInvalid bool,
+	) ([]APIToken, error)
+	GetAPITokenByToken(
+		ctx context.Context,
+		token SecureToken,
+	) (APIToken, error)
+
+	CreateAPIToken(ctx context.Context, t APIToken) (int, error)
+	TouchAPIToken(
+		ctx context.Context,
+		tokenID int,
+		lastUsedAt time.Time,
+	) error
+	RevokeAPIToken(ctx context.Context, tokenID int) error
+}
+
+// SSOStore defines methods for working with single sign-on configuration and
+// the identities people use to sign on.
+type SSOStore interface {
+	GetOrganizationSSO(ctx context.Context, orgID int) (OrganizationSSO, error)
+	SetOrganizationSSO(ctx context.Context, c OrganizationSSO) error
+	DeleteOrganizationSSO(ctx context.Context, orgID int) error
+
+	CreateOIDCLogin(ctx context.Context, l OIDCLogin) error
+	ConsumeOIDCLogin(ctx context.Context, state SecureToken) (OIDCLogin, error)
+
+	GetPersonByIdentity(
+		ctx context.Context,
+		issuer, subject string,
+	) (Person, error)
+	LinkPersonIdentity(
+		ctx context.Context,
+		personID int,
+		issuer, subject string,
+	) error
+	ProvisionSSOPerson(
+		ctx context.Context,
+		p Person,
+		orgID int,
+		issuer, subject string,
+	) (int, error)
+}
+
+// PermissionStore defines methods for working with the app.Permission grants
+// of each app.Role.
+type PermissionStore interface {
+	GetPermissionsForRole(ctx context.Context, role Role) ([]Permission, error)
+}
+
+// LoginAttemptStore defines methods for recording app.LoginAttempt objects
+// and summarizing recent failures.
+type LoginAttemptStore interface {
+	StartLoginAttempt(
+		ctx context.Context,
+		a LoginAttempt,
+		accountPolicy, ipPolicy LockoutPolicy,
+	) (attemptID int, wait time.Duration, err error)
+	SucceedLoginAttempt(ctx context.Context, attemptID int) error
+	ClearLoginFailuresForEmail(ctx context.Context, email string) error
+}
+
 // ApplicationStore defines methods for working with app.Application objects
 // in the database.
 type ApplicationStore interface {
@@ -95,15 +200,30 @@ type ApplicationStore interface {
 	GetApplicationsForOrganization(
 		ctx context.Context,
 		orgID int,
+		f ApplicationFilter,
 	) ([]Application, error)
 
 	CreateApplication(ctx context.Context, a Application) (int, error)
 
-	UpdateApplicationApproval(
+	TransitionApplication(
 		ctx context.Context,
 		appID int,
-		status bool,
-		reason string,
+		from, to ApplicationStatus,
+		message string,
+	) error
+	GetApplicationHistory(
+		ctx context.Context,
+		appID int,
+	) ([]ApplicationEvent, error)
+
+	GetApplicationForm(
+		ctx context.Context,
+		orgID int,
+	) ([]ApplicationQuestion, error)
+	SetApplicationForm(
+		ctx context.Context,
+		orgID int,
+		questions []ApplicationQuestion,
 	) error
 }
 
@@ -111,10 +231,29 @@ type ApplicationStore interface {
 type OrganizationStore interface {
 	GetAllOrganizations(ctx context.Context) ([]Organization, error)
 	GetOrganizationByID(ctx context.Context, orgID int) (Organization, error)
+	GetArchivedOrganizations(ctx context.Context) ([]Organization, error)
+	GetOrganizationsForPerson(
+		ctx context.Context,
+		personID int,
+	) ([]Organization, error)
+	GetOrganizationsAcceptingApplications(
+		ctx context.Context,
+	) ([]Organization, error)
 
 	CreateOrganization(ctx context.Context, org Organization) (int, error)
 	UpdateOrganization(ctx context.Context, org Organization) error
-	DeleteOrganization(ctx context.Context, orgID int) error
+	SetOrganizationLogo(
+		ctx context.Context,
+		orgID int,
+		key, contentType null.String,
+	) (previousKey null.String, err error)
+	ArchiveOrganization(ctx context.Context, orgID int) error
+	RestoreOrganization(ctx context.Context, orgID int) error
+	PurgeOrganization(
+		ctx context.Context,
+		orgID int,
+		retention time.Duration,
+	) (fileKeys []string, err error)
 }
 
 // CatalogStore defines methods for working with app.Product and
@@ -137,3 +276,80 @@ type CatalogStore interface {
 	AddProduct(ctx context.Context, p Product) (int, error)
 	MakeProductUnavailable(ctx context.Context, productID, orgID int) error
 }
+
+// AuditStore defines methods for reading app.AuditEvent objects. Events are
+// written by the audited methods of the other stores.
+type AuditStore interface {
+	GetAuditEvents(ctx context.Context, f AuditFilter) ([]AuditEvent, error)
+}
+
+// NotificationStore defines methods for working with app.Notification objects.
+// Notifications are created by the methods of the other stores that make the
+// changes they describe.
+type NotificationStore interface {
+	GetNotificationsForPerson(
+		ctx context.Context,
+		personID int,
+		unreadOnly bool,
+	) ([]Notification, error)
+	MarkNotificationRead(
+		ctx context.Context,
+		notificationID, personID int,
+	) error
+}
+
+// AccountInvitationStore defines methods for working with people created by an
+// administrator, who accept an app.AccountInvitation to choose their password.
+type AccountInvitationStore interface {
+	CreateInvitedPerson(
+		ctx context.Context,
+		p Person,
+		inv AccountInvitation,
+	) (int, error)
+	GetAccountInvitationByToken(
+		ctx context.Context,
+		token SecureToken,
+	) (AccountInvitation, error)
+	ReissueAccountInvitation(
+		ctx context.Context,
+		inv AccountInvitation,
+	) (int, error)
+	AcceptAccountInvitation(
+		ctx context.Context,
+		invitationID int,
+		p Password,
+	) error
+}
+
+// AttachmentStore defines methods for working with app.Attachment objects.
+type AttachmentStore interface {
+	CreateAttachment(ctx context.Context, a Attachment) (int, error)
+	GetAttachmentByID(
+		ctx context.Context,
+		attachmentID int,
+	) (Attachment, error)
+	GetAttachmentsForApplication(
+		ctx context.Context,
+		appID int,
+	) ([]Attachment, error)
+}
+
+// InvitationStore defines methods for working with app.Invitation objects,
+// which invite people to join an organization.
+type InvitationStore interface {
+	CreateInvitation(ctx context.Context, inv Invitation) (int, error)
+	GetInvitationsForOrganization(
+		ctx context.Context,
+		orgID int,
+	) ([]Invitation, error)
+	GetInvitationByToken(
+		ctx context.Context,
+		token SecureToken,
+	) (Invitation, error)
+	RevokeInvitation(ctx context.Context, orgID, invitationID int) error
+	AcceptInvitation(
+		ctx context.Context,
+		invitationID int,
+		newcomer Person,
+	) (int, error)
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// insertAccountInvitation inserts an invitation, returning its ID.
+func insertAccountInvitation(
+	ctx context.Context,
+	tx sqlx.QueryerContext,
+	inv app.AccountInvitation,
+) (int, error) {
+
+	var id int
+	err := sqlx.GetContext(ctx, tx, &id, `
+		INSERT INTO account_invitation (
+			token_hash,
+			person_id,
+			invited_by,
+			created_at,
+			expires_at
+		) VALUES ($1, $2, $3, $4, $5)
+		RETURNING account_invitation_id
+	`,
+		inv.Token.Hash(), // $1
+		inv.PersonID,     // $2
+		inv.InvitedBy,    // $3
+		inv.CreatedAt,    // $4
+		inv.ExpiresAt,    // $5
+	)
+
+	return id, errors.Wrap(err, "failed to insert account invitation")
+}
+
+// CreateInvitedPerson creates a person without a password, affiliated with the
+// organizations listed in their Affiliations, along with the invitation they
+// will use to choose their password. The PersonID of the invitation is
+// ignored. Returns the ID of the new person.
+func (db *database) CreateInvitedPerson(
+	ctx context.Context,
+	p app.Person,
+	inv app.AccountInvitation,
+) (int, error) {
+
+	// Invited people choose their password upon accepting the invitation.
+	p.Password = ""
+
+	var id int
+	err := db.Transact(func(tx *sqlx.Tx) (err error) {
+		if id, err = insertPerson(ctx, tx, p); err != nil {
+			return err
+		}
+
+		for _, orgID := range p.Affiliations {
+			if err = addAffiliation(ctx, tx, id, orgID); err != nil {
+				return err
+			}
+		}
+
+		inv.PersonID = id
+		_, err = insertAccountInvitation(ctx, tx, inv)
+		return err
+	})
+
+	return id, errors.Wrap(err, "failed to create invited person")
+}
+
+// GetAccountInvitationByToken fetches the invitation with a matching token.
+func (db *database) GetAccountInvitationByToken(
+	ctx context.Context,
+	token app.SecureToken,
+) (app.AccountInvitation, error) {
+
+	var inv app.AccountInvitation
+
+	err := db.GetContext(ctx, &inv, `
+		SELECT
+			account_invitation_id,
+			person_id,
+			invited_by,
+			created_at,
+			expires_at,
+			accepted_at,
+			is_revoked
+		FROM account_invitation
+		WHERE token_hash = $1
+	`, token.Hash())
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return inv, errors.Wrap(
+			app.ErrNotFound,
+			"no such account invitation by token",
+		)
+	} else if err != nil {
+		return inv, errors.Wrap(err, "failed to get account invitation")
+	}
+
+	return inv, nil
+}
+
+// ReissueAccountInvitation replaces the invitations of a person who has not
+// yet accepted one with a new invitation, so that only the newest may be
+// accepted. Returns the ID of the new invitation.
+func (db *database) ReissueAccountInvitation(
+	ctx context.Context,
+	inv app.AccountInvitation,
+) (int, error) {
+
+	var id int
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		var accepted null.Bool
+		err := tx.GetContext(ctx, &accepted, `
+			SELECT bool_or(accepted_at IS NOT NULL)
+			FROM account_invitation
+			WHERE person_id = $1
+		`, inv.PersonID)
+		if err != nil {
+			return errors.Wrap(err, "failed to check account invitations")
+		} else if !accepted.Valid || accepted.Bool {
+			return errors.Wrapf(
+				app.ErrNotFound,
+				"no pending account invitation for person %d",
+				inv.PersonID,
+			)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE account_invitation SET
+				is_revoked = TRUE
+			WHERE person_id = $1
+		`, inv.PersonID)
+		if err != nil {
+			return errors.Wrap(err, "failed to revoke account invitations")
+		}
+
+		id, err = insertAccountInvitation(ctx, tx, inv)
+		return err
+	})
+
+	return id, errors.Wrap(err, "failed to reissue account invitation")
+}
+
+// AcceptAccountInvitation accepts a valid invitation and sets the password of
+// its invitee. Invitations that were already accepted, revoked, or have
+// expired are not found.
+func (db *database) AcceptAccountInvitation(
+	ctx context.Context,
+	invitationID int,
+	p app.Password,
+) error {
+
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		var personID int
+		err := tx.GetContext(ctx, &personID, `
+			UPDATE account_invitation SET
+				accepted_at = NOW()
+			WHERE
+				account_invitation_id = $1
+				AND accepted_at IS NULL
+				AND NOT is_revoked
+				AND expires_at > NOW()
+			RETURNING person_id
+		`, invitationID)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.Wrapf(
+				app.ErrNotFound,
+				"no valid account invitation by id of %d", invitationID,
+			)
+		} else if err != nil {
+			return errors.Wrap(err, "failed to update account invitation")
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE person SET
+				pass_hash = $1
+			WHERE person_id = $2
+		`, p, personID)
+
+		return errors.Wrap(err, "failed to set person password")
+	})
+
+	return errors.Wrap(err, "failed to accept account invitation")
+}
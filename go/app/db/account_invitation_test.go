@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func TestAccountInvitations(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	orgID, err := db.CreateOrganization(ctx, app.Organization{
+		Name:       "Trucking Co.",
+		PointValue: 1,
+	})
+	require.NoError(t, err)
+
+	p := app.Person{
+		FirstName:    "Ben",
+		LastName:     "Godfrey",
+		Email:        "bfgodfr@clemson.edu",
+		Role:         app.RoleDriver,
+		Affiliations: []int{orgID},
+	}
+
+	first, err := app.NewAccountInvitation(0, null.Int{}, time.Hour)
+	require.NoError(t, err)
+
+	p.ID, err = db.CreateInvitedPerson(ctx, p, *first)
+	require.NoError(t, err)
+
+	t.Run("Created", func(t *testing.T) {
+		db.assertCountOf(t, "person", 1, `
+			person_id = $1
+			AND email = $2
+			AND role_id = $3
+			AND pass_hash = ''
+		`, p.ID, p.Email, p.Role)
+		db.assertCountOf(t, "affiliation", 1, `
+			person_id = $1
+			AND organization_id = $2
+			AND points = 0
+		`, p.ID, orgID)
+		db.assertCountOf(t, "audit_event", 1, `
+			action = $1
+			AND target_id = $2
+			AND before IS NULL
+		`, app.AuditActionPersonCreate, p.ID)
+
+		inv, err := db.GetAccountInvitationByToken(ctx, first.Token)
+		require.NoError(t, err)
+		assert.Equal(t, p.ID, inv.PersonID)
+		assert.True(t, inv.IsValid())
+	})
+
+	var second *app.AccountInvitation
+
+	t.Run("Reissue", func(t *testing.T) {
+		second, err = app.NewAccountInvitation(p.ID, null.Int{}, time.Hour)
+		require.NoError(t, err)
+
+		second.ID, err = db.ReissueAccountInvitation(ctx, *second)
+		require.NoError(t, err)
+
+		inv, err := db.GetAccountInvitationByToken(ctx, first.Token)
+		require.NoError(t, err)
+		assert.True(t, inv.IsRevoked)
+
+		err = db.AcceptAccountInvitation(ctx, inv.ID, "hash")
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+
+	t.Run("Accept", func(t *testing.T) {
+		err := db.AcceptAccountInvitation(ctx, second.ID, "hash")
+		require.NoError(t, err)
+
+		db.assertCountOf(t, "person", 1, `
+			person_id = $1
+			AND pass_hash = 'hash'
+		`, p.ID)
+
+		// Invitations may only be accepted once.
+		err = db.AcceptAccountInvitation(ctx, second.ID, "other")
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+
+	t.Run("ReissueAfterAccept", func(t *testing.T) {
+		inv, err := app.NewAccountInvitation(p.ID, null.Int{}, time.Hour)
+		require.NoError(t, err)
+
+		_, err = db.ReissueAccountInvitation(ctx, *inv)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+
+	t.Run("NoSuchToken", func(t *testing.T) {
+		_, err := db.GetAccountInvitationByToken(ctx, app.SecureToken{})
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+}
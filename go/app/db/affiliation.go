@@ -2,66 +2,394 @@ package db
 
 import (
 	"context"
+	"database/sql"
 
-	"gopkg.in/guregu/null.v4"
-
+	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
 
 	"github.com/BenJetson/CPSC491-project/go/app"
 )
 
-func (db *database) AddPersonAffiliation(
+// affiliationAuditSnapshotQuery captures an affiliation for the audit log.
+const affiliationAuditSnapshotQuery = `
+	SELECT to_jsonb(a)
+	FROM affiliation a
+	WHERE
+		a.person_id = $1
+		AND a.organization_id = $2
+	FOR UPDATE
+`
+
+// lockPersonRole fetches the role of a person, and locks their row until the
+// transaction ends so that their role and affiliations may be changed safely.
+func lockPersonRole(
 	ctx context.Context,
-	personID, orgID int,
+	tx *sqlx.Tx,
+	personID int,
+) (app.Role, error) {
+
+	var role app.Role
+	err := tx.GetContext(ctx, &role, `
+		SELECT role_id
+		FROM person
+		WHERE person_id = $1
+		FOR UPDATE
+	`, personID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return role, errors.Wrapf(
+			app.ErrNotFound,
+			"no such person by id of %d", personID,
+		)
+	}
+	return role, errors.Wrap(err, "failed to lock person")
+}
+
+// setPersonRole changes the role of a person, and records the change in the
+// audit log.
+func setPersonRole(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	personID int,
 	role app.Role,
 ) error {
 
+	before, err := auditSnapshot(ctx, tx, personAuditSnapshotQuery, personID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE person SET
+			role_id = $1
+		WHERE person_id = $2
+	`, role, personID)
+	if err != nil {
+		return errors.Wrap(err, "failed to update person role")
+	}
+
+	after, err := auditSnapshot(ctx, tx, personAuditSnapshotQuery, personID)
+	if err != nil {
+		return err
+	}
+
+	return recordAuditEvent(ctx, tx, app.AuditEvent{
+		Action:     app.AuditActionPersonRoleUpdate,
+		TargetType: app.AuditTargetPerson,
+		TargetID:   personID,
+		Before:     before,
+		After:      after,
+	})
+}
+
+// addAffiliation affiliates a person with an organization, unless they already
+// are. Drivers start with a balance of zero points, while sponsors have no
+// balance. Users become drivers upon their first affiliation, and admins may
+// not be affiliated at all. Nobody may join an archived organization.
+func addAffiliation(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	personID, orgID int,
+) error {
+
+	var archivedAt null.Time
+	err := tx.GetContext(ctx, &archivedAt, `
+		SELECT archived_at
+		FROM organization
+		WHERE organization_id = $1
+		FOR SHARE
+	`, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.Wrapf(app.ErrNotFound,
+			"no such organization by id of %d", orgID)
+	} else if err != nil {
+		return errors.Wrap(err, "failed to check organization")
+	} else if archivedAt.Valid {
+		return errors.Wrapf(app.ErrOrganizationArchived,
+			"cannot affiliate person %d with org %d", personID, orgID)
+	}
+
+	role, err := lockPersonRole(ctx, tx, personID)
+	if err != nil {
+		return err
+	}
+
 	var points null.Int
-	if role == app.RoleDriver {
+	switch role {
+	case app.RoleAdmin:
+		return errors.Wrapf(app.ErrConflict, "admin %d cannot be affiliated",
+			personID)
+	case app.RoleUser, app.RoleDriver:
 		points = null.IntFrom(0)
 	}
 
-	_, err := db.ExecContext(ctx, `
+	result, err := tx.ExecContext(ctx, `
 		INSERT INTO affiliation (
 			person_id,
 			organization_id,
 			points
 		) VALUES ($1, $2, $3)
 		ON CONFLICT (person_id, organization_id)
-		DO UPDATE SET points = $3
+		DO NOTHING
 	`, personID, orgID, points)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert affiliation")
+	}
 
-	return errors.Wrap(err, "failed to add affiliation")
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "failed to check result of affiliation insert")
+	} else if n < 1 {
+		// Already affiliated, so the existing balance is kept.
+		return nil
+	}
+
+	after, err := auditSnapshot(ctx, tx, affiliationAuditSnapshotQuery,
+		personID, orgID)
+	if err != nil {
+		return err
+	}
+
+	err = recordAuditEvent(ctx, tx, app.AuditEvent{
+		Action:         app.AuditActionAffiliationAdd,
+		TargetType:     app.AuditTargetAffiliation,
+		TargetID:       personID,
+		OrganizationID: null.IntFrom(int64(orgID)),
+		After:          after,
+	})
+	if err != nil {
+		return err
+	}
+
+	if role == app.RoleUser {
+		return setPersonRole(ctx, tx, personID, app.RoleDriver)
+	}
+	return nil
 }
 
-func (db *database) RemovePersonAffiliation(
+// removeAffiliation removes the affiliation of a person with an organization,
+// along with any points they had there. Drivers become users again upon losing
+// their last affiliation.
+func removeAffiliation(
 	ctx context.Context,
+	tx *sqlx.Tx,
 	personID, orgID int,
 ) error {
 
-	_, err := db.ExecContext(ctx, `
+	role, err := lockPersonRole(ctx, tx, personID)
+	if err != nil {
+		return err
+	}
+
+	before, err := auditSnapshot(ctx, tx, affiliationAuditSnapshotQuery,
+		personID, orgID)
+	if err != nil {
+		return err
+	} else if before == nil {
+		return errors.Wrapf(
+			app.ErrNotFound,
+			"no affiliation of person %d with organization %d",
+			personID, orgID,
+		)
+	}
+
+	_, err = tx.ExecContext(ctx, `
 		DELETE FROM affiliation
 		WHERE
 			person_id = $1
 			AND organization_id = $2
 	`, personID, orgID)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete affiliation")
+	}
+
+	err = recordAuditEvent(ctx, tx, app.AuditEvent{
+		Action:         app.AuditActionAffiliationRemove,
+		TargetType:     app.AuditTargetAffiliation,
+		TargetID:       personID,
+		OrganizationID: null.IntFrom(int64(orgID)),
+		Before:         before,
+	})
+	if err != nil || role != app.RoleDriver {
+		return err
+	}
 
-	return errors.Wrap(err, "failed to delete affiliation")
+	var remaining int
+	err = tx.GetContext(ctx, &remaining, `
+		SELECT COUNT(*)
+		FROM affiliation
+		WHERE person_id = $1
+	`, personID)
+	if err != nil {
+		return errors.Wrap(err, "failed to count affiliations")
+	} else if remaining > 0 {
+		return nil
+	}
+
+	return setPersonRole(ctx, tx, personID, app.RoleUser)
 }
 
+// AddPersonAffiliation affiliates a person with an organization, following
+// the role rules of addAffiliation.
+func (db *database) AddPersonAffiliation(
+	ctx context.Context,
+	personID, orgID int,
+) error {
+
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		return addAffiliation(ctx, tx, personID, orgID)
+	})
+
+	return errors.Wrap(err, "failed to add affiliation")
+}
+
+// RemovePersonAffiliation removes the affiliation of a person with an
+// organization, following the role rules of removeAffiliation.
+func (db *database) RemovePersonAffiliation(
+	ctx context.Context,
+	personID, orgID int,
+) error {
+
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		return removeAffiliation(ctx, tx, personID, orgID)
+	})
+
+	return errors.Wrap(err, "failed to remove affiliation")
+}
+
+// ChangePersonAffiliations affiliates a person with the organizations to add,
+// and removes their affiliations with the organizations to remove, all in a
+// single transaction. Removing an affiliation the person does not have is a
+// conflict, since it means their affiliations changed in the meantime.
+// Affiliations that are kept also keep their points.
+func (db *database) ChangePersonAffiliations(
+	ctx context.Context,
+	personID int,
+	add, remove []int,
+) error {
+
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		if _, err := lockPersonRole(ctx, tx, personID); err != nil {
+			return err
+		}
+
+		// Additions come first, so that a driver who changes organizations
+		// is never briefly demoted to a user.
+		for _, orgID := range add {
+			if err := addAffiliation(ctx, tx, personID, orgID); err != nil {
+				return err
+			}
+		}
+
+		for _, orgID := range remove {
+			err := removeAffiliation(ctx, tx, personID, orgID)
+			if errors.Is(err, app.ErrNotFound) {
+				return errors.Wrapf(app.ErrConflict,
+					"person %d is not affiliated with organization %d",
+					personID, orgID)
+			} else if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return errors.Wrap(err, "failed to change affiliations")
+}
+
+// GetAffiliationsForPerson fetches the affiliations of a person, ordered by the
+// name of their organization.
+func (db *database) GetAffiliationsForPerson(
+	ctx context.Context,
+	personID int,
+) ([]app.Affiliation, error) {
+
+	var as []app.Affiliation
+
+	err := db.SelectContext(ctx, &as, `
+		SELECT
+			a.person_id,
+			a.organization_id,
+			o.name,
+			a.points,
+			o.archived_at IS NOT NULL AS is_frozen
+		FROM affiliation a
+		JOIN organization o
+			ON a.organization_id = o.organization_id
+		WHERE a.person_id = $1
+		ORDER BY o.name, o.organization_id
+	`, personID)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err, "failed to select affiliations")
+	}
+
+	return as, nil
+}
+
+// SetPointsForAffiliation sets the points of a person in an organization.
+// Points are frozen while the organization is archived, and may not be set.
 func (db *database) SetPointsForAffiliation(
 	ctx context.Context,
 	personID, orgID int,
 	points null.Int,
 ) error {
 
-	_, err := db.ExecContext(ctx, `
-		UPDATE affiliation SET
-			points = $1
-		WHERE
-			person_id = $2
-			AND organization
-	`, points, personID, orgID)
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		before, err := auditSnapshot(ctx, tx, affiliationAuditSnapshotQuery,
+			personID, orgID)
+		if err != nil {
+			return err
+		} else if before == nil {
+			return errors.Wrapf(
+				app.ErrNotFound,
+				"no affiliation of person %d with organization %d",
+				personID, orgID,
+			)
+		}
+
+		var isFrozen bool
+		err = tx.GetContext(ctx, &isFrozen, `
+			SELECT archived_at IS NOT NULL
+			FROM organization
+			WHERE organization_id = $1
+		`, orgID)
+		if err != nil {
+			return errors.Wrap(err, "failed to check organization")
+		} else if isFrozen {
+			return errors.Wrapf(app.ErrOrganizationArchived,
+				"points of person %d in organization %d are frozen",
+				personID, orgID)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE affiliation SET
+				points = $1
+			WHERE
+				person_id = $2
+				AND organization_id = $3
+		`, points, personID, orgID)
+		if err != nil {
+			return err
+		}
+
+		after, err := auditSnapshot(ctx, tx, affiliationAuditSnapshotQuery,
+			personID, orgID)
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, tx, app.AuditEvent{
+			Action:         app.AuditActionPointsSet,
+			TargetType:     app.AuditTargetAffiliation,
+			TargetID:       personID,
+			OrganizationID: null.IntFrom(int64(orgID)),
+			Before:         before,
+			After:          after,
+		})
+	})
 
 	return errors.Wrap(err, "failed to update points for affiliation")
 }
@@ -80,7 +408,8 @@ func (db *database) GetBalancesForPerson(
 			p.last_name,
 			o.organization_id,
 			o.name,
-			a.points
+			a.points,
+			o.archived_at IS NOT NULL AS is_frozen
 		FROM affiliation a
 		JOIN person p
 			ON a.person_id = p.person_id
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func TestAffiliations(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	newPerson := func(email string, role app.Role) int {
+		id, err := db.CreatePerson(ctx, app.Person{
+			FirstName:    "Ben",
+			LastName:     "Godfrey",
+			Email:        email,
+			Password:     `qwerty`,
+			Role:         role,
+			Affiliations: make([]int, 0),
+		})
+		require.NoError(t, err)
+		return id
+	}
+
+	userID := newPerson("user@clemson.edu", app.RoleUser)
+	sponsorID := newPerson("sponsor@clemson.edu", app.RoleSponsor)
+	adminID := newPerson("admin@clemson.edu", app.RoleAdmin)
+
+	var orgIDs []int
+	for _, name := range []string{"Trucking Co.", "Freight Inc."} {
+		id, err := db.CreateOrganization(ctx, app.Organization{
+			Name:       name,
+			PointValue: 1,
+		})
+		require.NoError(t, err)
+		orgIDs = append(orgIDs, id)
+	}
+
+	assertRole := func(t *testing.T, personID int, role app.Role) {
+		db.assertCountOf(t, "person", 1, `
+			person_id = $1
+			AND role_id = $2
+		`, personID, role)
+	}
+
+	t.Run("UserBecomesDriver", func(t *testing.T) {
+		err := db.AddPersonAffiliation(ctx, userID, orgIDs[0])
+		require.NoError(t, err)
+
+		assertRole(t, userID, app.RoleDriver)
+		db.assertCountOf(t, "affiliation", 1, `
+			person_id = $1
+			AND organization_id = $2
+			AND points = 0
+		`, userID, orgIDs[0])
+	})
+
+	t.Run("AddAgainKeepsPoints", func(t *testing.T) {
+		err := db.SetPointsForAffiliation(ctx, userID, orgIDs[0],
+			null.IntFrom(50))
+		require.NoError(t, err)
+
+		err = db.AddPersonAffiliation(ctx, userID, orgIDs[0])
+		require.NoError(t, err)
+
+		db.assertCountOf(t, "affiliation", 1, `
+			person_id = $1
+			AND points = 50
+		`, userID)
+	})
+
+	t.Run("SponsorHasNoPoints", func(t *testing.T) {
+		err := db.AddPersonAffiliation(ctx, sponsorID, orgIDs[0])
+		require.NoError(t, err)
+
+		assertRole(t, sponsorID, app.RoleSponsor)
+		db.assertCountOf(t, "affiliation", 1, `
+			person_id = $1
+			AND points IS NULL
+		`, sponsorID)
+	})
+
+	t.Run("AdminRefused", func(t *testing.T) {
+		err := db.AddPersonAffiliation(ctx, adminID, orgIDs[0])
+		assert.True(t, errors.Is(err, app.ErrConflict))
+
+		db.assertCountOf(t, "affiliation", 0, `person_id = $1`, adminID)
+	})
+
+	t.Run("ChangeIsAtomic", func(t *testing.T) {
+		// The second organization does not exist, so nothing may change.
+		err := db.ChangePersonAffiliations(ctx, userID,
+			[]int{orgIDs[1], 999}, []int{orgIDs[0]})
+		require.Error(t, err)
+
+		db.assertCountOf(t, "affiliation", 1, `
+			person_id = $1
+			AND organization_id = $2
+		`, userID, orgIDs[0])
+		assertRole(t, userID, app.RoleDriver)
+	})
+
+	t.Run("RemoveChanged", func(t *testing.T) {
+		// Removing an affiliation that is already gone means the caller's
+		// view is stale, so nothing may change.
+		err := db.ChangePersonAffiliations(ctx, userID,
+			[]int{orgIDs[1]}, []int{orgIDs[1]})
+		assert.True(t, errors.Is(err, app.ErrConflict))
+
+		err = db.ChangePersonAffiliations(ctx, userID, nil, []int{orgIDs[1]})
+		assert.True(t, errors.Is(err, app.ErrConflict))
+
+		db.assertCountOf(t, "affiliation", 1, `person_id = $1`, userID)
+	})
+
+	t.Run("ChangeOrganizations", func(t *testing.T) {
+		err := db.ChangePersonAffiliations(ctx, userID,
+			[]int{orgIDs[1]}, []int{orgIDs[0]})
+		require.NoError(t, err)
+
+		as, err := db.GetAffiliationsForPerson(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, as, 1)
+		assert.Equal(t, orgIDs[1], as[0].OrganizationID)
+		assert.Equal(t, "Freight Inc.", as[0].OrganizationName)
+		assert.Equal(t, null.IntFrom(0), as[0].Points)
+
+		// A driver who changes organizations is never demoted.
+		assertRole(t, userID, app.RoleDriver)
+		db.assertCountOf(t, "audit_event", 0, `
+			action = $1
+			AND target_id = $2
+			AND after->>'role_id' = $3
+		`, app.AuditActionPersonRoleUpdate, userID, app.RoleUser)
+	})
+
+	t.Run("DriverBecomesUser", func(t *testing.T) {
+		err := db.RemovePersonAffiliation(ctx, userID, orgIDs[1])
+		require.NoError(t, err)
+
+		assertRole(t, userID, app.RoleUser)
+		db.assertCountOf(t, "affiliation", 0, `person_id = $1`, userID)
+	})
+
+	t.Run("RemoveMissing", func(t *testing.T) {
+		err := db.RemovePersonAffiliation(ctx, userID, orgIDs[1])
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+
+	t.Run("NoSuchPerson", func(t *testing.T) {
+		err := db.ChangePersonAffiliations(ctx, 999, []int{orgIDs[0]}, nil)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+}
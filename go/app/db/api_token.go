@@ -0,0 +1,220 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+type dbAPIToken struct {
+	ID         int            `db:"api_token_id"`
+	PersonID   int            `db:"person_id"`
+	Name       string         `db:"name"`
+	Scopes     pq.StringArray `db:"scopes"`
+	CreatedAt  time.Time      `db:"created_at"`
+	ExpiresAt  time.Time      `db:"expires_at"`
+	LastUsedAt null.Time      `db:"last_used_at"`
+	IsRevoked  bool           `db:"is_revoked"`
+}
+
+func (t *dbAPIToken) toAPIToken() app.APIToken {
+	out := app.APIToken{
+		ID:         t.ID,
+		PersonID:   t.PersonID,
+		Name:       t.Name,
+		Scopes:     make([]app.APITokenScope, len(t.Scopes)),
+		CreatedAt:  t.CreatedAt,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		IsRevoked:  t.IsRevoked,
+	}
+
+	for i := range t.Scopes {
+		out.Scopes[i] = app.APITokenScope(t.Scopes[i])
+	}
+
+	return out
+}
+
+// GetAPITokensForPerson fetches all API tokens for a given person of matching
+// ID, most recently created first.
+func (db *database) GetAPITokensForPerson(
+	ctx context.Context,
+	personID int,
+	includeInvalid bool,
+) ([]app.APIToken, error) {
+
+	// WARNING: if you change the logic here, make sure it matches the IsValid
+	// method logic of the app.APIToken type!
+
+	query := `
+		SELECT
+			api_token_id,
+			person_id,
+			name,
+			scopes,
+			created_at,
+			expires_at,
+			last_used_at,
+			is_revoked
+		FROM api_token
+		WHERE person_id = $1
+	`
+	params := []interface{}{personID}
+
+	if !includeInvalid {
+		now := time.Now().UTC().Round(time.Second)
+		params = append(params, now)
+		query += `
+			AND is_revoked = FALSE
+			AND $2::timestamptz < expires_at::timestamptz
+		`
+	}
+
+	query += `
+		ORDER BY created_at DESC, api_token_id DESC
+	`
+
+	var dbts []dbAPIToken
+	err := db.SelectContext(ctx, &dbts, query, params...)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err, "failed to select api tokens")
+	}
+
+	ts := make([]app.APIToken, len(dbts))
+	for idx, dbt := range dbts {
+		ts[idx] = dbt.toAPIToken()
+	}
+
+	return ts, nil
+}
+
+// GetAPITokenByToken fetches the API token with matching value, by its hash.
+func (db *database) GetAPITokenByToken(
+	ctx context.Context,
+	token app.SecureToken,
+) (app.APIToken, error) {
+
+	var dbt dbAPIToken
+
+	err := db.GetContext(ctx, &dbt, `
+		SELECT
+			api_token_id,
+			person_id,
+			name,
+			scopes,
+			created_at,
+			expires_at,
+			last_used_at,
+			is_revoked
+		FROM api_token
+		WHERE token_hash = $1
+	`, token.Hash())
+
+	if errors.Is(err, sql.ErrNoRows) {
+		// Never include the token itself in errors, since they are logged.
+		return app.APIToken{}, errors.Wrap(
+			app.ErrNotFound,
+			"no such api token by token",
+		)
+	}
+
+	return dbt.toAPIToken(), errors.Wrap(err, "failed to get api token")
+}
+
+// CreateAPIToken creates a new API token, ignoring the ID and LastUsedAt
+// fields.
+func (db *database) CreateAPIToken(
+	ctx context.Context,
+	t app.APIToken,
+) (int, error) {
+
+	scopes := make(pq.StringArray, len(t.Scopes))
+	for i := range t.Scopes {
+		scopes[i] = string(t.Scopes[i])
+	}
+
+	var id int
+	err := db.GetContext(ctx, &id, `
+		INSERT INTO api_token (
+			token_hash,
+			person_id,
+			name,
+			scopes,
+			created_at,
+			expires_at
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING api_token_id
+	`,
+		t.Token.Hash(), // $1
+		t.PersonID,     // $2
+		t.Name,         // $3
+		scopes,         // $4
+		t.CreatedAt,    // $5
+		t.ExpiresAt,    // $6
+	)
+
+	return id, errors.Wrap(err, "failed to insert api token")
+}
+
+// TouchAPIToken records that an API token was used at a given time.
+func (db *database) TouchAPIToken(
+	ctx context.Context,
+	tokenID int,
+	lastUsedAt time.Time,
+) error {
+
+	result, err := db.ExecContext(ctx, `
+		UPDATE api_token SET
+			last_used_at = $1
+		WHERE api_token_id = $2
+	`, lastUsedAt, tokenID)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to touch api token")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "failed to check result of api token touch")
+	} else if n != 1 {
+		return errors.Wrapf(
+			app.ErrNotFound,
+			"no such api token by id of %d", tokenID,
+		)
+	}
+
+	return nil
+}
+
+// RevokeAPIToken revokes an existing API token.
+func (db *database) RevokeAPIToken(ctx context.Context, tokenID int) error {
+	result, err := db.ExecContext(ctx, `
+		UPDATE api_token SET
+			is_revoked = TRUE
+		WHERE api_token_id = $1
+	`, tokenID)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke api token")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "failed to check result of revoke")
+	} else if n != 1 {
+		return errors.Wrapf(
+			app.ErrNotFound,
+			"no such api token by id of %d", tokenID,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func TestAPITokens(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	p := app.Person{
+		ID:           1,
+		FirstName:    "Ben",
+		LastName:     "Godfrey",
+		Email:        "bfgodfr@clemson.edu",
+		Password:     `qwerty`,
+		Role:         app.RoleDriver,
+		Affiliations: make([]int, 0),
+	}
+	_, err := db.CreatePerson(ctx, p)
+	require.NoError(t, err)
+
+	expires := time.Now().UTC().Add(24 * time.Hour)
+
+	var ts []app.APIToken
+	for _, name := range []string{"fleet sync", "reporting"} {
+		var tok *app.APIToken
+		tok, err = app.NewAPIToken(p.ID, name,
+			[]app.APITokenScope{app.ScopeDriverRead}, expires)
+		require.NoError(t, err)
+
+		tok.ID, err = db.CreateAPIToken(ctx, *tok)
+		require.NoError(t, err)
+
+		ts = append(ts, *tok)
+	}
+
+	db.assertCount(t, "api_token", len(ts))
+
+	t.Run("GetByToken", func(t *testing.T) {
+		actual, err := db.GetAPITokenByToken(ctx, ts[0].Token)
+		require.NoError(t, err)
+		assertEqualJSON(t, ts[0], actual)
+	})
+
+	t.Run("NoSuchToken", func(t *testing.T) {
+		_, err := db.GetAPITokenByToken(ctx, app.SecureToken{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+
+		err = db.RevokeAPIToken(ctx, 881)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+
+		err = db.TouchAPIToken(ctx, 881, time.Now())
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+
+	t.Run("Touch", func(t *testing.T) {
+		used := ts[0].CreatedAt.Add(time.Minute)
+
+		err := db.TouchAPIToken(ctx, ts[0].ID, used)
+		require.NoError(t, err)
+
+		db.assertCountOf(t, "api_token", 1, `
+			api_token_id = $1
+			AND last_used_at = $2::timestamptz
+		`, ts[0].ID, used)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		err := db.RevokeAPIToken(ctx, ts[0].ID)
+		require.NoError(t, err)
+
+		valid, err := db.GetAPITokensForPerson(ctx, p.ID, false)
+		require.NoError(t, err)
+		require.Len(t, valid, 1)
+		assert.Equal(t, ts[1].ID, valid[0].ID)
+
+		all, err := db.GetAPITokensForPerson(ctx, p.ID, true)
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+	})
+}
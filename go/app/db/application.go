@@ -2,9 +2,14 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"time"
 
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
 
 	"github.com/BenJetson/CPSC491-project/go/app"
 )
@@ -23,6 +28,7 @@ func (db *database) GetApplicationByID(
 			a.applicant_id,
 			a.organization_id,
 			a.comment,
+			a.status,
 			a.approved,
 			a.reason,
 			a.created_at,
@@ -31,7 +37,17 @@ func (db *database) GetApplicationByID(
 		WHERE application_id = $1
 	`, appID)
 
-	return a, errors.Wrap(err, "failed to get application by ID")
+	if errors.Is(err, sql.ErrNoRows) {
+		return a, errors.Wrapf(
+			app.ErrNotFound,
+			"no such application by id of %d", appID,
+		)
+	} else if err != nil {
+		return a, errors.Wrap(err, "failed to get application by ID")
+	}
+
+	a.Answers, err = db.getApplicationAnswers(ctx, appID)
+	return a, err
 }
 
 // GetApplicationsForPerson fetches all applications submitted by a person.
@@ -48,6 +64,7 @@ func (db *database) GetApplicationsForPerson(
 			a.applicant_id,
 			a.organization_id,
 			a.comment,
+			a.status,
 			a.approved,
 			a.reason,
 			a.created_at,
@@ -62,13 +79,39 @@ func (db *database) GetApplicationsForPerson(
 	return apps, errors.Wrap(err, "failed to select application for person")
 }
 
-// GetApplicationsForOrganization fetches all applications submitted for
-// an organization.
+// applicationSortClauses maps each app.ApplicationSort to its ORDER BY clause.
+var applicationSortClauses = map[app.ApplicationSort]string{
+	app.ApplicationSortNewest: "a.created_at DESC, a.application_id DESC",
+	app.ApplicationSortOldest: "a.created_at ASC, a.application_id ASC",
+	app.ApplicationSortApplicant: `
+		p.last_name ASC,
+		p.first_name ASC,
+		a.created_at DESC`,
+}
+
+// GetApplicationsForOrganization fetches the applications submitted for an
+// organization that match a filter.
 func (db *database) GetApplicationsForOrganization(
 	ctx context.Context,
 	orgID int,
+	f app.ApplicationFilter,
 ) ([]app.Application, error) {
 
+	if f.Sort == "" {
+		f.Sort = app.ApplicationSortNewest
+	}
+
+	// The sort is chosen from a fixed set of clauses, never from user input.
+	orderBy, ok := applicationSortClauses[f.Sort]
+	if !ok {
+		return nil, errors.Errorf("unknown application sort '%s'", f.Sort)
+	}
+
+	statuses := make(pq.StringArray, len(f.Statuses))
+	for i, status := range f.Statuses {
+		statuses[i] = string(status)
+	}
+
 	var apps []app.Application
 
 	err := db.SelectContext(ctx, &apps, `
@@ -77,19 +120,132 @@ func (db *database) GetApplicationsForOrganization(
 			a.applicant_id,
 			a.organization_id,
 			a.comment,
+			a.status,
 			a.approved,
 			a.reason,
 			a.created_at,
 			a.approved_at
 		FROM application a
+		JOIN person p ON p.person_id = a.applicant_id
+		WHERE
+			a.organization_id = $1
+			AND (cardinality($2::text[]) = 0 OR a.status = ANY($2))
+			AND ($3::timestamptz IS NULL OR a.created_at >= $3)
+			AND ($4::timestamptz IS NULL OR a.created_at < $4)
+		ORDER BY `+orderBy,
+
+		orgID,    // $1
+		statuses, // $2
+		f.Since,  // $3
+		f.Until,  // $4
+	)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err,
+			"failed to select applications for organization")
+	}
+
+	return apps, nil
+}
+
+// checkApplicationAllowed determines whether a person may apply to an
+// organization. They may not if it is archived or not accepting applications,
+// or if they already belong to it, already have an open application to it, or
+// were rejected by it too recently.
+//
+// The person must have been locked by the transaction, so that two
+// applications cannot both pass.
+func checkApplicationAllowed(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	personID, orgID int,
+	now time.Time,
+) error {
+
+	var org struct {
+		IsArchived bool `db:"is_archived"`
+		IsClosed   bool `db:"applications_closed"`
+	}
+	err := tx.GetContext(ctx, &org, `
+		SELECT
+			archived_at IS NOT NULL AS is_archived,
+			applications_closed
+		FROM organization
 		WHERE organization_id = $1
 	`, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.Wrapf(app.ErrNotFound,
+			"no such organization by id of %d", orgID)
+	} else if err != nil {
+		return errors.Wrap(err, "failed to check organization")
+	} else if org.IsArchived {
+		return errors.Wrapf(app.ErrOrganizationArchived,
+			"cannot apply to org %d", orgID)
+	} else if org.IsClosed {
+		return errors.Wrapf(app.ErrNotAcceptingApplications,
+			"cannot apply to org %d", orgID)
+	}
 
-	return apps, errors.Wrap(err,
-		"failed to select application for organization")
+	var affiliated bool
+	err = tx.GetContext(ctx, &affiliated, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM affiliation
+			WHERE person_id = $1 AND organization_id = $2
+		)
+	`, personID, orgID)
+	if err != nil {
+		return errors.Wrap(err, "failed to check affiliation")
+	} else if affiliated {
+		return errors.Wrapf(app.ErrAlreadyAffiliated,
+			"person %d in org %d", personID, orgID)
+	}
+
+	var open bool
+	err = tx.GetContext(ctx, &open, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM application
+			WHERE
+				applicant_id = $1
+				AND organization_id = $2
+				AND status = ANY($3)
+		)
+	`, personID, orgID, openApplicationStatuses)
+	if err != nil {
+		return errors.Wrap(err, "failed to check open applications")
+	} else if open {
+		return errors.Wrapf(app.ErrApplicationOpen,
+			"person %d to org %d", personID, orgID)
+	}
+
+	var until null.Time
+	err = tx.GetContext(ctx, &until, `
+		SELECT
+			MAX(a.approved_at)
+				+ make_interval(days => o.reapply_cooldown_days)
+		FROM application a
+		JOIN organization o ON o.organization_id = a.organization_id
+		WHERE
+			a.applicant_id = $1
+			AND a.organization_id = $2
+			AND a.status = $3
+		GROUP BY o.reapply_cooldown_days
+	`, personID, orgID, app.ApplicationRejected)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return errors.Wrap(err, "failed to check reapplication cooldown")
+	} else if until.Valid && until.Time.After(now) {
+		return errors.WithStack(&app.ReapplyCooldownError{Until: until.Time})
+	}
+
+	return nil
 }
 
-// CreateApplication creates a new application in the database.
+// CreateApplication creates a new application in the database, along with its
+// answers to the application form.
+//
+// Returns app.ErrAlreadyAffiliated, app.ErrApplicationOpen, or an
+// app.ReapplyCooldownError if the applicant may not apply to the organization.
 func (db *database) CreateApplication(
 	ctx context.Context,
 	a app.Application,
@@ -98,52 +254,274 @@ func (db *database) CreateApplication(
 	now := time.Now().UTC().Round(time.Second)
 
 	var id int
-	err := db.GetContext(ctx, &id, `
-		INSERT INTO application (
-			applicant_id,
-			organization_id,
-			comment,
-			created_at
-		) VALUES ($1, $2, $3, $4)
-		RETURNING application_id
-	`, a.ApplicantID, a.OrganizationID, a.Comment, now)
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		if _, err := lockPersonRole(ctx, tx, a.ApplicantID); err != nil {
+			return err
+		}
+
+		err := checkApplicationAllowed(ctx, tx, a.ApplicantID,
+			a.OrganizationID, now)
+		if err != nil {
+			return err
+		}
+
+		err = tx.GetContext(ctx, &id, `
+			INSERT INTO application (
+				applicant_id,
+				organization_id,
+				comment,
+				status,
+				created_at
+			) VALUES ($1, $2, $3, $4, $5)
+			RETURNING application_id
+		`, a.ApplicantID, a.OrganizationID, a.Comment,
+			app.ApplicationSubmitted, now)
+		if err != nil {
+			return err
+		}
+
+		err = insertApplicationAnswers(ctx, tx, id, a.Answers)
+		if err != nil {
+			return err
+		}
+
+		return recordApplicationEvent(ctx, tx, app.ApplicationEvent{
+			ApplicationID: id,
+			ToStatus:      app.ApplicationSubmitted,
+			CreatedAt:     now,
+		})
+	})
 
 	return id, errors.Wrap(err, "failed to insert application")
 }
 
-// UpdateApplicationApproval sets the application approval status in the
-// database.
-func (db *database) UpdateApplicationApproval(
+// TransitionApplication moves an application from one status to another,
+// recording the change in its history.
+//
+// Approving an application also affiliates the applicant with the
+// organization, promoting them to driver if need be. The applicant is notified
+// of decisions and requests for information; sponsors are notified when the
+// applicant answers.
+//
+// Returns app.ErrConflict if the application is no longer in the from status
+// or may not move to the to status, or when approving an applicant who is
+// neither a user nor a driver.
+func (db *database) TransitionApplication(
 	ctx context.Context,
 	appID int,
-	status bool,
-	reason string,
+	from, to app.ApplicationStatus,
+	message string,
 ) error {
 
 	now := time.Now().UTC().Round(time.Second)
 
-	result, err := db.ExecContext(ctx, `
-		UPDATE application SET
-			approved = $1,
-			reason = $2,
-			approved_at = $3
-		WHERE application_id = $4
-	`, status, reason, appID, now)
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		before, err := auditSnapshot(ctx, tx, appAuditSnapshotQuery, appID)
+		if err != nil {
+			return err
+		} else if before == nil {
+			return errors.Wrapf(
+				app.ErrNotFound,
+				"no such application by id of %d", appID,
+			)
+		}
 
-	if err != nil {
-		return errors.Wrap(err, "failed to update application approval")
-	}
+		var a app.Application
+		err = tx.GetContext(ctx, &a, `
+			SELECT
+				a.application_id,
+				a.applicant_id,
+				a.organization_id,
+				a.status,
+				o.name
+			FROM application a
+			JOIN organization o
+				ON a.organization_id = o.organization_id
+			WHERE a.application_id = $1
+		`, appID)
+		if err != nil {
+			return err
+		}
 
-	n, err := result.RowsAffected()
-	if err != nil {
-		return errors.Wrap(err,
-			"failed to check result of application approval update")
-	} else if n != 1 {
-		return errors.Wrapf(
-			app.ErrNotFound,
-			"no such application by id of %d", appID,
-		)
+		if a.Status != from || !from.CanTransitionTo(to) {
+			return errors.Wrapf(
+				app.ErrConflict,
+				"application %d is %s, so cannot move from %s to %s",
+				appID, a.Status, from, to,
+			)
+		}
+
+		isDecision := to == app.ApplicationApproved ||
+			to == app.ApplicationRejected
+
+		if isDecision {
+			_, err = tx.ExecContext(ctx, `
+				UPDATE application SET
+					status = $1,
+					approved = $2,
+					reason = $3,
+					approved_at = $4
+				WHERE application_id = $5
+			`, to, to == app.ApplicationApproved, message, now, appID)
+		} else {
+			_, err = tx.ExecContext(ctx, `
+				UPDATE application SET
+					status = $1
+				WHERE application_id = $2
+			`, to, appID)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = recordApplicationEvent(ctx, tx, app.ApplicationEvent{
+			ApplicationID: appID,
+			FromStatus:    from,
+			ToStatus:      to,
+			Message:       null.NewString(message, len(message) > 0),
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return err
+		}
+
+		if isDecision {
+			after, err := auditSnapshot(ctx, tx, appAuditSnapshotQuery, appID)
+			if err != nil {
+				return err
+			}
+
+			err = recordAuditEvent(ctx, tx, app.AuditEvent{
+				Action:         app.AuditActionApplicationDecide,
+				TargetType:     app.AuditTargetApplication,
+				TargetID:       appID,
+				OrganizationID: null.IntFrom(int64(a.OrganizationID)),
+				Before:         before,
+				After:          after,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		switch to {
+		case app.ApplicationApproved:
+			// Approval may only make drivers, so that applicants of any other
+			// role cannot gain a role in the organization through it.
+			role, err := lockPersonRole(ctx, tx, a.ApplicantID)
+			if err != nil {
+				return err
+			} else if role != app.RoleUser && role != app.RoleDriver {
+				return errors.Wrapf(app.ErrConflict,
+					"applicant %d has role %d, so cannot become a driver",
+					a.ApplicantID, role)
+			}
+
+			err = addAffiliation(ctx, tx, a.ApplicantID, a.OrganizationID)
+			if err != nil {
+				return err
+			}
+
+			return notifyPerson(ctx, tx, a.ApplicantID,
+				app.NotificationAppApproved,
+				fmt.Sprintf("Your application to %s was approved.",
+					a.OrganizationTitle))
+		case app.ApplicationRejected:
+			return notifyPerson(ctx, tx, a.ApplicantID,
+				app.NotificationAppRejected,
+				fmt.Sprintf("Your application to %s was not approved.",
+					a.OrganizationTitle))
+		case app.ApplicationInfoRequested:
+			return notifyPerson(ctx, tx, a.ApplicantID,
+				app.NotificationAppInfoRequested,
+				fmt.Sprintf("%s needs more information about your "+
+					"application: %s", a.OrganizationTitle, message))
+		case app.ApplicationSubmitted:
+			if from != app.ApplicationInfoRequested {
+				return nil
+			}
+
+			return notifySponsorsOfOrganization(ctx, tx, a.OrganizationID,
+				a.ApplicantID, app.NotificationAppInfoProvided,
+				"%s %s answered your request for more information.")
+		}
+
+		return nil
+	})
+
+	return errors.Wrap(err, "failed to transition application")
+}
+
+// GetApplicationHistory fetches the changes in status of an application, in
+// the order that they happened.
+func (db *database) GetApplicationHistory(
+	ctx context.Context,
+	appID int,
+) ([]app.ApplicationEvent, error) {
+
+	var events []app.ApplicationEvent
+
+	err := db.SelectContext(ctx, &events, `
+		SELECT
+			application_event_id,
+			application_id,
+			COALESCE(from_status, '') AS from_status,
+			to_status,
+			actor_id,
+			message,
+			created_at
+		FROM application_event
+		WHERE application_id = $1
+		ORDER BY created_at, application_event_id
+	`, appID)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err, "failed to select application history")
 	}
 
-	return nil
+	return events, nil
+}
+
+// recordApplicationEvent adds a change in status to the history of an
+// application. The actor is taken from the context, as for audit events.
+//
+// It should be called in the same transaction as the change that it records.
+func recordApplicationEvent(
+	ctx context.Context,
+	tx sqlx.ExecerContext,
+	e app.ApplicationEvent,
+) error {
+
+	actor := app.AuditActorFromContext(ctx)
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO application_event (
+			application_id,
+			from_status,
+			to_status,
+			actor_id,
+			message,
+			created_at
+		) VALUES ($1, $2, $3, $4, $5, $6)
+	`, e.ApplicationID, null.NewString(string(e.FromStatus),
+		len(e.FromStatus) > 0), e.ToStatus, actor.PersonID, e.Message,
+		e.CreatedAt)
+
+	return errors.Wrap(err, "failed to record application event")
 }
+
+// openApplicationStatuses are the statuses of applications that have been
+// neither decided nor withdrawn.
+var openApplicationStatuses = pq.StringArray{
+	string(app.ApplicationSubmitted),
+	string(app.ApplicationOnHold),
+	string(app.ApplicationInfoRequested),
+}
+
+// appAuditSnapshotQuery captures an application for the audit log.
+const appAuditSnapshotQuery = `
+	SELECT to_jsonb(a)
+	FROM application a
+	WHERE a.application_id = $1
+	FOR UPDATE
+`
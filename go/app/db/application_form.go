@@ -0,0 +1,252 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+type dbApplicationQuestion struct {
+	ID             int              `db:"application_question_id"`
+	OrganizationID int              `db:"organization_id"`
+	Prompt         string           `db:"prompt"`
+	Type           app.QuestionType `db:"question_type"`
+	IsRequired     bool             `db:"is_required"`
+	Choices        pq.StringArray   `db:"choices"`
+	Min            null.Float       `db:"min_value"`
+	Max            null.Float       `db:"max_value"`
+}
+
+func (q *dbApplicationQuestion) toQuestion() app.ApplicationQuestion {
+	return app.ApplicationQuestion{
+		ID:             q.ID,
+		OrganizationID: q.OrganizationID,
+		Prompt:         q.Prompt,
+		Type:           q.Type,
+		IsRequired:     q.IsRequired,
+		Choices:        []string(q.Choices),
+		Min:            q.Min,
+		Max:            q.Max,
+	}
+}
+
+// GetApplicationForm fetches the questions on the application form of an
+// organization, in the order that they are asked.
+func (db *database) GetApplicationForm(
+	ctx context.Context,
+	orgID int,
+) ([]app.ApplicationQuestion, error) {
+
+	var dbQuestions []dbApplicationQuestion
+
+	err := db.SelectContext(ctx, &dbQuestions, `
+		SELECT
+			application_question_id,
+			organization_id,
+			prompt,
+			question_type,
+			is_required,
+			choices,
+			min_value,
+			max_value
+		FROM application_question
+		WHERE organization_id = $1
+		ORDER BY position
+	`, orgID)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err, "failed to select application form")
+	}
+
+	questions := make([]app.ApplicationQuestion, len(dbQuestions))
+	for i := range dbQuestions {
+		questions[i] = dbQuestions[i].toQuestion()
+	}
+
+	return questions, nil
+}
+
+// SetApplicationForm replaces the application form of an organization with
+// the given questions, in order. Questions with an ID are updated, those
+// without are added, and those left out are removed. Answers that have
+// already been given are not affected.
+func (db *database) SetApplicationForm(
+	ctx context.Context,
+	orgID int,
+	questions []app.ApplicationQuestion,
+) error {
+
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		exists, err := auditSnapshot(ctx, tx, orgAuditSnapshotQuery, orgID)
+		if err != nil {
+			return err
+		} else if exists == nil {
+			return errors.Wrapf(
+				app.ErrNotFound,
+				"no such organization by id of %d", orgID,
+			)
+		}
+
+		before, err := auditSnapshot(ctx, tx, formAuditSnapshotQuery, orgID)
+		if err != nil {
+			return err
+		}
+
+		keep := make(pq.Int64Array, 0, len(questions))
+		for i, q := range questions {
+			choices := pq.StringArray(q.Choices)
+			if choices == nil {
+				choices = pq.StringArray{}
+			}
+
+			if q.ID == 0 {
+				err = tx.GetContext(ctx, &q.ID, `
+					INSERT INTO application_question (
+						organization_id,
+						position,
+						prompt,
+						question_type,
+						is_required,
+						choices,
+						min_value,
+						max_value
+					) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+					RETURNING application_question_id
+				`, orgID, i, q.Prompt, q.Type, q.IsRequired, choices, q.Min,
+					q.Max)
+			} else {
+				err = tx.GetContext(ctx, &q.ID, `
+					UPDATE application_question SET
+						position = $3,
+						prompt = $4,
+						question_type = $5,
+						is_required = $6,
+						choices = $7,
+						min_value = $8,
+						max_value = $9
+					WHERE
+						application_question_id = $1
+						AND organization_id = $2
+					RETURNING application_question_id
+				`, q.ID, orgID, i, q.Prompt, q.Type, q.IsRequired, choices,
+					q.Min, q.Max)
+
+				if errors.Is(err, sql.ErrNoRows) {
+					return errors.Wrapf(
+						app.ErrNotFound,
+						"no question %d on the form of organization %d",
+						q.ID, orgID,
+					)
+				}
+			}
+
+			if err != nil {
+				return errors.Wrap(err, "failed to save question")
+			}
+			keep = append(keep, int64(q.ID))
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM application_question
+			WHERE
+				organization_id = $1
+				AND NOT application_question_id = ANY($2)
+		`, orgID, keep)
+		if err != nil {
+			return errors.Wrap(err, "failed to remove questions")
+		}
+
+		after, err := auditSnapshot(ctx, tx, formAuditSnapshotQuery, orgID)
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, tx, app.AuditEvent{
+			Action:         app.AuditActionApplicationForm,
+			TargetType:     app.AuditTargetOrganization,
+			TargetID:       orgID,
+			OrganizationID: null.IntFrom(int64(orgID)),
+			Before:         before,
+			After:          after,
+		})
+	})
+
+	return errors.Wrap(err, "failed to set application form")
+}
+
+// insertApplicationAnswers stores the answers given with an application, which
+// must already have been validated against the form.
+func insertApplicationAnswers(
+	ctx context.Context,
+	tx sqlx.ExecerContext,
+	appID int,
+	answers []app.ApplicationAnswer,
+) error {
+
+	for i, a := range answers {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO application_answer (
+				application_id,
+				position,
+				application_question_id,
+				prompt,
+				question_type,
+				value
+			) VALUES ($1, $2, $3, $4, $5, $6)
+		`, appID, i, a.QuestionID, a.Prompt, a.Type, []byte(a.Value))
+
+		if err != nil {
+			return errors.Wrap(err, "failed to insert application answer")
+		}
+	}
+
+	return nil
+}
+
+// getApplicationAnswers fetches the answers given with an application. The
+// question ID is zero for questions that have since been removed.
+func (db *database) getApplicationAnswers(
+	ctx context.Context,
+	appID int,
+) ([]app.ApplicationAnswer, error) {
+
+	var answers []app.ApplicationAnswer
+
+	err := db.SelectContext(ctx, &answers, `
+		SELECT
+			COALESCE(application_question_id, 0) AS application_question_id,
+			prompt,
+			question_type,
+			value
+		FROM application_answer
+		WHERE application_id = $1
+		ORDER BY position
+	`, appID)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err, "failed to select application answers")
+	}
+
+	if answers == nil {
+		answers = make([]app.ApplicationAnswer, 0)
+	}
+
+	return answers, nil
+}
+
+// formAuditSnapshotQuery captures the application form of an organization for
+// the audit log.
+const formAuditSnapshotQuery = `
+	SELECT COALESCE(
+		jsonb_agg(to_jsonb(q) ORDER BY q.position),
+		'[]'::jsonb
+	)
+	FROM application_question q
+	WHERE q.organization_id = $1
+`
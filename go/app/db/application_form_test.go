@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func TestApplicationForm(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	orgID, err := db.CreateOrganization(ctx, app.Organization{
+		Name:       "Trucking Co.",
+		PointValue: 1,
+	})
+	require.NoError(t, err)
+
+	driverID, err := db.CreatePerson(ctx, app.Person{
+		FirstName:    "Ben",
+		LastName:     "Godfrey",
+		Email:        "bfgodfr@clemson.edu",
+		Password:     `qwerty`,
+		Role:         app.RoleDriver,
+		Affiliations: make([]int, 0),
+	})
+	require.NoError(t, err)
+
+	var form []app.ApplicationQuestion
+
+	t.Run("Create", func(t *testing.T) {
+		err := db.SetApplicationForm(ctx, orgID, []app.ApplicationQuestion{
+			{Prompt: "CDL number", Type: app.QuestionText, IsRequired: true},
+			{
+				Prompt: "Years driving",
+				Type:   app.QuestionNumber,
+				Min:    null.FloatFrom(0),
+				Max:    null.FloatFrom(60),
+			},
+			{
+				Prompt:  "License class",
+				Type:    app.QuestionChoice,
+				Choices: []string{"A", "B", "C"},
+			},
+		})
+		require.NoError(t, err)
+
+		form, err = db.GetApplicationForm(ctx, orgID)
+		require.NoError(t, err)
+		require.Len(t, form, 3)
+
+		assert.Equal(t, "CDL number", form[0].Prompt)
+		assert.True(t, form[0].IsRequired)
+		assert.Empty(t, form[0].Choices)
+		assert.Equal(t, null.FloatFrom(60), form[1].Max)
+		assert.Equal(t, []string{"A", "B", "C"}, form[2].Choices)
+
+		db.assertCountOf(t, "audit_event", 1, `
+			action = $1
+			AND target_id = $2
+		`, app.AuditActionApplicationForm, orgID)
+	})
+
+	var appID int
+
+	t.Run("Answer", func(t *testing.T) {
+		answers, _, err := app.ValidateApplicationAnswers(form,
+			[]app.ApplicationAnswer{
+				{QuestionID: form[0].ID, Value: json.RawMessage(`"X1234"`)},
+				{QuestionID: form[2].ID, Value: json.RawMessage(`"B"`)},
+			})
+		require.NoError(t, err)
+
+		appID, err = db.CreateApplication(ctx, app.Application{
+			ApplicantID:    driverID,
+			OrganizationID: orgID,
+			Comment:        "Please sponsor me.",
+			Answers:        answers,
+		})
+		require.NoError(t, err)
+
+		a, err := db.GetApplicationByID(ctx, appID)
+		require.NoError(t, err)
+		require.Len(t, a.Answers, 2)
+		assert.Equal(t, form[0].ID, a.Answers[0].QuestionID)
+		assert.JSONEq(t, `"X1234"`, string(a.Answers[0].Value))
+		assert.Equal(t, app.QuestionChoice, a.Answers[1].Type)
+		assert.JSONEq(t, `"B"`, string(a.Answers[1].Value))
+	})
+
+	t.Run("Reorder", func(t *testing.T) {
+		// Move the last question first, reword one, and drop another.
+		form[0].Prompt = "Commercial license number"
+		err := db.SetApplicationForm(ctx, orgID, []app.ApplicationQuestion{
+			form[2],
+			form[0],
+		})
+		require.NoError(t, err)
+
+		updated, err := db.GetApplicationForm(ctx, orgID)
+		require.NoError(t, err)
+		require.Len(t, updated, 2)
+		assert.Equal(t, form[2].ID, updated[0].ID)
+		assert.Equal(t, form[0].ID, updated[1].ID)
+		assert.Equal(t, "Commercial license number", updated[1].Prompt)
+
+		// Answers keep the question as it was asked.
+		a, err := db.GetApplicationByID(ctx, appID)
+		require.NoError(t, err)
+		require.Len(t, a.Answers, 2)
+		assert.Equal(t, "CDL number", a.Answers[0].Prompt)
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		err := db.SetApplicationForm(ctx, orgID, nil)
+		require.NoError(t, err)
+
+		updated, err := db.GetApplicationForm(ctx, orgID)
+		require.NoError(t, err)
+		assert.Empty(t, updated)
+
+		a, err := db.GetApplicationByID(ctx, appID)
+		require.NoError(t, err)
+		require.Len(t, a.Answers, 2)
+		assert.Equal(t, 0, a.Answers[0].QuestionID)
+	})
+
+	t.Run("OtherOrganization", func(t *testing.T) {
+		otherID, err := db.CreateOrganization(ctx, app.Organization{
+			Name:       "Freight Inc.",
+			PointValue: 1,
+		})
+		require.NoError(t, err)
+
+		err = db.SetApplicationForm(ctx, otherID, []app.ApplicationQuestion{
+			{Prompt: "CDL number", Type: app.QuestionText},
+		})
+		require.NoError(t, err)
+
+		other, err := db.GetApplicationForm(ctx, otherID)
+		require.NoError(t, err)
+		require.Len(t, other, 1)
+
+		// Questions cannot be moved between organizations.
+		err = db.SetApplicationForm(ctx, orgID, other)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+
+	t.Run("NoSuchOrganization", func(t *testing.T) {
+		err := db.SetApplicationForm(ctx, 999, nil)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+}
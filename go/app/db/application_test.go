@@ -0,0 +1,421 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func TestApplicationLifecycle(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	newPerson := func(email string, role app.Role) int {
+		id, err := db.CreatePerson(ctx, app.Person{
+			FirstName:    "Ben",
+			LastName:     "Godfrey",
+			Email:        email,
+			Password:     `qwerty`,
+			Role:         role,
+			Affiliations: make([]int, 0),
+		})
+		require.NoError(t, err)
+		return id
+	}
+
+	approvedID := newPerson("approved@clemson.edu", app.RoleUser)
+	rejectedID := newPerson("rejected@clemson.edu", app.RoleUser)
+	pendingID := newPerson("pending@clemson.edu", app.RoleUser)
+
+	orgID, err := db.CreateOrganization(ctx, app.Organization{
+		Name:       "Trucking Co.",
+		PointValue: 1,
+	})
+	require.NoError(t, err)
+
+	newApplication := func(personID int) int {
+		id, err := db.CreateApplication(ctx, app.Application{
+			ApplicantID:    personID,
+			OrganizationID: orgID,
+			Comment:        "Please sponsor me.",
+		})
+		require.NoError(t, err)
+		return id
+	}
+
+	approvedApp := newApplication(approvedID)
+	rejectedApp := newApplication(rejectedID)
+	pendingApp := newApplication(pendingID)
+
+	sponsorID := newPerson("sponsor@clemson.edu", app.RoleSponsor)
+	require.NoError(t, db.AddPersonAffiliation(ctx, sponsorID, orgID))
+
+	t.Run("Approve", func(t *testing.T) {
+		err := db.TransitionApplication(ctx, approvedApp,
+			app.ApplicationSubmitted, app.ApplicationApproved, "Welcome!")
+		require.NoError(t, err)
+
+		db.assertCountOf(t, "person", 1, `
+			person_id = $1
+			AND role_id = $2
+		`, approvedID, app.RoleDriver)
+		db.assertCountOf(t, "affiliation", 1, `
+			person_id = $1
+			AND organization_id = $2
+			AND points = 0
+		`, approvedID, orgID)
+
+		ns, err := db.GetNotificationsForPerson(ctx, approvedID, true)
+		require.NoError(t, err)
+		require.Len(t, ns, 1)
+		assert.Equal(t, app.NotificationAppApproved, ns[0].Kind)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		err := db.TransitionApplication(ctx, rejectedApp,
+			app.ApplicationSubmitted, app.ApplicationRejected, "Sorry.")
+		require.NoError(t, err)
+
+		db.assertCountOf(t, "person", 1, `
+			person_id = $1
+			AND role_id = $2
+		`, rejectedID, app.RoleUser)
+		db.assertCountOf(t, "affiliation", 0, `person_id = $1`, rejectedID)
+
+		ns, err := db.GetNotificationsForPerson(ctx, rejectedID, true)
+		require.NoError(t, err)
+		require.Len(t, ns, 1)
+		assert.Equal(t, app.NotificationAppRejected, ns[0].Kind)
+	})
+
+	t.Run("DecisionIsFinal", func(t *testing.T) {
+		err := db.TransitionApplication(ctx, rejectedApp,
+			app.ApplicationRejected, app.ApplicationApproved, "Oops.")
+		assert.True(t, errors.Is(err, app.ErrConflict))
+
+		a, err := db.GetApplicationByID(ctx, rejectedApp)
+		require.NoError(t, err)
+		assert.Equal(t, app.ApplicationRejected, a.Status)
+		assert.False(t, a.Approved.Bool)
+		assert.Equal(t, "Sorry.", a.Reason.String)
+
+		db.assertCountOf(t, "affiliation", 0, `person_id = $1`, rejectedID)
+	})
+
+	t.Run("StaleStatus", func(t *testing.T) {
+		// The application was decided since the caller last looked.
+		err := db.TransitionApplication(ctx, rejectedApp,
+			app.ApplicationSubmitted, app.ApplicationOnHold, "")
+		assert.True(t, errors.Is(err, app.ErrConflict))
+	})
+
+	t.Run("RequestInfo", func(t *testing.T) {
+		err := db.TransitionApplication(ctx, pendingApp,
+			app.ApplicationSubmitted, app.ApplicationInfoRequested,
+			"How long have you driven?")
+		require.NoError(t, err)
+
+		ns, err := db.GetNotificationsForPerson(ctx, pendingID, true)
+		require.NoError(t, err)
+		require.Len(t, ns, 1)
+		assert.Equal(t, app.NotificationAppInfoRequested, ns[0].Kind)
+		assert.Equal(t, "Trucking Co. needs more information about your "+
+			"application: How long have you driven?", ns[0].Message)
+
+		// Approval must wait for the answer.
+		err = db.TransitionApplication(ctx, pendingApp,
+			app.ApplicationInfoRequested, app.ApplicationApproved, "")
+		assert.True(t, errors.Is(err, app.ErrConflict))
+	})
+
+	t.Run("Respond", func(t *testing.T) {
+		err := db.TransitionApplication(ctx, pendingApp,
+			app.ApplicationInfoRequested, app.ApplicationSubmitted,
+			"Ten years.")
+		require.NoError(t, err)
+
+		ns, err := db.GetNotificationsForPerson(ctx, sponsorID, true)
+		require.NoError(t, err)
+		require.Len(t, ns, 1)
+		assert.Equal(t, app.NotificationAppInfoProvided, ns[0].Kind)
+	})
+
+	t.Run("Withdraw", func(t *testing.T) {
+		err := db.TransitionApplication(ctx, pendingApp,
+			app.ApplicationSubmitted, app.ApplicationWithdrawn, "")
+		require.NoError(t, err)
+
+		db.assertCountOf(t, "affiliation", 0, `person_id = $1`, pendingID)
+	})
+
+	t.Run("History", func(t *testing.T) {
+		events, err := db.GetApplicationHistory(ctx, pendingApp)
+		require.NoError(t, err)
+
+		expect := []struct {
+			from, to app.ApplicationStatus
+			message  string
+		}{
+			{"", app.ApplicationSubmitted, ""},
+			{
+				app.ApplicationSubmitted,
+				app.ApplicationInfoRequested,
+				"How long have you driven?",
+			},
+			{app.ApplicationInfoRequested, app.ApplicationSubmitted,
+				"Ten years."},
+			{app.ApplicationSubmitted, app.ApplicationWithdrawn, ""},
+		}
+
+		require.Len(t, events, len(expect))
+		for i, e := range expect {
+			assert.Equal(t, e.from, events[i].FromStatus)
+			assert.Equal(t, e.to, events[i].ToStatus)
+			assert.Equal(t, e.message, events[i].Message.String)
+		}
+	})
+
+	t.Run("NoSuchApplication", func(t *testing.T) {
+		err := db.TransitionApplication(ctx, 999,
+			app.ApplicationSubmitted, app.ApplicationApproved, "Welcome!")
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+
+		_, err = db.GetApplicationByID(ctx, 999)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+}
+
+func TestApplicationRules(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	personID, err := db.CreatePerson(ctx, app.Person{
+		FirstName:    "Ben",
+		LastName:     "Godfrey",
+		Email:        "bfgodfr@clemson.edu",
+		Password:     `qwerty`,
+		Role:         app.RoleUser,
+		Affiliations: make([]int, 0),
+	})
+	require.NoError(t, err)
+
+	strictID, err := db.CreateOrganization(ctx, app.Organization{
+		Name:         "Trucking Co.",
+		PointValue:   1,
+		CooldownDays: 30,
+	})
+	require.NoError(t, err)
+
+	lenientID, err := db.CreateOrganization(ctx, app.Organization{
+		Name:       "Freight Inc.",
+		PointValue: 1,
+	})
+	require.NoError(t, err)
+
+	apply := func(orgID int) (int, error) {
+		return db.CreateApplication(ctx, app.Application{
+			ApplicantID:    personID,
+			OrganizationID: orgID,
+			Comment:        "Please sponsor me.",
+		})
+	}
+
+	decide := func(appID int, to app.ApplicationStatus) {
+		err := db.TransitionApplication(ctx, appID,
+			app.ApplicationSubmitted, to, "Decided.")
+		require.NoError(t, err)
+	}
+
+	firstApp, err := apply(strictID)
+	require.NoError(t, err)
+
+	t.Run("OneOpen", func(t *testing.T) {
+		_, err := apply(strictID)
+		assert.True(t, errors.Is(err, app.ErrApplicationOpen))
+		assert.True(t, errors.Is(err, app.ErrConflict))
+
+		db.assertCountOf(t, "application", 1, "organization_id = $1",
+			strictID)
+	})
+
+	t.Run("Cooldown", func(t *testing.T) {
+		decide(firstApp, app.ApplicationRejected)
+
+		_, err := apply(strictID)
+		var cooldown *app.ReapplyCooldownError
+		require.True(t, errors.As(err, &cooldown))
+		assert.WithinDuration(t, time.Now().AddDate(0, 0, 30),
+			cooldown.Until, time.Minute)
+
+		// Once the cooldown has passed, the person may apply again.
+		_, err = db.Exec(`
+			UPDATE application SET
+				approved_at = approved_at - interval '31 days'
+			WHERE application_id = $1
+		`, firstApp)
+		require.NoError(t, err)
+
+		secondApp, err := apply(strictID)
+		require.NoError(t, err)
+
+		decide(secondApp, app.ApplicationApproved)
+	})
+
+	t.Run("AlreadyAffiliated", func(t *testing.T) {
+		_, err := apply(strictID)
+		assert.True(t, errors.Is(err, app.ErrAlreadyAffiliated))
+	})
+
+	t.Run("NoCooldown", func(t *testing.T) {
+		appID, err := apply(lenientID)
+		require.NoError(t, err)
+		decide(appID, app.ApplicationRejected)
+
+		_, err = apply(lenientID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("SponsorNotApproved", func(t *testing.T) {
+		sponsorID, err := db.CreatePerson(ctx, app.Person{
+			FirstName:    "Ben",
+			LastName:     "Godfrey",
+			Email:        "sponsor@clemson.edu",
+			Password:     `qwerty`,
+			Role:         app.RoleSponsor,
+			Affiliations: make([]int, 0),
+		})
+		require.NoError(t, err)
+
+		appID, err := db.CreateApplication(ctx, app.Application{
+			ApplicantID:    sponsorID,
+			OrganizationID: lenientID,
+		})
+		require.NoError(t, err)
+
+		err = db.TransitionApplication(ctx, appID, app.ApplicationSubmitted,
+			app.ApplicationApproved, "Welcome!")
+		assert.True(t, errors.Is(err, app.ErrConflict))
+
+		db.assertCountOf(t, "affiliation", 0, "person_id = $1", sponsorID)
+		db.assertCountOf(t, "application", 1, `
+			application_id = $1
+			AND status = $2
+		`, appID, app.ApplicationSubmitted)
+	})
+}
+
+func TestApplicationFilter(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	orgID, err := db.CreateOrganization(ctx, app.Organization{
+		Name:       "Trucking Co.",
+		PointValue: 1,
+	})
+	require.NoError(t, err)
+
+	// Applications are made oldest first, by applicants whose names sort in
+	// the opposite order.
+	start := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	var appIDs []int
+	for i, lastName := range []string{"Young", "Moore", "Adams"} {
+		personID, err := db.CreatePerson(ctx, app.Person{
+			FirstName:    "Ben",
+			LastName:     lastName,
+			Email:        lastName + "@clemson.edu",
+			Password:     `qwerty`,
+			Role:         app.RoleUser,
+			Affiliations: make([]int, 0),
+		})
+		require.NoError(t, err)
+
+		appID, err := db.CreateApplication(ctx, app.Application{
+			ApplicantID:    personID,
+			OrganizationID: orgID,
+			Comment:        "Please sponsor me.",
+		})
+		require.NoError(t, err)
+
+		_, err = db.Exec(`
+			UPDATE application SET
+				created_at = $1
+			WHERE application_id = $2
+		`, start.AddDate(0, 0, i), appID)
+		require.NoError(t, err)
+
+		appIDs = append(appIDs, appID)
+	}
+
+	err = db.TransitionApplication(ctx, appIDs[1],
+		app.ApplicationSubmitted, app.ApplicationOnHold, "")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		alias  string
+		filter app.ApplicationFilter
+		expect []int
+	}{
+		{
+			alias:  "Default",
+			expect: []int{appIDs[2], appIDs[1], appIDs[0]},
+		},
+		{
+			alias:  "Oldest",
+			filter: app.ApplicationFilter{Sort: app.ApplicationSortOldest},
+			expect: []int{appIDs[0], appIDs[1], appIDs[2]},
+		},
+		{
+			alias:  "Applicant",
+			filter: app.ApplicationFilter{Sort: app.ApplicationSortApplicant},
+			expect: []int{appIDs[2], appIDs[1], appIDs[0]},
+		},
+		{
+			alias: "Status",
+			filter: app.ApplicationFilter{
+				Statuses: []app.ApplicationStatus{app.ApplicationOnHold},
+			},
+			expect: []int{appIDs[1]},
+		},
+		{
+			alias: "Dates",
+			filter: app.ApplicationFilter{
+				Since: null.TimeFrom(start.AddDate(0, 0, 1)),
+				Until: null.TimeFrom(start.AddDate(0, 0, 2)),
+			},
+			expect: []int{appIDs[1]},
+		},
+		{
+			alias: "NoMatch",
+			filter: app.ApplicationFilter{
+				Statuses: []app.ApplicationStatus{app.ApplicationApproved},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			apps, err := db.GetApplicationsForOrganization(ctx, orgID,
+				tc.filter)
+			require.NoError(t, err)
+
+			var ids []int
+			for _, a := range apps {
+				ids = append(ids, a.ID)
+			}
+			assert.Equal(t, tc.expect, ids)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// CreateAttachment records an attachment to an application, whose file must
+// already be in file storage. The uploader is taken from the audit actor.
+func (db *database) CreateAttachment(
+	ctx context.Context,
+	a app.Attachment,
+) (int, error) {
+
+	var id int
+	err := db.GetContext(ctx, &id, `
+		INSERT INTO attachment (
+			application_id,
+			storage_key,
+			file_name,
+			content_type,
+			size_bytes,
+			uploaded_by
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING attachment_id
+	`, a.ApplicationID, a.StorageKey, a.FileName, a.ContentType, a.Size,
+		app.AuditActorFromContext(ctx).PersonID)
+
+	return id, errors.Wrap(err, "failed to insert attachment")
+}
+
+// GetAttachmentByID fetches an attachment by its ID number.
+func (db *database) GetAttachmentByID(
+	ctx context.Context,
+	attachmentID int,
+) (app.Attachment, error) {
+
+	var a app.Attachment
+
+	err := db.GetContext(ctx, &a, `
+		SELECT
+			attachment_id,
+			application_id,
+			storage_key,
+			file_name,
+			content_type,
+			size_bytes,
+			created_at
+		FROM attachment
+		WHERE attachment_id = $1
+	`, attachmentID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return a, errors.Wrapf(
+			app.ErrNotFound,
+			"no such attachment by id of %d", attachmentID,
+		)
+	}
+
+	return a, errors.Wrap(err, "failed to get attachment by ID")
+}
+
+// GetAttachmentsForApplication fetches the attachments to an application,
+// oldest first.
+func (db *database) GetAttachmentsForApplication(
+	ctx context.Context,
+	appID int,
+) ([]app.Attachment, error) {
+
+	var attachments []app.Attachment
+
+	err := db.SelectContext(ctx, &attachments, `
+		SELECT
+			attachment_id,
+			application_id,
+			storage_key,
+			file_name,
+			content_type,
+			size_bytes,
+			created_at
+		FROM attachment
+		WHERE application_id = $1
+		ORDER BY created_at, attachment_id
+	`, appID)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err, "failed to select attachments")
+	}
+
+	return attachments, nil
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func TestAttachments(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	orgID, err := db.CreateOrganization(ctx, app.Organization{
+		Name:       "Trucking Co.",
+		PointValue: 1,
+	})
+	require.NoError(t, err)
+
+	driverID, err := db.CreatePerson(ctx, app.Person{
+		FirstName:    "Ben",
+		LastName:     "Godfrey",
+		Email:        "bfgodfr@clemson.edu",
+		Password:     `qwerty`,
+		Role:         app.RoleDriver,
+		Affiliations: make([]int, 0),
+	})
+	require.NoError(t, err)
+
+	appID, err := db.CreateApplication(ctx, app.Application{
+		ApplicantID:    driverID,
+		OrganizationID: orgID,
+		Comment:        "Please sponsor me.",
+	})
+	require.NoError(t, err)
+
+	driverCtx := app.ContextWithAuditActor(ctx, app.AuditActor{
+		PersonID: null.IntFrom(int64(driverID)),
+	})
+
+	var ids []int
+	for _, name := range []string{"license.pdf", "record.png"} {
+		id, err := db.CreateAttachment(driverCtx, app.Attachment{
+			ApplicationID: appID,
+			FileName:      name,
+			ContentType:   "application/pdf",
+			Size:          1234,
+			StorageKey:    "key-" + name,
+		})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	db.assertCountOf(t, "attachment", 2, "uploaded_by = $1", driverID)
+
+	a, err := db.GetAttachmentByID(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, appID, a.ApplicationID)
+	assert.Equal(t, "license.pdf", a.FileName)
+	assert.Equal(t, "key-license.pdf", a.StorageKey)
+	assert.Equal(t, int64(1234), a.Size)
+
+	attachments, err := db.GetAttachmentsForApplication(ctx, appID)
+	require.NoError(t, err)
+	require.Len(t, attachments, 2)
+	assert.Equal(t, ids[0], attachments[0].ID)
+	assert.Equal(t, ids[1], attachments[1].ID)
+
+	_, err = db.GetAttachmentByID(ctx, ids[1]+1)
+	assert.True(t, errors.Is(err, app.ErrNotFound))
+
+	// Storage keys are unique, since each names one file.
+	_, err = db.CreateAttachment(ctx, app.Attachment{
+		ApplicationID: appID,
+		FileName:      "copy.pdf",
+		ContentType:   "application/pdf",
+		Size:          1234,
+		StorageKey:    "key-license.pdf",
+	})
+	assert.Error(t, err)
+}
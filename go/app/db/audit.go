@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// auditSnapshot captures the single row selected by a query as JSON, for use
+// as the before or after state of an audit event. The query should select a
+// single jsonb column, and should lock the row when capturing the before state.
+//
+// Returns nil when no row was selected.
+func auditSnapshot(
+	ctx context.Context,
+	tx sqlx.QueryerContext,
+	query string,
+	args ...interface{},
+) (json.RawMessage, error) {
+
+	var snapshot []byte
+
+	err := sqlx.GetContext(ctx, tx, &snapshot, query, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to capture audit snapshot")
+	}
+
+	return snapshot, nil
+}
+
+// recordAuditEvent inserts an audit event, attributing it to the actor
+// attached to the context. It must be called in the same transaction as the
+// change that it describes.
+func recordAuditEvent(
+	ctx context.Context,
+	tx sqlx.ExecerContext,
+	e app.AuditEvent,
+) error {
+
+	actor := app.AuditActorFromContext(ctx)
+
+	// A nil RawMessage is stored as SQL NULL rather than the JSON null value.
+	var before, after []byte
+	if e.Before != nil {
+		before = e.Before
+	}
+	if e.After != nil {
+		after = e.After
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO audit_event (
+			actor_id,
+			impersonator_id,
+			request_id,
+			action,
+			target_type,
+			target_id,
+			organization_id,
+			reason,
+			before,
+			after
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+
+		actor.PersonID,       // $1
+		actor.ImpersonatorID, // $2
+		actor.RequestID,      // $3
+		e.Action,             // $4
+		e.TargetType,         // $5
+		e.TargetID,           // $6
+		e.OrganizationID,     // $7
+		e.Reason,             // $8
+		before,               // $9
+		after,                // $10
+	)
+
+	return errors.Wrapf(err, "failed to record audit event %s", e.Action)
+}
+
+// GetAuditEvents fetches the audit events matching a filter, newest first.
+func (db *database) GetAuditEvents(
+	ctx context.Context,
+	f app.AuditFilter,
+) ([]app.AuditEvent, error) {
+
+	var events []app.AuditEvent
+
+	err := db.SelectContext(ctx, &events, `
+		SELECT
+			audit_event_id,
+			created_at,
+			actor_id,
+			impersonator_id,
+			request_id,
+			action,
+			target_type,
+			target_id,
+			organization_id,
+			reason,
+			COALESCE(before, 'null') AS before,
+			COALESCE(after, 'null') AS after
+		FROM audit_event
+		WHERE
+			($1::int IS NULL OR actor_id = $1)
+			AND ($2 = '' OR action = $2)
+			AND ($3 = '' OR target_type = $3)
+			AND ($4::int IS NULL OR target_id = $4)
+			AND ($5::int IS NULL OR organization_id = $5)
+			AND ($6::timestamptz IS NULL OR created_at >= $6)
+			AND ($7::timestamptz IS NULL OR created_at < $7)
+			AND ($8::int IS NULL OR audit_event_id < $8)
+		ORDER BY audit_event_id DESC
+		LIMIT NULLIF($9, 0)`,
+
+		f.ActorID,        // $1
+		f.Action,         // $2
+		f.TargetType,     // $3
+		f.TargetID,       // $4
+		f.OrganizationID, // $5
+		f.Since,          // $6
+		f.Until,          // $7
+		f.BeforeID,       // $8
+		f.Limit,          // $9
+	)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err, "failed to select audit events")
+	}
+
+	return events, nil
+}
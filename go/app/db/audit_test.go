@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func TestAuditEvents(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	actor := app.AuditActor{
+		PersonID:       null.IntFrom(2),
+		ImpersonatorID: null.IntFrom(1),
+		RequestID:      null.StringFrom("request"),
+	}
+	ctx := app.ContextWithAuditActor(context.Background(), actor)
+
+	p := app.Person{
+		FirstName:    "Ben",
+		LastName:     "Godfrey",
+		Email:        "bfgodfr@clemson.edu",
+		Password:     `qwerty`,
+		Role:         app.RoleDriver,
+		Affiliations: make([]int, 0),
+	}
+	personID, err := db.CreatePerson(ctx, p)
+	require.NoError(t, err)
+
+	orgID, err := db.CreateOrganization(ctx, app.Organization{
+		Name:       "Trucking Co.",
+		PointValue: 1,
+	})
+	require.NoError(t, err)
+
+	err = db.DeactivatePerson(ctx, personID, "Left the company.")
+	require.NoError(t, err)
+
+	err = db.UpdateOrganization(ctx, app.Organization{
+		ID:         orgID,
+		Name:       "Trucking Inc.",
+		PointValue: 2,
+	})
+	require.NoError(t, err)
+
+	db.assertCount(t, "audit_event", 3)
+
+	t.Run("FailedChangeNotRecorded", func(t *testing.T) {
+		err := db.DeactivatePerson(ctx, 111, "")
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+
+		db.assertCount(t, "audit_event", 3)
+	})
+
+	t.Run("AppendOnly", func(t *testing.T) {
+		_, err := db.Exec(`UPDATE audit_event SET action = 'forged'`)
+		assert.Error(t, err)
+
+		_, err = db.Exec(`DELETE FROM audit_event`)
+		assert.Error(t, err)
+
+		db.assertCount(t, "audit_event", 3)
+	})
+
+	t.Run("All", func(t *testing.T) {
+		events, err := db.GetAuditEvents(ctx, app.AuditFilter{})
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+
+		// Events are returned newest first.
+		assert.Equal(t, app.AuditActionOrganizationUpdate, events[0].Action)
+		assert.Equal(t, app.AuditActionPersonDeactivate, events[1].Action)
+		assert.Equal(t, app.AuditActionOrganizationCreate, events[2].Action)
+
+		for _, e := range events {
+			assert.Equal(t, actor.PersonID, e.ActorID)
+			assert.Equal(t, actor.ImpersonatorID, e.ImpersonatorID)
+			assert.Equal(t, actor.RequestID, e.RequestID)
+		}
+	})
+
+	t.Run("BeforeAndAfter", func(t *testing.T) {
+		events, err := db.GetAuditEvents(ctx, app.AuditFilter{
+			TargetType: app.AuditTargetPerson,
+			TargetID:   null.IntFrom(int64(personID)),
+		})
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+
+		var before, after map[string]interface{}
+		require.NoError(t, json.Unmarshal(events[0].Before, &before))
+		require.NoError(t, json.Unmarshal(events[0].After, &after))
+
+		assert.Equal(t, null.StringFrom("Left the company."), events[0].Reason)
+		assert.Equal(t, false, before["is_deactivated"])
+		assert.Equal(t, true, after["is_deactivated"])
+		assert.NotContains(t, before, "pass_hash")
+		assert.NotContains(t, after, "pass_hash")
+	})
+
+	t.Run("CreatedHasNoBefore", func(t *testing.T) {
+		events, err := db.GetAuditEvents(ctx, app.AuditFilter{
+			Action: app.AuditActionOrganizationCreate,
+		})
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+
+		assert.Equal(t, null.IntFrom(int64(orgID)), events[0].OrganizationID)
+		assert.Equal(t, json.RawMessage("null"), events[0].Before)
+		assert.NotEqual(t, json.RawMessage("null"), events[0].After)
+	})
+
+	t.Run("Paging", func(t *testing.T) {
+		first, err := db.GetAuditEvents(ctx, app.AuditFilter{Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, first, 2)
+
+		rest, err := db.GetAuditEvents(ctx, app.AuditFilter{
+			BeforeID: null.IntFrom(int64(first[1].ID)),
+		})
+		require.NoError(t, err)
+		require.Len(t, rest, 1)
+		assert.Equal(t, app.AuditActionOrganizationCreate, rest[0].Action)
+	})
+
+	t.Run("ByOrganization", func(t *testing.T) {
+		events, err := db.GetAuditEvents(ctx, app.AuditFilter{
+			OrganizationID: null.IntFrom(int64(orgID)),
+		})
+		require.NoError(t, err)
+		assert.Len(t, events, 2)
+	})
+}
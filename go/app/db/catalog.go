@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"strings"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
 
 	"github.com/BenJetson/CPSC491-project/go/app"
 )
@@ -157,31 +159,64 @@ func (db *database) AddProduct(
 ) (int, error) {
 
 	var id int
-	err := db.GetContext(ctx, &id, `
-		INSERT INTO product (
-			vendor_id,
-			organization_id,
-			title,
-			description,
-			image_url,
-			price
-		) VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (vendor_id, organization_id)
-		DO UPDATE SET
-			title = $3,
-			description = $4,
-			image_url = $5,
-			price = $6,
-			is_available = TRUE
-		RETURNING product_id `,
-
-		p.VendorID,       // $1
-		p.OrganizationID, // $2
-		p.Title,          // $3
-		p.Description,    // $4
-		p.ImageURL,       // $5
-		p.Price,          // $6
-	)
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		// Products that were previously removed are restored.
+		before, err := auditSnapshot(ctx, tx, `
+			SELECT to_jsonb(p)
+			FROM product p
+			WHERE
+				p.vendor_id = $1
+				AND p.organization_id = $2
+			FOR UPDATE
+		`, p.VendorID, p.OrganizationID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.GetContext(ctx, &id, `
+			INSERT INTO product (
+				vendor_id,
+				organization_id,
+				title,
+				description,
+				image_url,
+				price
+			) VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (vendor_id, organization_id)
+			DO UPDATE SET
+				title = $3,
+				description = $4,
+				image_url = $5,
+				price = $6,
+				is_available = TRUE
+			RETURNING product_id `,
+
+			p.VendorID,       // $1
+			p.OrganizationID, // $2
+			p.Title,          // $3
+			p.Description,    // $4
+			p.ImageURL,       // $5
+			p.Price,          // $6
+		)
+		if err != nil {
+			return err
+		}
+
+		after, err := auditSnapshot(ctx, tx, productAuditSnapshotQuery,
+			id, p.OrganizationID)
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, tx, app.AuditEvent{
+			Action:         app.AuditActionProductAdd,
+			TargetType:     app.AuditTargetProduct,
+			TargetID:       id,
+			OrganizationID: null.IntFrom(int64(p.OrganizationID)),
+			Before:         before,
+			After:          after,
+		})
+	})
 
 	return id, errors.Wrap(err, "failed to insert product")
 }
@@ -191,28 +226,56 @@ func (db *database) MakeProductUnavailable(
 	productID, orgID int,
 ) error {
 
-	result, err := db.ExecContext(ctx, `
-		UPDATE product SET
-			is_available = FALSE
-		WHERE
-			product_id = $1
-			AND organization_id = $2
-	`, productID, orgID)
-
-	if err != nil {
-		return errors.Wrap(err, "failed to flag product as unavailable")
-	}
-
-	n, err := result.RowsAffected()
-	if err != nil {
-		return errors.Wrap(err,
-			"failed to check result of product availability update")
-	} else if n != 1 {
-		return errors.Wrapf(
-			app.ErrNotFound,
-			"no such product by id of %d in organization %d", productID, orgID,
-		)
-	}
-
-	return nil
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		before, err := auditSnapshot(ctx, tx, productAuditSnapshotQuery,
+			productID, orgID)
+		if err != nil {
+			return err
+		} else if before == nil {
+			return errors.Wrapf(
+				app.ErrNotFound,
+				"no such product by id of %d in organization %d",
+				productID, orgID,
+			)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE product SET
+				is_available = FALSE
+			WHERE
+				product_id = $1
+				AND organization_id = $2
+		`, productID, orgID)
+		if err != nil {
+			return err
+		}
+
+		after, err := auditSnapshot(ctx, tx, productAuditSnapshotQuery,
+			productID, orgID)
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, tx, app.AuditEvent{
+			Action:         app.AuditActionProductRemove,
+			TargetType:     app.AuditTargetProduct,
+			TargetID:       productID,
+			OrganizationID: null.IntFrom(int64(orgID)),
+			Before:         before,
+			After:          after,
+		})
+	})
+
+	return errors.Wrap(err, "failed to flag product as unavailable")
 }
+
+// productAuditSnapshotQuery captures a product of an organization for the
+// audit log.
+const productAuditSnapshotQuery = `
+	SELECT to_jsonb(p)
+	FROM product p
+	WHERE
+		p.product_id = $1
+		AND p.organization_id = $2
+	FOR UPDATE
+`
@@ -0,0 +1,408 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// invitationAuditSnapshotQuery captures an invitation for the audit log,
+// leaving out the hash of its token.
+const invitationAuditSnapshotQuery = `
+	SELECT to_jsonb(i) - 'token_hash'
+	FROM invitation i
+	WHERE i.invitation_id = $1
+	FOR UPDATE
+`
+
+// CreateInvitation records an invitation to join an organization, revoking
+// any earlier invitations to the same email address, ignoring case, that are
+// still pending.
+// Returns the ID of the new invitation.
+func (db *database) CreateInvitation(
+	ctx context.Context,
+	inv app.Invitation,
+) (int, error) {
+
+	var id int
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE invitation SET
+				status = $1
+			WHERE
+				organization_id = $2
+				AND lower(email) = lower($3)
+				AND status = $4
+		`, app.InvitationRevoked, inv.OrganizationID, inv.Email,
+			app.InvitationPending)
+		if err != nil {
+			return errors.Wrap(err, "failed to revoke earlier invitations")
+		}
+
+		err = tx.GetContext(ctx, &id, `
+			INSERT INTO invitation (
+				token_hash,
+				organization_id,
+				email,
+				role_id,
+				invited_by,
+				created_at,
+				expires_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING invitation_id
+		`,
+			inv.Token.Hash(),   // $1
+			inv.OrganizationID, // $2
+			inv.Email,          // $3
+			inv.Role,           // $4
+			inv.InvitedBy,      // $5
+			inv.CreatedAt,      // $6
+			inv.ExpiresAt,      // $7
+		)
+		if err != nil {
+			return errors.Wrap(err, "failed to insert invitation")
+		}
+
+		after, err := auditSnapshot(ctx, tx, invitationAuditSnapshotQuery, id)
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, tx, app.AuditEvent{
+			Action:         app.AuditActionInvitationCreate,
+			TargetType:     app.AuditTargetInvitation,
+			TargetID:       id,
+			OrganizationID: null.IntFrom(int64(inv.OrganizationID)),
+			After:          after,
+		})
+	})
+
+	return id, errors.Wrap(err, "failed to create invitation")
+}
+
+// GetInvitationsForOrganization fetches the invitations to an organization,
+// newest first.
+func (db *database) GetInvitationsForOrganization(
+	ctx context.Context,
+	orgID int,
+) ([]app.Invitation, error) {
+
+	var invitations []app.Invitation
+
+	err := db.SelectContext(ctx, &invitations, `
+		SELECT
+			i.invitation_id,
+			i.organization_id,
+			o.name AS organization_name,
+			i.email,
+			i.role_id,
+			i.invited_by,
+			CASE
+				WHEN i.status = $2 AND i.expires_at <= now() THEN $3
+				ELSE i.status
+			END AS status,
+			i.created_at,
+			i.expires_at,
+			i.accepted_at,
+			i.accepted_by
+		FROM invitation i
+		JOIN organization o
+			ON i.organization_id = o.organization_id
+		WHERE i.organization_id = $1
+		ORDER BY i.created_at DESC, i.invitation_id DESC
+	`, orgID, app.InvitationPending, app.InvitationExpired)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err, "failed to select invitations")
+	}
+
+	return invitations, nil
+}
+
+// GetInvitationByToken fetches the invitation with a matching token.
+func (db *database) GetInvitationByToken(
+	ctx context.Context,
+	token app.SecureToken,
+) (app.Invitation, error) {
+
+	var inv app.Invitation
+
+	err := db.GetContext(ctx, &inv, `
+		SELECT
+			i.invitation_id,
+			i.organization_id,
+			o.name AS organization_name,
+			i.email,
+			i.role_id,
+			i.invited_by,
+			CASE
+				WHEN i.status = $2 AND i.expires_at <= now() THEN $3
+				ELSE i.status
+			END AS status,
+			i.created_at,
+			i.expires_at,
+			i.accepted_at,
+			i.accepted_by
+		FROM invitation i
+		JOIN organization o
+			ON i.organization_id = o.organization_id
+		WHERE i.token_hash = $1
+	`, token.Hash(), app.InvitationPending, app.InvitationExpired)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return inv, errors.Wrap(app.ErrNotFound, "no such invitation by token")
+	} else if err != nil {
+		return inv, errors.Wrap(err, "failed to get invitation")
+	}
+
+	return inv, nil
+}
+
+// RevokeInvitation revokes a pending invitation to an organization, so that it
+// may no longer be accepted. Invitations to other organizations, and those
+// that are no longer pending, are not found.
+func (db *database) RevokeInvitation(
+	ctx context.Context,
+	orgID, invitationID int,
+) error {
+
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		var status app.InvitationStatus
+		err := tx.GetContext(ctx, &status, `
+			SELECT status
+			FROM invitation
+			WHERE
+				invitation_id = $1
+				AND organization_id = $2
+			FOR UPDATE
+		`, invitationID, orgID)
+
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return errors.Wrap(err, "failed to lock invitation")
+		} else if err != nil || status != app.InvitationPending {
+			return errors.Wrapf(
+				app.ErrNotFound,
+				"no pending invitation by id of %d in organization %d",
+				invitationID, orgID,
+			)
+		}
+
+		before, err := auditSnapshot(ctx, tx, invitationAuditSnapshotQuery,
+			invitationID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE invitation SET
+				status = $1
+			WHERE invitation_id = $2
+		`, app.InvitationRevoked, invitationID)
+		if err != nil {
+			return errors.Wrap(err, "failed to update invitation")
+		}
+
+		after, err := auditSnapshot(ctx, tx, invitationAuditSnapshotQuery,
+			invitationID)
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, tx, app.AuditEvent{
+			Action:         app.AuditActionInvitationRevoke,
+			TargetType:     app.AuditTargetInvitation,
+			TargetID:       invitationID,
+			OrganizationID: null.IntFrom(int64(orgID)),
+			Before:         before,
+			After:          after,
+		})
+	})
+
+	return errors.Wrap(err, "failed to revoke invitation")
+}
+
+// joinInvitedPerson brings the person with an invited email address, ignoring
+// case, into the role of their invitation, creating them from the newcomer if
+// they do not yet exist. Returns their ID.
+//
+// Existing people keep their role, except that users invited as sponsors
+// become sponsors. Admins, deactivated people, and those whose role differs
+// from their invitation cannot accept it.
+func joinInvitedPerson(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	inv app.Invitation,
+	newcomer app.Person,
+) (int, error) {
+
+	var existing struct {
+		ID            int      `db:"person_id"`
+		Role          app.Role `db:"role_id"`
+		IsDeactivated bool     `db:"is_deactivated"`
+	}
+
+	err := tx.GetContext(ctx, &existing, `
+		SELECT
+			person_id,
+			role_id,
+			is_deactivated
+		FROM person
+		WHERE lower(email) = lower($1)
+		ORDER BY person_id
+		LIMIT 1
+		FOR UPDATE
+	`, inv.Email)
+
+	if errors.Is(err, sql.ErrNoRows) && len(newcomer.Password) < 1 {
+		return 0, errors.Wrapf(app.ErrConflict,
+			"no account for invitee %s, and no password to create one",
+			inv.Email)
+	} else if errors.Is(err, sql.ErrNoRows) {
+		var id int
+		err = tx.GetContext(ctx, &id, `
+			INSERT INTO person (
+				first_name,
+				last_name,
+				email,
+				role_id,
+				pass_hash
+			) VALUES ($1, $2, $3, $4, $5)
+			RETURNING person_id
+		`, newcomer.FirstName, newcomer.LastName, inv.Email, inv.Role,
+			newcomer.Password)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to insert person")
+		}
+
+		after, err := auditSnapshot(ctx, tx, personAuditSnapshotQuery, id)
+		if err != nil {
+			return 0, err
+		}
+
+		return id, recordAuditEvent(ctx, tx, app.AuditEvent{
+			Action:     app.AuditActionPersonCreate,
+			TargetType: app.AuditTargetPerson,
+			TargetID:   id,
+			After:      after,
+		})
+	} else if err != nil {
+		return 0, errors.Wrap(err, "failed to lock invitee")
+	}
+
+	if existing.IsDeactivated {
+		return 0, errors.Wrapf(app.ErrConflict,
+			"invitee %d is deactivated", existing.ID)
+	}
+
+	switch {
+	case existing.Role == inv.Role:
+		return existing.ID, nil
+	case existing.Role == app.RoleUser && inv.Role == app.RoleDriver:
+		// Users become drivers upon their first affiliation.
+		return existing.ID, nil
+	case existing.Role == app.RoleUser && inv.Role == app.RoleSponsor:
+		return existing.ID,
+			setPersonRole(ctx, tx, existing.ID, app.RoleSponsor)
+	}
+
+	return 0, errors.Wrapf(app.ErrConflict,
+		"invitee %d has role %d, but was invited as %d",
+		existing.ID, existing.Role, inv.Role)
+}
+
+// AcceptInvitation accepts a valid invitation and affiliates its invitee with
+// the organization, creating their account from the newcomer if they do not
+// have one. Only the name and password of the newcomer are used. Returns the
+// ID of the invitee.
+//
+// Invitations that were already accepted, revoked, or have expired are not
+// found. Invitees who may not join in the invited role are in conflict.
+func (db *database) AcceptInvitation(
+	ctx context.Context,
+	invitationID int,
+	newcomer app.Person,
+) (int, error) {
+
+	var personID int
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		var inv app.Invitation
+		err := tx.GetContext(ctx, &inv, `
+			SELECT
+				invitation_id,
+				organization_id,
+				email,
+				role_id
+			FROM invitation
+			WHERE
+				invitation_id = $1
+				AND status = $2
+				AND expires_at > now()
+			FOR UPDATE
+		`, invitationID, app.InvitationPending)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.Wrapf(
+				app.ErrNotFound,
+				"no valid invitation by id of %d", invitationID,
+			)
+		} else if err != nil {
+			return errors.Wrap(err, "failed to lock invitation")
+		}
+
+		personID, err = joinInvitedPerson(ctx, tx, inv, newcomer)
+		if err != nil {
+			return err
+		}
+
+		err = addAffiliation(ctx, tx, personID, inv.OrganizationID)
+		if err != nil {
+			return err
+		}
+
+		before, err := auditSnapshot(ctx, tx, invitationAuditSnapshotQuery,
+			inv.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE invitation SET
+				status = $1,
+				accepted_at = now(),
+				accepted_by = $2
+			WHERE invitation_id = $3
+		`, app.InvitationAccepted, personID, inv.ID)
+		if err != nil {
+			return errors.Wrap(err, "failed to update invitation")
+		}
+
+		after, err := auditSnapshot(ctx, tx, invitationAuditSnapshotQuery,
+			inv.ID)
+		if err != nil {
+			return err
+		}
+
+		err = recordAuditEvent(ctx, tx, app.AuditEvent{
+			Action:         app.AuditActionInvitationAccept,
+			TargetType:     app.AuditTargetInvitation,
+			TargetID:       inv.ID,
+			OrganizationID: null.IntFrom(int64(inv.OrganizationID)),
+			Before:         before,
+			After:          after,
+		})
+		if err != nil {
+			return err
+		}
+
+		return notifySponsorsOfOrganization(ctx, tx, inv.OrganizationID,
+			personID, app.NotificationInvitationAccepted,
+			"%s %s accepted an invitation to join your organization.")
+	})
+
+	return personID, errors.Wrap(err, "failed to accept invitation")
+}
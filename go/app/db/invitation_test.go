@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func TestInvitations(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	orgID, err := db.CreateOrganization(ctx, app.Organization{
+		Name:       "Trucking Co.",
+		PointValue: 1,
+	})
+	require.NoError(t, err)
+
+	sponsorID, err := db.CreatePerson(ctx, app.Person{
+		FirstName: "Roger",
+		LastName:  "Van Scoy",
+		Email:     "vanscoy@clemson.edu",
+		Password:  `qwerty`,
+		Role:      app.RoleSponsor,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AddPersonAffiliation(ctx, sponsorID, orgID))
+
+	invite := func(email string, role app.Role) *app.Invitation {
+		inv, err := app.NewInvitation(orgID, email, role,
+			null.IntFrom(int64(sponsorID)), time.Hour)
+		require.NoError(t, err)
+
+		inv.ID, err = db.CreateInvitation(ctx, *inv)
+		require.NoError(t, err)
+		return inv
+	}
+
+	newcomer := app.Person{
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Password:  `hash`,
+	}
+
+	t.Run("NewDriver", func(t *testing.T) {
+		first := invite("jdoe@clemson.edu", app.RoleDriver)
+		second := invite("jdoe@clemson.edu", app.RoleDriver)
+
+		// Inviting the same person again replaces the first invitation.
+		inv, err := db.GetInvitationByToken(ctx, first.Token)
+		require.NoError(t, err)
+		assert.Equal(t, app.InvitationRevoked, inv.Status)
+		assert.Equal(t, "Trucking Co.", inv.OrganizationName)
+
+		_, err = db.AcceptInvitation(ctx, first.ID, newcomer)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+
+		personID, err := db.AcceptInvitation(ctx, second.ID, newcomer)
+		require.NoError(t, err)
+
+		db.assertCountOf(t, "person", 1, `
+			person_id = $1
+			AND email = $2
+			AND role_id = $3
+			AND pass_hash = 'hash'
+		`, personID, "jdoe@clemson.edu", app.RoleDriver)
+		db.assertCountOf(t, "affiliation", 1, `
+			person_id = $1
+			AND organization_id = $2
+			AND points = 0
+		`, personID, orgID)
+		db.assertCountOf(t, "invitation", 1, `
+			invitation_id = $1
+			AND status = $2
+			AND accepted_by = $3
+		`, second.ID, app.InvitationAccepted, personID)
+		db.assertCountOf(t, "notification", 1, `
+			person_id = $1
+			AND kind = $2
+		`, sponsorID, app.NotificationInvitationAccepted)
+
+		// Invitations may only be accepted once.
+		_, err = db.AcceptInvitation(ctx, second.ID, newcomer)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+
+	t.Run("ExistingUserAsSponsor", func(t *testing.T) {
+		userID, err := db.CreatePerson(ctx, app.Person{
+			FirstName: "Ben",
+			LastName:  "Godfrey",
+			Email:     "bfgodfr@clemson.edu",
+			Password:  `qwerty`,
+			Role:      app.RoleUser,
+		})
+		require.NoError(t, err)
+
+		inv := invite("bfgodfr@clemson.edu", app.RoleSponsor)
+
+		personID, err := db.AcceptInvitation(ctx, inv.ID, app.Person{})
+		require.NoError(t, err)
+		assert.Equal(t, userID, personID)
+
+		db.assertCountOf(t, "person", 1, `
+			person_id = $1
+			AND role_id = $2
+			AND pass_hash = 'qwerty'
+		`, userID, app.RoleSponsor)
+		db.assertCountOf(t, "affiliation", 1, `
+			person_id = $1
+			AND organization_id = $2
+			AND points IS NULL
+		`, userID, orgID)
+	})
+
+	t.Run("ExistingEmailIgnoresCase", func(t *testing.T) {
+		userID, err := db.CreatePerson(ctx, app.Person{
+			FirstName: "John",
+			LastName:  "Smith",
+			Email:     "JSmith@Clemson.edu",
+			Password:  `qwerty`,
+			Role:      app.RoleUser,
+		})
+		require.NoError(t, err)
+
+		inv := invite("jsmith@clemson.edu", app.RoleDriver)
+
+		personID, err := db.AcceptInvitation(ctx, inv.ID, newcomer)
+		require.NoError(t, err)
+		assert.Equal(t, userID, personID)
+
+		db.assertCountOf(t, "person", 1, `
+			lower(email) = 'jsmith@clemson.edu'
+		`)
+	})
+
+	t.Run("RoleConflict", func(t *testing.T) {
+		inv := invite("vanscoy@clemson.edu", app.RoleDriver)
+
+		_, err := db.AcceptInvitation(ctx, inv.ID, app.Person{})
+		assert.True(t, errors.Is(err, app.ErrConflict))
+
+		db.assertCountOf(t, "invitation", 1, `
+			invitation_id = $1
+			AND status = $2
+		`, inv.ID, app.InvitationPending)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		inv := invite("other@clemson.edu", app.RoleDriver)
+
+		err := db.RevokeInvitation(ctx, orgID+1, inv.ID)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+
+		require.NoError(t, db.RevokeInvitation(ctx, orgID, inv.ID))
+
+		err = db.RevokeInvitation(ctx, orgID, inv.ID)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+
+		db.assertCountOf(t, "audit_event", 1, `
+			action = $1
+			AND target_id = $2
+		`, app.AuditActionInvitationRevoke, inv.ID)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		inv, err := app.NewInvitation(orgID, "late@clemson.edu",
+			app.RoleDriver, null.Int{}, -time.Hour)
+		require.NoError(t, err)
+
+		inv.ID, err = db.CreateInvitation(ctx, *inv)
+		require.NoError(t, err)
+
+		got, err := db.GetInvitationByToken(ctx, inv.Token)
+		require.NoError(t, err)
+		assert.Equal(t, app.InvitationExpired, got.Status)
+
+		_, err = db.AcceptInvitation(ctx, inv.ID, newcomer)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+
+	t.Run("List", func(t *testing.T) {
+		invitations, err := db.GetInvitationsForOrganization(ctx, orgID)
+		require.NoError(t, err)
+		assert.Len(t, invitations, 7)
+
+		invitations, err = db.GetInvitationsForOrganization(ctx, orgID+1)
+		require.NoError(t, err)
+		assert.Empty(t, invitations)
+	})
+
+	t.Run("NoSuchToken", func(t *testing.T) {
+		_, err := db.GetInvitationByToken(ctx, app.SecureToken{})
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// Login attempts are serialized per email address and per IP address using
+// transaction-level advisory locks. These classes keep the two kinds of keys
+// from colliding with one another.
+const (
+	loginAttemptEmailLockClass = 1
+	loginAttemptIPLockClass    = 2
+)
+
+// StartLoginAttempt checks the recent failed login attempts for both the
+// account and the client IP address of a login attempt against the given
+// policies. When neither calls for a wait, the attempt is recorded as failed,
+// ignoring its ID and Succeeded fields, until SucceedLoginAttempt is called.
+//
+// Attempts for the same email or IP address are serialized, so that each one
+// counts all of those that came before it, even when they are concurrent.
+//
+// Returns the ID of the recorded attempt, or how long the client must wait
+// when the attempt was refused and nothing was recorded.
+func (db *database) StartLoginAttempt(
+	ctx context.Context,
+	a app.LoginAttempt,
+	accountPolicy, ipPolicy app.LockoutPolicy,
+) (attemptID int, wait time.Duration, err error) {
+
+	err = db.Transact(func(tx *sqlx.Tx) error {
+		// Always lock the email before the IP address, to avoid deadlocks.
+		_, err := tx.ExecContext(ctx, `
+			SELECT
+				pg_advisory_xact_lock($1, hashtext($2)),
+				pg_advisory_xact_lock($3, hashtext($4))
+		`, loginAttemptEmailLockClass, a.Email,
+			loginAttemptIPLockClass, a.IPAddress)
+		if err != nil {
+			return errors.Wrap(err, "failed to lock login attempts")
+		}
+
+		accountFailures, err := getLoginFailuresForEmail(ctx, tx, a.Email,
+			a.AttemptedAt.Add(-accountPolicy.Window))
+		if err != nil {
+			return err
+		}
+
+		ipFailures, err := getLoginFailuresForIP(ctx, tx, a.IPAddress,
+			a.AttemptedAt.Add(-ipPolicy.Window))
+		if err != nil {
+			return err
+		}
+
+		wait = accountPolicy.RetryAfter(accountFailures, a.AttemptedAt)
+		ipWait := ipPolicy.RetryAfter(ipFailures, a.AttemptedAt)
+		if ipWait > wait {
+			wait = ipWait
+		}
+
+		if wait > 0 {
+			return nil
+		}
+
+		err = tx.GetContext(ctx, &attemptID, `
+			INSERT INTO login_attempt (
+				email,
+				ip_address,
+				succeeded,
+				attempted_at
+			) VALUES ($1, $2, FALSE, $3)
+			RETURNING login_attempt_id
+		`, a.Email, a.IPAddress, a.AttemptedAt)
+
+		return errors.Wrap(err, "failed to insert login attempt")
+	})
+
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to start login attempt")
+	}
+	return attemptID, wait, nil
+}
+
+// SucceedLoginAttempt marks a login attempt recorded by StartLoginAttempt as
+// having succeeded, so that it no longer counts as a failure.
+func (db *database) SucceedLoginAttempt(
+	ctx context.Context,
+	attemptID int,
+) error {
+
+	result, err := db.ExecContext(ctx, `
+		UPDATE login_attempt SET
+			succeeded = TRUE
+		WHERE login_attempt_id = $1
+	`, attemptID)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to update login attempt")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err,
+			"failed to check result of login attempt update")
+	} else if n != 1 {
+		return errors.Wrapf(
+			app.ErrNotFound,
+			"no such login attempt by id of %d", attemptID,
+		)
+	}
+
+	return nil
+}
+
+// getLoginFailuresForEmail summarizes the failed login attempts for an email
+// address since the given time, not counting those that have been cleared.
+func getLoginFailuresForEmail(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	email string,
+	since time.Time,
+) (app.LoginFailures, error) {
+
+	var f app.LoginFailures
+
+	err := tx.GetContext(ctx, &f, `
+		SELECT
+			COUNT(*) AS count,
+			MAX(attempted_at) AS last_failed_at
+		FROM login_attempt
+		WHERE
+			email = $1
+			AND succeeded = FALSE
+			AND is_cleared = FALSE
+			AND attempted_at >= $2
+	`, email, since)
+
+	return f, errors.Wrap(err, "failed to get login failures for email")
+}
+
+// getLoginFailuresForIP summarizes the failed login attempts from an IP
+// address since the given time.
+//
+// Clearing the failures for an email address does not affect this count, so
+// that a client cannot reset its own limit by logging in to an account it
+// controls.
+func getLoginFailuresForIP(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	ipAddress string,
+	since time.Time,
+) (app.LoginFailures, error) {
+
+	var f app.LoginFailures
+
+	err := tx.GetContext(ctx, &f, `
+		SELECT
+			COUNT(*) AS count,
+			MAX(attempted_at) AS last_failed_at
+		FROM login_attempt
+		WHERE
+			ip_address = $1
+			AND succeeded = FALSE
+			AND attempted_at >= $2
+	`, ipAddress, since)
+
+	return f, errors.Wrap(err, "failed to get login failures for ip address")
+}
+
+// ClearLoginFailuresForEmail clears all outstanding failed login attempts for
+// an email address, lifting any delay or lockout on that account. The
+// failures still count against the IP addresses they came from.
+func (db *database) ClearLoginFailuresForEmail(
+	ctx context.Context,
+	email string,
+) error {
+
+	_, err := db.ExecContext(ctx, `
+		UPDATE login_attempt SET
+			is_cleared = TRUE
+		WHERE
+			email = $1
+			AND succeeded = FALSE
+			AND is_cleared = FALSE
+	`, email)
+
+	return errors.Wrap(err, "failed to clear login failures for email")
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func TestLoginFailures(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	now := time.Now().UTC().Round(time.Second)
+
+	attempts := []app.LoginAttempt{
+		{
+			Email:       "bfgodfr@clemson.edu",
+			IPAddress:   "192.0.2.1",
+			AttemptedAt: now.Add(-2 * time.Hour), // outside window
+		},
+		{
+			Email:       "bfgodfr@clemson.edu",
+			IPAddress:   "192.0.2.1",
+			AttemptedAt: now.Add(-time.Minute),
+		},
+		{
+			Email:       "bfgodfr@clemson.edu",
+			IPAddress:   "192.0.2.2",
+			AttemptedAt: now,
+		},
+		{
+			Email:       "vanscoy@clemson.edu",
+			IPAddress:   "192.0.2.1",
+			AttemptedAt: now.Add(-30 * time.Second),
+		},
+		{
+			Email:       "vanscoy@clemson.edu",
+			IPAddress:   "192.0.2.1",
+			Succeeded:   true,
+			AttemptedAt: now,
+		},
+	}
+
+	// An open policy never refuses an attempt.
+	open := app.LockoutPolicy{Window: time.Hour}
+	lockAfter := func(failures int) app.LockoutPolicy {
+		return app.LockoutPolicy{
+			Window:           time.Hour,
+			LockoutThreshold: failures,
+			LockoutDuration:  time.Hour,
+		}
+	}
+
+	for _, a := range attempts {
+		id, wait, err := db.StartLoginAttempt(ctx, a, open, open)
+		require.NoError(t, err)
+		require.Zero(t, wait)
+
+		if a.Succeeded {
+			require.NoError(t, db.SucceedLoginAttempt(ctx, id))
+		}
+	}
+
+	db.assertCount(t, "login_attempt", len(attempts))
+	db.assertCountOf(t, "login_attempt", 1, "succeeded")
+
+	start := func(
+		email, ipAddress string,
+		accountPolicy, ipPolicy app.LockoutPolicy,
+	) (int, time.Duration) {
+
+		id, wait, err := db.StartLoginAttempt(ctx, app.LoginAttempt{
+			Email:       email,
+			IPAddress:   ipAddress,
+			AttemptedAt: now,
+		}, accountPolicy, ipPolicy)
+		require.NoError(t, err)
+		return id, wait
+	}
+
+	t.Run("ForEmail", func(t *testing.T) {
+		// Two failures within the window, the last of them just now.
+		id, wait := start("bfgodfr@clemson.edu", "192.0.2.9",
+			lockAfter(2), open)
+		assert.Zero(t, id)
+		assert.Equal(t, time.Hour, wait)
+
+		db.assertCount(t, "login_attempt", len(attempts))
+	})
+
+	t.Run("ForIP", func(t *testing.T) {
+		// Two failures within the window, the last of them 30 seconds ago.
+		id, wait := start("nobody@clemson.edu", "192.0.2.1",
+			open, lockAfter(2))
+		assert.Zero(t, id)
+		assert.Equal(t, time.Hour-30*time.Second, wait)
+
+		db.assertCount(t, "login_attempt", len(attempts))
+	})
+
+	t.Run("Recorded", func(t *testing.T) {
+		id, wait := start("nobody@clemson.edu", "192.0.2.9",
+			lockAfter(1), lockAfter(1))
+		require.NotZero(t, id)
+		assert.Zero(t, wait)
+
+		// The attempt counts as a failure until it succeeds.
+		_, wait = start("nobody@clemson.edu", "192.0.2.10",
+			lockAfter(1), open)
+		assert.Equal(t, time.Hour, wait)
+
+		require.NoError(t, db.SucceedLoginAttempt(ctx, id))
+		db.assertCountOf(t, "login_attempt", 1, `
+			login_attempt_id = $1
+			AND succeeded
+		`, id)
+
+		err := db.SucceedLoginAttempt(ctx, 494942)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		err := db.ClearLoginFailuresForEmail(ctx, "bfgodfr@clemson.edu")
+		require.NoError(t, err)
+
+		id, wait := start("bfgodfr@clemson.edu", "192.0.2.9",
+			lockAfter(1), open)
+		assert.NotZero(t, id)
+		assert.Zero(t, wait)
+
+		// Clearing an account must not affect the count for its addresses.
+		_, wait = start("vanscoy@clemson.edu", "192.0.2.2",
+			open, lockAfter(1))
+		assert.Equal(t, time.Hour, wait)
+	})
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// notifySponsorsOfPerson notifies every sponsor who shares an organization
+// with a person. The message is a format string, which is given the person's
+// first and last name.
+//
+// It should be called in the same transaction as the change that it describes.
+func notifySponsorsOfPerson(
+	ctx context.Context,
+	tx sqlx.ExecerContext,
+	personID int,
+	kind app.NotificationKind,
+	message string,
+) error {
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO notification (
+			person_id,
+			kind,
+			message
+		)
+		SELECT DISTINCT
+			s.person_id,
+			$2,
+			format($3, p.first_name, p.last_name)
+		FROM affiliation a
+		JOIN person p ON p.person_id = a.person_id
+		JOIN affiliation sa ON sa.organization_id = a.organization_id
+		JOIN person s ON s.person_id = sa.person_id
+		WHERE
+			a.person_id = $1
+			AND s.person_id <> $1
+			AND s.role_id = $4
+			AND NOT s.is_deactivated
+	`, personID, kind, message, app.RoleSponsor)
+
+	return errors.Wrapf(err, "failed to notify sponsors of %s", kind)
+}
+
+// notifySponsorsOfOrganization notifies every sponsor of an organization about
+// a person. The message is a format string, which is given the person's first
+// and last name.
+//
+// It should be called in the same transaction as the change that it describes.
+func notifySponsorsOfOrganization(
+	ctx context.Context,
+	tx sqlx.ExecerContext,
+	orgID, personID int,
+	kind app.NotificationKind,
+	message string,
+) error {
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO notification (
+			person_id,
+			kind,
+			message
+		)
+		SELECT
+			s.person_id,
+			$3,
+			format($4, p.first_name, p.last_name)
+		FROM affiliation sa
+		JOIN person s ON s.person_id = sa.person_id
+		JOIN person p ON p.person_id = $2
+		WHERE
+			sa.organization_id = $1
+			AND s.person_id <> $2
+			AND s.role_id = $5
+			AND NOT s.is_deactivated
+	`, orgID, personID, kind, message, app.RoleSponsor)
+
+	return errors.Wrapf(err, "failed to notify sponsors of %s", kind)
+}
+
+// notifyDriversOfOrganization notifies every driver of an organization about
+// it. The message is a format string, which is given the organization's name.
+//
+// It should be called in the same transaction as the change that it describes.
+func notifyDriversOfOrganization(
+	ctx context.Context,
+	tx sqlx.ExecerContext,
+	orgID int,
+	kind app.NotificationKind,
+	message string,
+) error {
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO notification (
+			person_id,
+			kind,
+			message
+		)
+		SELECT
+			d.person_id,
+			$2,
+			format($3, o.name)
+		FROM affiliation a
+		JOIN person d ON d.person_id = a.person_id
+		JOIN organization o ON o.organization_id = a.organization_id
+		WHERE
+			a.organization_id = $1
+			AND d.role_id = $4
+			AND NOT d.is_deactivated
+	`, orgID, kind, message, app.RoleDriver)
+
+	return errors.Wrapf(err, "failed to notify drivers of %s", kind)
+}
+
+// notifyPerson notifies a single person.
+//
+// It should be called in the same transaction as the change that it describes.
+func notifyPerson(
+	ctx context.Context,
+	tx sqlx.ExecerContext,
+	personID int,
+	kind app.NotificationKind,
+	message string,
+) error {
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO notification (
+			person_id,
+			kind,
+			message
+		) VALUES ($1, $2, $3)
+	`, personID, kind, message)
+
+	return errors.Wrapf(err, "failed to notify person of %s", kind)
+}
+
+// GetNotificationsForPerson fetches the notifications of a person, newest
+// first.
+func (db *database) GetNotificationsForPerson(
+	ctx context.Context,
+	personID int,
+	unreadOnly bool,
+) ([]app.Notification, error) {
+
+	var notifications []app.Notification
+
+	err := db.SelectContext(ctx, &notifications, `
+		SELECT
+			notification_id,
+			person_id,
+			kind,
+			message,
+			created_at,
+			read_at
+		FROM notification
+		WHERE
+			person_id = $1
+			AND (NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC, notification_id DESC
+	`, personID, unreadOnly)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err, "failed to select notifications")
+	}
+
+	return notifications, nil
+}
+
+// MarkNotificationRead marks a notification of a person as read. Marking a
+// notification that has already been read does nothing.
+func (db *database) MarkNotificationRead(
+	ctx context.Context,
+	notificationID, personID int,
+) error {
+
+	result, err := db.ExecContext(ctx, `
+		UPDATE notification SET
+			read_at = COALESCE(read_at, now())
+		WHERE
+			notification_id = $1
+			AND person_id = $2
+	`, notificationID, personID)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to mark notification read")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "failed to check result of notification update")
+	} else if n != 1 {
+		return errors.Wrapf(
+			app.ErrNotFound,
+			"no such notification by id of %d for person %d",
+			notificationID, personID,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func TestAccountStatusNotifications(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	driver := app.Person{
+		FirstName:    "Ben",
+		LastName:     "Godfrey",
+		Email:        "bfgodfr@clemson.edu",
+		Password:     `qwerty`,
+		Role:         app.RoleDriver,
+		Affiliations: make([]int, 0),
+	}
+	sponsor := app.Person{
+		FirstName:    "Roger",
+		LastName:     "Van Scoy",
+		Email:        "vanscoy@clemson.edu",
+		Password:     `asdf`,
+		Role:         app.RoleSponsor,
+		Affiliations: make([]int, 0),
+	}
+	outsider := app.Person{
+		FirstName:    "Brian",
+		LastName:     "Malloy",
+		Email:        "malloy@clemson.edu",
+		Password:     `zxcvbn`,
+		Role:         app.RoleSponsor,
+		Affiliations: make([]int, 0),
+	}
+
+	var err error
+	for _, p := range []*app.Person{&driver, &sponsor, &outsider} {
+		p.ID, err = db.CreatePerson(ctx, *p)
+		require.NoError(t, err)
+	}
+
+	orgID, err := db.CreateOrganization(ctx, app.Organization{
+		Name:       "Trucking Co.",
+		PointValue: 1,
+	})
+	require.NoError(t, err)
+
+	otherOrgID, err := db.CreateOrganization(ctx, app.Organization{
+		Name:       "Freight Inc.",
+		PointValue: 1,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, db.AddPersonAffiliation(ctx, driver.ID, orgID))
+	require.NoError(t, db.AddPersonAffiliation(ctx, sponsor.ID, orgID))
+	require.NoError(t, db.AddPersonAffiliation(ctx, outsider.ID, otherOrgID))
+
+	s, err := app.NewSession(driver, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+	_, err = db.CreateSession(ctx, *s)
+	require.NoError(t, err)
+
+	tok, err := app.NewAPIToken(driver.ID, "fleet sync",
+		[]app.APITokenScope{app.ScopeDriverRead},
+		time.Now().UTC().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = db.CreateAPIToken(ctx, *tok)
+	require.NoError(t, err)
+
+	_, err = db.CreateApplication(ctx, app.Application{
+		ApplicantID:    driver.ID,
+		OrganizationID: otherOrgID,
+		Comment:        "Please sponsor me.",
+	})
+	require.NoError(t, err)
+
+	t.Run("Deactivate", func(t *testing.T) {
+		err := db.DeactivatePerson(ctx, driver.ID, "Left the company.")
+		require.NoError(t, err)
+
+		db.assertCountOf(t, "session", 1, `
+			person_id = $1
+			AND is_revoked = TRUE
+		`, driver.ID)
+		db.assertCountOf(t, "api_token", 1, `
+			person_id = $1
+			AND is_revoked = TRUE
+		`, driver.ID)
+		db.assertCountOf(t, "application", 1, `
+			applicant_id = $1
+			AND status = $2
+			AND approved IS NULL
+		`, driver.ID, app.ApplicationWithdrawn)
+		db.assertCountOf(t, "application_event", 1, `
+			from_status = $1
+			AND to_status = $2
+			AND message = $3
+		`, app.ApplicationSubmitted, app.ApplicationWithdrawn,
+			applicationWithdrawnReason)
+
+		db.assertCount(t, "notification", 1)
+
+		ns, err := db.GetNotificationsForPerson(ctx, sponsor.ID, false)
+		require.NoError(t, err)
+		require.Len(t, ns, 1)
+		assert.Equal(t, app.NotificationPersonDeactivated, ns[0].Kind)
+		assert.Equal(t, "The account of Ben Godfrey has been deactivated.",
+			ns[0].Message)
+		assert.False(t, ns[0].ReadAt.Valid)
+	})
+
+	t.Run("Activate", func(t *testing.T) {
+		err := db.ActivatePerson(ctx, driver.ID, "Rehired.")
+		require.NoError(t, err)
+
+		db.assertCountOf(t, "person", 1, `
+			person_id = $1
+			AND is_deactivated = FALSE
+		`, driver.ID)
+
+		// Withdrawn applications and revoked sessions stay that way.
+		db.assertCountOf(t, "session", 1, `
+			person_id = $1
+			AND is_revoked = TRUE
+		`, driver.ID)
+		db.assertCountOf(t, "application", 0, `approved IS NULL`)
+
+		ns, err := db.GetNotificationsForPerson(ctx, sponsor.ID, false)
+		require.NoError(t, err)
+		require.Len(t, ns, 2)
+		assert.Equal(t, app.NotificationPersonReactivated, ns[0].Kind)
+	})
+
+	t.Run("MarkRead", func(t *testing.T) {
+		ns, err := db.GetNotificationsForPerson(ctx, sponsor.ID, true)
+		require.NoError(t, err)
+		require.Len(t, ns, 2)
+
+		err = db.MarkNotificationRead(ctx, ns[0].ID, sponsor.ID)
+		require.NoError(t, err)
+
+		// Marking twice is harmless.
+		err = db.MarkNotificationRead(ctx, ns[0].ID, sponsor.ID)
+		require.NoError(t, err)
+
+		unread, err := db.GetNotificationsForPerson(ctx, sponsor.ID, true)
+		require.NoError(t, err)
+		require.Len(t, unread, 1)
+		assert.Equal(t, ns[1].ID, unread[0].ID)
+	})
+
+	t.Run("MarkOthersNotification", func(t *testing.T) {
+		ns, err := db.GetNotificationsForPerson(ctx, sponsor.ID, false)
+		require.NoError(t, err)
+		require.NotEmpty(t, ns)
+
+		err = db.MarkNotificationRead(ctx, ns[0].ID, outsider.ID)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+
+	t.Run("Outsider", func(t *testing.T) {
+		ns, err := db.GetNotificationsForPerson(ctx, outsider.ID, false)
+		require.NoError(t, err)
+		assert.Empty(t, ns)
+	})
+}
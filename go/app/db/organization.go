@@ -3,12 +3,17 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
 
 	"github.com/BenJetson/CPSC491-project/go/app"
 )
 
+// GetAllOrganizations fetches every organization that is not archived, ordered
+// by name.
 func (db *database) GetAllOrganizations(
 	ctx context.Context,
 ) ([]app.Organization, error) {
@@ -19,8 +24,18 @@ func (db *database) GetAllOrganizations(
 		SELECT
 			organization_id,
 			name,
-			point_value
+			point_value,
+			reapply_cooldown_days,
+			archived_at,
+			description,
+			contact_email,
+			website,
+			logo_key,
+			logo_content_type,
+			applications_closed,
+			points_name
 		FROM organization
+		WHERE archived_at IS NULL
 		ORDER BY name ASC
 	`)
 
@@ -42,7 +57,16 @@ func (db *database) GetOrganizationByID(
 		SELECT
 			organization_id,
 			name,
-			point_value
+			point_value,
+			reapply_cooldown_days,
+			archived_at,
+			description,
+			contact_email,
+			website,
+			logo_key,
+			logo_content_type,
+			applications_closed,
+			points_name
 		FROM organization
 		WHERE organization_id = $1
 	`, orgID)
@@ -57,19 +81,164 @@ func (db *database) GetOrganizationByID(
 	return org, errors.Wrap(err, "failed to select organizations")
 }
 
+// GetOrganizationsAcceptingApplications fetches every organization that is
+// accepting applications, ordered by name. Archived organizations are not.
+func (db *database) GetOrganizationsAcceptingApplications(
+	ctx context.Context,
+) ([]app.Organization, error) {
+
+	var orgs []app.Organization
+
+	err := db.SelectContext(ctx, &orgs, `
+		SELECT
+			organization_id,
+			name,
+			point_value,
+			reapply_cooldown_days,
+			archived_at,
+			description,
+			contact_email,
+			website,
+			logo_key,
+			logo_content_type,
+			applications_closed,
+			points_name
+		FROM organization
+		WHERE
+			archived_at IS NULL
+			AND NOT applications_closed
+		ORDER BY name ASC
+	`)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err, "failed to fetch orgs")
+	}
+
+	return orgs, nil
+}
+
+// GetOrganizationsForPerson fetches every organization that a person is
+// affiliated with, ordered by name. Archived organizations are included, and
+// may be told apart by their ArchivedAt field.
+func (db *database) GetOrganizationsForPerson(
+	ctx context.Context,
+	personID int,
+) ([]app.Organization, error) {
+
+	var orgs []app.Organization
+
+	err := db.SelectContext(ctx, &orgs, `
+		SELECT
+			o.organization_id,
+			o.name,
+			o.point_value,
+			o.reapply_cooldown_days,
+			o.archived_at,
+			o.description,
+			o.contact_email,
+			o.website,
+			o.logo_key,
+			o.logo_content_type,
+			o.applications_closed,
+			o.points_name
+		FROM affiliation a
+		JOIN organization o
+			ON a.organization_id = o.organization_id
+		WHERE a.person_id = $1
+		ORDER BY o.name ASC
+	`, personID)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err, "failed to fetch orgs")
+	}
+
+	return orgs, nil
+}
+
+// GetArchivedOrganizations fetches every archived organization, most recently
+// archived first.
+func (db *database) GetArchivedOrganizations(
+	ctx context.Context,
+) ([]app.Organization, error) {
+
+	var orgs []app.Organization
+
+	err := db.SelectContext(ctx, &orgs, `
+		SELECT
+			organization_id,
+			name,
+			point_value,
+			reapply_cooldown_days,
+			archived_at,
+			description,
+			contact_email,
+			website,
+			logo_key,
+			logo_content_type,
+			applications_closed,
+			points_name
+		FROM organization
+		WHERE archived_at IS NOT NULL
+		ORDER BY archived_at DESC, organization_id DESC
+	`)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err, "failed to fetch archived orgs")
+	}
+
+	return orgs, nil
+}
+
 func (db *database) CreateOrganization(
 	ctx context.Context,
 	org app.Organization,
 ) (int, error) {
 
+	if org.PointsName == "" {
+		org.PointsName = app.DefaultPointsName
+	}
+
 	var id int
-	err := db.GetContext(ctx, &id, `
-		INSERT INTO organization (
-			name,
-			point_value
-		) VALUES ($1, $2)
-		RETURNING organization_id
-	`, org.Name, org.PointValue)
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		err := tx.GetContext(ctx, &id, `
+			INSERT INTO organization (
+				name,
+				point_value,
+				reapply_cooldown_days,
+				description,
+				contact_email,
+				website,
+				applications_closed,
+				points_name
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING organization_id
+		`,
+			org.Name,         // $1
+			org.PointValue,   // $2
+			org.CooldownDays, // $3
+			org.Description,  // $4
+			org.ContactEmail, // $5
+			org.Website,      // $6
+			org.IsClosed,     // $7
+			org.PointsName,   // $8
+		)
+		if err != nil {
+			return err
+		}
+
+		after, err := auditSnapshot(ctx, tx, orgAuditSnapshotQuery, id)
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, tx, app.AuditEvent{
+			Action:         app.AuditActionOrganizationCreate,
+			TargetType:     app.AuditTargetOrganization,
+			TargetID:       id,
+			OrganizationID: null.IntFrom(int64(id)),
+			After:          after,
+		})
+	})
 
 	return id, errors.Wrap(err, "failed to insert organization")
 }
@@ -79,20 +248,329 @@ func (db *database) UpdateOrganization(
 	org app.Organization,
 ) error {
 
-	_, err := db.ExecContext(ctx, `
-		UPDATE organization SET
-			name = $1,
-			point_value = $2
-		WHERE organization_id = $3
-	`, org.Name, org.PointValue, org.ID)
+	if org.PointsName == "" {
+		org.PointsName = app.DefaultPointsName
+	}
+
+	err := db.auditedOrganizationUpdate(ctx, org.ID,
+		app.AuditActionOrganizationUpdate, `
+			UPDATE organization SET
+				name = $1,
+				point_value = $2,
+				reapply_cooldown_days = $3,
+				description = $4,
+				contact_email = $5,
+				website = $6,
+				applications_closed = $7,
+				points_name = $8
+			WHERE organization_id = $9
+		`,
+		org.Name,         // $1
+		org.PointValue,   // $2
+		org.CooldownDays, // $3
+		org.Description,  // $4
+		org.ContactEmail, // $5
+		org.Website,      // $6
+		org.IsClosed,     // $7
+		org.PointsName,   // $8
+		org.ID,           // $9
+	)
 
 	return errors.Wrap(err, "failed to update organization")
 }
 
-func (db *database) DeleteOrganization(ctx context.Context, orgID int) error {
-	_, err := db.ExecContext(ctx, `
-		DELETE FROM organization
-		WHERE organization_id = $1
-	`, orgID)
-	return errors.Wrap(err, "failed to delete organization")
+// SetOrganizationLogo replaces the logo of an organization with the file
+// stored under the key, or removes its logo when the key is null. Returns the
+// key of the logo that was replaced, if any, so that its file may be deleted.
+func (db *database) SetOrganizationLogo(
+	ctx context.Context,
+	orgID int,
+	key, contentType null.String,
+) (null.String, error) {
+
+	var previous null.String
+	err := db.auditedOrganizationChange(ctx, orgID,
+		app.AuditActionOrganizationUpdate, func(tx *sqlx.Tx) error {
+			err := tx.GetContext(ctx, &previous, `
+				SELECT logo_key
+				FROM organization
+				WHERE organization_id = $1
+			`, orgID)
+			if err != nil {
+				return errors.Wrap(err, "failed to get previous logo")
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				UPDATE organization SET
+					logo_key = $1,
+					logo_content_type = $2
+				WHERE organization_id = $3
+			`, key, contentType, orgID)
+			return errors.Wrap(err, "failed to update organization")
+		})
+
+	return previous, errors.Wrap(err, "failed to set organization logo")
+}
+
+// ArchiveOrganization archives an organization, which hides it from drivers and
+// freezes the points of its drivers. Its undecided applications are withdrawn,
+// its pending invitations are revoked, and its drivers are notified.
+func (db *database) ArchiveOrganization(ctx context.Context, orgID int) error {
+	err := db.auditedOrganizationChange(ctx, orgID,
+		app.AuditActionOrgArchive, func(tx *sqlx.Tx) error {
+			result, err := tx.ExecContext(ctx, `
+				UPDATE organization SET
+					archived_at = now()
+				WHERE
+					organization_id = $1
+					AND archived_at IS NULL
+			`, orgID)
+			if err != nil {
+				return errors.Wrap(err, "failed to update organization")
+			}
+
+			n, err := result.RowsAffected()
+			if err != nil {
+				return errors.Wrap(err, "failed to check result of update")
+			} else if n < 1 {
+				return errors.Wrapf(app.ErrOrganizationArchived,
+					"organization %d", orgID)
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				WITH withdrawn AS (
+					UPDATE application a SET
+						status = $2
+					FROM application prev
+					WHERE
+						a.application_id = prev.application_id
+						AND a.organization_id = $1
+						AND a.status = ANY($3)
+					RETURNING a.application_id, prev.status
+				)
+				INSERT INTO application_event (
+					application_id,
+					from_status,
+					to_status,
+					actor_id,
+					message
+				)
+				SELECT application_id, status, $2, $4, $5
+				FROM withdrawn
+			`, orgID, app.ApplicationWithdrawn, openApplicationStatuses,
+				app.AuditActorFromContext(ctx).PersonID,
+				orgArchivedReason)
+			if err != nil {
+				return errors.Wrap(err, "failed to withdraw applications")
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				UPDATE invitation SET
+					status = $1
+				WHERE
+					organization_id = $2
+					AND status = $3
+			`, app.InvitationRevoked, orgID, app.InvitationPending)
+			if err != nil {
+				return errors.Wrap(err, "failed to revoke invitations")
+			}
+
+			return notifyDriversOfOrganization(ctx, tx, orgID,
+				app.NotificationOrgArchived,
+				"%s has been archived. Your points there are frozen until "+
+					"it is restored.")
+		})
+
+	return errors.Wrap(err, "failed to archive organization")
+}
+
+// RestoreOrganization restores an archived organization, which thaws the
+// points of its drivers, and notifies its drivers.
+//
+// Applications and invitations, which were withdrawn and revoked upon
+// archival, are not restored.
+func (db *database) RestoreOrganization(ctx context.Context, orgID int) error {
+	err := db.auditedOrganizationChange(ctx, orgID,
+		app.AuditActionOrgRestore, func(tx *sqlx.Tx) error {
+			result, err := tx.ExecContext(ctx, `
+				UPDATE organization SET
+					archived_at = NULL
+				WHERE
+					organization_id = $1
+					AND archived_at IS NOT NULL
+			`, orgID)
+			if err != nil {
+				return errors.Wrap(err, "failed to update organization")
+			}
+
+			n, err := result.RowsAffected()
+			if err != nil {
+				return errors.Wrap(err, "failed to check result of update")
+			} else if n < 1 {
+				return errors.Wrapf(app.ErrConflict,
+					"organization %d is not archived", orgID)
+			}
+
+			return notifyDriversOfOrganization(ctx, tx, orgID,
+				app.NotificationOrgRestored,
+				"%s has been restored, and your points there may be "+
+					"used again.")
+		})
+
+	return errors.Wrap(err, "failed to restore organization")
+}
+
+// PurgeOrganization permanently deletes an organization, along with everything
+// that belongs to it. Only organizations that were archived at least the
+// retention period ago may be purged; others are in conflict. Members are
+// removed as by RemovePersonAffiliation, so drivers who belonged to no other
+// organization become users. Returns the keys of the files that belonged to
+// the organization, which the caller must delete from file storage once the
+// purge has succeeded.
+func (db *database) PurgeOrganization(
+	ctx context.Context,
+	orgID int,
+	retention time.Duration,
+) ([]string, error) {
+
+	var fileKeys []string
+	err := db.auditedOrganizationChange(ctx, orgID,
+		app.AuditActionOrganizationDelete, func(tx *sqlx.Tx) error {
+			var archivedAt null.Time
+			err := tx.GetContext(ctx, &archivedAt, `
+				SELECT archived_at
+				FROM organization
+				WHERE organization_id = $1
+			`, orgID)
+			if err != nil {
+				return errors.Wrap(err, "failed to check organization")
+			} else if !archivedAt.Valid {
+				return errors.Wrapf(app.ErrConflict,
+					"organization %d is not archived", orgID)
+			} else if time.Since(archivedAt.Time) < retention {
+				return errors.Wrapf(app.ErrConflict,
+					"organization %d was archived at %s, within retention",
+					orgID, archivedAt.Time)
+			}
+
+			// The attachments of applications and the logo are deleted along
+			// with the organization, but their files are not.
+			err = tx.SelectContext(ctx, &fileKeys, `
+				SELECT at.storage_key
+				FROM attachment at
+				JOIN application a
+					ON at.application_id = a.application_id
+				WHERE a.organization_id = $1
+				UNION ALL
+				SELECT logo_key
+				FROM organization
+				WHERE
+					organization_id = $1
+					AND logo_key IS NOT NULL
+			`, orgID)
+			if err != nil {
+				return errors.Wrap(err, "failed to select file keys")
+			}
+
+			// Members are removed first rather than by the cascade, so that
+			// drivers left without an organization lose their driver role.
+			var personIDs []int
+			err = tx.SelectContext(ctx, &personIDs, `
+				SELECT person_id
+				FROM affiliation
+				WHERE organization_id = $1
+				ORDER BY person_id
+			`, orgID)
+			if err != nil {
+				return errors.Wrap(err, "failed to select members")
+			}
+
+			for _, personID := range personIDs {
+				err = removeAffiliation(ctx, tx, personID, orgID)
+				if err != nil {
+					return err
+				}
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				DELETE FROM organization
+				WHERE organization_id = $1
+			`, orgID)
+			return errors.Wrap(err, "failed to delete organization")
+		})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to purge organization")
+	}
+	return fileKeys, nil
+}
+
+// orgArchivedReason is recorded in the history of applications that are
+// withdrawn when their organization is archived.
+const orgArchivedReason = "Withdrawn because the organization was archived."
+
+// orgAuditSnapshotQuery captures an organization for the audit log.
+const orgAuditSnapshotQuery = `
+	SELECT to_jsonb(o)
+	FROM organization o
+	WHERE o.organization_id = $1
+	FOR UPDATE
+`
+
+// auditedOrganizationUpdate executes a query that changes a single
+// organization, and records the change in the audit log with the given action.
+func (db *database) auditedOrganizationUpdate(
+	ctx context.Context,
+	orgID int,
+	action app.AuditAction,
+	query string,
+	args ...interface{},
+) error {
+
+	return db.auditedOrganizationChange(ctx, orgID, action,
+		func(tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, query, args...)
+			return errors.Wrap(err, "failed to change organization")
+		})
+}
+
+// auditedOrganizationChange makes a change to, or deletes, a single
+// organization in a transaction, and records the change in the audit log with
+// the given action.
+func (db *database) auditedOrganizationChange(
+	ctx context.Context,
+	orgID int,
+	action app.AuditAction,
+	change func(tx *sqlx.Tx) error,
+) error {
+
+	return db.Transact(func(tx *sqlx.Tx) error {
+		before, err := auditSnapshot(ctx, tx, orgAuditSnapshotQuery, orgID)
+		if err != nil {
+			return err
+		} else if before == nil {
+			return errors.Wrapf(
+				app.ErrNotFound,
+				"no such organization by id of %d", orgID,
+			)
+		}
+
+		if err = change(tx); err != nil {
+			return err
+		}
+
+		after, err := auditSnapshot(ctx, tx, orgAuditSnapshotQuery, orgID)
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, tx, app.AuditEvent{
+			Action:         action,
+			TargetType:     app.AuditTargetOrganization,
+			TargetID:       orgID,
+			OrganizationID: null.IntFrom(int64(orgID)),
+			Before:         before,
+			After:          after,
+		})
+	})
 }
@@ -0,0 +1,298 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func TestOrganizationArchive(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	orgID, err := db.CreateOrganization(ctx, app.Organization{
+		Name:       "Trucking Co.",
+		PointValue: 1,
+	})
+	require.NoError(t, err)
+
+	newPerson := func(email string, role app.Role) int {
+		id, err := db.CreatePerson(ctx, app.Person{
+			FirstName:    "Ben",
+			LastName:     "Godfrey",
+			Email:        email,
+			Password:     `qwerty`,
+			Role:         role,
+			Affiliations: make([]int, 0),
+		})
+		require.NoError(t, err)
+		return id
+	}
+
+	driverID := newPerson("driver@clemson.edu", app.RoleDriver)
+	require.NoError(t, db.AddPersonAffiliation(ctx, driverID, orgID))
+	require.NoError(t, db.SetPointsForAffiliation(ctx, driverID, orgID,
+		null.IntFrom(100)))
+
+	applicantID := newPerson("applicant@clemson.edu", app.RoleUser)
+	appID, err := db.CreateApplication(ctx, app.Application{
+		ApplicantID:    applicantID,
+		OrganizationID: orgID,
+		Comment:        "Please sponsor me.",
+	})
+	require.NoError(t, err)
+
+	inv, err := app.NewInvitation(orgID, "invitee@clemson.edu",
+		app.RoleDriver, null.Int{}, time.Hour)
+	require.NoError(t, err)
+	inv.ID, err = db.CreateInvitation(ctx, *inv)
+	require.NoError(t, err)
+
+	t.Run("Archive", func(t *testing.T) {
+		require.NoError(t, db.ArchiveOrganization(ctx, orgID))
+
+		err := db.ArchiveOrganization(ctx, orgID)
+		assert.True(t, errors.Is(err, app.ErrOrganizationArchived))
+
+		orgs, err := db.GetAllOrganizations(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, orgs)
+
+		orgs, err = db.GetArchivedOrganizations(ctx)
+		require.NoError(t, err)
+		require.Len(t, orgs, 1)
+		assert.True(t, orgs[0].ArchivedAt.Valid)
+
+		// Members still see the organizations they belong to.
+		orgs, err = db.GetOrganizationsForPerson(ctx, driverID)
+		require.NoError(t, err)
+		require.Len(t, orgs, 1)
+		assert.True(t, orgs[0].ArchivedAt.Valid)
+
+		db.assertCountOf(t, "application", 1, `
+			application_id = $1
+			AND status = $2
+		`, appID, app.ApplicationWithdrawn)
+		db.assertCountOf(t, "invitation", 1, `
+			invitation_id = $1
+			AND status = $2
+		`, inv.ID, app.InvitationRevoked)
+		db.assertCountOf(t, "notification", 1, `
+			person_id = $1
+			AND kind = $2
+		`, driverID, app.NotificationOrgArchived)
+	})
+
+	t.Run("PointsFrozen", func(t *testing.T) {
+		bs, err := db.GetBalancesForPerson(ctx, driverID)
+		require.NoError(t, err)
+		require.Len(t, bs, 1)
+		assert.True(t, bs[0].IsFrozen)
+		assert.Equal(t, 100, bs[0].Points)
+
+		err = db.SetPointsForAffiliation(ctx, driverID, orgID,
+			null.IntFrom(0))
+		assert.True(t, errors.Is(err, app.ErrOrganizationArchived))
+	})
+
+	t.Run("NoApplications", func(t *testing.T) {
+		_, err := db.CreateApplication(ctx, app.Application{
+			ApplicantID:    newPerson("late@clemson.edu", app.RoleUser),
+			OrganizationID: orgID,
+		})
+		assert.True(t, errors.Is(err, app.ErrOrganizationArchived))
+	})
+
+	t.Run("NoNewMembers", func(t *testing.T) {
+		err := db.AddPersonAffiliation(ctx,
+			newPerson("joiner@clemson.edu", app.RoleUser), orgID)
+		assert.True(t, errors.Is(err, app.ErrOrganizationArchived))
+
+		invitation, err := app.NewAccountInvitation(0, null.Int{}, time.Hour)
+		require.NoError(t, err)
+
+		_, err = db.CreateInvitedPerson(ctx, app.Person{
+			FirstName:    "Ben",
+			LastName:     "Godfrey",
+			Email:        "invited@clemson.edu",
+			Role:         app.RoleDriver,
+			Affiliations: []int{orgID},
+		}, *invitation)
+		assert.True(t, errors.Is(err, app.ErrOrganizationArchived))
+
+		db.assertCountOf(t, "person", 0, `email = 'invited@clemson.edu'`)
+	})
+
+	t.Run("PurgeWithinRetention", func(t *testing.T) {
+		_, err := db.PurgeOrganization(ctx, orgID, time.Hour)
+		assert.True(t, errors.Is(err, app.ErrConflict))
+
+		db.assertCount(t, "organization", 1)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		require.NoError(t, db.RestoreOrganization(ctx, orgID))
+
+		err := db.RestoreOrganization(ctx, orgID)
+		assert.True(t, errors.Is(err, app.ErrConflict))
+
+		bs, err := db.GetBalancesForPerson(ctx, driverID)
+		require.NoError(t, err)
+		require.Len(t, bs, 1)
+		assert.False(t, bs[0].IsFrozen)
+
+		db.assertCountOf(t, "notification", 1, `
+			person_id = $1
+			AND kind = $2
+		`, driverID, app.NotificationOrgRestored)
+
+		// Only archived organizations may be purged.
+		_, err = db.PurgeOrganization(ctx, orgID, 0)
+		assert.True(t, errors.Is(err, app.ErrConflict))
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		_, err := db.SetOrganizationLogo(ctx, orgID, null.StringFrom("logo"),
+			null.StringFrom("image/png"))
+		require.NoError(t, err)
+		_, err = db.CreateAttachment(ctx, app.Attachment{
+			ApplicationID: appID,
+			StorageKey:    "attachment",
+			FileName:      "license.pdf",
+			ContentType:   "application/pdf",
+			Size:          4,
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, db.ArchiveOrganization(ctx, orgID))
+		fileKeys, err := db.PurgeOrganization(ctx, orgID, 0)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"logo", "attachment"}, fileKeys)
+
+		db.assertCount(t, "organization", 0)
+		db.assertCount(t, "affiliation", 0)
+		db.assertCount(t, "attachment", 0)
+
+		// The driver belonged to no other organization.
+		db.assertCountOf(t, "person", 1, `
+			person_id = $1
+			AND role_id = $2
+		`, driverID, app.RoleUser)
+		db.assertCountOf(t, "audit_event", 1, `
+			action = $1
+			AND target_id = $2
+			AND organization_id = $3
+		`, app.AuditActionAffiliationRemove, driverID, orgID)
+
+		_, err = db.PurgeOrganization(ctx, orgID, 0)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+}
+
+func TestOrganizationProfile(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	orgID, err := db.CreateOrganization(ctx, app.Organization{
+		Name:         "Trucking Co.",
+		PointValue:   1,
+		Description:  "We haul things.",
+		ContactEmail: "fleet@trucking.example",
+		Website:      "https://trucking.example",
+	})
+	require.NoError(t, err)
+
+	t.Run("Defaults", func(t *testing.T) {
+		org, err := db.GetOrganizationByID(ctx, orgID)
+		require.NoError(t, err)
+
+		assert.Equal(t, "We haul things.", org.Description)
+		assert.Equal(t, "fleet@trucking.example", org.ContactEmail)
+		assert.Equal(t, "https://trucking.example", org.Website)
+		assert.Equal(t, app.DefaultPointsName, org.PointsName)
+		assert.False(t, org.IsClosed)
+		assert.False(t, org.LogoKey.Valid)
+
+		orgs, err := db.GetOrganizationsAcceptingApplications(ctx)
+		require.NoError(t, err)
+		assert.Len(t, orgs, 1)
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		err := db.UpdateOrganization(ctx, app.Organization{
+			ID:         orgID,
+			Name:       "Trucking Co.",
+			PointValue: 1,
+			IsClosed:   true,
+			PointsName: "Safety Bucks",
+		})
+		require.NoError(t, err)
+
+		org, err := db.GetOrganizationByID(ctx, orgID)
+		require.NoError(t, err)
+		assert.Equal(t, "Safety Bucks", org.PointsName)
+		assert.Empty(t, org.Description)
+
+		orgs, err := db.GetOrganizationsAcceptingApplications(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, orgs)
+
+		// Closed organizations are still listed for administrators.
+		orgs, err = db.GetAllOrganizations(ctx)
+		require.NoError(t, err)
+		assert.Len(t, orgs, 1)
+
+		applicantID, err := db.CreatePerson(ctx, app.Person{
+			FirstName:    "Ben",
+			LastName:     "Godfrey",
+			Email:        "bfgodfr@clemson.edu",
+			Password:     `qwerty`,
+			Role:         app.RoleUser,
+			Affiliations: make([]int, 0),
+		})
+		require.NoError(t, err)
+
+		_, err = db.CreateApplication(ctx, app.Application{
+			ApplicantID:    applicantID,
+			OrganizationID: orgID,
+		})
+		assert.True(t, errors.Is(err, app.ErrNotAcceptingApplications))
+	})
+
+	t.Run("Logo", func(t *testing.T) {
+		previous, err := db.SetOrganizationLogo(ctx, orgID,
+			null.StringFrom("first"), null.StringFrom("image/png"))
+		require.NoError(t, err)
+		assert.False(t, previous.Valid)
+
+		previous, err = db.SetOrganizationLogo(ctx, orgID,
+			null.StringFrom("second"), null.StringFrom("image/jpeg"))
+		require.NoError(t, err)
+		assert.Equal(t, null.StringFrom("first"), previous)
+
+		org, err := db.GetOrganizationByID(ctx, orgID)
+		require.NoError(t, err)
+		assert.Equal(t, null.StringFrom("second"), org.LogoKey)
+		assert.Equal(t, null.StringFrom("image/jpeg"), org.LogoContentType)
+
+		previous, err = db.SetOrganizationLogo(ctx, orgID, null.String{},
+			null.String{})
+		require.NoError(t, err)
+		assert.Equal(t, null.StringFrom("second"), previous)
+
+		_, err = db.SetOrganizationLogo(ctx, orgID+1, null.String{},
+			null.String{})
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+}
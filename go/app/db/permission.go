@@ -0,0 +1,30 @@
+package db
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// GetPermissionsForRole fetches the permissions granted to a given role, in
+// alphabetical order.
+func (db *database) GetPermissionsForRole(
+	ctx context.Context,
+	role app.Role,
+) ([]app.Permission, error) {
+
+	var perms []app.Permission
+
+	err := db.SelectContext(ctx, &perms, `
+		SELECT p.name
+		FROM role_permission rp
+		JOIN permission p
+			ON rp.permission_id = p.permission_id
+		WHERE rp.role_id = $1
+		ORDER BY p.name
+	`, role)
+
+	return perms, errors.Wrap(err, "failed to get permissions for role")
+}
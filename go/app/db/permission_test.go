@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func TestGetPermissionsForRole(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	testCases := []struct {
+		alias  string
+		role   app.Role
+		expect []app.Permission
+	}{
+		{
+			alias: "Admin",
+			role:  app.RoleAdmin,
+			expect: []app.Permission{
+				app.PermissionAuditView,
+				app.PermissionOrganizationsManage,
+				app.PermissionProfileManage,
+				app.PermissionUsersManage,
+			},
+		},
+		{
+			alias: "Sponsor",
+			role:  app.RoleSponsor,
+			expect: []app.Permission{
+				app.PermissionApplicationsReview,
+				app.PermissionCatalogManage,
+				app.PermissionDriversManage,
+				app.PermissionOrganizationAuditView,
+				app.PermissionOrganizationManage,
+				app.PermissionOrganizationView,
+				app.PermissionPointsAward,
+				app.PermissionProfileManage,
+			},
+		},
+		{
+			alias:  "User",
+			role:   app.RoleUser,
+			expect: []app.Permission{app.PermissionApplicationsSubmit},
+		},
+		{
+			alias: "Driver",
+			role:  app.RoleDriver,
+			expect: []app.Permission{
+				app.PermissionApplicationsSubmit,
+				app.PermissionProfileManage,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			perms, err := db.GetPermissionsForRole(ctx, tc.role)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, perms)
+		})
+	}
+}
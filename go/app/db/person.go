@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
 
 	"github.com/BenJetson/CPSC491-project/go/app"
 )
@@ -163,6 +165,42 @@ func (db *database) CreatePerson(
 	return id, errors.Wrap(err, "failed to insert person")
 }
 
+// insertPerson creates a new person within a transaction, and records their
+// creation in the audit log. Ignores the ID and Affiliations fields.
+func insertPerson(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	p app.Person,
+) (int, error) {
+
+	var id int
+	err := tx.GetContext(ctx, &id, `
+		INSERT INTO person (
+			first_name,
+			last_name,
+			email,
+			role_id,
+			pass_hash
+		) VALUES ($1, $2, $3, $4, $5)
+		RETURNING person_id
+	`, p.FirstName, p.LastName, p.Email, p.Role, p.Password)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to insert person")
+	}
+
+	after, err := auditSnapshot(ctx, tx, personAuditSnapshotQuery, id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, recordAuditEvent(ctx, tx, app.AuditEvent{
+		Action:     app.AuditActionPersonCreate,
+		TargetType: app.AuditTargetPerson,
+		TargetID:   id,
+		After:      after,
+	})
+}
+
 // UpdatePersonName updates a person's first and last name.
 func (db *database) UpdatePersonName(
 	ctx context.Context,
@@ -231,106 +269,311 @@ func (db *database) UpdatePersonRole(
 	roleType app.Role,
 ) error {
 
-	result, err := db.ExecContext(ctx, `
-		UPDATE person SET
-			role_id = $1
-		WHERE person_id = $2
-	`, roleType, personID)
-
-	if err != nil {
-		return errors.Wrap(err, "failed to update person role")
-	}
+	err := db.auditedPersonUpdate(ctx, personID,
+		app.AuditActionPersonRoleUpdate, `
+			UPDATE person SET
+				role_id = $1
+			WHERE person_id = $2
+		`, roleType, personID)
 
-	n, err := result.RowsAffected()
-	if err != nil {
-		return errors.Wrap(err, "failed to check result of person role update")
-	} else if n != 1 {
-		return errors.Wrapf(
-			app.ErrNotFound,
-			"no such person by id of %d", personID,
-		)
-	}
-
-	return nil
+	return errors.Wrap(err, "failed to update person role")
 }
 
-// UpdatePersonPassword updates a person's password.
+// UpdatePersonPassword updates a person's password. The replaced password is
+// kept in their password history.
 func (db *database) UpdatePersonPassword(
 	ctx context.Context,
 	personID int,
 	newPass app.Password,
 ) error {
 
+	return db.Transact(func(tx *sqlx.Tx) error {
+		// People who sign on through SSO may not have had a password.
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO password_history (
+				person_id,
+				pass_hash
+			)
+			SELECT person_id, pass_hash
+			FROM person
+			WHERE
+				person_id = $1
+				AND pass_hash <> ''
+		`, personID)
+
+		if err != nil {
+			return errors.Wrap(err, "failed to record password history")
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			UPDATE person SET
+				pass_hash = $1
+			WHERE person_id = $2
+		`, newPass, personID)
+
+		if err != nil {
+			return errors.Wrap(err, "failed to update person password")
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return errors.Wrap(err,
+				"failed to check result of person password update")
+		} else if n != 1 {
+			return errors.Wrapf(
+				app.ErrNotFound,
+				"no such person by id of %d", personID,
+			)
+		}
+
+		return nil
+	})
+}
+
+// RehashPersonPassword replaces the hash of a person's password with another
+// hash of the same password. Since the password itself is unchanged, nothing
+// is added to their password history. The hash is left alone if it is no
+// longer oldPass, such as when the password was changed in the meantime.
+func (db *database) RehashPersonPassword(
+	ctx context.Context,
+	personID int,
+	oldPass, newPass app.Password,
+) error {
+
 	result, err := db.ExecContext(ctx, `
 		UPDATE person SET
 			pass_hash = $1
-		WHERE person_id = $2
-	`, newPass, personID)
+		WHERE
+			person_id = $2
+			AND pass_hash = $3
+	`, newPass, personID, oldPass)
 
 	if err != nil {
-		return errors.Wrap(err, "failed to update person password")
+		return errors.Wrap(err, "failed to rehash person password")
 	}
 
 	n, err := result.RowsAffected()
 	if err != nil {
 		return errors.Wrap(err,
-			"failed to check result of person password update")
+			"failed to check result of person password rehash")
 	} else if n != 1 {
 		return errors.Wrapf(
 			app.ErrNotFound,
-			"no such person by id of %d", personID,
+			"no such person by id of %d with that password", personID,
 		)
 	}
 
 	return nil
 }
 
-// ActivatePerson activates a person's account.
-func (db *database) ActivatePerson(ctx context.Context, personID int) error {
-	result, err := db.ExecContext(ctx, `
-		UPDATE person SET
-			is_deactivated = FALSE
+// GetPreviousPasswords fetches up to limit of the passwords that a person has
+// replaced, newest first.
+func (db *database) GetPreviousPasswords(
+	ctx context.Context,
+	personID int,
+	limit int,
+) ([]app.Password, error) {
+
+	var passwords []app.Password
+
+	err := db.SelectContext(ctx, &passwords, `
+		SELECT pass_hash
+		FROM password_history
 		WHERE person_id = $1
-	`, personID)
+		ORDER BY password_history_id DESC
+		LIMIT $2
+	`, personID, limit)
 
-	if err != nil {
-		return errors.Wrap(err, "failed to activate person")
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err, "failed to select previous passwords")
 	}
 
-	n, err := result.RowsAffected()
-	if err != nil {
-		return errors.Wrap(err, "failed to check result of person activation")
-	} else if n != 1 {
-		return errors.Wrapf(
-			app.ErrNotFound,
-			"no such person by id of %d", personID,
-		)
-	}
+	return passwords, nil
+}
 
-	return nil
+// ActivatePerson reactivates a person's account, for the given reason, and
+// notifies the sponsors of their organizations.
+//
+// Their sessions and applications, which were revoked and withdrawn upon
+// deactivation, are not restored.
+func (db *database) ActivatePerson(
+	ctx context.Context,
+	personID int,
+	reason string,
+) error {
+
+	err := db.auditedPersonChange(ctx, personID,
+		app.AuditActionPersonActivate, reason, func(tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, `
+				UPDATE person SET
+					is_deactivated = FALSE
+				WHERE person_id = $1
+			`, personID)
+
+			if err != nil {
+				return errors.Wrap(err, "failed to update person")
+			}
+
+			return notifySponsorsOfPerson(ctx, tx, personID,
+				app.NotificationPersonReactivated,
+				"The account of %s %s has been reactivated.")
+		})
+
+	return errors.Wrap(err, "failed to activate person")
 }
 
-// DeactivatePerson deactivates a person's account.
-func (db *database) DeactivatePerson(ctx context.Context, personID int) error {
-	result, err := db.ExecContext(ctx, `
-		UPDATE person SET
-			is_deactivated = TRUE
-		WHERE person_id = $1
-	`, personID)
+// DeactivatePerson deactivates a person's account, for the given reason. All of
+// their sessions and API tokens are revoked, their undecided applications are
+// withdrawn, and the sponsors of their organizations are notified.
+func (db *database) DeactivatePerson(
+	ctx context.Context,
+	personID int,
+	reason string,
+) error {
 
-	if err != nil {
-		return errors.Wrap(err, "failed to deactivate person")
-	}
+	err := db.auditedPersonChange(ctx, personID,
+		app.AuditActionPersonDeactivate, reason, func(tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, `
+				UPDATE person SET
+					is_deactivated = TRUE
+				WHERE person_id = $1
+			`, personID)
+
+			if err != nil {
+				return errors.Wrap(err, "failed to update person")
+			}
+
+			// This includes sessions in which this person impersonates
+			// somebody else.
+			_, err = tx.ExecContext(ctx, `
+				UPDATE session SET
+					is_revoked = TRUE
+				WHERE
+					person_id = $1
+					OR impersonator_id = $1
+			`, personID)
+
+			if err != nil {
+				return errors.Wrap(err, "failed to revoke sessions")
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				UPDATE api_token SET
+					is_revoked = TRUE
+				WHERE person_id = $1
+			`, personID)
+
+			if err != nil {
+				return errors.Wrap(err, "failed to revoke api tokens")
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				WITH withdrawn AS (
+					UPDATE application a SET
+						status = $2
+					FROM application prev
+					WHERE
+						a.application_id = prev.application_id
+						AND a.applicant_id = $1
+						AND a.status = ANY($3)
+					RETURNING a.application_id, prev.status
+				)
+				INSERT INTO application_event (
+					application_id,
+					from_status,
+					to_status,
+					actor_id,
+					message
+				)
+				SELECT application_id, status, $2, $4, $5
+				FROM withdrawn
+			`, personID, app.ApplicationWithdrawn, openApplicationStatuses,
+				app.AuditActorFromContext(ctx).PersonID,
+				applicationWithdrawnReason)
+
+			if err != nil {
+				return errors.Wrap(err, "failed to withdraw applications")
+			}
+
+			return notifySponsorsOfPerson(ctx, tx, personID,
+				app.NotificationPersonDeactivated,
+				"The account of %s %s has been deactivated.")
+		})
+
+	return errors.Wrap(err, "failed to deactivate person")
+}
 
-	n, err := result.RowsAffected()
-	if err != nil {
-		return errors.Wrap(err, "failed to check result of person deactivation")
-	} else if n != 1 {
-		return errors.Wrapf(
-			app.ErrNotFound,
-			"no such person by id of %d", personID,
-		)
-	}
+// applicationWithdrawnReason is recorded in the history of applications that
+// are withdrawn when their applicant is deactivated.
+const applicationWithdrawnReason = "Withdrawn because the applicant's " +
+	"account was deactivated."
+
+// personAuditSnapshotQuery captures a person for the audit log, without their
+// password hash.
+const personAuditSnapshotQuery = `
+	SELECT to_jsonb(p) - 'pass_hash'
+	FROM person p
+	WHERE p.person_id = $1
+	FOR UPDATE
+`
+
+// auditedPersonUpdate executes a query that changes a single person, and
+// records the change in the audit log with the given action.
+func (db *database) auditedPersonUpdate(
+	ctx context.Context,
+	personID int,
+	action app.AuditAction,
+	query string,
+	args ...interface{},
+) error {
 
-	return nil
+	return db.auditedPersonChange(ctx, personID, action, "",
+		func(tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, query, args...)
+			return errors.Wrap(err, "failed to update person")
+		})
+}
+
+// auditedPersonChange makes a change to a single person in a transaction, and
+// records the change in the audit log with the given action and reason. The
+// reason may be blank.
+func (db *database) auditedPersonChange(
+	ctx context.Context,
+	personID int,
+	action app.AuditAction,
+	reason string,
+	change func(tx *sqlx.Tx) error,
+) error {
+
+	return db.Transact(func(tx *sqlx.Tx) error {
+		before, err := auditSnapshot(ctx, tx, personAuditSnapshotQuery,
+			personID)
+		if err != nil {
+			return err
+		} else if before == nil {
+			return errors.Wrapf(
+				app.ErrNotFound,
+				"no such person by id of %d", personID,
+			)
+		}
+
+		if err = change(tx); err != nil {
+			return err
+		}
+
+		after, err := auditSnapshot(ctx, tx, personAuditSnapshotQuery,
+			personID)
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, tx, app.AuditEvent{
+			Action:     action,
+			TargetType: app.AuditTargetPerson,
+			TargetID:   personID,
+			Reason:     null.NewString(reason, len(reason) > 0),
+			Before:     before,
+			After:      after,
+		})
+	})
 }
@@ -319,6 +319,44 @@ func TestUpdatePersonPassword(t *testing.T) {
 		require.Error(t, err)
 		assert.True(t, errors.Is(err, app.ErrNotFound))
 	})
+
+	t.Run("History", func(t *testing.T) {
+		err = db.UpdatePersonPassword(ctx, 1, pass3)
+		require.NoError(t, err)
+
+		previous, err := db.GetPreviousPasswords(ctx, 1, 5)
+		require.NoError(t, err)
+		assert.Equal(t, []app.Password{pass2, pass1}, previous)
+
+		previous, err = db.GetPreviousPasswords(ctx, 1, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []app.Password{pass2}, previous)
+
+		previous, err = db.GetPreviousPasswords(ctx, 494942, 5)
+		require.NoError(t, err)
+		assert.Empty(t, previous)
+	})
+
+	t.Run("Rehash", func(t *testing.T) {
+		rehashed, err := app.NewPassword("ijkl")
+		require.NoError(t, err)
+
+		err = db.RehashPersonPassword(ctx, 1, pass3, rehashed)
+		require.NoError(t, err)
+
+		db.assertCountOf(t, "person", 1, `
+			person_id = 1
+			AND pass_hash = $1
+		`, rehashed)
+
+		previous, err := db.GetPreviousPasswords(ctx, 1, 5)
+		require.NoError(t, err)
+		assert.Equal(t, []app.Password{pass2, pass1}, previous)
+
+		// A stale hash means the password has changed since it was read.
+		err = db.RehashPersonPassword(ctx, 1, pass3, pass1)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
 }
 
 func TestDeactivatePerson(t *testing.T) {
@@ -340,7 +378,7 @@ func TestDeactivatePerson(t *testing.T) {
 	require.NoError(t, err)
 
 	t.Run("DoUpdate", func(t *testing.T) {
-		err = db.DeactivatePerson(ctx, 1)
+		err = db.DeactivatePerson(ctx, 1, "")
 
 		db.assertCount(t, "person", 1)
 		db.assertCountOf(t, "person", 1, `
@@ -355,7 +393,7 @@ func TestDeactivatePerson(t *testing.T) {
 	})
 
 	t.Run("NoSuchPerson", func(t *testing.T) {
-		err = db.DeactivatePerson(ctx, 122)
+		err = db.DeactivatePerson(ctx, 122, "")
 		require.Error(t, err)
 		assert.True(t, errors.Is(err, app.ErrNotFound))
 	})
@@ -379,11 +417,11 @@ func TestActivatePerson(t *testing.T) {
 	_, err := db.CreatePerson(ctx, p)
 	require.NoError(t, err)
 
-	err = db.DeactivatePerson(ctx, 1)
+	err = db.DeactivatePerson(ctx, 1, "")
 	require.NoError(t, err)
 
 	t.Run("DoUpdate", func(t *testing.T) {
-		err = db.ActivatePerson(ctx, 1)
+		err = db.ActivatePerson(ctx, 1, "")
 
 		db.assertCount(t, "person", 1)
 		db.assertCountOf(t, "person", 1, `
@@ -397,7 +435,7 @@ func TestActivatePerson(t *testing.T) {
 	})
 
 	t.Run("NoSuchPerson", func(t *testing.T) {
-		err = db.ActivatePerson(ctx, 122)
+		err = db.ActivatePerson(ctx, 122, "")
 		require.Error(t, err)
 		assert.True(t, errors.Is(err, app.ErrNotFound))
 	})
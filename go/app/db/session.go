@@ -5,8 +5,8 @@ import (
 	"database/sql"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
 
 	"github.com/BenJetson/CPSC491-project/go/app"
 )
@@ -14,20 +14,42 @@ import (
 type dbSession struct {
 	dbPerson
 	ID        int       `db:"session_id"`
-	Token     uuid.UUID `db:"token"`
 	CreatedAt time.Time `db:"created_at"`
 	ExpiresAt time.Time `db:"expires_at"`
 	IsRevoked bool      `db:"is_revoked"`
+
+	MaxExpiresAt time.Time `db:"max_expires_at"`
+	IsRememberMe bool      `db:"is_remember_me"`
+
+	UserAgent  string    `db:"user_agent"`
+	IPAddress  string    `db:"ip_address"`
+	LastSeenAt time.Time `db:"last_seen_at"`
+
+	ActiveOrganizationID null.Int `db:"active_organization_id"`
+
+	ImpersonatorID        null.Int `db:"impersonator_id"`
+	ImpersonatorSessionID null.Int `db:"impersonator_session_id"`
 }
 
 func (s *dbSession) toSession() app.Session {
 	return app.Session{
 		Person:    s.dbPerson.toPerson(),
 		ID:        s.ID,
-		Token:     s.Token,
 		CreatedAt: s.CreatedAt,
 		ExpiresAt: s.ExpiresAt,
 		IsRevoked: s.IsRevoked,
+
+		MaxExpiresAt: s.MaxExpiresAt,
+		IsRememberMe: s.IsRememberMe,
+
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		LastSeenAt: s.LastSeenAt,
+
+		ActiveOrganizationID: s.ActiveOrganizationID,
+
+		ImpersonatorID:        s.ImpersonatorID,
+		ImpersonatorSessionID: s.ImpersonatorSessionID,
 	}
 }
 
@@ -44,10 +66,17 @@ func (db *database) GetSessionsForPerson(
 	query := `
 		SELECT
 			s.session_id,
-			s.token,
 			s.created_at,
 			s.expires_at,
+			s.max_expires_at,
+			s.is_remember_me,
 			s.is_revoked,
+			s.user_agent,
+			s.ip_address,
+			s.last_seen_at,
+			s.active_organization_id,
+			s.impersonator_id,
+			s.impersonator_session_id,
 			p.person_id,
 			p.first_name,
 			p.last_name,
@@ -76,6 +105,7 @@ func (db *database) GetSessionsForPerson(
 		query += `
 			AND $2::timestamptz >= s.created_at::timestamptz
 			AND $2::timestamptz < s.expires_at::timestamptz
+			AND $2::timestamptz < s.max_expires_at::timestamptz
 		`
 	}
 
@@ -84,6 +114,7 @@ func (db *database) GetSessionsForPerson(
 			s.session_id,
 			p.person_id,
 			a.person_id
+		ORDER BY s.last_seen_at DESC
 	`
 
 	var dbss []dbSession
@@ -101,10 +132,10 @@ func (db *database) GetSessionsForPerson(
 	return ss, nil
 }
 
-// GetSessionByToken fetches the session with matching token.
+// GetSessionByToken fetches the session with matching token, by its hash.
 func (db *database) GetSessionByToken(
 	ctx context.Context,
-	token uuid.UUID,
+	token app.SecureToken,
 ) (app.Session, error) {
 
 	var dbs dbSession
@@ -112,10 +143,17 @@ func (db *database) GetSessionByToken(
 	err := db.GetContext(ctx, &dbs, `
 		SELECT
 			s.session_id,
-			s.token,
 			s.created_at,
 			s.expires_at,
+			s.max_expires_at,
+			s.is_remember_me,
 			s.is_revoked,
+			s.user_agent,
+			s.ip_address,
+			s.last_seen_at,
+			s.active_organization_id,
+			s.impersonator_id,
+			s.impersonator_session_id,
 			p.person_id,
 			p.first_name,
 			p.last_name,
@@ -129,17 +167,18 @@ func (db *database) GetSessionByToken(
 			ON s.person_id = p.person_id
 		LEFT JOIN affiliation a
 			ON p.person_id = a.person_id
-		WHERE s.token = $1
+		WHERE s.token_hash = $1
 		GROUP BY
 			s.session_id,
 			p.person_id,
 			a.person_id
-	`, token)
+	`, token.Hash())
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return app.Session{}, errors.Wrapf(
+		// Never include the token itself in errors, since they are logged.
+		return app.Session{}, errors.Wrap(
 			app.ErrNotFound,
-			"no such session by token '%s'", token,
+			"no such session by token",
 		)
 	}
 
@@ -155,17 +194,105 @@ func (db *database) CreateSession(
 	var id int
 	err := db.GetContext(ctx, &id, `
 		INSERT INTO session (
-			token,
+			token_hash,
 			person_id,
 			created_at,
-			expires_at
-		) VALUES ($1, $2, $3, $4)
+			expires_at,
+			max_expires_at,
+			is_remember_me,
+			user_agent,
+			ip_address,
+			last_seen_at,
+			impersonator_id,
+			impersonator_session_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING session_id
-	`, s.Token, s.Person.ID, s.CreatedAt, s.ExpiresAt)
+	`,
+		s.Token.Hash(),          // $1
+		s.Person.ID,             // $2
+		s.CreatedAt,             // $3
+		s.ExpiresAt,             // $4
+		s.MaxExpiresAt,          // $5
+		s.IsRememberMe,          // $6
+		s.UserAgent,             // $7
+		s.IPAddress,             // $8
+		s.LastSeenAt,            // $9
+		s.ImpersonatorID,        // $10
+		s.ImpersonatorSessionID, // $11
+	)
 
 	return id, errors.Wrap(err, "failed to insert session")
 }
 
+// RenewSession records that a session was used at a given time from a given
+// IP address, and extends its idle expiration.
+//
+// The permanent expiration of the session is never extended, even if the
+// given expiration is later.
+func (db *database) RenewSession(
+	ctx context.Context,
+	sessionID int,
+	ipAddress string,
+	lastSeenAt, expiresAt time.Time,
+) error {
+
+	result, err := db.ExecContext(ctx, `
+		UPDATE session SET
+			ip_address = $1,
+			last_seen_at = $2,
+			expires_at = LEAST($3::timestamptz, max_expires_at)
+		WHERE session_id = $4
+	`, ipAddress, lastSeenAt, expiresAt, sessionID)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to renew session")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "failed to check result of session renewal")
+	} else if n != 1 {
+		return errors.Wrapf(
+			app.ErrNotFound,
+			"no such session by id of %d", sessionID,
+		)
+	}
+
+	return nil
+}
+
+// SetSessionActiveOrganization changes the organization that a session is
+// working on. A null organization ID clears the selection.
+func (db *database) SetSessionActiveOrganization(
+	ctx context.Context,
+	sessionID int,
+	orgID null.Int,
+) error {
+
+	result, err := db.ExecContext(ctx, `
+		UPDATE session SET
+			active_organization_id = $1
+		WHERE session_id = $2
+	`, orgID, sessionID)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to set active organization")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err,
+			"failed to check result of setting active organization")
+	} else if n != 1 {
+		return errors.Wrapf(
+			app.ErrNotFound,
+			"no such session by id of %d", sessionID,
+		)
+	}
+
+	return nil
+}
+
 // RevokeSession revokes an existing session.
 func (db *database) RevokeSession(ctx context.Context, sessionID int) error {
 	result, err := db.ExecContext(ctx, `
@@ -8,9 +8,9 @@ import (
 	"testing"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
 
 	"github.com/BenJetson/CPSC491-project/go/app"
 )
@@ -74,7 +74,7 @@ func TestGetSessionsForPerson(t *testing.T) {
 	var s *app.Session
 	var ss1, ss2 []app.Session
 	for i := 0; i < 5; i++ {
-		s, err = app.NewSession(p1)
+		s, err = app.NewSession(p1, app.DefaultSessionPolicy(), false)
 		require.NoError(t, err)
 		require.NotNil(t, s)
 
@@ -87,7 +87,7 @@ func TestGetSessionsForPerson(t *testing.T) {
 	}
 
 	for i := 0; i < 3; i++ {
-		s, err = app.NewSession(p2)
+		s, err = app.NewSession(p2, app.DefaultSessionPolicy(), false)
 		require.NoError(t, err)
 		require.NotNil(t, s)
 
@@ -128,7 +128,7 @@ func TestGetSessionsForPerson(t *testing.T) {
 	require.NoError(t, err)
 
 	// Add one invalid session each.
-	s, err = app.NewSession(p1)
+	s, err = app.NewSession(p1, app.DefaultSessionPolicy(), false)
 	require.NoError(t, err)
 	require.NotNil(t, s)
 
@@ -139,7 +139,7 @@ func TestGetSessionsForPerson(t *testing.T) {
 
 	ss1 = append(ss1, *s)
 
-	s, err = app.NewSession(p2)
+	s, err = app.NewSession(p2, app.DefaultSessionPolicy(), false)
 	require.NoError(t, err)
 	require.NotNil(t, s)
 
@@ -211,7 +211,7 @@ func TestGetSessionByToken(t *testing.T) {
 	var s *app.Session
 	var ss1, ss2 []app.Session
 	for i := 0; i < 5; i++ {
-		s, err = app.NewSession(p1)
+		s, err = app.NewSession(p1, app.DefaultSessionPolicy(), false)
 		require.NoError(t, err)
 		require.NotNil(t, s)
 
@@ -224,7 +224,7 @@ func TestGetSessionByToken(t *testing.T) {
 	}
 
 	for i := 0; i < 3; i++ {
-		s, err = app.NewSession(p2)
+		s, err = app.NewSession(p2, app.DefaultSessionPolicy(), false)
 		require.NoError(t, err)
 		require.NotNil(t, s)
 
@@ -237,7 +237,7 @@ func TestGetSessionByToken(t *testing.T) {
 	}
 
 	t.Run("NoSuchSession", func(t *testing.T) {
-		_, err = db.GetSessionByToken(ctx, uuid.Nil)
+		_, err = db.GetSessionByToken(ctx, app.SecureToken{})
 		require.Error(t, err)
 		assert.True(t, errors.Is(err, app.ErrNotFound))
 	})
@@ -294,17 +294,17 @@ func TestCreateSession(t *testing.T) {
 
 		for _, s := range ss {
 			db.assertCountOf(t, "session", 1, `
-				token = $1
+				token_hash = $1
 				AND person_id = $2
 				AND created_at = $3::timestamptz
 				AND expires_at = $4::timestamptz
 				AND session_id = $5
-			`, s.Token, s.Person.ID, s.CreatedAt, s.ExpiresAt, s.ID)
+			`, s.Token.Hash(), s.Person.ID, s.CreatedAt, s.ExpiresAt, s.ID)
 		}
 	}
 
 	t.Run("NoSuchPerson", func(t *testing.T) {
-		s, err = app.NewSession(app.Person{})
+		s, err = app.NewSession(app.Person{}, app.DefaultSessionPolicy(), false)
 		require.NoError(t, err)
 		require.NotNil(t, s)
 
@@ -323,7 +323,7 @@ func TestCreateSession(t *testing.T) {
 				p = p2
 			}
 
-			s, err = app.NewSession(p)
+			s, err = app.NewSession(p, app.DefaultSessionPolicy(), false)
 			require.NoError(t, err)
 			require.NotNil(t, s)
 
@@ -376,12 +376,12 @@ func TestRevokeSession(t *testing.T) {
 
 		for _, s := range ss {
 			db.assertCountOf(t, "session", 1, `
-				token = $1
+				token_hash = $1
 				AND person_id = $2
 				AND created_at = $3::timestamptz
 				AND expires_at = $4::timestamptz
 				AND session_id = $5
-			`, s.Token, s.Person.ID, s.CreatedAt, s.ExpiresAt, s.ID)
+			`, s.Token.Hash(), s.Person.ID, s.CreatedAt, s.ExpiresAt, s.ID)
 		}
 	}
 
@@ -400,7 +400,7 @@ func TestRevokeSession(t *testing.T) {
 			p = p2
 		}
 
-		s, err = app.NewSession(p)
+		s, err = app.NewSession(p, app.DefaultSessionPolicy(), false)
 		require.NoError(t, err)
 		require.NotNil(t, s)
 
@@ -463,12 +463,12 @@ func TestRevokeSessionsForPersonExcept(t *testing.T) {
 
 		for _, s := range append(ss1, ss2...) {
 			db.assertCountOf(t, "session", 1, `
-				token = $1
+				token_hash = $1
 				AND person_id = $2
 				AND created_at = $3::timestamptz
 				AND expires_at = $4::timestamptz
 				AND session_id = $5
-			`, s.Token, s.Person.ID, s.CreatedAt, s.ExpiresAt, s.ID)
+			`, s.Token.Hash(), s.Person.ID, s.CreatedAt, s.ExpiresAt, s.ID)
 		}
 	}
 
@@ -479,7 +479,7 @@ func TestRevokeSessionsForPersonExcept(t *testing.T) {
 			p = p2
 		}
 
-		s, err = app.NewSession(p)
+		s, err = app.NewSession(p, app.DefaultSessionPolicy(), false)
 		require.NoError(t, err)
 		require.NotNil(t, s)
 
@@ -530,3 +530,125 @@ func TestRevokeSessionsForPersonExcept(t *testing.T) {
 		assertSessionPresence(t)
 	})
 }
+
+func TestRenewSession(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	p := app.Person{
+		ID:           1,
+		FirstName:    "Ben",
+		LastName:     "Godfrey",
+		Email:        "bfgodfr@clemson.edu",
+		Password:     `qwerty`,
+		Role:         app.RoleAdmin,
+		Affiliations: make([]int, 0),
+	}
+	_, err := db.CreatePerson(ctx, p)
+	require.NoError(t, err)
+
+	s, err := app.NewSession(p, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+
+	s.UserAgent = "Mozilla/5.0 (X11; Linux x86_64)"
+	s.IPAddress = "192.0.2.1"
+
+	s.ID, err = db.CreateSession(ctx, *s)
+	require.NoError(t, err)
+
+	t.Run("NoSuchSession", func(t *testing.T) {
+		now := time.Now()
+		err = db.RenewSession(ctx, 881, "192.0.2.2", now, now)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+
+	t.Run("Renew", func(t *testing.T) {
+		seen := s.CreatedAt.Add(5 * time.Minute)
+		expires := seen.Add(time.Hour)
+
+		err = db.RenewSession(ctx, s.ID, "192.0.2.2", seen, expires)
+		require.NoError(t, err)
+
+		db.assertCountOf(t, "session", 1, `
+			session_id = $1
+			AND user_agent = $2
+			AND ip_address = $3
+			AND last_seen_at = $4::timestamptz
+			AND expires_at = $5::timestamptz
+		`, s.ID, s.UserAgent, "192.0.2.2", seen, expires)
+	})
+
+	t.Run("CappedAtMaxExpiration", func(t *testing.T) {
+		seen := s.MaxExpiresAt.Add(-time.Minute)
+		expires := s.MaxExpiresAt.Add(time.Hour)
+
+		err = db.RenewSession(ctx, s.ID, "192.0.2.2", seen, expires)
+		require.NoError(t, err)
+
+		db.assertCountOf(t, "session", 1, `
+			session_id = $1
+			AND expires_at = $2::timestamptz
+		`, s.ID, s.MaxExpiresAt)
+	})
+}
+
+func TestSetSessionActiveOrganization(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	orgID, err := db.CreateOrganization(ctx, app.Organization{
+		Name:       "Acme Freight",
+		PointValue: 1,
+	})
+	require.NoError(t, err)
+
+	p := app.Person{
+		ID:           1,
+		FirstName:    "Ben",
+		LastName:     "Godfrey",
+		Email:        "bfgodfr@clemson.edu",
+		Password:     `qwerty`,
+		Role:         app.RoleSponsor,
+		Affiliations: make([]int, 0),
+	}
+	_, err = db.CreatePerson(ctx, p)
+	require.NoError(t, err)
+
+	s, err := app.NewSession(p, app.DefaultSessionPolicy(), false)
+	require.NoError(t, err)
+
+	s.ID, err = db.CreateSession(ctx, *s)
+	require.NoError(t, err)
+
+	t.Run("NoSuchSession", func(t *testing.T) {
+		err = db.SetSessionActiveOrganization(ctx, 881,
+			null.IntFrom(int64(orgID)))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		err = db.SetSessionActiveOrganization(ctx, s.ID,
+			null.IntFrom(int64(orgID)))
+		require.NoError(t, err)
+
+		got, err := db.GetSessionByToken(ctx, s.Token)
+		require.NoError(t, err)
+		assert.Equal(t, null.IntFrom(int64(orgID)), got.ActiveOrganizationID)
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		err = db.SetSessionActiveOrganization(ctx, s.ID, null.Int{})
+		require.NoError(t, err)
+
+		got, err := db.GetSessionByToken(ctx, s.Token)
+		require.NoError(t, err)
+		assert.False(t, got.ActiveOrganizationID.Valid)
+	})
+}
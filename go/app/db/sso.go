@@ -0,0 +1,341 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+type dbOrganizationSSO struct {
+	OrganizationID int            `db:"organization_id"`
+	IssuerURL      string         `db:"issuer_url"`
+	ClientID       string         `db:"client_id"`
+	ClientSecret   string         `db:"client_secret"`
+	EmailDomains   pq.StringArray `db:"email_domains"`
+	IsEnabled      bool           `db:"is_enabled"`
+}
+
+func (c *dbOrganizationSSO) toOrganizationSSO() app.OrganizationSSO {
+	return app.OrganizationSSO{
+		OrganizationID: c.OrganizationID,
+		IssuerURL:      c.IssuerURL,
+		ClientID:       c.ClientID,
+		ClientSecret:   c.ClientSecret,
+		EmailDomains:   []string(c.EmailDomains),
+		IsEnabled:      c.IsEnabled,
+	}
+}
+
+// GetOrganizationSSO fetches the single sign-on configuration of an
+// organization.
+func (db *database) GetOrganizationSSO(
+	ctx context.Context,
+	orgID int,
+) (app.OrganizationSSO, error) {
+
+	var c dbOrganizationSSO
+
+	err := db.GetContext(ctx, &c, `
+		SELECT
+			organization_id,
+			issuer_url,
+			client_id,
+			client_secret,
+			email_domains,
+			is_enabled
+		FROM organization_sso
+		WHERE organization_id = $1
+	`, orgID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return app.OrganizationSSO{}, errors.Wrapf(
+			app.ErrNotFound,
+			"no sso configuration for organization %d", orgID,
+		)
+	}
+
+	return c.toOrganizationSSO(), errors.Wrap(err, "failed to get sso config")
+}
+
+// ssoAuditSnapshotQuery captures the single sign-on configuration of an
+// organization for the audit log, without its client secret.
+const ssoAuditSnapshotQuery = `
+	SELECT to_jsonb(s) - 'client_secret'
+	FROM organization_sso s
+	WHERE s.organization_id = $1
+	FOR UPDATE
+`
+
+// SetOrganizationSSO creates or replaces the single sign-on configuration of
+// an organization.
+func (db *database) SetOrganizationSSO(
+	ctx context.Context,
+	c app.OrganizationSSO,
+) error {
+
+	domains := pq.StringArray(c.EmailDomains)
+	if domains == nil {
+		domains = pq.StringArray{}
+	}
+
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		before, err := auditSnapshot(ctx, tx, ssoAuditSnapshotQuery,
+			c.OrganizationID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO organization_sso (
+				organization_id,
+				issuer_url,
+				client_id,
+				client_secret,
+				email_domains,
+				is_enabled
+			) VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (organization_id) DO UPDATE SET
+				issuer_url = EXCLUDED.issuer_url,
+				client_id = EXCLUDED.client_id,
+				client_secret = EXCLUDED.client_secret,
+				email_domains = EXCLUDED.email_domains,
+				is_enabled = EXCLUDED.is_enabled
+		`,
+			c.OrganizationID, // $1
+			c.IssuerURL,      // $2
+			c.ClientID,       // $3
+			c.ClientSecret,   // $4
+			domains,          // $5
+			c.IsEnabled,      // $6
+		)
+		if err != nil {
+			return errors.Wrap(err, "failed to upsert sso config")
+		}
+
+		after, err := auditSnapshot(ctx, tx, ssoAuditSnapshotQuery,
+			c.OrganizationID)
+		if err != nil {
+			return err
+		}
+
+		return recordAuditEvent(ctx, tx, app.AuditEvent{
+			Action:         app.AuditActionSSOUpdate,
+			TargetType:     app.AuditTargetSSO,
+			TargetID:       c.OrganizationID,
+			OrganizationID: null.IntFrom(int64(c.OrganizationID)),
+			Before:         before,
+			After:          after,
+		})
+	})
+
+	return errors.Wrap(err, "failed to set sso config")
+}
+
+// DeleteOrganizationSSO removes the single sign-on configuration of an
+// organization.
+func (db *database) DeleteOrganizationSSO(
+	ctx context.Context,
+	orgID int,
+) error {
+
+	err := db.Transact(func(tx *sqlx.Tx) error {
+		before, err := auditSnapshot(ctx, tx, ssoAuditSnapshotQuery, orgID)
+		if err != nil {
+			return err
+		} else if before == nil {
+			return errors.Wrapf(
+				app.ErrNotFound,
+				"no sso configuration for organization %d", orgID,
+			)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM organization_sso
+			WHERE organization_id = $1
+		`, orgID)
+		if err != nil {
+			return errors.Wrap(err, "failed to delete sso config")
+		}
+
+		return recordAuditEvent(ctx, tx, app.AuditEvent{
+			Action:         app.AuditActionSSODelete,
+			TargetType:     app.AuditTargetSSO,
+			TargetID:       orgID,
+			OrganizationID: null.IntFrom(int64(orgID)),
+			Before:         before,
+		})
+	})
+
+	return errors.Wrap(err, "failed to delete sso config")
+}
+
+// CreateOIDCLogin records a new sign on attempt.
+func (db *database) CreateOIDCLogin(
+	ctx context.Context,
+	l app.OIDCLogin,
+) error {
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO oidc_login (
+			state_hash,
+			organization_id,
+			code_verifier,
+			nonce,
+			remember_me,
+			created_at,
+			expires_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`,
+		l.State.Hash(),   // $1
+		l.OrganizationID, // $2
+		l.CodeVerifier,   // $3
+		l.Nonce,          // $4
+		l.RememberMe,     // $5
+		l.CreatedAt,      // $6
+		l.ExpiresAt,      // $7
+	)
+
+	return errors.Wrap(err, "failed to insert oidc login")
+}
+
+// ConsumeOIDCLogin fetches the sign on attempt with matching state and removes
+// it, so that each attempt may only be completed once. Attempts that have
+// expired are removed as well.
+func (db *database) ConsumeOIDCLogin(
+	ctx context.Context,
+	state app.SecureToken,
+) (app.OIDCLogin, error) {
+
+	var l app.OIDCLogin
+
+	err := db.GetContext(ctx, &l, `
+		DELETE FROM oidc_login
+		WHERE state_hash = $1
+		RETURNING
+			organization_id,
+			code_verifier,
+			nonce,
+			remember_me,
+			created_at,
+			expires_at
+	`, state.Hash())
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return app.OIDCLogin{}, errors.Wrap(
+			app.ErrNotFound,
+			"no such oidc login by state",
+		)
+	} else if err != nil {
+		return app.OIDCLogin{}, errors.Wrap(err, "failed to get oidc login")
+	}
+
+	// Opportunistically clean up attempts that were abandoned.
+	_, err = db.ExecContext(ctx, `
+		DELETE FROM oidc_login
+		WHERE expires_at < NOW()
+	`)
+
+	return l, errors.Wrap(err, "failed to delete expired oidc logins")
+}
+
+// GetPersonByIdentity fetches the person who signs on with a given subject
+// identifier at a given issuer.
+func (db *database) GetPersonByIdentity(
+	ctx context.Context,
+	issuer, subject string,
+) (app.Person, error) {
+
+	var dbp dbPerson
+
+	err := db.GetContext(ctx, &dbp, `
+		SELECT
+			p.person_id,
+			p.first_name,
+			p.last_name,
+			p.email,
+			p.role_id,
+			p.pass_hash,
+			p.is_deactivated,
+			array_remove(array_agg(a.organization_id), NULL) as affiliations
+		FROM person_identity i
+		JOIN person p
+			ON i.person_id = p.person_id
+		LEFT JOIN affiliation a
+			ON p.person_id = a.person_id
+		WHERE
+			i.issuer_url = $1
+			AND i.subject = $2
+		GROUP BY p.person_id
+	`, issuer, subject)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return app.Person{}, errors.Wrapf(
+			app.ErrNotFound,
+			"no such person by identity '%s' at '%s'", subject, issuer,
+		)
+	}
+
+	return dbp.toPerson(), errors.Wrap(err, "failed to get person")
+}
+
+// linkPersonIdentity links an identity to a person within a transaction.
+func linkPersonIdentity(
+	ctx context.Context,
+	tx sqlx.ExecerContext,
+	personID int,
+	issuer, subject string,
+) error {
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO person_identity (
+			issuer_url,
+			subject,
+			person_id
+		) VALUES ($1, $2, $3)
+	`, issuer, subject, personID)
+
+	return errors.Wrap(err, "failed to insert person identity")
+}
+
+// LinkPersonIdentity allows an existing person to sign on with a subject
+// identifier at a given issuer.
+func (db *database) LinkPersonIdentity(
+	ctx context.Context,
+	personID int,
+	issuer, subject string,
+) error {
+
+	return linkPersonIdentity(ctx, db, personID, issuer, subject)
+}
+
+// ProvisionSSOPerson creates a person upon their first sign on, affiliates
+// them with the organization they signed on to, and links their identity, all
+// at once. Ignores the ID and Affiliations fields of the person.
+func (db *database) ProvisionSSOPerson(
+	ctx context.Context,
+	p app.Person,
+	orgID int,
+	issuer, subject string,
+) (int, error) {
+
+	var id int
+	err := db.Transact(func(tx *sqlx.Tx) (err error) {
+		if id, err = insertPerson(ctx, tx, p); err != nil {
+			return err
+		}
+
+		if err = addAffiliation(ctx, tx, id, orgID); err != nil {
+			return err
+		}
+
+		return linkPersonIdentity(ctx, tx, id, issuer, subject)
+	})
+
+	return id, errors.Wrap(err, "failed to provision person")
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func TestOrganizationSSO(t *testing.T) {
+	db := newTestDB(t)
+	defer db.cleanup(t)
+
+	ctx := context.Background()
+
+	orgID, err := db.CreateOrganization(ctx, app.Organization{
+		Name:       "Trucking Co.",
+		PointValue: 1,
+	})
+	require.NoError(t, err)
+
+	c := app.OrganizationSSO{
+		OrganizationID: orgID,
+		IssuerURL:      "https://sso.trucking.example",
+		ClientID:       "client",
+		ClientSecret:   "secret",
+		EmailDomains:   []string{"trucking.example"},
+		IsEnabled:      true,
+	}
+
+	t.Run("Set", func(t *testing.T) {
+		require.NoError(t, db.SetOrganizationSSO(ctx, c))
+
+		c.IsEnabled = false
+		require.NoError(t, db.SetOrganizationSSO(ctx, c))
+
+		got, err := db.GetOrganizationSSO(ctx, orgID)
+		require.NoError(t, err)
+		assert.Equal(t, c, got)
+
+		db.assertCountOf(t, "audit_event", 1, `
+			action = $1
+			AND target_id = $2
+			AND before IS NULL
+		`, app.AuditActionSSOUpdate, orgID)
+		db.assertCountOf(t, "audit_event", 1, `
+			action = $1
+			AND target_id = $2
+			AND (before->>'is_enabled')::boolean
+			AND NOT (after->>'is_enabled')::boolean
+		`, app.AuditActionSSOUpdate, orgID)
+		// The client secret is never written to the audit log.
+		db.assertCountOf(t, "audit_event", 0, `
+			before ? 'client_secret'
+			OR after ? 'client_secret'
+		`)
+	})
+
+	t.Run("Provision", func(t *testing.T) {
+		id, err := db.ProvisionSSOPerson(ctx, app.Person{
+			FirstName: "Ben",
+			LastName:  "Godfrey",
+			Email:     "bfgodfr@trucking.example",
+			Role:      app.RoleSponsor,
+		}, orgID, c.IssuerURL, "subject")
+		require.NoError(t, err)
+
+		p, err := db.GetPersonByIdentity(ctx, c.IssuerURL, "subject")
+		require.NoError(t, err)
+		assert.Equal(t, id, p.ID)
+		assert.Equal(t, []int{orgID}, p.Affiliations)
+
+		db.assertCountOf(t, "audit_event", 1, `
+			action = $1
+			AND target_id = $2
+		`, app.AuditActionPersonCreate, id)
+		db.assertCountOf(t, "audit_event", 1, `
+			action = $1
+			AND target_id = $2
+			AND organization_id = $3
+		`, app.AuditActionAffiliationAdd, id, orgID)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, db.DeleteOrganizationSSO(ctx, orgID))
+
+		err := db.DeleteOrganizationSSO(ctx, orgID)
+		assert.True(t, errors.Is(err, app.ErrNotFound))
+
+		db.assertCountOf(t, "audit_event", 1, `
+			action = $1
+			AND target_id = $2
+			AND after IS NULL
+		`, app.AuditActionSSODelete, orgID)
+	})
+}
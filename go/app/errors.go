@@ -16,3 +16,8 @@ import "github.com/pkg/errors"
 //     return errors.Wrapf(app.ErrNotFound, "book #%d", id)
 //
 var ErrNotFound = errors.New("not found")
+
+// ErrConflict may be returned by a DataStore implementation when a change
+// cannot be made because of the current state of the data, such as when a
+// decision that is final has already been made.
+var ErrConflict = errors.New("conflict")
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// A FileStore keeps the contents of uploaded files. Files are identified by
+// keys that the FileStore chooses.
+type FileStore interface {
+	PutFile(ctx context.Context, content io.Reader) (key string, err error)
+	OpenFile(ctx context.Context, key string) (io.ReadCloser, error)
+	DeleteFile(ctx context.Context, key string) error
+}
+
+// A FileScanner checks uploaded files for malware before they are stored.
+//
+// ScanFile returns an error wrapping ErrFileInfected when the file must be
+// refused, or another error if the file could not be scanned.
+type FileScanner interface {
+	ScanFile(ctx context.Context, content io.Reader) error
+}
+
+// ErrFileInfected is returned by a FileScanner for files that contain malware.
+var ErrFileInfected = errors.New("file is infected")
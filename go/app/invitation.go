@@ -0,0 +1,100 @@
+package app
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// DefaultInvitationLifetime is how long a person has to accept an invitation
+// to join an organization, unless configured otherwise.
+const DefaultInvitationLifetime = 7 * 24 * time.Hour
+
+// An InvitationStatus describes where an Invitation stands.
+type InvitationStatus string
+
+// These are the statuses that an invitation may have.
+const (
+	// InvitationPending invitations may still be accepted.
+	InvitationPending InvitationStatus = "pending"
+	// InvitationAccepted invitations were accepted, and may not be again.
+	InvitationAccepted InvitationStatus = "accepted"
+	// InvitationRevoked invitations were withdrawn by a sponsor, or replaced
+	// by a newer invitation to the same person.
+	InvitationRevoked InvitationStatus = "revoked"
+	// InvitationExpired invitations were not accepted in time. This status is
+	// never stored; pending invitations report it once they expire.
+	InvitationExpired InvitationStatus = "expired"
+)
+
+// An Invitation asks a person, by email, to join an organization as a driver
+// or sponsor. Accepting it creates their account if they have none, and
+// affiliates them with the organization without an application.
+type Invitation struct {
+	// ID is the identifying number of this invitation. It is not secret.
+	ID int `db:"invitation_id" json:"id"`
+	// Token is the secret sent to the invitee in their invitation link.
+	//
+	// Only the hash of the token is stored, so this will be the zero value
+	// for invitations retrieved from a DataStore.
+	Token SecureToken `db:"-" json:"-"`
+	// OrganizationID identifies the organization the invitee is invited to.
+	OrganizationID int `db:"organization_id" json:"organization_id"`
+	// OrganizationName is the name of that organization.
+	OrganizationName string `db:"organization_name" json:"organization_name"`
+	// Email is the address that the invitation was sent to.
+	Email string `db:"email" json:"email"`
+	// Role is the role that the invitee will have in the organization, which
+	// is either RoleDriver or RoleSponsor.
+	Role Role `db:"role_id" json:"role_id"`
+	// InvitedBy identifies the sponsor who sent this invitation. Will be null
+	// if they no longer exist.
+	InvitedBy null.Int `db:"invited_by" json:"invited_by"`
+	// Status describes whether this invitation may still be accepted.
+	Status InvitationStatus `db:"status" json:"status"`
+	// CreatedAt is the timestamp this invitation was sent at.
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	// ExpiresAt is the timestamp after which this invitation cannot be
+	// accepted.
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+	// AcceptedAt is the timestamp this invitation was accepted at. Will be
+	// null if it has not been accepted.
+	AcceptedAt null.Time `db:"accepted_at" json:"accepted_at"`
+	// AcceptedBy identifies the person who accepted this invitation. Will be
+	// null if it has not been accepted, or they no longer exist.
+	AcceptedBy null.Int `db:"accepted_by" json:"accepted_by"`
+}
+
+// NewInvitation creates a new pending invitation with a secure random token,
+// which expires after the given lifetime.
+func NewInvitation(
+	orgID int,
+	email string,
+	role Role,
+	invitedBy null.Int,
+	lifetime time.Duration,
+) (*Invitation, error) {
+
+	now := time.Now().UTC().Round(time.Second)
+
+	token, err := NewSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Invitation{
+		Token:          token,
+		OrganizationID: orgID,
+		Email:          email,
+		Role:           role,
+		InvitedBy:      invitedBy,
+		Status:         InvitationPending,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(lifetime),
+	}, nil
+}
+
+// IsValid determines whether or not this invitation may still be accepted.
+func (i *Invitation) IsValid() bool {
+	return i.Status == InvitationPending && time.Now().Before(i.ExpiresAt)
+}
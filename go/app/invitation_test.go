@@ -0,0 +1,47 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestInvitationIsValid(t *testing.T) {
+	fresh := func(lifetime time.Duration) Invitation {
+		inv, err := NewInvitation(1, "bfgodfr@clemson.edu", RoleDriver,
+			null.IntFrom(2), lifetime)
+		require.NoError(t, err)
+		require.False(t, inv.Token.IsZero())
+		return *inv
+	}
+
+	accepted := fresh(time.Hour)
+	accepted.Status = InvitationAccepted
+
+	revoked := fresh(time.Hour)
+	revoked.Status = InvitationRevoked
+
+	expired := fresh(time.Hour)
+	expired.Status = InvitationExpired
+
+	testCases := []struct {
+		alias  string
+		inv    Invitation
+		expect bool
+	}{
+		{alias: "Fresh", inv: fresh(time.Hour), expect: true},
+		{alias: "PastExpiry", inv: fresh(-time.Second)},
+		{alias: "Accepted", inv: accepted},
+		{alias: "Revoked", inv: revoked},
+		{alias: "Expired", inv: expired},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.inv.IsValid())
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// A LoginAttempt records a single attempt to log in using a password.
+type LoginAttempt struct {
+	// ID uniquely identifies this login attempt.
+	ID int `db:"login_attempt_id"`
+	// Email is the email address that was submitted with this attempt. It is
+	// recorded even when no such account exists.
+	Email string `db:"email"`
+	// IPAddress is the address of the client that made this attempt.
+	IPAddress string `db:"ip_address"`
+	// Succeeded is true when the attempt resulted in a new login session.
+	Succeeded bool `db:"succeeded"`
+	// AttemptedAt is the timestamp of this attempt.
+	AttemptedAt time.Time `db:"attempted_at"`
+}
+
+// LoginFailures summarizes the recent failed login attempts for a particular
+// account or client.
+type LoginFailures struct {
+	// Count is the number of failed attempts.
+	Count int `db:"count"`
+	// LastFailedAt is the timestamp of the most recent failed attempt. Will be
+	// null when Count is zero.
+	LastFailedAt null.Time `db:"last_failed_at"`
+}
+
+// A LockoutPolicy describes how repeated failed login attempts are throttled.
+//
+// After DelayAfter failures, each further attempt must wait a progressively
+// longer delay, beginning at BaseDelay and doubling up to MaxDelay. Once
+// LockoutThreshold failures have accumulated, all attempts are refused until
+// LockoutDuration has passed since the most recent failure.
+type LockoutPolicy struct {
+	// Window is how far back failed attempts are considered.
+	Window time.Duration
+	// DelayAfter is the number of failures allowed before delays begin. Zero
+	// disables delays.
+	DelayAfter int
+	// BaseDelay is the first delay applied once DelayAfter is reached.
+	BaseDelay time.Duration
+	// MaxDelay is the upper bound for progressive delays.
+	MaxDelay time.Duration
+	// LockoutThreshold is the number of failures that triggers a temporary
+	// lockout. Zero disables lockouts.
+	LockoutThreshold int
+	// LockoutDuration is how long a lockout lasts after the last failure.
+	LockoutDuration time.Duration
+}
+
+// DefaultAccountLockoutPolicy is the suggested policy for failures keyed on a
+// particular account.
+var DefaultAccountLockoutPolicy = LockoutPolicy{
+	Window:           15 * time.Minute,
+	DelayAfter:       3,
+	BaseDelay:        time.Second,
+	MaxDelay:         30 * time.Second,
+	LockoutThreshold: 10,
+	LockoutDuration:  15 * time.Minute,
+}
+
+// DefaultIPLockoutPolicy is the suggested policy for failures keyed on a
+// client IP address. It is more lenient than the account policy, since many
+// legitimate users may share an address.
+var DefaultIPLockoutPolicy = LockoutPolicy{
+	Window:           15 * time.Minute,
+	DelayAfter:       10,
+	BaseDelay:        time.Second,
+	MaxDelay:         30 * time.Second,
+	LockoutThreshold: 50,
+	LockoutDuration:  15 * time.Minute,
+}
+
+// IsLockedOut determines whether the given failures exceed the lockout
+// threshold of this policy.
+func (p LockoutPolicy) IsLockedOut(f LoginFailures) bool {
+	return p.LockoutThreshold > 0 && f.Count >= p.LockoutThreshold
+}
+
+// RetryAfter computes how long a client must wait before another attempt is
+// permitted, given its recent failures. Returns zero when an attempt may be
+// made right away.
+func (p LockoutPolicy) RetryAfter(
+	f LoginFailures,
+	now time.Time,
+) time.Duration {
+
+	if !f.LastFailedAt.Valid {
+		return 0
+	}
+
+	var wait time.Duration
+	if p.IsLockedOut(f) {
+		wait = p.LockoutDuration
+	} else if p.DelayAfter > 0 && f.Count >= p.DelayAfter {
+		wait = p.BaseDelay
+		for i := p.DelayAfter; i < f.Count && wait < p.MaxDelay; i++ {
+			wait *= 2
+		}
+
+		if wait > p.MaxDelay {
+			wait = p.MaxDelay
+		}
+	}
+
+	if wait <= 0 {
+		return 0
+	}
+
+	remaining := f.LastFailedAt.Time.Add(wait).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestLockoutPolicyRetryAfter(t *testing.T) {
+	now := time.Date(2021, time.March, 14, 15, 9, 26, 0, time.UTC)
+
+	p := LockoutPolicy{
+		Window:           15 * time.Minute,
+		DelayAfter:       3,
+		BaseDelay:        time.Second,
+		MaxDelay:         10 * time.Second,
+		LockoutThreshold: 8,
+		LockoutDuration:  15 * time.Minute,
+	}
+
+	failures := func(count int, ago time.Duration) LoginFailures {
+		return LoginFailures{
+			Count:        count,
+			LastFailedAt: null.TimeFrom(now.Add(-ago)),
+		}
+	}
+
+	testCases := []struct {
+		alias    string
+		policy   LockoutPolicy
+		failures LoginFailures
+		expect   time.Duration
+	}{
+		{
+			alias:  "NoFailures",
+			policy: p,
+			expect: 0,
+		},
+		{
+			alias:    "BelowDelay",
+			policy:   p,
+			failures: failures(2, 0),
+			expect:   0,
+		},
+		{
+			alias:    "FirstDelay",
+			policy:   p,
+			failures: failures(3, 0),
+			expect:   time.Second,
+		},
+		{
+			alias:    "DoubledDelay",
+			policy:   p,
+			failures: failures(5, 0),
+			expect:   4 * time.Second,
+		},
+		{
+			alias:    "CappedDelay",
+			policy:   p,
+			failures: failures(7, 0),
+			expect:   10 * time.Second,
+		},
+		{
+			alias:    "DelayPartlyElapsed",
+			policy:   p,
+			failures: failures(5, 3*time.Second),
+			expect:   time.Second,
+		},
+		{
+			alias:    "DelayElapsed",
+			policy:   p,
+			failures: failures(5, time.Minute),
+			expect:   0,
+		},
+		{
+			alias:    "LockedOut",
+			policy:   p,
+			failures: failures(8, time.Minute),
+			expect:   14 * time.Minute,
+		},
+		{
+			alias:    "LockoutElapsed",
+			policy:   p,
+			failures: failures(8, time.Hour),
+			expect:   0,
+		},
+		{
+			alias:    "Disabled",
+			policy:   LockoutPolicy{},
+			failures: failures(100, 0),
+			expect:   0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.policy.RetryAfter(tc.failures, now))
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package app
+
+import "context"
+
+// A Mail is a plain text email message to a single recipient.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// A Mailer sends email on behalf of our app.
+type Mailer interface {
+	SendMail(ctx context.Context, m Mail) error
+}
@@ -0,0 +1,139 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// These are assertions, which will cause the build to fail if the mailers do
+// not implement the app.Mailer interface.
+var (
+	_ app.Mailer = (*SMTPMailer)(nil)
+	_ app.Mailer = (*LogMailer)(nil)
+)
+
+// An SMTPMailer sends email through an SMTP relay.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer creates a new SMTPMailer that relays mail through the given
+// host, sending from the given address. When username is blank, the relay is
+// used without authentication.
+func NewSMTPMailer(
+	host string,
+	port int,
+	username, password string,
+	from string,
+) *SMTPMailer {
+
+	m := &SMTPMailer{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		from: from,
+	}
+
+	if len(username) > 0 {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return m
+}
+
+// SendMail sends a message through the SMTP relay.
+func (m *SMTPMailer) SendMail(ctx context.Context, msg app.Mail) error {
+	data, err := formatMessage(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, data)
+	return errors.Wrapf(err, "failed to send mail to %s", msg.To)
+}
+
+// formatMessage encodes a message as an RFC 5322 plain text email.
+func formatMessage(from string, msg app.Mail, date time.Time) ([]byte, error) {
+	for _, header := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, errors.New("mail headers may not contain line breaks")
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return b.Bytes(), nil
+}
+
+// A LogMailer writes email to the log instead of sending it, for use when no
+// SMTP relay is configured.
+type LogMailer struct {
+	logger *logrus.Logger
+}
+
+// NewLogMailer creates a new LogMailer that writes to the given logger.
+func NewLogMailer(logger *logrus.Logger) *LogMailer {
+	return &LogMailer{logger: logger}
+}
+
+// SendMail writes a message to the log.
+func (m *LogMailer) SendMail(ctx context.Context, msg app.Mail) error {
+	m.logger.WithFields(logrus.Fields{
+		"to":      msg.To,
+		"subject": msg.Subject,
+	}).Infof("Not sending mail, since no SMTP relay is set.\n%s", msg.Body)
+
+	return nil
+}
+
+// NewMailerFromEnv attempts to initialize a Mailer using settings from the
+// environment. When SMTP_HOST is not set, mail is only written to the log.
+func NewMailerFromEnv(logger *logrus.Logger) (app.Mailer, error) {
+	host := os.Getenv("SMTP_HOST")
+	if len(host) < 1 {
+		return NewLogMailer(logger), nil
+	}
+
+	port := 587
+	if value := os.Getenv("SMTP_PORT"); len(value) > 0 {
+		var err error
+		if port, err = strconv.Atoi(value); err != nil {
+			return nil, errors.New("SMTP_PORT must be an integer")
+		}
+	}
+
+	from := os.Getenv("MAIL_FROM")
+	if len(from) < 1 {
+		return nil, errors.New("must set MAIL_FROM")
+	}
+
+	return NewSMTPMailer(
+		host,
+		port,
+		os.Getenv("SMTP_USERNAME"),
+		os.Getenv("SMTP_PASSWORD"),
+		from,
+	), nil
+}
@@ -0,0 +1,60 @@
+package mail
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func TestFormatMessage(t *testing.T) {
+	date := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		alias       string
+		msg         app.Mail
+		expect      string
+		expectError bool
+	}{
+		{
+			alias: "Normal",
+			msg: app.Mail{
+				To:      "bfgodfr@clemson.edu",
+				Subject: "Hello",
+				Body:    "First line.\nSecond line.\r\n",
+			},
+			expect: "From: noreply@teamxiv.space\r\n" +
+				"To: bfgodfr@clemson.edu\r\n" +
+				"Subject: Hello\r\n" +
+				"Date: Mon, 01 Mar 2021 12:00:00 +0000\r\n" +
+				"MIME-Version: 1.0\r\n" +
+				"Content-Type: text/plain; charset=UTF-8\r\n" +
+				"\r\n" +
+				"First line.\r\nSecond line.\r\n",
+		},
+		{
+			alias: "HeaderInjection",
+			msg: app.Mail{
+				To:      "bfgodfr@clemson.edu",
+				Subject: "Hello\r\nBcc: victim@example.com",
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			data, err := formatMessage("noreply@teamxiv.space", tc.msg, date)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, string(data))
+		})
+	}
+}
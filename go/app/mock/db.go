@@ -2,8 +2,8 @@ package mock
 
 import (
 	"context"
+	"time"
 
-	"github.com/google/uuid"
 	"gopkg.in/guregu/null.v4"
 
 	"github.com/BenJetson/CPSC491-project/go/app"
@@ -94,10 +94,31 @@ func (db *DB) UpdatePersonPassword(
 	return nil
 }
 
+// RehashPersonPassword mocks replacing a person's password hash.
+func (db *DB) RehashPersonPassword(
+	ctx context.Context,
+	personID int,
+	oldPass, newPass app.Password,
+) error {
+
+	return nil
+}
+
+// GetPreviousPasswords mocks getting the passwords a person has replaced.
+func (db *DB) GetPreviousPasswords(
+	ctx context.Context,
+	personID int,
+	limit int,
+) ([]app.Password, error) {
+
+	return nil, nil
+}
+
 // ActivatePerson mocks activating a person's account.
 func (db *DB) ActivatePerson(
 	ctx context.Context,
 	personID int,
+	reason string,
 ) error {
 
 	return nil
@@ -107,6 +128,7 @@ func (db *DB) ActivatePerson(
 func (db *DB) DeactivatePerson(
 	ctx context.Context,
 	personID int,
+	reason string,
 ) error {
 
 	return nil
@@ -130,7 +152,7 @@ func (db *DB) GetSessionsForPerson(ctx context.Context,
 // GetSessionByToken mocks fetching a session by its token.
 func (db *DB) GetSessionByToken(
 	ctx context.Context,
-	token uuid.UUID,
+	token app.SecureToken,
 ) (app.Session, error) {
 
 	return app.Session{}, nil
@@ -141,6 +163,28 @@ func (db *DB) CreateSession(ctx context.Context, s app.Session) (int, error) {
 	return 0, nil
 }
 
+// RenewSession mocks recording activity for a session and extending it.
+func (db *DB) RenewSession(
+	ctx context.Context,
+	sessionID int,
+	ipAddress string,
+	lastSeenAt, expiresAt time.Time,
+) error {
+
+	return nil
+}
+
+// SetSessionActiveOrganization mocks changing the active organization of a
+// session.
+func (db *DB) SetSessionActiveOrganization(
+	ctx context.Context,
+	sessionID int,
+	orgID null.Int,
+) error {
+
+	return nil
+}
+
 // RevokeSession mocks revoking a session.
 func (db *DB) RevokeSession(ctx context.Context, sessionID int) error {
 	return nil
@@ -156,17 +200,189 @@ func (db *DB) RevokeSessionsForPersonExcept(
 	return nil
 }
 
+//
+//
+// APITokenStore methods
+//
+//
+
+// GetAPITokensForPerson mocks fetching all API tokens for a person.
+func (db *DB) GetAPITokensForPerson(
+	ctx context.Context,
+	personID int,
+	includeInvalid bool,
+) ([]app.APIToken, error) {
+
+	return nil, nil
+}
+
+// GetAPITokenByToken mocks fetching an API token by its secret value.
+func (db *DB) GetAPITokenByToken(
+	ctx context.Context,
+	token app.SecureToken,
+) (app.APIToken, error) {
+
+	return app.APIToken{}, nil
+}
+
+// CreateAPIToken mocks creating a new API token.
+func (db *DB) CreateAPIToken(ctx context.Context, t app.APIToken) (int, error) {
+	return 0, nil
+}
+
+// TouchAPIToken mocks recording the use of an API token.
+func (db *DB) TouchAPIToken(
+	ctx context.Context,
+	tokenID int,
+	lastUsedAt time.Time,
+) error {
+
+	return nil
+}
+
+// RevokeAPIToken mocks revoking an API token.
+func (db *DB) RevokeAPIToken(ctx context.Context, tokenID int) error {
+	return nil
+}
+
+//
+//
+// SSOStore methods
+//
+//
+
+// GetOrganizationSSO mocks fetching the sign on configuration of an org.
+func (db *DB) GetOrganizationSSO(
+	ctx context.Context,
+	orgID int,
+) (app.OrganizationSSO, error) {
+
+	return app.OrganizationSSO{}, nil
+}
+
+// SetOrganizationSSO mocks storing the sign on configuration of an org.
+func (db *DB) SetOrganizationSSO(
+	ctx context.Context,
+	c app.OrganizationSSO,
+) error {
+
+	return nil
+}
+
+// DeleteOrganizationSSO mocks removing the sign on configuration of an org.
+func (db *DB) DeleteOrganizationSSO(ctx context.Context, orgID int) error {
+	return nil
+}
+
+// CreateOIDCLogin mocks starting a sign on attempt.
+func (db *DB) CreateOIDCLogin(ctx context.Context, l app.OIDCLogin) error {
+	return nil
+}
+
+// ConsumeOIDCLogin mocks fetching and removing a sign on attempt.
+func (db *DB) ConsumeOIDCLogin(
+	ctx context.Context,
+	state app.SecureToken,
+) (app.OIDCLogin, error) {
+
+	return app.OIDCLogin{}, nil
+}
+
+// GetPersonByIdentity mocks fetching a person by their sign on identity.
+func (db *DB) GetPersonByIdentity(
+	ctx context.Context,
+	issuer, subject string,
+) (app.Person, error) {
+
+	return app.Person{}, nil
+}
+
+// LinkPersonIdentity mocks linking a sign on identity to a person.
+func (db *DB) LinkPersonIdentity(
+	ctx context.Context,
+	personID int,
+	issuer, subject string,
+) error {
+
+	return nil
+}
+
+// ProvisionSSOPerson mocks creating a person upon their first sign on.
+func (db *DB) ProvisionSSOPerson(
+	ctx context.Context,
+	p app.Person,
+	orgID int,
+	issuer, subject string,
+) (int, error) {
+
+	return 0, nil
+}
+
+//
+//
+// PermissionStore methods
+//
+//
+
+// GetPermissionsForRole mocks fetching the permissions granted to a role.
+func (db *DB) GetPermissionsForRole(
+	ctx context.Context,
+	role app.Role,
+) ([]app.Permission, error) {
+
+	return nil, nil
+}
+
+//
+//
+// LoginAttemptStore methods
+//
+//
+
+// StartLoginAttempt mocks checking the lockout for and recording a login
+// attempt.
+func (db *DB) StartLoginAttempt(
+	ctx context.Context,
+	a app.LoginAttempt,
+	accountPolicy, ipPolicy app.LockoutPolicy,
+) (int, time.Duration, error) {
+
+	return 0, 0, nil
+}
+
+// SucceedLoginAttempt mocks marking a login attempt as succeeded.
+func (db *DB) SucceedLoginAttempt(ctx context.Context, attemptID int) error {
+	return nil
+}
+
+// ClearLoginFailuresForEmail mocks clearing failed logins for an email.
+func (db *DB) ClearLoginFailuresForEmail(
+	ctx context.Context,
+	email string,
+) error {
+
+	return nil
+}
+
 //
 //
 // AffiliationStore methods
 //
 //
 
+// GetAffiliationsForPerson mocks fetching a person's affiliations.
+func (db *DB) GetAffiliationsForPerson(
+	ctx context.Context,
+	personID int,
+) ([]app.Affiliation, error) {
+
+	return nil, nil
+}
+
 // AddPersonAffiliation mocks adding an affiliation for a person.
 func (db *DB) AddPersonAffiliation(
 	ctx context.Context,
 	personID, orgID int,
-	role app.Role,
 ) error {
 
 	return nil
@@ -181,6 +397,16 @@ func (db *DB) RemovePersonAffiliation(
 	return nil
 }
 
+// ChangePersonAffiliations mocks adding and removing a person's affiliations.
+func (db *DB) ChangePersonAffiliations(
+	ctx context.Context,
+	personID int,
+	add, remove []int,
+) error {
+
+	return nil
+}
+
 // SetPointsForAffiliation mocks setting points for an affiliation.
 func (db *DB) SetPointsForAffiliation(
 	ctx context.Context,
@@ -223,6 +449,33 @@ func (db *DB) GetOrganizationByID(
 	return app.Organization{}, nil
 }
 
+// GetArchivedOrganizations mocks fetching all archived organizations.
+func (db *DB) GetArchivedOrganizations(
+	ctx context.Context,
+) ([]app.Organization, error) {
+
+	return nil, nil
+}
+
+// GetOrganizationsForPerson mocks fetching the organizations that a person is
+// affiliated with.
+func (db *DB) GetOrganizationsForPerson(
+	ctx context.Context,
+	personID int,
+) ([]app.Organization, error) {
+
+	return nil, nil
+}
+
+// GetOrganizationsAcceptingApplications mocks fetching the organizations that
+// are accepting applications.
+func (db *DB) GetOrganizationsAcceptingApplications(
+	ctx context.Context,
+) ([]app.Organization, error) {
+
+	return nil, nil
+}
+
 // CreateOrganization mocks creating a new organization.
 func (db *DB) CreateOrganization(
 	ctx context.Context,
@@ -241,11 +494,36 @@ func (db *DB) UpdateOrganization(
 	return nil
 }
 
-// DeleteOrganization mocks deleting an organization.
-func (db *DB) DeleteOrganization(ctx context.Context, orgID int) error {
+// SetOrganizationLogo mocks setting or clearing the logo of an organization.
+func (db *DB) SetOrganizationLogo(
+	ctx context.Context,
+	orgID int,
+	key, contentType null.String,
+) (null.String, error) {
+
+	return null.String{}, nil
+}
+
+// ArchiveOrganization mocks archiving an organization.
+func (db *DB) ArchiveOrganization(ctx context.Context, orgID int) error {
 	return nil
 }
 
+// RestoreOrganization mocks restoring an archived organization.
+func (db *DB) RestoreOrganization(ctx context.Context, orgID int) error {
+	return nil
+}
+
+// PurgeOrganization mocks permanently deleting an archived organization.
+func (db *DB) PurgeOrganization(
+	ctx context.Context,
+	orgID int,
+	retention time.Duration,
+) ([]string, error) {
+
+	return nil, nil
+}
+
 //
 //
 // CatalogStore methods
@@ -320,11 +598,12 @@ func (db *DB) GetApplicationsForPerson(
 	return nil, nil
 }
 
-// GetApplicationsForOrganization mocks getting all applications submitted for
-// an organization.
+// GetApplicationsForOrganization mocks getting the applications submitted for
+// an organization that match a filter.
 func (db *DB) GetApplicationsForOrganization(
 	ctx context.Context,
 	orgID int,
+	f app.ApplicationFilter,
 ) ([]app.Application, error) {
 
 	return nil, nil
@@ -339,13 +618,212 @@ func (db *DB) CreateApplication(
 	return 0, nil
 }
 
-// UpdateApplicationApproval mocks setting application approval.
-func (db *DB) UpdateApplicationApproval(
+// TransitionApplication mocks changing the status of an application.
+func (db *DB) TransitionApplication(
 	ctx context.Context,
 	appID int,
-	status bool,
-	reason string,
+	from, to app.ApplicationStatus,
+	message string,
 ) error {
 
 	return nil
 }
+
+// GetApplicationHistory mocks fetching the history of an application.
+func (db *DB) GetApplicationHistory(
+	ctx context.Context,
+	appID int,
+) ([]app.ApplicationEvent, error) {
+
+	return nil, nil
+}
+
+// GetApplicationForm mocks fetching the application form of an organization.
+func (db *DB) GetApplicationForm(
+	ctx context.Context,
+	orgID int,
+) ([]app.ApplicationQuestion, error) {
+
+	return nil, nil
+}
+
+// SetApplicationForm mocks replacing the application form of an organization.
+func (db *DB) SetApplicationForm(
+	ctx context.Context,
+	orgID int,
+	questions []app.ApplicationQuestion,
+) error {
+
+	return nil
+}
+
+//
+//
+// AuditStore methods
+//
+//
+
+// GetAuditEvents mocks fetching audit events matching a filter.
+func (db *DB) GetAuditEvents(
+	ctx context.Context,
+	f app.AuditFilter,
+) ([]app.AuditEvent, error) {
+
+	return nil, nil
+}
+
+//
+//
+// NotificationStore methods
+//
+//
+
+// GetNotificationsForPerson mocks fetching the notifications of a person.
+func (db *DB) GetNotificationsForPerson(
+	ctx context.Context,
+	personID int,
+	unreadOnly bool,
+) ([]app.Notification, error) {
+
+	return nil, nil
+}
+
+// MarkNotificationRead mocks marking a notification as read.
+func (db *DB) MarkNotificationRead(
+	ctx context.Context,
+	notificationID, personID int,
+) error {
+
+	return nil
+}
+
+//
+//
+// AccountInvitationStore methods
+//
+//
+
+// CreateInvitedPerson mocks creating a person with an account invitation.
+func (db *DB) CreateInvitedPerson(
+	ctx context.Context,
+	p app.Person,
+	inv app.AccountInvitation,
+) (int, error) {
+
+	return 0, nil
+}
+
+// GetAccountInvitationByToken mocks fetching an account invitation by token.
+func (db *DB) GetAccountInvitationByToken(
+	ctx context.Context,
+	token app.SecureToken,
+) (app.AccountInvitation, error) {
+
+	return app.AccountInvitation{}, nil
+}
+
+// ReissueAccountInvitation mocks replacing a person's account invitations.
+func (db *DB) ReissueAccountInvitation(
+	ctx context.Context,
+	inv app.AccountInvitation,
+) (int, error) {
+
+	return 0, nil
+}
+
+// AcceptAccountInvitation mocks accepting an account invitation.
+func (db *DB) AcceptAccountInvitation(
+	ctx context.Context,
+	invitationID int,
+	p app.Password,
+) error {
+
+	return nil
+}
+
+//
+//
+// AttachmentStore methods
+//
+//
+
+// CreateAttachment mocks recording an attachment.
+func (db *DB) CreateAttachment(
+	ctx context.Context,
+	a app.Attachment,
+) (int, error) {
+
+	return 0, nil
+}
+
+// GetAttachmentByID mocks fetching an attachment by its ID.
+func (db *DB) GetAttachmentByID(
+	ctx context.Context,
+	attachmentID int,
+) (app.Attachment, error) {
+
+	return app.Attachment{}, nil
+}
+
+// GetAttachmentsForApplication mocks fetching the attachments to an
+// application.
+func (db *DB) GetAttachmentsForApplication(
+	ctx context.Context,
+	appID int,
+) ([]app.Attachment, error) {
+
+	return nil, nil
+}
+
+//
+//
+// InvitationStore methods
+//
+//
+
+// CreateInvitation mocks recording an invitation to an organization.
+func (db *DB) CreateInvitation(
+	ctx context.Context,
+	inv app.Invitation,
+) (int, error) {
+
+	return 0, nil
+}
+
+// GetInvitationsForOrganization mocks fetching the invitations to an
+// organization.
+func (db *DB) GetInvitationsForOrganization(
+	ctx context.Context,
+	orgID int,
+) ([]app.Invitation, error) {
+
+	return nil, nil
+}
+
+// GetInvitationByToken mocks fetching an invitation by token.
+func (db *DB) GetInvitationByToken(
+	ctx context.Context,
+	token app.SecureToken,
+) (app.Invitation, error) {
+
+	return app.Invitation{}, nil
+}
+
+// RevokeInvitation mocks revoking an invitation to an organization.
+func (db *DB) RevokeInvitation(
+	ctx context.Context,
+	orgID, invitationID int,
+) error {
+
+	return nil
+}
+
+// AcceptInvitation mocks accepting an invitation to an organization.
+func (db *DB) AcceptInvitation(
+	ctx context.Context,
+	invitationID int,
+	newcomer app.Person,
+) (int, error) {
+
+	return 0, nil
+}
@@ -0,0 +1,83 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// These are assertions, which will cause the build to fail if the mock types
+// do not implement their app interfaces.
+var (
+	_ app.FileStore   = (*FileStore)(nil)
+	_ app.FileScanner = (*FileScanner)(nil)
+)
+
+// A FileStore mocks keeping files, holding them in memory.
+type FileStore struct {
+	Files map[string][]byte
+}
+
+// PutFile keeps a file under the next numeric key.
+func (s *FileStore) PutFile(
+	ctx context.Context,
+	content io.Reader,
+) (string, error) {
+
+	if s.Files == nil {
+		s.Files = make(map[string][]byte)
+	}
+
+	b, err := ioutil.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+
+	key := strconv.Itoa(len(s.Files) + 1)
+	s.Files[key] = b
+	return key, nil
+}
+
+// OpenFile opens a kept file.
+func (s *FileStore) OpenFile(
+	ctx context.Context,
+	key string,
+) (io.ReadCloser, error) {
+
+	b, ok := s.Files[key]
+	if !ok {
+		return nil, errors.Wrapf(app.ErrNotFound, "no file by key %s", key)
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+// DeleteFile forgets a kept file.
+func (s *FileStore) DeleteFile(ctx context.Context, key string) error {
+	delete(s.Files, key)
+	return nil
+}
+
+// A FileScanner mocks scanning files for malware. Files containing the
+// Infected marker are reported as infected.
+type FileScanner struct {
+	Infected []byte
+}
+
+// ScanFile checks a file for the Infected marker.
+func (s *FileScanner) ScanFile(ctx context.Context, content io.Reader) error {
+	b, err := ioutil.ReadAll(content)
+	if err != nil {
+		return err
+	}
+
+	if len(s.Infected) > 0 && bytes.Contains(b, s.Infected) {
+		return errors.Wrap(app.ErrFileInfected, "mock scanner")
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// This is an assertion, which will cause the build to fail if the Mailer type
+// does not implement the app.Mailer interface.
+var _ app.Mailer = (*Mailer)(nil)
+
+// A Mailer mocks sending email, keeping every message that it was asked to
+// send.
+type Mailer struct {
+	Sent []app.Mail
+}
+
+// SendMail records a message as sent.
+func (m *Mailer) SendMail(ctx context.Context, msg app.Mail) error {
+	m.Sent = append(m.Sent, msg)
+	return nil
+}
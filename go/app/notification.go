@@ -0,0 +1,51 @@
+package app
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+// A NotificationKind describes what a Notification is about, so that clients
+// may present each kind differently.
+type NotificationKind string
+
+// These are the kinds of notifications that people may receive about people.
+const (
+	NotificationPersonDeactivated NotificationKind = "person.deactivated"
+	NotificationPersonReactivated NotificationKind = "person.reactivated"
+)
+
+// These are the kinds of notifications that people may receive about
+// applications.
+const (
+	NotificationAppApproved      NotificationKind = "application.approved"
+	NotificationAppRejected      NotificationKind = "application.rejected"
+	NotificationAppInfoRequested NotificationKind = "application.info_requested"
+	NotificationAppInfoProvided  NotificationKind = "application.info_provided"
+)
+
+// These are the kinds of notifications that people may receive about
+// organizations.
+const (
+	NotificationOrgArchived NotificationKind = "organization.archived"
+	NotificationOrgRestored NotificationKind = "organization.restored"
+)
+
+// These are the kinds of notifications that people may receive about
+// invitations.
+const (
+	NotificationInvitationAccepted NotificationKind = "invitation.accepted"
+)
+
+// A Notification tells a person about a change that concerns them.
+type Notification struct {
+	ID        int              `db:"notification_id" json:"id"`
+	PersonID  int              `db:"person_id" json:"person_id"`
+	Kind      NotificationKind `db:"kind" json:"kind"`
+	Message   string           `db:"message" json:"message"`
+	CreatedAt time.Time        `db:"created_at" json:"created_at"`
+	// ReadAt is when the person marked this notification as read. Will be
+	// null for unread notifications.
+	ReadAt null.Time `db:"read_at" json:"read_at"`
+}
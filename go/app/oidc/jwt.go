@@ -0,0 +1,229 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// clockSkew is how much the clocks of our app and a provider may disagree
+// when checking the timestamps of an ID token.
+const clockSkew = time.Minute
+
+// An audience is the aud claim of an ID token, which may be either a single
+// string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(b, &multiple); err != nil {
+		return errors.Wrap(err, "aud must be a string or array of strings")
+	}
+
+	*a = multiple
+	return nil
+}
+
+func (a audience) contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// Claims are the verified contents of an ID token that describe a person.
+type Claims struct {
+	Issuer          string   `json:"iss"`
+	Subject         string   `json:"sub"`
+	Audience        audience `json:"aud"`
+	AuthorizedParty string   `json:"azp"`
+	Expiry          int64    `json:"exp"`
+	IssuedAt        int64    `json:"iat"`
+	Nonce           string   `json:"nonce"`
+
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+	Name          string `json:"name"`
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// decodeSegment decodes a single base64url segment of a JWT, optionally
+// unmarshaling it as JSON into out.
+func decodeSegment(segment string, out interface{}) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad base64 in token")
+	}
+
+	if out != nil {
+		if err = json.Unmarshal(b, out); err != nil {
+			return nil, errors.Wrap(err, "bad json in token")
+		}
+	}
+
+	return b, nil
+}
+
+// publicKey fetches the key set of this provider and returns the RSA key with
+// the given ID. When the ID is blank, the key set must have only one key.
+func (p *Provider) publicKey(
+	ctx context.Context,
+	keyID string,
+) (*rsa.PublicKey, error) {
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.JWKSURI, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create key set request")
+	}
+
+	var set jsonWebKeySet
+	if err = doJSON(p.client, req, &set); err != nil {
+		return nil, errors.Wrap(err, "failed to fetch key set")
+	}
+
+	var candidates []jsonWebKey
+	for _, k := range set.Keys {
+		if k.KeyType == "RSA" && (k.Use == "" || k.Use == "sig") &&
+			(keyID == "" || k.KeyID == keyID) {
+
+			candidates = append(candidates, k)
+		}
+	}
+
+	if len(candidates) != 1 {
+		return nil, errors.Errorf("no unique signing key with id '%s'", keyID)
+	}
+
+	n, err := decodeSegment(candidates[0].N, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad key modulus")
+	}
+
+	e, err := decodeSegment(candidates[0].E, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad key exponent")
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("key exponent is too large")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
+
+// Verify checks the signature and claims of a raw ID token issued by this
+// provider to the given client, returning its claims when it is valid.
+//
+// Only the RS256 algorithm is supported, which all OpenID Connect providers
+// are required to support.
+func (p *Provider) Verify(
+	ctx context.Context,
+	clientID, rawIDToken, nonce string,
+) (Claims, error) {
+
+	segments := strings.Split(rawIDToken, ".")
+	if len(segments) != 3 {
+		return Claims{}, errors.New("id token must have three segments")
+	}
+
+	var header jwtHeader
+	if _, err := decodeSegment(segments[0], &header); err != nil {
+		return Claims{}, errors.Wrap(err, "bad id token header")
+	} else if header.Algorithm != "RS256" {
+		return Claims{}, errors.Errorf("unsupported algorithm '%s'",
+			header.Algorithm)
+	}
+
+	signature, err := decodeSegment(segments[2], nil)
+	if err != nil {
+		return Claims{}, errors.Wrap(err, "bad id token signature")
+	}
+
+	key, err := p.publicKey(ctx, header.KeyID)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	digest := sha256.Sum256([]byte(segments[0] + "." + segments[1]))
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	if err != nil {
+		return Claims{}, errors.Wrap(err, "id token signature is invalid")
+	}
+
+	// The payload may only be trusted after the signature is verified.
+	var c Claims
+	if _, err = decodeSegment(segments[1], &c); err != nil {
+		return Claims{}, errors.Wrap(err, "bad id token payload")
+	}
+
+	if err = c.validate(p.Issuer, clientID, nonce, time.Now()); err != nil {
+		return Claims{}, err
+	}
+
+	return c, nil
+}
+
+// validate checks the claims of an ID token whose signature is known to be
+// valid.
+func (c *Claims) validate(issuer, clientID, nonce string, now time.Time) error {
+	if c.Issuer != issuer {
+		return errors.Errorf("id token issuer '%s' is not '%s'",
+			c.Issuer, issuer)
+	} else if !c.Audience.contains(clientID) {
+		return errors.Errorf("id token was not issued to client '%s'",
+			clientID)
+	} else if len(c.Audience) > 1 && c.AuthorizedParty != clientID {
+		return errors.New("id token has multiple audiences but wrong azp")
+	} else if c.Subject == "" {
+		return errors.New("id token is missing subject")
+	} else if c.Nonce != nonce {
+		return errors.New("id token nonce does not match")
+	}
+
+	if now.Add(-clockSkew).After(time.Unix(c.Expiry, 0)) {
+		return errors.New("id token is expired")
+	} else if now.Add(clockSkew).Before(time.Unix(c.IssuedAt, 0)) {
+		return errors.New("id token was issued in the future")
+	}
+
+	return nil
+}
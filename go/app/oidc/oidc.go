@@ -0,0 +1,204 @@
+// Package oidc implements the parts of OpenID Connect needed to sign people in
+// using an external identity provider: discovery, the authorization code flow
+// with PKCE, and verification of ID tokens signed using RS256.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// An HTTPClient performs HTTP requests on behalf of a Provider. The standard
+// *http.Client satisfies this interface.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DefaultHTTPClient is used when no other client is specified.
+var DefaultHTTPClient HTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// scopes are requested during authorization, so that the ID token will
+// contain the email address and name of the person.
+const scopes = "openid email profile"
+
+// A Config identifies our app to an identity provider as a client.
+type Config struct {
+	// ClientID is the identifier our app was registered with.
+	ClientID string
+	// ClientSecret is the secret our app was issued upon registration.
+	ClientSecret string
+	// RedirectURL is where the provider will send the person after they
+	// have authenticated. Must match the URL registered with the provider.
+	RedirectURL string
+}
+
+// A Provider is an OpenID Connect identity provider, as described by its
+// discovery document.
+type Provider struct {
+	// Issuer is the identifier of the provider, which must match the issuer
+	// claim of every ID token it issues.
+	Issuer string `json:"issuer"`
+	// AuthorizationEndpoint is where people are sent to authenticate.
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	// TokenEndpoint is where authorization codes are exchanged for tokens.
+	TokenEndpoint string `json:"token_endpoint"`
+	// JWKSURI is where the public keys used to sign ID tokens are published.
+	JWKSURI string `json:"jwks_uri"`
+
+	client HTTPClient
+}
+
+// Discover fetches the discovery document for the provider with the given
+// issuer URL. If client is nil, DefaultHTTPClient is used.
+func Discover(
+	ctx context.Context,
+	client HTTPClient,
+	issuer string,
+) (*Provider, error) {
+
+	if client == nil {
+		client = DefaultHTTPClient
+	}
+
+	issuer = strings.TrimSuffix(issuer, "/")
+	wellKnown := issuer + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, "GET", wellKnown, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create discovery request")
+	}
+
+	p := &Provider{client: client}
+	if err = doJSON(client, req, p); err != nil {
+		return nil, errors.Wrap(err, "failed to fetch discovery document")
+	}
+
+	// Per the specification, the issuer must exactly match the URL that was
+	// used for discovery, preventing one provider from impersonating another.
+	if p.Issuer != issuer {
+		return nil, errors.Errorf("discovered issuer '%s' does not match '%s'",
+			p.Issuer, issuer)
+	} else if p.AuthorizationEndpoint == "" ||
+		p.TokenEndpoint == "" ||
+		p.JWKSURI == "" {
+
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	return p, nil
+}
+
+// CodeChallenge computes the PKCE code challenge for a code verifier, using
+// the S256 method.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL builds the URL that a person should be redirected to in order to
+// authenticate with this provider.
+//
+// The state is returned to our app unchanged and must be checked upon return,
+// the nonce will appear in the ID token, and the verifier must be kept secret
+// until the code is exchanged.
+func (p *Provider) AuthCodeURL(
+	cfg Config,
+	state, nonce, verifier string,
+) string {
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", cfg.ClientID)
+	params.Set("redirect_uri", cfg.RedirectURL)
+	params.Set("scope", scopes)
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", CodeChallenge(verifier))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return p.AuthorizationEndpoint + separator + params.Encode()
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange trades an authorization code for an ID token, then verifies the
+// token and returns its claims.
+func (p *Provider) Exchange(
+	ctx context.Context,
+	cfg Config,
+	code, verifier, nonce string,
+) (Claims, error) {
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", cfg.RedirectURL)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.TokenEndpoint,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return Claims{}, errors.Wrap(err, "failed to create token request")
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(cfg.ClientID),
+		url.QueryEscape(cfg.ClientSecret))
+
+	var res tokenResponse
+	if err = doJSON(p.client, req, &res); err != nil {
+		if res.Error != "" {
+			err = errors.Errorf("%s: %s", res.Error, res.ErrorDescription)
+		}
+		return Claims{}, errors.Wrap(err, "failed to exchange code")
+	} else if res.IDToken == "" {
+		return Claims{}, errors.New("token response is missing id_token")
+	}
+
+	return p.Verify(ctx, cfg.ClientID, res.IDToken, nonce)
+}
+
+// doJSON performs a request and decodes the JSON response body into out. The
+// body is decoded even for unsuccessful responses, since they often describe
+// the error, but an error is still returned.
+func doJSON(client HTTPClient, req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "request failed")
+	}
+	defer res.Body.Close()
+
+	// Guard against unreasonably large responses.
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return errors.Wrap(err, "failed to read response")
+	}
+
+	decodeErr := json.Unmarshal(body, out)
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return errors.Wrap(decodeErr, "failed to decode response")
+}
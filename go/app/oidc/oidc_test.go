@@ -0,0 +1,216 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app/oidc"
+	"github.com/BenJetson/CPSC491-project/go/app/oidc/oidctest"
+)
+
+// noRedirectClient captures redirects instead of following them.
+var noRedirectClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp, err := oidctest.NewProvider("dispatch", "s3cret")
+	require.NoError(t, err)
+	defer idp.Close()
+
+	idp.SetUser(oidctest.User{
+		Subject:       "abc123",
+		Email:         "dispatcher@acme.example",
+		EmailVerified: true,
+		GivenName:     "Dana",
+		FamilyName:    "Dispatcher",
+	})
+
+	ctx := context.Background()
+
+	p, err := oidc.Discover(ctx, nil, idp.Issuer())
+	require.NoError(t, err)
+
+	cfg := oidc.Config{
+		ClientID:     "dispatch",
+		ClientSecret: "s3cret",
+		RedirectURL:  "http://localhost:8000/api/sso/callback",
+	}
+
+	const verifier = "a-very-long-code-verifier-that-is-random-enough"
+	authURL := p.AuthCodeURL(cfg, "the-state", "the-nonce", verifier)
+
+	res, err := noRedirectClient.Get(authURL)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusFound, res.StatusCode)
+
+	callback, err := url.Parse(res.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "the-state", callback.Query().Get("state"))
+	code := callback.Query().Get("code")
+
+	t.Run("WrongVerifier", func(t *testing.T) {
+		_, err := p.Exchange(ctx, cfg, code, "not-the-verifier", "the-nonce")
+		assert.Error(t, err)
+	})
+
+	// The failed attempt above consumed the code, so authorize again.
+	res, err = noRedirectClient.Get(authURL)
+	require.NoError(t, err)
+	res.Body.Close()
+	callback, err = url.Parse(res.Header.Get("Location"))
+	require.NoError(t, err)
+	code = callback.Query().Get("code")
+
+	t.Run("WrongNonce", func(t *testing.T) {
+		_, err := p.Exchange(ctx, cfg, code, verifier, "another-nonce")
+		assert.Error(t, err)
+	})
+
+	res, err = noRedirectClient.Get(authURL)
+	require.NoError(t, err)
+	res.Body.Close()
+	callback, err = url.Parse(res.Header.Get("Location"))
+	require.NoError(t, err)
+	code = callback.Query().Get("code")
+
+	t.Run("Success", func(t *testing.T) {
+		c, err := p.Exchange(ctx, cfg, code, verifier, "the-nonce")
+		require.NoError(t, err)
+
+		assert.Equal(t, "abc123", c.Subject)
+		assert.Equal(t, "dispatcher@acme.example", c.Email)
+		assert.True(t, c.EmailVerified)
+		assert.Equal(t, "Dana", c.GivenName)
+		assert.Equal(t, "Dispatcher", c.FamilyName)
+	})
+
+	t.Run("CodeReuse", func(t *testing.T) {
+		_, err := p.Exchange(ctx, cfg, code, verifier, "the-nonce")
+		assert.Error(t, err)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	idp, err := oidctest.NewProvider("dispatch", "s3cret")
+	require.NoError(t, err)
+	defer idp.Close()
+
+	other, err := oidctest.NewProvider("dispatch", "s3cret")
+	require.NoError(t, err)
+	defer other.Close()
+
+	ctx := context.Background()
+
+	p, err := oidc.Discover(ctx, nil, idp.Issuer())
+	require.NoError(t, err)
+
+	now := time.Now()
+	valid := oidc.Claims{
+		Issuer:   idp.Issuer(),
+		Subject:  "abc123",
+		Audience: []string{"dispatch"},
+		Expiry:   now.Add(time.Minute).Unix(),
+		IssuedAt: now.Unix(),
+		Nonce:    "n",
+	}
+
+	testCases := []struct {
+		alias  string
+		signer *oidctest.Provider
+		mutate func(c *oidc.Claims)
+		tamper bool
+		expect bool
+	}{
+		{
+			alias:  "Valid",
+			signer: idp,
+			expect: true,
+		},
+		{
+			alias:  "WrongKey",
+			signer: other,
+		},
+		{
+			alias:  "Tampered",
+			signer: idp,
+			tamper: true,
+		},
+		{
+			alias:  "WrongIssuer",
+			signer: idp,
+			mutate: func(c *oidc.Claims) { c.Issuer = other.Issuer() },
+		},
+		{
+			alias:  "WrongAudience",
+			signer: idp,
+			mutate: func(c *oidc.Claims) { c.Audience = []string{"else"} },
+		},
+		{
+			alias:  "Expired",
+			signer: idp,
+			mutate: func(c *oidc.Claims) {
+				c.Expiry = now.Add(-time.Hour).Unix()
+			},
+		},
+		{
+			alias:  "MissingSubject",
+			signer: idp,
+			mutate: func(c *oidc.Claims) { c.Subject = "" },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			c := valid
+			if tc.mutate != nil {
+				tc.mutate(&c)
+			}
+
+			raw, err := tc.signer.Sign(c)
+			require.NoError(t, err)
+
+			if tc.tamper {
+				// Splice in the payload of a token for someone else.
+				evil := c
+				evil.Subject = "evil"
+				evilRaw, err := tc.signer.Sign(evil)
+				require.NoError(t, err)
+
+				segments := strings.Split(raw, ".")
+				segments[1] = strings.Split(evilRaw, ".")[1]
+				raw = strings.Join(segments, ".")
+			}
+
+			_, err = p.Verify(ctx, "dispatch", raw, "n")
+			if tc.expect {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestDiscoverIssuerMismatch(t *testing.T) {
+	idp, err := oidctest.NewProvider("dispatch", "s3cret")
+	require.NoError(t, err)
+	defer idp.Close()
+
+	// Using a different spelling of the same host must fail, since the
+	// issuer must match exactly.
+	issuer := strings.Replace(idp.Issuer(), "127.0.0.1", "localhost", 1)
+
+	_, err = oidc.Discover(context.Background(), nil, issuer)
+	assert.Error(t, err)
+}
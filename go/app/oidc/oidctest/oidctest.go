@@ -0,0 +1,288 @@
+// Package oidctest provides a mock OpenID Connect identity provider for tests
+// and local development.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/BenJetson/CPSC491-project/go/app/oidc"
+)
+
+// keyID identifies the only signing key of a mock provider.
+const keyID = "oidctest"
+
+// A User is the identity that a mock provider will authenticate.
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+}
+
+type grant struct {
+	user          User
+	redirectURL   string
+	nonce         string
+	codeChallenge string
+}
+
+// A Provider is a mock identity provider backed by an httptest.Server. It
+// authenticates every person as its current User without any interaction,
+// redirecting straight back to the client with an authorization code.
+type Provider struct {
+	// Server is the underlying test server. Its URL is the issuer.
+	Server *httptest.Server
+	// ClientID and ClientSecret are the only client credentials accepted.
+	ClientID     string
+	ClientSecret string
+
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	user   User
+	grants map[string]grant
+}
+
+// NewProvider starts a new mock identity provider that accepts the given
+// client credentials. It must be closed after use.
+func NewProvider(clientID, clientSecret string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate signing key")
+	}
+
+	p := &Provider{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		grants:       make(map[string]grant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("/jwks", p.handleKeySet)
+	mux.HandleFunc("/authorize", p.handleAuthorize)
+	mux.HandleFunc("/token", p.handleToken)
+
+	p.Server = httptest.NewServer(mux)
+	return p, nil
+}
+
+// Issuer returns the issuer URL of this provider.
+func (p *Provider) Issuer() string {
+	return p.Server.URL
+}
+
+// SetUser changes the identity that will be authenticated next.
+func (p *Provider) SetUser(u User) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.user = u
+}
+
+// Close shuts down the underlying test server.
+func (p *Provider) Close() {
+	p.Server.Close()
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func writeTokenError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+func (p *Provider) handleDiscovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 p.Issuer(),
+		"authorization_endpoint": p.Issuer() + "/authorize",
+		"token_endpoint":         p.Issuer() + "/token",
+		"jwks_uri":               p.Issuer() + "/jwks",
+	})
+}
+
+func (p *Provider) handleKeySet(w http.ResponseWriter, _ *http.Request) {
+	pub := p.key.PublicKey
+	e := big.NewInt(int64(pub.E)).Bytes()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(e),
+		}},
+	})
+}
+
+func (p *Provider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if q.Get("client_id") != p.ClientID {
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	} else if q.Get("response_type") != "code" {
+		http.Error(w, "unsupported response type", http.StatusBadRequest)
+		return
+	} else if q.Get("code_challenge_method") != "S256" ||
+		q.Get("code_challenge") == "" {
+
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || !redirect.IsAbs() {
+		http.Error(w, "bad redirect uri", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+
+	p.mu.Lock()
+	p.grants[code] = grant{
+		user:          p.user,
+		redirectURL:   redirect.String(),
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+	}
+	p.mu.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	}
+
+	if !ok || clientID != p.ClientID || clientSecret != p.ClientSecret {
+		writeTokenError(w, "invalid_client", "bad client credentials")
+		return
+	} else if err := r.ParseForm(); err != nil {
+		writeTokenError(w, "invalid_request", "bad form")
+		return
+	} else if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeTokenError(w, "unsupported_grant_type", "")
+		return
+	}
+
+	// Codes may only be used once.
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	g, found := p.grants[code]
+	delete(p.grants, code)
+	p.mu.Unlock()
+
+	if !found {
+		writeTokenError(w, "invalid_grant", "unknown code")
+		return
+	} else if r.PostForm.Get("redirect_uri") != g.redirectURL {
+		writeTokenError(w, "invalid_grant", "redirect uri mismatch")
+		return
+	}
+
+	verifier := r.PostForm.Get("code_verifier")
+	if oidc.CodeChallenge(verifier) != g.codeChallenge {
+		writeTokenError(w, "invalid_grant", "code verifier mismatch")
+		return
+	}
+
+	now := time.Now()
+	idToken, err := p.Sign(oidc.Claims{
+		Issuer:        p.Issuer(),
+		Subject:       g.user.Subject,
+		Audience:      []string{p.ClientID},
+		Expiry:        now.Add(5 * time.Minute).Unix(),
+		IssuedAt:      now.Unix(),
+		Nonce:         g.nonce,
+		Email:         g.user.Email,
+		EmailVerified: g.user.EmailVerified,
+		GivenName:     g.user.GivenName,
+		FamilyName:    g.user.FamilyName,
+	})
+	if err != nil {
+		writeTokenError(w, "server_error", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// Sign creates an ID token with the given claims, signed by the key of this
+// provider.
+func (p *Provider) Sign(c oidc.Claims) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"kid": keyID,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode header")
+	}
+
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode claims")
+	}
+
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString(header) + "." +
+		enc.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256,
+		digest[:])
+	if err != nil {
+		return "", errors.Wrap(err, "failed to sign token")
+	}
+
+	return signingInput + "." + enc.EncodeToString(signature), nil
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -1,5 +1,12 @@
 package app
 
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
+)
+
 // An Organization contains information about a particular sponsor organization.
 type Organization struct {
 	// ID uniquely identifies this organization.
@@ -9,4 +16,62 @@ type Organization struct {
 	// PointValue describes the ratio between points and real dollars.
 	// Each point is worth a PointValue amount of Money.
 	PointValue Money `db:"point_value" json:"point_value"`
+	// CooldownDays is how many days a person whose application was
+	// rejected must wait before applying again.
+	CooldownDays int `db:"reapply_cooldown_days" json:"reapply_cooldown_days"`
+	// ArchivedAt is the timestamp this organization was archived at. Will be
+	// null unless it is archived.
+	ArchivedAt null.Time `db:"archived_at" json:"archived_at"`
+
+	// Description tells drivers about this organization.
+	Description  string `db:"description" json:"description"`
+	ContactEmail string `db:"contact_email" json:"contact_email"`
+	Website      string `db:"website" json:"website"`
+	// LogoKey identifies the logo of this organization in the FileStore. Will
+	// be null when it has no logo.
+	LogoKey         null.String `db:"logo_key" json:"-"`
+	LogoContentType null.String `db:"logo_content_type" json:"-"`
+	// LogoURL is a link to the logo of this organization, if it has one.
+	LogoURL string `db:"-" json:"logo_url,omitempty"`
+
+	// IsClosed is true when this organization is not accepting applications
+	// from drivers.
+	IsClosed bool `db:"applications_closed" json:"applications_closed"`
+	// PointsName is what this organization calls its points, such as
+	// "Safety Bucks".
+	PointsName string `db:"points_name" json:"points_name"`
 }
+
+// MaxCooldownDays is the longest cooldown an organization may set.
+const MaxCooldownDays = 365
+
+// DefaultPointsName is what points are called, unless an organization chooses
+// another name.
+const DefaultPointsName = "points"
+
+// MaxPointsName is the longest name an organization may give its points.
+const MaxPointsName = 32
+
+// MaxOrganizationDescription is the longest description an organization may
+// have, in characters.
+const MaxOrganizationDescription = 2000
+
+// MaxLogoSize is the largest logo that may be uploaded, in bytes.
+const MaxLogoSize = 1 << 20
+
+// LogoContentTypes lists the MIME types of images that may be used as logos.
+var LogoContentTypes = []string{"image/jpeg", "image/png"}
+
+// DefaultOrganizationRetention is how long an organization must have been
+// archived before it may be purged, unless configured otherwise.
+const DefaultOrganizationRetention = 90 * 24 * time.Hour
+
+// ErrOrganizationArchived means that an organization is archived, so that it
+// cannot be changed that way. It is also an ErrConflict.
+var ErrOrganizationArchived = errors.WithMessage(ErrConflict,
+	"organization is archived")
+
+// ErrNotAcceptingApplications means that an organization has stopped accepting
+// applications. It is also an ErrConflict.
+var ErrNotAcceptingApplications = errors.WithMessage(ErrConflict,
+	"organization is not accepting applications")
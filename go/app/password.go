@@ -1,34 +1,206 @@
 package app
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
 	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// A Password is a wrapper type for bcrypt password hashes.
+// A PasswordAlgorithm names an algorithm that passwords may be hashed with.
+type PasswordAlgorithm string
+
+const (
+	// PasswordAlgorithmBcrypt hashes passwords with bcrypt.
+	PasswordAlgorithmBcrypt PasswordAlgorithm = "bcrypt"
+	// PasswordAlgorithmArgon2id hashes passwords with argon2id.
+	PasswordAlgorithmArgon2id PasswordAlgorithm = "argon2id"
+)
+
+// PasswordHashParams describes how a password is hashed. Only the fields for
+// the chosen Algorithm are used; the others must be left zero.
+type PasswordHashParams struct {
+	Algorithm PasswordAlgorithm
+
+	// BcryptCost is the bcrypt cost factor.
+	BcryptCost int
+
+	// Argon2Time is the number of passes over memory.
+	Argon2Time uint32
+	// Argon2Memory is the amount of memory used, in KiB.
+	Argon2Memory uint32
+	// Argon2Threads is the degree of parallelism.
+	Argon2Threads uint8
+	// Argon2SaltLength is the length of the random salt, in bytes.
+	Argon2SaltLength uint32
+	// Argon2KeyLength is the length of the derived key, in bytes.
+	Argon2KeyLength uint32
+}
+
+// DefaultPasswordHashParams are used to hash all new passwords. Passwords that
+// were hashed differently are re-hashed with these upon the next login, so
+// that strengthening these upgrades every account over time.
+//
+// These follow the OWASP recommendation for argon2id.
+var DefaultPasswordHashParams = PasswordHashParams{
+	Algorithm:        PasswordAlgorithmArgon2id,
+	Argon2Time:       2,
+	Argon2Memory:     19 * 1024,
+	Argon2Threads:    1,
+	Argon2SaltLength: 16,
+	Argon2KeyLength:  32,
+}
+
+// A Password is a wrapper type for password hashes. Hashes carry their own
+// algorithm and parameters: bcrypt hashes use the modular crypt format, and
+// argon2id hashes use the PHC string format.
 type Password string
 
-// NewPassword creates a new hashed Password given its plaintext.
+// argon2Encoding encodes salts and keys of argon2id hashes, as in the PHC
+// string format.
+var argon2Encoding = base64.RawStdEncoding
+
+// NewPassword creates a new hashed Password given its plaintext, using the
+// DefaultPasswordHashParams.
 func NewPassword(plaintext string) (Password, error) {
-	plainBytes := []byte(plaintext)
-	hashBytes, err := bcrypt.GenerateFromPassword(
-		plainBytes,
-		bcrypt.DefaultCost,
-	)
+	return HashPassword(plaintext, DefaultPasswordHashParams)
+}
+
+// HashPassword creates a new hashed Password given its plaintext, using the
+// given parameters.
+func HashPassword(
+	plaintext string,
+	params PasswordHashParams,
+) (Password, error) {
+
+	switch params.Algorithm {
+	case PasswordAlgorithmBcrypt:
+		hashBytes, err := bcrypt.GenerateFromPassword(
+			[]byte(plaintext),
+			params.BcryptCost,
+		)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to hash password")
+		}
+		return Password(hashBytes), nil
+
+	case PasswordAlgorithmArgon2id:
+		salt := make([]byte, params.Argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", errors.Wrap(err, "failed to generate password salt")
+		}
+
+		key := argon2.IDKey([]byte(plaintext), salt, params.Argon2Time,
+			params.Argon2Memory, params.Argon2Threads, params.Argon2KeyLength)
+
+		return Password(fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version,
+			params.Argon2Memory,
+			params.Argon2Time,
+			params.Argon2Threads,
+			argon2Encoding.EncodeToString(salt),
+			argon2Encoding.EncodeToString(key),
+		)), nil
+	}
+
+	return "", errors.Errorf("unknown password algorithm '%s'",
+		params.Algorithm)
+}
 
+// parseArgon2id splits an argon2id hash into its parameters, salt, and key.
+func (p Password) parseArgon2id() (
+	params PasswordHashParams,
+	salt, key []byte,
+	err error,
+) {
+
+	// The leading $ yields an empty first part.
+	parts := strings.Split(string(p), "$")
+	if len(parts) != 6 || parts[1] != string(PasswordAlgorithmArgon2id) {
+		err = errors.New("malformed argon2id hash")
+		return
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		err = errors.Wrap(err, "malformed argon2id version")
+		return
+	} else if version != argon2.Version {
+		err = errors.Errorf("unsupported argon2id version %d", version)
+		return
+	}
+
+	params.Algorithm = PasswordAlgorithmArgon2id
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Argon2Memory,
+		&params.Argon2Time, &params.Argon2Threads)
+	if err != nil {
+		err = errors.Wrap(err, "malformed argon2id parameters")
+		return
+	}
+
+	if salt, err = argon2Encoding.DecodeString(parts[4]); err != nil {
+		err = errors.Wrap(err, "malformed argon2id salt")
+		return
+	} else if key, err = argon2Encoding.DecodeString(parts[5]); err != nil {
+		err = errors.Wrap(err, "malformed argon2id key")
+		return
+	}
+
+	params.Argon2SaltLength = uint32(len(salt))
+	params.Argon2KeyLength = uint32(len(key))
+	return
+}
+
+// Params determines the algorithm and parameters that this Password was
+// hashed with.
+func (p Password) Params() (PasswordHashParams, error) {
+	if strings.HasPrefix(string(p), "$argon2id$") {
+		params, _, _, err := p.parseArgon2id()
+		return params, err
+	}
+
+	cost, err := bcrypt.Cost([]byte(p))
 	if err != nil {
-		return "", errors.Wrap(err, "failed to hash password")
+		return PasswordHashParams{}, errors.Wrap(err, "unknown password hash")
 	}
 
-	return Password(hashBytes), nil
+	return PasswordHashParams{
+		Algorithm:  PasswordAlgorithmBcrypt,
+		BcryptCost: cost,
+	}, nil
 }
 
-// Verify verifies a hashed Password against a given plaintext.
-func (p Password) Verify(plaintext string) bool {
-	hashBytes := []byte(p)
-	plainBytes := []byte(plaintext)
+// Verify verifies a hashed Password against a given plaintext. When the
+// plaintext matches, needsRehash reports whether this Password was hashed
+// differently than the DefaultPasswordHashParams, in which case it should be
+// replaced by a new hash of the plaintext.
+func (p Password) Verify(plaintext string) (ok, needsRehash bool) {
+	params, err := p.Params()
+	if err != nil {
+		return false, false
+	}
+
+	switch params.Algorithm {
+	case PasswordAlgorithmBcrypt:
+		// CompareHashAndPassword returns nil error when the plaintext and
+		// hash match, and an error otherwise.
+		ok = bcrypt.CompareHashAndPassword([]byte(p), []byte(plaintext)) == nil
+
+	case PasswordAlgorithmArgon2id:
+		_, salt, key, err := p.parseArgon2id()
+		if err != nil {
+			return false, false
+		}
+
+		actual := argon2.IDKey([]byte(plaintext), salt, params.Argon2Time,
+			params.Argon2Memory, params.Argon2Threads, params.Argon2KeyLength)
+		ok = subtle.ConstantTimeCompare(actual, key) == 1
+	}
 
-	// CompareHashAndPassword returns nil error when the plaintext and hash
-	// match, and an error otherwise.
-	return bcrypt.CompareHashAndPassword(hashBytes, plainBytes) == nil
+	return ok, ok && params != DefaultPasswordHashParams
 }
@@ -0,0 +1,149 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MaxPasswordBytes is the longest password that bcrypt can hash. Any bytes
+// beyond this are silently ignored by bcrypt, so longer passwords are refused
+// no matter which algorithm DefaultPasswordHashParams uses.
+const MaxPasswordBytes = 72
+
+// minPersonalInfoLength is the shortest name or email fragment that passwords
+// are checked for, so that very short names do not block too many passwords.
+const minPersonalInfoLength = 3
+
+// A PasswordPolicy describes the requirements that new passwords must meet.
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters in a password.
+	MinLength int
+	// MaxLength is the maximum number of bytes in a password. Zero or values
+	// beyond MaxPasswordBytes are treated as MaxPasswordBytes.
+	MaxLength int
+	// HistorySize is the number of a person's most recent passwords, including
+	// their current one, that may not be reused. Zero allows any reuse.
+	HistorySize int
+	// Blocklist holds breached or common passwords that may not be used, in
+	// lowercase.
+	Blocklist map[string]struct{}
+}
+
+// DefaultPasswordPolicy is the suggested PasswordPolicy for our app. It has no
+// blocklist, since one must be loaded using ReadPasswordBlocklist.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:   8,
+	MaxLength:   MaxPasswordBytes,
+	HistorySize: 5,
+}
+
+// ReadPasswordBlocklist reads a blocklist with one password per line. Blank
+// lines and lines beginning with # are ignored.
+func ReadPasswordBlocklist(r io.Reader) (map[string]struct{}, error) {
+	blocklist := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) < 1 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		blocklist[strings.ToLower(line)] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read password blocklist")
+	}
+	return blocklist, nil
+}
+
+// LoadPasswordBlocklist reads a blocklist from the file at the given path. See
+// ReadPasswordBlocklist for the file format.
+func LoadPasswordBlocklist(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open password blocklist")
+	}
+	defer f.Close()
+
+	return ReadPasswordBlocklist(f)
+}
+
+// Validate checks a new plaintext password for the given owner against this
+// policy. The owner must have their name and email set, but need not exist yet.
+// Previous holds the owner's most recent password hashes, newest first, and
+// should include their current password.
+//
+// Upon failure, returns an error and a message for the user.
+func (p PasswordPolicy) Validate(
+	plaintext string,
+	owner Person,
+	previous []Password,
+) (message string, err error) {
+
+	maxLength := p.MaxLength
+	if maxLength < 1 || maxLength > MaxPasswordBytes {
+		maxLength = MaxPasswordBytes
+	}
+
+	if n := len([]rune(plaintext)); n < p.MinLength {
+		err = errors.New("password does not meet length requirement")
+		message = fmt.Sprintf("Password must be at least %d characters long.",
+			p.MinLength)
+		return
+	} else if len(plaintext) > maxLength {
+		err = errors.New("password exceeds maximum length")
+		message = fmt.Sprintf("Password must be at most %d bytes long.",
+			maxLength)
+		return
+	}
+
+	lower := strings.ToLower(plaintext)
+
+	if _, blocked := p.Blocklist[lower]; blocked {
+		err = errors.New("password is on the blocklist")
+		message = "This password is too common or has appeared in a data " +
+			"breach. Please choose another."
+		return
+	}
+
+	localPart := owner.Email
+	if at := strings.LastIndex(localPart, "@"); at >= 0 {
+		localPart = localPart[:at]
+	}
+
+	for _, info := range []string{
+		owner.FirstName,
+		owner.LastName,
+		localPart,
+	} {
+		info = strings.ToLower(strings.TrimSpace(info))
+		if len(info) >= minPersonalInfoLength &&
+			strings.Contains(lower, info) {
+
+			err = errors.New("password contains personal information")
+			message = "Password cannot contain your name or email address."
+			return
+		}
+	}
+
+	for i, hash := range previous {
+		if i >= p.HistorySize {
+			break
+		}
+
+		if matches, _ := hash.Verify(plaintext); matches {
+			err = errors.Errorf("password matches previous password %d", i)
+			message = fmt.Sprintf("Password cannot be the same as any of "+
+				"your last %d passwords.", p.HistorySize)
+			return
+		}
+	}
+
+	return
+}
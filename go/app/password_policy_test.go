@@ -0,0 +1,118 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPasswordBlocklist(t *testing.T) {
+	blocklist, err := ReadPasswordBlocklist(strings.NewReader(
+		"# comment\n\nPassword1\n  letmein1  \n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]struct{}{
+		"password1": {},
+		"letmein1":  {},
+	}, blocklist)
+}
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	current, err := NewPassword("current-secret")
+	require.NoError(t, err)
+	older, err := NewPassword("older-secret")
+	require.NoError(t, err)
+	oldest, err := NewPassword("oldest-secret")
+	require.NoError(t, err)
+
+	policy := PasswordPolicy{
+		MinLength:   8,
+		MaxLength:   MaxPasswordBytes,
+		HistorySize: 2,
+		Blocklist:   map[string]struct{}{"password1": {}},
+	}
+
+	owner := Person{
+		FirstName: "Jo",
+		LastName:  "Godfrey",
+		Email:     "bfgodfr@clemson.edu",
+	}
+	previous := []Password{current, older, oldest}
+
+	testCases := []struct {
+		alias       string
+		plaintext   string
+		expectError bool
+	}{
+		{
+			alias:     "Valid",
+			plaintext: "correct horse battery staple",
+		},
+		{
+			alias:       "TooShort",
+			plaintext:   "short",
+			expectError: true,
+		},
+		{
+			alias:     "MultibyteCountsCharacters",
+			plaintext: "ééééééééé",
+		},
+		{
+			alias:       "TooLong",
+			plaintext:   strings.Repeat("a", MaxPasswordBytes+1),
+			expectError: true,
+		},
+		{
+			alias:     "MaxLength",
+			plaintext: strings.Repeat("a", MaxPasswordBytes),
+		},
+		{
+			alias:       "Blocklisted",
+			plaintext:   "PassWord1",
+			expectError: true,
+		},
+		{
+			alias:       "ContainsLastName",
+			plaintext:   "mr-godfrey-2021",
+			expectError: true,
+		},
+		{
+			alias:       "ContainsEmail",
+			plaintext:   "x-BFGODFR-x",
+			expectError: true,
+		},
+		{
+			alias:     "ShortNameIgnored",
+			plaintext: "jovial-trucks-2021",
+		},
+		{
+			alias:       "Current",
+			plaintext:   "current-secret",
+			expectError: true,
+		},
+		{
+			alias:       "Previous",
+			plaintext:   "older-secret",
+			expectError: true,
+		},
+		{
+			alias:     "BeyondHistory",
+			plaintext: "oldest-secret",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			message, err := policy.Validate(tc.plaintext, owner, previous)
+			if tc.expectError {
+				assert.Error(t, err)
+				assert.NotEmpty(t, message)
+			} else {
+				assert.NoError(t, err)
+				assert.Empty(t, message)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordVerify(t *testing.T) {
+	const plaintext = "correct horse battery staple"
+
+	hash := func(params PasswordHashParams) Password {
+		p, err := HashPassword(plaintext, params)
+		require.NoError(t, err)
+		return p
+	}
+
+	weakArgon2id := DefaultPasswordHashParams
+	weakArgon2id.Argon2Time = 1
+
+	bcryptParams := PasswordHashParams{
+		Algorithm:  PasswordAlgorithmBcrypt,
+		BcryptCost: 4,
+	}
+
+	current := hash(DefaultPasswordHashParams)
+
+	testCases := []struct {
+		alias        string
+		password     Password
+		plaintext    string
+		expectOK     bool
+		expectRehash bool
+	}{
+		{
+			alias:     "Current",
+			password:  current,
+			plaintext: plaintext,
+			expectOK:  true,
+		},
+		{
+			alias:     "CurrentWrong",
+			password:  current,
+			plaintext: "incorrect horse",
+		},
+		{
+			alias:        "WeakerArgon2id",
+			password:     hash(weakArgon2id),
+			plaintext:    plaintext,
+			expectOK:     true,
+			expectRehash: true,
+		},
+		{
+			alias:        "Bcrypt",
+			password:     hash(bcryptParams),
+			plaintext:    plaintext,
+			expectOK:     true,
+			expectRehash: true,
+		},
+		{
+			alias:     "BcryptWrong",
+			password:  hash(bcryptParams),
+			plaintext: "incorrect horse",
+		},
+		{
+			alias:     "Empty",
+			password:  "",
+			plaintext: "",
+		},
+		{
+			alias:     "MalformedArgon2id",
+			password:  "$argon2id$v=19$m=x,t=2,p=1$c2FsdA$a2V5",
+			plaintext: plaintext,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			ok, needsRehash := tc.password.Verify(tc.plaintext)
+			assert.Equal(t, tc.expectOK, ok)
+			assert.Equal(t, tc.expectRehash, needsRehash)
+		})
+	}
+}
+
+func TestPasswordParams(t *testing.T) {
+	p, err := NewPassword("correct horse battery staple")
+	require.NoError(t, err)
+
+	params, err := p.Params()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultPasswordHashParams, params)
+
+	p, err = HashPassword("correct horse battery staple", PasswordHashParams{
+		Algorithm:  PasswordAlgorithmBcrypt,
+		BcryptCost: 5,
+	})
+	require.NoError(t, err)
+
+	params, err = p.Params()
+	require.NoError(t, err)
+	assert.Equal(t, PasswordHashParams{
+		Algorithm:  PasswordAlgorithmBcrypt,
+		BcryptCost: 5,
+	}, params)
+
+	_, err = HashPassword("x", PasswordHashParams{Algorithm: "md5"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package app
+
+// A Permission allows a person to perform some kind of operation. Permissions
+// are granted to each Role through the DataStore, so that the abilities of a
+// role may be changed without changing the code.
+type Permission string
+
+const (
+	// PermissionProfileManage allows a person to manage their own profile,
+	// sessions, and API tokens.
+	PermissionProfileManage Permission = "profile.manage"
+	// PermissionUsersManage allows a person to manage the accounts of all
+	// people, and to act on behalf of any person.
+	PermissionUsersManage Permission = "users.manage"
+	// PermissionOrganizationsManage allows a person to manage all sponsor
+	// organizations, and to act on behalf of any organization.
+	PermissionOrganizationsManage Permission = "organizations.manage"
+	// PermissionOrganizationManage allows a person to manage the profile of
+	// the organizations they are affiliated with.
+	PermissionOrganizationManage Permission = "organization.manage"
+	// PermissionOrganizationView allows a person to see and switch between
+	// the organizations they are affiliated with.
+	PermissionOrganizationView Permission = "organization.view"
+	// PermissionCatalogManage allows a person to manage the catalog of the
+	// organizations they are affiliated with.
+	PermissionCatalogManage Permission = "catalog.manage"
+	// PermissionDriversManage allows a person to manage the drivers of the
+	// organizations they are affiliated with.
+	PermissionDriversManage Permission = "drivers.manage"
+	// PermissionPointsAward allows a person to award and deduct points for
+	// the drivers of the organizations they are affiliated with.
+	PermissionPointsAward Permission = "points.award"
+	// PermissionApplicationsReview allows a person to review driver
+	// applications to the organizations they are affiliated with.
+	PermissionApplicationsReview Permission = "applications.review"
+	// PermissionApplicationsSubmit allows a person to apply to join
+	// organizations, and to manage their own applications.
+	PermissionApplicationsSubmit Permission = "applications.submit"
+	// PermissionAuditView allows a person to view the audit log of all
+	// privileged changes.
+	PermissionAuditView Permission = "audit.view"
+	// PermissionOrganizationAuditView allows a person to view the audit log
+	// of changes to the organizations they are affiliated with.
+	PermissionOrganizationAuditView Permission = "organization.audit.view"
+)
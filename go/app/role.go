@@ -1,5 +1,7 @@
 package app
 
+import "fmt"
+
 // A Role specifies what role a user has in our app and therefore what
 // permissions they might have.
 type Role int
@@ -18,3 +20,19 @@ const (
 	// as they are affiliated with a sponsor organization.
 	RoleDriver Role = 4
 )
+
+// String returns the name of a role, matching its title in the database.
+func (r Role) String() string {
+	switch r {
+	case RoleAdmin:
+		return "admin"
+	case RoleSponsor:
+		return "sponsor"
+	case RoleUser:
+		return "user"
+	case RoleDriver:
+		return "driver"
+	}
+
+	return fmt.Sprintf("Role(%d)", int(r))
+}
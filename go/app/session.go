@@ -3,13 +3,89 @@ package app
 import (
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v4"
 )
 
-// SessionLength defines how long a login session for this app will last, at
-// a MAXIMUM. Sessions may be revoked prior.
-const SessionLength = 6 * time.Hour
+// A SessionLifetime describes how long a login session may last.
+type SessionLifetime struct {
+	// IdleTimeout is how long a session lasts without activity. Each use of
+	// the session extends it by this much, up to MaxLifetime.
+	IdleTimeout time.Duration
+	// MaxLifetime is how long a session may last after it is created, at a
+	// MAXIMUM, no matter how active it is. Sessions may be revoked prior.
+	MaxLifetime time.Duration
+}
+
+// FallbackSessionLifetime is used for any role that a SessionPolicy does not
+// specify a lifetime for.
+var FallbackSessionLifetime = SessionLifetime{
+	IdleTimeout: time.Hour,
+	MaxLifetime: 6 * time.Hour,
+}
+
+// A SessionPolicy specifies the lifetimes of login sessions for each role.
+type SessionPolicy struct {
+	// Standard holds the lifetimes of ordinary sessions.
+	Standard map[Role]SessionLifetime
+	// RememberMe holds the lifetimes of sessions where the person asked to be
+	// remembered on their device.
+	RememberMe map[Role]SessionLifetime
+}
+
+// DefaultSessionPolicy creates the suggested SessionPolicy for our app.
+// Administrators have shorter sessions, since their accounts are the most
+// sensitive.
+func DefaultSessionPolicy() SessionPolicy {
+	const day = 24 * time.Hour
+
+	admin := SessionLifetime{
+		IdleTimeout: 30 * time.Minute,
+		MaxLifetime: 6 * time.Hour,
+	}
+	adminRemembered := SessionLifetime{
+		IdleTimeout: 12 * time.Hour,
+		MaxLifetime: day,
+	}
+
+	others := SessionLifetime{
+		IdleTimeout: 2 * time.Hour,
+		MaxLifetime: 12 * time.Hour,
+	}
+	othersRemembered := SessionLifetime{
+		IdleTimeout: 7 * day,
+		MaxLifetime: 30 * day,
+	}
+
+	return SessionPolicy{
+		Standard: map[Role]SessionLifetime{
+			RoleAdmin:   admin,
+			RoleSponsor: others,
+			RoleUser:    others,
+			RoleDriver:  others,
+		},
+		RememberMe: map[Role]SessionLifetime{
+			RoleAdmin:   adminRemembered,
+			RoleSponsor: othersRemembered,
+			RoleUser:    othersRemembered,
+			RoleDriver:  othersRemembered,
+		},
+	}
+}
+
+// LifetimeFor determines the session lifetime for a given role and whether or
+// not the person asked to be remembered.
+func (p SessionPolicy) LifetimeFor(role Role, rememberMe bool) SessionLifetime {
+	lifetimes := p.Standard
+	if rememberMe {
+		lifetimes = p.RememberMe
+	}
+
+	if lifetime, ok := lifetimes[role]; ok {
+		return lifetime
+	}
+	return FallbackSessionLifetime
+}
 
 // A Session represents an individual user session with our app.
 type Session struct {
@@ -17,42 +93,149 @@ type Session struct {
 	Person
 	// ID is the session's identifying number. It is not secret.
 	ID int `db:"session_id"`
-	// Token is a unique, securely random generated UUIDv4 that also uniquely
+	// Token is a unique, securely random generated value that also uniquely
 	// identifies this session. It must be kept secret between our API server
 	// and the web browser for the session.
-	Token uuid.UUID `db:"token"`
+	//
+	// Only the hash of the token is stored, so this will be the zero value
+	// for sessions retrieved from a DataStore.
+	Token SecureToken `db:"-" json:"-"`
 	// CreatedAt is the timestamp that this session was started at.
 	CreatedAt time.Time `db:"created_at"`
-	// ExpiresAt is the timestamp when this session will expire permanently.
+	// ExpiresAt is the timestamp when this session will expire due to
+	// inactivity. It is extended as the session is used, up to MaxExpiresAt.
 	ExpiresAt time.Time `db:"expires_at"`
+	// MaxExpiresAt is the timestamp when this session will expire permanently,
+	// regardless of activity.
+	MaxExpiresAt time.Time `db:"max_expires_at"`
+	// IsRememberMe is true when the person asked to be remembered on this
+	// device, giving the session a longer lifetime.
+	IsRememberMe bool `db:"is_remember_me"`
 	// IsRevoked is true when the session was manually revoked.
 	IsRevoked bool `db:"is_revoked"`
+	// UserAgent is the User-Agent header of the client that started this
+	// session, so that people may recognize their devices.
+	UserAgent string `db:"user_agent"`
+	// IPAddress is the most recent IP address this session was used from.
+	IPAddress string `db:"ip_address"`
+	// LastSeenAt is the approximate timestamp this session was last used at.
+	LastSeenAt time.Time `db:"last_seen_at"`
+	// ActiveOrganizationID is the organization that a person affiliated with
+	// several organizations has chosen to work on, if any.
+	ActiveOrganizationID null.Int `db:"active_organization_id"`
+	// ImpersonatorID is the person ID of the administrator who is viewing the
+	// app as the person of this session, if any.
+	ImpersonatorID null.Int `db:"impersonator_id"`
+	// ImpersonatorSessionID is the session of that administrator. This session
+	// is only valid alongside it.
+	ImpersonatorSessionID null.Int `db:"impersonator_session_id"`
+	// Permissions lists what the person may do, as granted to their role.
+	// This is not stored with the session, so that changes to grants take
+	// effect immediately.
+	Permissions []Permission `db:"-" json:"-"`
+}
+
+// HasPermission determines whether the person of this session has been
+// granted the given permission.
+func (s *Session) HasPermission(perm Permission) bool {
+	for _, p := range s.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
 }
 
 // NewSession creates a new login session with a secure random token for a given
-// person. It shall expire after SessionLength time has passed.
-func NewSession(p Person) (*Session, error) {
+// person. Its lifetime is determined by the policy for the person's role.
+func NewSession(
+	p Person,
+	policy SessionPolicy,
+	rememberMe bool,
+) (*Session, error) {
+
 	now := time.Now().UTC().Round(time.Second)
 
-	token, err := uuid.NewRandom()
+	token, err := NewSecureToken()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create session token")
+	}
+
+	lifetime := policy.LifetimeFor(p.Role, rememberMe)
+
+	s := &Session{
+		Token:        token,
+		Person:       p,
+		CreatedAt:    now,
+		MaxExpiresAt: now.Add(lifetime.MaxLifetime),
+		IsRememberMe: rememberMe,
+	}
+	s.Renew(policy, now)
+
+	return s, nil
+}
+
+// ImpersonationLifetime is the longest that an administrator may view the app
+// as another person before they must start over.
+const ImpersonationLifetime = 30 * time.Minute
+
+// NewImpersonationSession creates a new session for a target person that is
+// linked to the session of the administrator impersonating them. It expires
+// after ImpersonationLifetime, or when the administrator's session does.
+func NewImpersonationSession(
+	admin Session,
+	target Person,
+	policy SessionPolicy,
+) (*Session, error) {
+
+	s, err := NewSession(target, policy, false)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create random UUID")
+		return nil, err
 	}
 
-	return &Session{
-		Token:     token,
-		Person:    p,
-		CreatedAt: now,
-		ExpiresAt: now.Add(SessionLength),
-	}, nil
+	s.ImpersonatorID = null.IntFrom(int64(admin.Person.ID))
+	s.ImpersonatorSessionID = null.IntFrom(int64(admin.ID))
+
+	maxExpiresAt := s.CreatedAt.Add(ImpersonationLifetime)
+	if admin.MaxExpiresAt.Before(maxExpiresAt) {
+		maxExpiresAt = admin.MaxExpiresAt
+	}
+	if s.MaxExpiresAt.After(maxExpiresAt) {
+		s.MaxExpiresAt = maxExpiresAt
+	}
+	s.Renew(policy, s.CreatedAt)
+
+	return s, nil
+}
+
+// IsImpersonation determines whether this session belongs to an administrator
+// viewing the app as another person.
+func (s *Session) IsImpersonation() bool {
+	return s.ImpersonatorID.Valid
+}
+
+// Renew extends the idle expiration of a session following activity at the
+// given time, never past its permanent expiration.
+func (s *Session) Renew(policy SessionPolicy, now time.Time) {
+	lifetime := policy.LifetimeFor(s.Person.Role, s.IsRememberMe)
+
+	s.LastSeenAt = now
+	s.ExpiresAt = now.Add(lifetime.IdleTimeout)
+	if s.ExpiresAt.After(s.MaxExpiresAt) {
+		s.ExpiresAt = s.MaxExpiresAt
+	}
 }
 
 // IsValid determines whether or not a session is still valid.
 func (s *Session) IsValid() bool {
 	now := time.Now().UTC().Round(time.Second)
 
+	// WARNING: if you change the logic here, make sure it matches the SQL
+	// query logic of the GetSessionsForPerson method in the db package!
+
 	return !s.IsRevoked &&
 		!s.Person.IsDeactivated &&
 		(now.After(s.CreatedAt) || now.Equal(s.CreatedAt)) &&
-		now.Before(s.ExpiresAt)
+		now.Before(s.ExpiresAt) &&
+		now.Before(s.MaxExpiresAt)
 }
@@ -0,0 +1,158 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionPolicyLifetimeFor(t *testing.T) {
+	p := DefaultSessionPolicy()
+
+	assert.Equal(t, p.Standard[RoleDriver], p.LifetimeFor(RoleDriver, false))
+	assert.Equal(t, p.RememberMe[RoleAdmin], p.LifetimeFor(RoleAdmin, true))
+
+	var empty SessionPolicy
+	assert.Equal(t, FallbackSessionLifetime, empty.LifetimeFor(RoleUser, true))
+}
+
+func TestSessionRenew(t *testing.T) {
+	policy := SessionPolicy{
+		Standard: map[Role]SessionLifetime{
+			RoleDriver: {IdleTimeout: time.Hour, MaxLifetime: 3 * time.Hour},
+		},
+	}
+
+	s, err := NewSession(Person{Role: RoleDriver}, policy, false)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+
+	assert.Equal(t, s.CreatedAt.Add(time.Hour), s.ExpiresAt)
+	assert.Equal(t, s.CreatedAt.Add(3*time.Hour), s.MaxExpiresAt)
+	assert.True(t, s.IsValid())
+
+	testCases := []struct {
+		alias         string
+		after         time.Duration
+		expectExpires time.Duration
+	}{
+		{
+			alias:         "Extended",
+			after:         90 * time.Minute,
+			expectExpires: 150 * time.Minute,
+		},
+		{
+			alias:         "CappedAtMaxExpiration",
+			after:         150 * time.Minute,
+			expectExpires: 3 * time.Hour,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			renewed := *s
+			renewed.Renew(policy, s.CreatedAt.Add(tc.after))
+
+			assert.Equal(t, s.CreatedAt.Add(tc.after), renewed.LastSeenAt)
+			assert.Equal(t, s.CreatedAt.Add(tc.expectExpires),
+				renewed.ExpiresAt)
+		})
+	}
+}
+
+func TestSessionIsValid(t *testing.T) {
+	now := time.Now().UTC()
+
+	valid := Session{
+		CreatedAt:    now.Add(-time.Hour),
+		ExpiresAt:    now.Add(time.Hour),
+		MaxExpiresAt: now.Add(2 * time.Hour),
+	}
+
+	testCases := []struct {
+		alias  string
+		mutate func(s *Session)
+		expect bool
+	}{
+		{
+			alias:  "Valid",
+			mutate: func(s *Session) {},
+			expect: true,
+		},
+		{
+			alias:  "Idle",
+			mutate: func(s *Session) { s.ExpiresAt = now.Add(-time.Minute) },
+		},
+		{
+			alias: "PastMaxExpiration",
+			mutate: func(s *Session) {
+				s.MaxExpiresAt = now.Add(-time.Minute)
+			},
+		},
+		{
+			alias:  "Revoked",
+			mutate: func(s *Session) { s.IsRevoked = true },
+		},
+		{
+			alias:  "Deactivated",
+			mutate: func(s *Session) { s.Person.IsDeactivated = true },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			s := valid
+			tc.mutate(&s)
+			assert.Equal(t, tc.expect, s.IsValid())
+		})
+	}
+}
+
+func TestNewImpersonationSession(t *testing.T) {
+	now := time.Now().UTC()
+	policy := DefaultSessionPolicy()
+	target := Person{ID: 2, Role: RoleDriver}
+
+	testCases := []struct {
+		alias          string
+		adminExpiresAt time.Time
+		expectMax      time.Duration
+	}{
+		{
+			alias:          "LimitedByLifetime",
+			adminExpiresAt: now.Add(24 * time.Hour),
+			expectMax:      ImpersonationLifetime,
+		},
+		{
+			alias:          "LimitedByAdminSession",
+			adminExpiresAt: now.Add(10 * time.Minute),
+			expectMax:      10 * time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			admin := Session{
+				Person:       Person{ID: 1, Role: RoleAdmin},
+				ID:           7,
+				MaxExpiresAt: tc.adminExpiresAt,
+			}
+
+			s, err := NewImpersonationSession(admin, target, policy)
+			require.NoError(t, err)
+
+			assert.True(t, s.IsImpersonation())
+			assert.Equal(t, target, s.Person)
+			assert.Equal(t, int64(1), s.ImpersonatorID.Int64)
+			assert.Equal(t, int64(7), s.ImpersonatorSessionID.Int64)
+			assert.False(t, s.IsRememberMe)
+
+			assert.WithinDuration(t, now.Add(tc.expectMax), s.MaxExpiresAt,
+				2*time.Second)
+			assert.False(t, s.ExpiresAt.After(s.MaxExpiresAt))
+			assert.True(t, s.IsValid())
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"strings"
+	"time"
+)
+
+// OrganizationSSO is the OpenID Connect single sign-on configuration for a
+// sponsor organization, allowing its people to log in using the identity
+// provider of their company.
+type OrganizationSSO struct {
+	// OrganizationID identifies the organization this configuration is for.
+	OrganizationID int `db:"organization_id" json:"organization_id"`
+	// IssuerURL identifies the identity provider. Its discovery document is
+	// found relative to this URL.
+	IssuerURL string `db:"issuer_url" json:"issuer_url"`
+	// ClientID is the identifier our app was registered with at the identity
+	// provider.
+	ClientID string `db:"client_id" json:"client_id"`
+	// ClientSecret is the secret our app was issued by the identity provider.
+	// It is never sent to clients.
+	ClientSecret string `db:"client_secret" json:"-"`
+	// EmailDomains lists the domains of email addresses that are allowed to
+	// sign on, such as "example.com". When empty, nobody may sign on, since
+	// an identity provider may be shared with people outside of the
+	// organization.
+	EmailDomains []string `db:"email_domains" json:"email_domains"`
+	// IsEnabled is true when people may currently sign on.
+	IsEnabled bool `db:"is_enabled" json:"is_enabled"`
+}
+
+// AllowsEmail determines whether a person with the given email address may
+// sign on using this configuration.
+func (c *OrganizationSSO) AllowsEmail(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 1 || at == len(email)-1 {
+		return false
+	}
+
+	domain := email[at+1:]
+	for _, allowed := range c.EmailDomains {
+		if strings.EqualFold(domain, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// OIDCLoginLifetime is how long a person has to complete sign on at their
+// identity provider.
+const OIDCLoginLifetime = 10 * time.Minute
+
+// An OIDCLogin tracks a single sign on attempt, between sending a person to
+// their identity provider and receiving them back.
+type OIDCLogin struct {
+	// State is sent to the identity provider and returned unchanged, tying
+	// the response to this attempt. Only its hash is stored, so this will be
+	// the zero value for logins retrieved from a DataStore.
+	State SecureToken `db:"-"`
+	// OrganizationID identifies the organization being signed on to.
+	OrganizationID int `db:"organization_id"`
+	// CodeVerifier is the PKCE secret used to exchange the authorization code.
+	CodeVerifier string `db:"code_verifier"`
+	// Nonce must appear in the ID token issued for this attempt.
+	Nonce string `db:"nonce"`
+	// RememberMe is true when the person asked to be remembered.
+	RememberMe bool `db:"remember_me"`
+	// CreatedAt is the timestamp this attempt was started at.
+	CreatedAt time.Time `db:"created_at"`
+	// ExpiresAt is the timestamp after which this attempt cannot complete.
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+// NewOIDCLogin starts a new sign on attempt for an organization, with fresh
+// random secrets.
+func NewOIDCLogin(orgID int, rememberMe bool) (*OIDCLogin, error) {
+	now := time.Now().UTC().Round(time.Second)
+
+	// The state, verifier, and nonce must each be unguessable.
+	var secrets [3]SecureToken
+	for i := range secrets {
+		var err error
+		if secrets[i], err = NewSecureToken(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &OIDCLogin{
+		State:          secrets[0],
+		OrganizationID: orgID,
+		CodeVerifier:   secrets[1].String(),
+		Nonce:          secrets[2].String(),
+		RememberMe:     rememberMe,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(OIDCLoginLifetime),
+	}, nil
+}
+
+// IsValid determines whether or not this sign on attempt may still complete.
+func (l *OIDCLogin) IsValid() bool {
+	return time.Now().Before(l.ExpiresAt)
+}
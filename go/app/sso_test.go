@@ -0,0 +1,21 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrganizationSSOAllowsEmail(t *testing.T) {
+	c := OrganizationSSO{EmailDomains: []string{"acme.example"}}
+
+	assert.True(t, c.AllowsEmail("dana@acme.example"))
+	assert.True(t, c.AllowsEmail("dana@ACME.example"))
+	assert.False(t, c.AllowsEmail("dana@evil.test"))
+	assert.False(t, c.AllowsEmail("dana@"))
+	assert.False(t, c.AllowsEmail("acme.example"))
+
+	// Without any domains, nobody may sign on.
+	c.EmailDomains = nil
+	assert.False(t, c.AllowsEmail("dana@acme.example"))
+}
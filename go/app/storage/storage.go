@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+// These are assertions, which will cause the build to fail if the stores and
+// scanners do not implement their app interfaces.
+var (
+	_ app.FileStore   = (*DiskFileStore)(nil)
+	_ app.FileScanner = (*ClamdScanner)(nil)
+	_ app.FileScanner = (*LogScanner)(nil)
+)
+
+// A DiskFileStore keeps files in a directory on the local disk.
+type DiskFileStore struct {
+	root string
+}
+
+// NewDiskFileStore creates a new DiskFileStore that keeps files in the given
+// directory, creating it if need be.
+func NewDiskFileStore(root string) (*DiskFileStore, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, errors.Wrapf(err, "failed to create directory %s", root)
+	}
+
+	return &DiskFileStore{root: root}, nil
+}
+
+// path determines where the file with the given key is kept. Keys are always
+// secure tokens, so a key that is not cannot escape the root directory.
+func (s *DiskFileStore) path(key string) (string, error) {
+	if _, err := app.ParseSecureToken(key); err != nil {
+		return "", errors.Wrap(err, "invalid file key")
+	}
+
+	return filepath.Join(s.root, key), nil
+}
+
+// PutFile writes a file to the disk under a new random key.
+func (s *DiskFileStore) PutFile(
+	ctx context.Context,
+	content io.Reader,
+) (string, error) {
+
+	token, err := app.NewSecureToken()
+	if err != nil {
+		return "", err
+	}
+	key := token.String()
+
+	path, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file first, so that a partial file is never seen
+	// under its key.
+	tmp, err := ioutil.TempFile(s.root, ".upload-")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create temporary file")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return "", errors.Wrap(err, "failed to write file")
+	} else if err = tmp.Close(); err != nil {
+		return "", errors.Wrap(err, "failed to close file")
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	return key, errors.Wrap(err, "failed to move file into place")
+}
+
+// OpenFile opens the file with the given key for reading.
+func (s *DiskFileStore) OpenFile(
+	ctx context.Context,
+	key string,
+) (io.ReadCloser, error) {
+
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, errors.Wrapf(app.ErrNotFound, "no file by key %s", key)
+	}
+
+	return f, errors.Wrap(err, "failed to open file")
+}
+
+// DeleteFile removes the file with the given key, if it exists.
+func (s *DiskFileStore) DeleteFile(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return errors.Wrap(err, "failed to delete file")
+}
+
+// clamdChunkSize is the largest chunk that ClamdScanner streams at once.
+const clamdChunkSize = 64 << 10
+
+// A ClamdScanner scans files with a ClamAV daemon, using its INSTREAM command.
+type ClamdScanner struct {
+	network string
+	addr    string
+	timeout time.Duration
+}
+
+// NewClamdScanner creates a new ClamdScanner that connects to clamd at the
+// given address. Addresses that begin with a slash are Unix sockets, and all
+// others are TCP host:port pairs.
+func NewClamdScanner(addr string, timeout time.Duration) *ClamdScanner {
+	network := "tcp"
+	if strings.HasPrefix(addr, "/") {
+		network = "unix"
+	}
+
+	return &ClamdScanner{
+		network: network,
+		addr:    addr,
+		timeout: timeout,
+	}
+}
+
+// ScanFile streams a file to clamd and reports whether it is infected.
+func (s *ClamdScanner) ScanFile(ctx context.Context, content io.Reader) error {
+	d := net.Dialer{Timeout: s.timeout}
+	conn, err := d.DialContext(ctx, s.network, s.addr)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to clamd")
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(s.timeout))
+	}
+
+	if _, err = conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return errors.Wrap(err, "failed to send clamd command")
+	}
+
+	// Each chunk is prefixed by its length, and a zero length ends the stream.
+	chunk := make([]byte, clamdChunkSize)
+	for {
+		n, readErr := content.Read(chunk)
+		if n > 0 {
+			var size [4]byte
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+
+			if _, err = conn.Write(append(size[:], chunk[:n]...)); err != nil {
+				return errors.Wrap(err, "failed to stream file to clamd")
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		} else if readErr != nil {
+			return errors.Wrap(readErr, "failed to read file to scan")
+		}
+	}
+
+	if _, err = conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return errors.Wrap(err, "failed to end clamd stream")
+	}
+
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && err != io.EOF {
+		return errors.Wrap(err, "failed to read clamd reply")
+	}
+	reply = bytes.TrimRight(reply, "\x00\n")
+
+	// Replies look like "stream: OK" or "stream: Eicar-Signature FOUND".
+	switch {
+	case bytes.HasSuffix(reply, []byte(" OK")):
+		return nil
+	case bytes.HasSuffix(reply, []byte(" FOUND")):
+		return errors.Wrapf(app.ErrFileInfected, "clamd: %s", reply)
+	default:
+		return errors.Errorf("unexpected clamd reply: %s", reply)
+	}
+}
+
+// A LogScanner accepts every file without scanning it, noting so in the log.
+// It is for use when no virus scanner is configured.
+type LogScanner struct {
+	logger *logrus.Logger
+}
+
+// NewLogScanner creates a new LogScanner that writes to the given logger.
+func NewLogScanner(logger *logrus.Logger) *LogScanner {
+	return &LogScanner{logger: logger}
+}
+
+// ScanFile accepts the file.
+func (s *LogScanner) ScanFile(ctx context.Context, content io.Reader) error {
+	s.logger.Warnln("Not scanning uploaded file, since no CLAMD_ADDR is set.")
+	return nil
+}
+
+// NewFileStoreFromEnv attempts to initialize a FileStore using settings from
+// the environment. Files are kept in the directory named by FILE_STORAGE_DIR.
+func NewFileStoreFromEnv() (app.FileStore, error) {
+	root := os.Getenv("FILE_STORAGE_DIR")
+	if len(root) < 1 {
+		return nil, errors.New("must set FILE_STORAGE_DIR")
+	}
+
+	return NewDiskFileStore(root)
+}
+
+// NewScannerFromEnv attempts to initialize a FileScanner using settings from
+// the environment. When CLAMD_ADDR is not set, files are not scanned.
+func NewScannerFromEnv(logger *logrus.Logger) (app.FileScanner, error) {
+	addr := os.Getenv("CLAMD_ADDR")
+	if len(addr) < 1 {
+		return NewLogScanner(logger), nil
+	}
+
+	timeout := 30 * time.Second
+	if value := os.Getenv("CLAMD_TIMEOUT"); len(value) > 0 {
+		var err error
+		if timeout, err = time.ParseDuration(value); err != nil {
+			return nil, errors.New("CLAMD_TIMEOUT must be a duration")
+		}
+	}
+
+	return NewClamdScanner(addr, timeout), nil
+}
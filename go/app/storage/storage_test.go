@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BenJetson/CPSC491-project/go/app"
+)
+
+func TestDiskFileStore(t *testing.T) {
+	root, err := ioutil.TempDir("", "storage-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	s, err := NewDiskFileStore(root)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	key, err := s.PutFile(ctx, strings.NewReader("license scan"))
+	require.NoError(t, err)
+
+	f, err := s.OpenFile(ctx, key)
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, "license scan", string(content))
+
+	// Only the file itself is left behind.
+	entries, err := ioutil.ReadDir(root)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = s.OpenFile(ctx, "../../etc/passwd")
+	assert.Error(t, err)
+
+	require.NoError(t, s.DeleteFile(ctx, key))
+	require.NoError(t, s.DeleteFile(ctx, key))
+
+	_, err = s.OpenFile(ctx, key)
+	assert.True(t, errors.Is(err, app.ErrNotFound))
+}
+
+// fakeClamd accepts a single INSTREAM command and replies based on whether
+// the streamed content contains the word "virus".
+func fakeClamd(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		defer l.Close()
+
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		if cmd, err := r.ReadString(0); err != nil || cmd != "zINSTREAM\x00" {
+			return
+		}
+
+		var content []byte
+		for {
+			var size uint32
+			if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+				return
+			} else if size == 0 {
+				break
+			}
+
+			chunk := make([]byte, size)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				return
+			}
+			content = append(content, chunk...)
+		}
+
+		reply := "stream: OK\x00"
+		if strings.Contains(string(content), "virus") {
+			reply = "stream: Eicar-Signature FOUND\x00"
+		}
+		conn.Write([]byte(reply))
+	}()
+
+	return l.Addr().String()
+}
+
+func TestClamdScanner(t *testing.T) {
+	ctx := context.Background()
+
+	clean := NewClamdScanner(fakeClamd(t), time.Second)
+	err := clean.ScanFile(ctx, strings.NewReader(strings.Repeat("a", 1<<17)))
+	assert.NoError(t, err)
+
+	infected := NewClamdScanner(fakeClamd(t), time.Second)
+	err = infected.ScanFile(ctx, strings.NewReader("a virus"))
+	assert.True(t, errors.Is(err, app.ErrFileInfected))
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+)
+
+// SecureTokenLength is the number of random bytes in a SecureToken.
+const SecureTokenLength = 32
+
+// A SecureToken is a 256-bit securely random secret, such as a session token,
+// that is given to a client. Only its Hash should ever be stored, so that the
+// token cannot be recovered by anyone with access to the database.
+type SecureToken [SecureTokenLength]byte
+
+// tokenEncoding is used to represent tokens as text. It is safe to use in
+// cookies, headers, and URLs.
+var tokenEncoding = base64.RawURLEncoding
+
+// NewSecureToken creates a new random SecureToken.
+func NewSecureToken() (SecureToken, error) {
+	var t SecureToken
+	if _, err := rand.Read(t[:]); err != nil {
+		return SecureToken{}, errors.Wrap(err, "failed to read random bytes")
+	}
+	return t, nil
+}
+
+// ParseSecureToken parses the textual representation of a SecureToken, as
+// produced by its String method.
+func ParseSecureToken(s string) (SecureToken, error) {
+	var t SecureToken
+
+	b, err := tokenEncoding.DecodeString(s)
+	if err != nil {
+		return t, errors.Wrap(err, "failed to decode token")
+	} else if len(b) != SecureTokenLength {
+		return t, errors.Errorf("token must be %d bytes, got %d",
+			SecureTokenLength, len(b))
+	}
+
+	copy(t[:], b)
+	return t, nil
+}
+
+// String encodes this token as text, for transmission to the client.
+func (t SecureToken) String() string {
+	return tokenEncoding.EncodeToString(t[:])
+}
+
+// Hash computes the hex-encoded SHA-256 hash of this token, which is safe to
+// store and look up tokens by.
+//
+// A fast hash is fine here, unlike for passwords, since tokens have enough
+// entropy that they cannot be guessed.
+func (t SecureToken) Hash() string {
+	sum := sha256.Sum256(t[:])
+	return hex.EncodeToString(sum[:])
+}
+
+// IsZero is true when this token was never assigned a random value.
+func (t SecureToken) IsZero() bool {
+	return t == SecureToken{}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecureToken(t *testing.T) {
+	t1, err := NewSecureToken()
+	require.NoError(t, err)
+	require.False(t, t1.IsZero())
+
+	t2, err := NewSecureToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, t1, t2)
+	assert.NotEqual(t, t1.Hash(), t2.Hash())
+
+	// SHA-256 is 32 bytes, hex encoded.
+	assert.Len(t, t1.Hash(), 64)
+
+	parsed, err := ParseSecureToken(t1.String())
+	require.NoError(t, err)
+	assert.Equal(t, t1, parsed)
+	assert.Equal(t, t1.Hash(), parsed.Hash())
+}
+
+func TestParseSecureToken(t *testing.T) {
+	testCases := []struct {
+		alias string
+		input string
+	}{
+		{
+			alias: "Empty",
+			input: "",
+		},
+		{
+			alias: "UUID",
+			input: "8b6f1c1e-5d2a-4b8e-9a43-6f0f2d8e4c11",
+		},
+		{
+			alias: "TooShort",
+			input: strings.Repeat("A", 42),
+		},
+		{
+			alias: "NotBase64",
+			input: strings.Repeat("!", 43),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alias, func(t *testing.T) {
+			_, err := ParseSecureToken(tc.input)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -16,4 +16,29 @@ const DoAccountRegistration = async ({
   });
 };
 
-export { DoAccountRegistration };
+const AcceptAccountInvitation = async (token, password) =>
+  await Request("POST", "/account/invitation/accept", {
+    token: token,
+    password: password,
+  });
+
+const GetOrganizationInvitation = async (token) =>
+  await Request("POST", "/account/organization-invitation", { token: token });
+
+const AcceptOrganizationInvitation = async (
+  token,
+  { firstName = "", lastName = "", password = "" } = {}
+) =>
+  await Request("POST", "/account/organization-invitation/accept", {
+    token: token,
+    first_name: firstName,
+    last_name: lastName,
+    password: password,
+  });
+
+export {
+  DoAccountRegistration,
+  AcceptAccountInvitation,
+  GetOrganizationInvitation,
+  AcceptOrganizationInvitation,
+};
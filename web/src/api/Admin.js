@@ -38,16 +38,46 @@ const UpdateUserPassword = async (userID, password) =>
     new_password: password,
   });
 
-const UpdateUserAffiliations = async (userID, organization_ids) =>
+// Admins and users may not have organizations; drivers and sponsors must.
+const CreateUser = async ({
+  firstName,
+  lastName,
+  email,
+  roleID,
+  organizationIDs = [],
+}) =>
+  await Request("POST", `/admin/users/create`, {
+    first_name: firstName,
+    last_name: lastName,
+    email: email,
+    role_id: roleID,
+    organization_ids: organizationIDs,
+  });
+
+const ResendInvitation = async (userID) =>
+  await Request("POST", `/admin/users/${userID}/invitation/resend`);
+
+const GetUserAffiliations = async (userID) =>
+  await Request("GET", `/admin/users/${userID}/affiliations`);
+
+const UpdateUserAffiliations = async (userID, add = [], remove = []) =>
   await Request("POST", `/admin/users/${userID}/affiliations`, {
-    organization_ids: organization_ids,
+    add: add,
+    remove: remove,
   });
 
-const ActivateUser = async (userID) =>
-  await Request("POST", `/admin/users/${userID}/activate`);
+const ActivateUser = async (userID, reason) =>
+  await Request("POST", `/admin/users/${userID}/activate`, {
+    reason: reason,
+  });
+
+const DeactivateUser = async (userID, reason) =>
+  await Request("POST", `/admin/users/${userID}/deactivate`, {
+    reason: reason,
+  });
 
-const DeactivateUser = async (userID) =>
-  await Request("POST", `/admin/users/${userID}/deactivate`);
+const ImpersonateUser = async (userID) =>
+  await Request("POST", `/admin/users/${userID}/impersonate`);
 
 const GetOrganizations = async () =>
   await Request("GET", "/admin/organizations");
@@ -55,20 +85,73 @@ const GetOrganizations = async () =>
 const GetOrganizationByID = async (orgID) =>
   await Request("GET", `/admin/organizations/${orgID}`);
 
-const CreateOrganization = async (name, point_value) =>
+// The profile may include description, contact_email, website,
+// applications_closed, and points_name.
+const CreateOrganization = async (
+  name,
+  point_value,
+  reapply_cooldown_days = 0,
+  profile = {}
+) =>
   await Request("POST", `/admin/organizations/create`, {
+    ...profile,
     name: name,
     point_value: point_value,
+    reapply_cooldown_days: reapply_cooldown_days,
   });
 
-const UpdateOrganization = async (orgID, name, point_value) =>
+// Any parts of the profile left out are cleared.
+const UpdateOrganization = async (
+  orgID,
+  name,
+  point_value,
+  reapply_cooldown_days = 0,
+  profile = {}
+) =>
   await Request("POST", `/admin/organizations/${orgID}/update`, {
+    ...profile,
     name: name,
     point_value: point_value,
+    reapply_cooldown_days: reapply_cooldown_days,
   });
 
-const DeleteOrganization = async (orgID) =>
-  await Request("POST", `/admin/organizations/${orgID}/delete`);
+// The image is sent as a form rather than as JSON.
+const UploadOrganizationLogo = async (orgID, file) => {
+  const form = new FormData();
+  form.append("file", file);
+
+  return await Request(
+    "POST",
+    `/admin/organizations/${orgID}/logo`,
+    undefined,
+    { body: form }
+  );
+};
+
+const DeleteOrganizationLogo = async (orgID) =>
+  await Request("POST", `/admin/organizations/${orgID}/logo/delete`);
+
+const GetArchivedOrganizations = async () =>
+  await Request("GET", "/admin/organizations/archived");
+
+const ArchiveOrganization = async (orgID) =>
+  await Request("POST", `/admin/organizations/${orgID}/archive`);
+
+const RestoreOrganization = async (orgID) =>
+  await Request("POST", `/admin/organizations/${orgID}/restore`);
+
+const PurgeOrganization = async (orgID) =>
+  await Request("POST", `/admin/organizations/${orgID}/purge`);
+
+// Filters may include actor_id, action, target_type, target_id,
+// organization_id, since, until, before_id, and limit.
+const GetApplicationAttachments = async (appID) =>
+  await Request("GET", `/admin/applications/${appID}/attachments`);
+
+const GetAuditEvents = async (filters = {}) => {
+  const query = new URLSearchParams(filters).toString();
+  return await Request("GET", `/admin/audit?${query}`);
+};
 
 export {
   GetAllUsers,
@@ -76,12 +159,23 @@ export {
   UpdateUserName,
   UpdateUserEmail,
   UpdateUserPassword,
+  GetUserAffiliations,
   UpdateUserAffiliations,
+  CreateUser,
+  ResendInvitation,
   ActivateUser,
   DeactivateUser,
+  ImpersonateUser,
   GetOrganizations,
   GetOrganizationByID,
   CreateOrganization,
   UpdateOrganization,
-  DeleteOrganization,
+  UploadOrganizationLogo,
+  DeleteOrganizationLogo,
+  GetArchivedOrganizations,
+  ArchiveOrganization,
+  RestoreOrganization,
+  PurgeOrganization,
+  GetApplicationAttachments,
+  GetAuditEvents,
 };
@@ -1,6 +1,6 @@
 import React, { useEffect, useState } from "react";
 import { useHistory } from "react-router-dom";
-import { Request } from "./Base";
+import { Request, SetCSRFToken } from "./Base";
 import Roles from "./Roles";
 
 const AuthContext = React.createContext({ user: null });
@@ -11,14 +11,23 @@ const GetCurrentUser = async () => {
   if (res.error !== null) {
     return null;
   }
+
+  SetCSRFToken(res.data?.["csrf_token"]);
   return res.data;
 };
 
-const DoLogin = async (email, password) =>
-  await Request("POST", "/login", { email, password });
+const DoLogin = async (email, password, rememberMe = false) =>
+  await Request("POST", "/login", {
+    email,
+    password,
+    remember_me: rememberMe,
+  });
 
 const DoLogout = async () => await Request("POST", "/logout");
 
+const StopImpersonating = async () =>
+  await Request("POST", "/impersonation/stop");
+
 const AuthProvider = ({ children }) => {
   const history = useHistory();
 
@@ -56,6 +65,13 @@ const AuthProvider = ({ children }) => {
   const isSponsor = () => isRole(Roles.IDOf.SPONSOR);
   const isDriver = () => isRole(Roles.IDOf.DRIVER);
 
+  // Permissions are granted to roles by the server, such as "catalog.manage".
+  const hasPermission = (permission) =>
+    user?.["permissions"]?.includes(permission) ?? false;
+
+  // Administrators may view the app as someone else.
+  const isImpersonating = () => (user?.["impersonator_id"] ?? null) !== null;
+
   const getName = () => {
     const firstName = user?.["first_name"] ?? "Unknown";
     const lastName = user?.["last_name"] ?? "Unknown";
@@ -87,6 +103,8 @@ const AuthProvider = ({ children }) => {
         isAdmin,
         isSponsor,
         isDriver,
+        hasPermission,
+        isImpersonating,
 
         refreshLoginStatus,
       }}
@@ -98,4 +116,11 @@ const AuthProvider = ({ children }) => {
 
 const WithUser = AuthContext.Consumer;
 
-export { AuthProvider, WithUser, DoLogin, DoLogout, GetCurrentUser };
+export {
+  AuthProvider,
+  WithUser,
+  DoLogin,
+  DoLogout,
+  StopImpersonating,
+  GetCurrentUser,
+};
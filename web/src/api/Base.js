@@ -36,6 +36,15 @@ const GetBaseURL = () => {
   return `${protocol}://${hostname}:${port}/api`;
 };
 
+// The CSRF token of the current login session. It must be sent with every
+// request that may change state, and is learned from /login and /whoami.
+const CSRF_HEADER = "X-CSRF-Token";
+let csrfToken = null;
+
+const SetCSRFToken = (token) => {
+  csrfToken = token ?? null;
+};
+
 const Request = async (method, endpoint, data = undefined, options = {}) => {
   // Craft the full request URL.
   const url = GetBaseURL() + endpoint;
@@ -43,19 +52,32 @@ const Request = async (method, endpoint, data = undefined, options = {}) => {
   // Attach the body data, marshaling to JSON.
   if (data !== undefined) {
     options.body = JSON.stringify(data);
-    options.header = {
-      ...options?.header,
+    options.headers = {
+      ...options?.headers,
       Accept: "application/json",
       "Content-Type": "application/json",
     };
   }
 
+  // Prove that requests which may change state come from our own app.
+  if (!["GET", "HEAD", "OPTIONS"].includes(method) && csrfToken !== null) {
+    options.headers = {
+      ...options?.headers,
+      [CSRF_HEADER]: csrfToken,
+    };
+  }
+
   // Request the data from the backend.
   const res = await fetch(url, {
     method: method,
     ...options,
   });
 
+  // A new login session comes with a new CSRF token.
+  if (res.headers.has(CSRF_HEADER)) {
+    SetCSRFToken(res.headers.get(CSRF_HEADER));
+  }
+
   // Decode the response received from the server.
   let outData = null;
   let error = null;
@@ -111,4 +133,4 @@ const Request = async (method, endpoint, data = undefined, options = {}) => {
   };
 };
 
-export { GetTier, GetBaseURL, Request };
+export { GetTier, GetBaseURL, Request, SetCSRFToken };
@@ -5,12 +5,51 @@ const GetBalances = async () => await Request("GET", "/driver/balances");
 const GetApplications = async () =>
   await Request("GET", "/driver/applications");
 
-const SubmitApplication = async (organizationID, comment) =>
+const SubmitApplication = async (organizationID, comment, answers = []) =>
   await Request("POST", "/driver/applications/submit", {
     organization_id: organizationID,
     comment: comment,
+    answers: answers,
   });
 
+const GetApplicationForm = async (organizationID) =>
+  await Request(
+    "GET",
+    `/driver/organizations/${organizationID}/application-form`
+  );
+
+const GetApplication = async (appID) =>
+  await Request("GET", `/driver/applications/${appID}`);
+
+const GetApplicationHistory = async (appID) =>
+  await Request("GET", `/driver/applications/${appID}/history`);
+
+const WithdrawApplication = async (appID, message = "") =>
+  await Request("POST", `/driver/applications/${appID}/withdraw`, {
+    message: message,
+  });
+
+const RespondToApplication = async (appID, message) =>
+  await Request("POST", `/driver/applications/${appID}/respond`, {
+    message: message,
+  });
+
+const GetApplicationAttachments = async (appID) =>
+  await Request("GET", `/driver/applications/${appID}/attachments`);
+
+// The file is sent as a form rather than as JSON.
+const UploadApplicationAttachment = async (appID, file) => {
+  const form = new FormData();
+  form.append("file", file);
+
+  return await Request(
+    "POST",
+    `/driver/applications/${appID}/attachments`,
+    undefined,
+    { body: form }
+  );
+};
+
 const GetMyOrganizations = async () =>
   await Request("GET", "/driver/organizations");
 
@@ -33,6 +72,13 @@ export {
   GetBalances,
   GetApplications,
   SubmitApplication,
+  GetApplicationForm,
+  GetApplication,
+  GetApplicationHistory,
+  WithdrawApplication,
+  RespondToApplication,
+  GetApplicationAttachments,
+  UploadApplicationAttachment,
   GetAllOrganizations,
   GetMyOrganizations,
   SearchOrganizationCatalog,
@@ -23,10 +23,18 @@ const UpdateUserPassword = async (currentPassword, newPassword) =>
 const DeactivateUser = async () =>
   await Request("POST", `/my/profile/deactivate`);
 
+const GetMyNotifications = async (unreadOnly = false) =>
+  await Request("GET", `/my/notifications?unread=${unreadOnly}`);
+
+const MarkNotificationRead = async (notificationID) =>
+  await Request("POST", `/my/notifications/${notificationID}/read`);
+
 export {
   GetMyUser,
   UpdateUserName,
   UpdateUserEmail,
   UpdateUserPassword,
   DeactivateUser,
+  GetMyNotifications,
+  MarkNotificationRead,
 };
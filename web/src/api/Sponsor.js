@@ -26,12 +26,111 @@ const RemoveCatalogProduct = async (productID) =>
 const GetSponsorOrganization = async () =>
   await Request("GET", "/sponsor/organization");
 
-const UpdateSponsorOrganization = async (name, pointValue) =>
+// The profile may include description, contact_email, website,
+// applications_closed, and points_name. Any left out are cleared.
+const UpdateSponsorOrganization = async (
+  name,
+  pointValue,
+  reapplyCooldownDays = 0,
+  profile = {}
+) =>
   await Request("POST", "/sponsor/organization/update", {
+    ...profile,
     name: name,
     point_value: pointValue,
+    reapply_cooldown_days: reapplyCooldownDays,
   });
 
+// The image is sent as a form rather than as JSON.
+const UploadSponsorOrganizationLogo = async (file) => {
+  const form = new FormData();
+  form.append("file", file);
+
+  return await Request("POST", "/sponsor/organization/logo", undefined, {
+    body: form,
+  });
+};
+
+const DeleteSponsorOrganizationLogo = async () =>
+  await Request("POST", "/sponsor/organization/logo/delete");
+
+const GetApplicationForm = async () =>
+  await Request("GET", "/sponsor/organization/application-form");
+
+const UpdateApplicationForm = async (questions) =>
+  await Request("POST", "/sponsor/organization/application-form/update", {
+    questions: questions,
+  });
+
+const GetMySponsorOrganizations = async () =>
+  await Request("GET", "/sponsor/organizations");
+
+const SwitchSponsorOrganization = async (orgID) =>
+  await Request("POST", "/sponsor/organization/switch", {
+    organization_id: orgID,
+  });
+
+const GetSponsorAuditEvents = async (filters = {}) => {
+  const query = new URLSearchParams(filters).toString();
+  return await Request("GET", `/sponsor/audit?${query}`);
+};
+
+// Filters may include status (comma separated), since, until, and sort, which
+// is one of newest, oldest, or applicant.
+const GetApplications = async (filters = {}) => {
+  const query = new URLSearchParams(filters).toString();
+  return await Request("GET", `/sponsor/applications?${query}`);
+};
+
+const GetApplication = async (appID) =>
+  await Request("GET", `/sponsor/applications/${appID}`);
+
+const GetApplicationHistory = async (appID) =>
+  await Request("GET", `/sponsor/applications/${appID}/history`);
+
+const GetApplicationAttachments = async (appID) =>
+  await Request("GET", `/sponsor/applications/${appID}/attachments`);
+
+const DecideApplication = async (appID, isApproved, reason) =>
+  await Request("POST", `/sponsor/applications/${appID}/approve`, {
+    is_approved: isApproved,
+    reason: reason,
+  });
+
+const DecideApplications = async (appIDs, isApproved, reason) =>
+  await Request("POST", "/sponsor/applications/decide", {
+    application_ids: appIDs,
+    is_approved: isApproved,
+    reason: reason,
+  });
+
+const HoldApplication = async (appID, message = "") =>
+  await Request("POST", `/sponsor/applications/${appID}/hold`, {
+    message: message,
+  });
+
+const ResumeApplication = async (appID, message = "") =>
+  await Request("POST", `/sponsor/applications/${appID}/resume`, {
+    message: message,
+  });
+
+const RequestApplicationInfo = async (appID, message) =>
+  await Request("POST", `/sponsor/applications/${appID}/request-info`, {
+    message: message,
+  });
+
+const GetInvitations = async () =>
+  await Request("GET", "/sponsor/invitations");
+
+const CreateInvitation = async (email, roleID) =>
+  await Request("POST", "/sponsor/invitations/create", {
+    email: email,
+    role_id: roleID,
+  });
+
+const RevokeInvitation = async (invitationID) =>
+  await Request("POST", `/sponsor/invitations/${invitationID}/revoke`);
+
 export {
   SearchVendorProducts,
   GetVendorProduct,
@@ -41,4 +140,23 @@ export {
   RemoveCatalogProduct,
   GetSponsorOrganization,
   UpdateSponsorOrganization,
+  UploadSponsorOrganizationLogo,
+  DeleteSponsorOrganizationLogo,
+  GetApplicationForm,
+  UpdateApplicationForm,
+  GetMySponsorOrganizations,
+  SwitchSponsorOrganization,
+  GetSponsorAuditEvents,
+  GetApplications,
+  GetApplication,
+  GetApplicationHistory,
+  GetApplicationAttachments,
+  DecideApplication,
+  DecideApplications,
+  HoldApplication,
+  ResumeApplication,
+  RequestApplicationInfo,
+  GetInvitations,
+  CreateInvitation,
+  RevokeInvitation,
 };
@@ -4,7 +4,7 @@ import {
   GetOrganizationByID,
   //CreateOrganization,
   UpdateOrganization,
-  //DeleteOrganization,
+  //ArchiveOrganization,
 } from "../api/Admin";
 
 import * as yup from "yup";
@@ -65,7 +65,20 @@ const AdminOrgEditor = () => {
     enableReinitialize: true,
     validationSchema: nameValidationSchema,
     onSubmit: async (values) => {
-      const res = await UpdateOrganization(orgID, values.name, values.rate);
+      // Updates replace the whole organization, so keep its profile.
+      const res = await UpdateOrganization(
+        orgID,
+        values.name,
+        values.rate,
+        org.reapply_cooldown_days ?? 0,
+        {
+          description: org.description ?? "",
+          contact_email: org.contact_email ?? "",
+          website: org.website ?? "",
+          applications_closed: org.applications_closed ?? false,
+          points_name: org.points_name ?? "",
+        }
+      );
 
       setOrg({
         // Force dirty state validation.
@@ -108,6 +108,31 @@ const ApplicationForm = () => {
           ))}
         </TextField>
 
+        {organizations
+          .filter((org) => org.id === formik.values.organization)
+          .map((org) => (
+            <Alert severity="info" icon={false} key={org.id}>
+              {org.logo_url && (
+                <img
+                  src={org.logo_url}
+                  alt={`Logo of ${org.name}`}
+                  style={{ maxHeight: 60 }} // FIXME
+                />
+              )}
+              {org.description && <Typography>{org.description}</Typography>}
+              {org.website && (
+                <Typography>
+                  <a href={org.website} target="_blank" rel="noreferrer">
+                    {org.website}
+                  </a>
+                </Typography>
+              )}
+              {org.contact_email && (
+                <Typography>Contact: {org.contact_email}</Typography>
+              )}
+            </Alert>
+          ))}
+
         <TextField
           color="secondary"
           variant="outlined"
@@ -26,7 +26,7 @@ const validationSchema = yup.object({
   password: yup
     .string("Enter your password.")
     .required("Password is required."),
-  remember: yup.boolean("Select to remember your email address."),
+  remember: yup.boolean("Select to stay signed in on this device."),
 });
 
 const useStyles = makeStyles((theme) => ({
@@ -51,7 +51,10 @@ let Login = () => {
   const rememberedEmail = localStorage.getItem(emailMemoryKey);
   const didRemember = rememberedEmail !== null;
 
-  const [error, setError] = useState(null);
+  // Failed single sign-on attempts are redirected here with a message.
+  const ssoError = new URLSearchParams(window.location.search).get("error");
+
+  const [error, setError] = useState(ssoError);
   const classes = useStyles();
   const formik = useFormik({
     initialValues: {
@@ -61,7 +64,11 @@ let Login = () => {
     },
     validationSchema: validationSchema,
     onSubmit: async (values) => {
-      const res = await DoLogin(values.email, values.password);
+      const res = await DoLogin(
+        values.email,
+        values.password,
+        values.remember
+      );
       setError(res.error);
 
       if (!res.error) {
@@ -3,14 +3,18 @@ import { useParams } from "react-router-dom";
 import {
   GetSponsorOrganization,
   UpdateSponsorOrganization,
+  UploadSponsorOrganizationLogo,
+  DeleteSponsorOrganizationLogo,
 } from "../api/Sponsor";
-// import { DeleteOrganization } from "../api/Admin";
+// import { ArchiveOrganization } from "../api/Admin";
 import * as yup from "yup";
 import { useFormik } from "formik";
 import {
   Button,
   Card,
   CardContent,
+  Checkbox,
+  FormControlLabel,
   TextField,
   Typography,
   withStyles,
@@ -26,6 +30,13 @@ const FormCard = withStyles((theme) => ({
 const emptyOrg = {
   name: "",
   rate: 1,
+  reapply_cooldown_days: 0,
+  description: "",
+  contact_email: "",
+  website: "",
+  applications_closed: false,
+  points_name: "points",
+  logo_url: "",
 };
 
 const validationSchema = yup.object({
@@ -33,6 +44,21 @@ const validationSchema = yup.object({
     .string("Enter the new organization name.")
     .required("Organization name is required."),
   rate: yup.number().min(1, "Exchange rate must be a positive integer."),
+  cooldown: yup
+    .number()
+    .integer("Cooldown must be a whole number of days.")
+    .min(0, "Cooldown cannot be negative.")
+    .max(365, "Cooldown cannot be longer than 365 days."),
+  description: yup
+    .string()
+    .max(2000, "Description may be at most 2000 characters."),
+  contactEmail: yup.string().email("Enter a valid email address."),
+  website: yup
+    .string()
+    .url("Enter a valid website address, starting with https://."),
+  pointsName: yup
+    .string()
+    .max(32, "Points name may be at most 32 characters."),
 });
 
 const OrgProfileEditor = () => {
@@ -62,17 +88,40 @@ const OrgProfileEditor = () => {
     initialValues: {
       name: org.name,
       rate: org.point_value,
+      cooldown: org.reapply_cooldown_days,
+      description: org.description ?? "",
+      contactEmail: org.contact_email ?? "",
+      website: org.website ?? "",
+      closed: org.applications_closed ?? false,
+      pointsName: org.points_name ?? "",
     },
     enableReinitialize: true,
     validationSchema: validationSchema,
     onSubmit: async (values) => {
-      const res = await UpdateSponsorOrganization(values.name, values.rate);
+      const profile = {
+        description: values.description,
+        contact_email: values.contactEmail,
+        website: values.website,
+        applications_closed: values.closed,
+        points_name: values.pointsName,
+      };
+
+      const res = await UpdateSponsorOrganization(
+        values.name,
+        values.rate,
+        values.cooldown,
+        profile
+      );
 
       setOrg({
         // Force dirty state validation.
         ...org,
+        ...(!res.error ? profile : {}),
         name: !res.error ? values.name : org.name,
         point_value: !res.error ? values.rate : org.point_value,
+        reapply_cooldown_days: !res.error
+          ? values.cooldown
+          : org.reapply_cooldown_days,
       });
 
       setStatus(
@@ -83,11 +132,33 @@ const OrgProfileEditor = () => {
     },
   });
 
+  const [logoStatus, setLogoStatus] = useState(null);
+  const changeLogo = async (file) => {
+    const res = file
+      ? await UploadSponsorOrganizationLogo(file)
+      : await DeleteSponsorOrganizationLogo();
+
+    setLogoStatus(
+      res.error
+        ? { success: false, message: res.error }
+        : { success: true, message: "Updated logo successfully." }
+    );
+    if (res.error) {
+      return;
+    }
+
+    // The link to the logo changes along with it.
+    const updated = await GetSponsorOrganization();
+    if (!updated.error) {
+      setOrg(updated.data);
+    }
+  };
+
   // TODO this is broken, so it is disabled.
   //
   // const [activationStatus, setActivationStatus] = useState(null);
   // const doDeactivation = async () => {
-  //   const res = await DeleteOrganization();
+  //   const res = await ArchiveOrganization();
   //   setOrg({
   //     // Force dirty state validation.
   //     ...org,
@@ -139,6 +210,98 @@ const OrgProfileEditor = () => {
               error={formik.touched.rate && Boolean(formik.errors.rate)}
               helperText={formik.touched.rate && formik.errors.rate}
             />
+            <TextField
+              variant="outlined"
+              required
+              fullWidth
+              margin="normal"
+              id="cooldown"
+              name="cooldown"
+              label="Days Before Rejected Applicants May Reapply"
+              type="number"
+              value={formik.values.cooldown}
+              onChange={formik.handleChange}
+              error={
+                formik.touched.cooldown && Boolean(formik.errors.cooldown)
+              }
+              helperText={formik.touched.cooldown && formik.errors.cooldown}
+            />
+            <TextField
+              variant="outlined"
+              fullWidth
+              multiline
+              rows={4}
+              margin="normal"
+              id="description"
+              name="description"
+              label="Description"
+              value={formik.values.description}
+              onChange={formik.handleChange}
+              error={
+                formik.touched.description &&
+                Boolean(formik.errors.description)
+              }
+              helperText={
+                formik.touched.description && formik.errors.description
+              }
+            />
+            <TextField
+              variant="outlined"
+              fullWidth
+              margin="normal"
+              id="contactEmail"
+              name="contactEmail"
+              label="Contact Email"
+              value={formik.values.contactEmail}
+              onChange={formik.handleChange}
+              error={
+                formik.touched.contactEmail &&
+                Boolean(formik.errors.contactEmail)
+              }
+              helperText={
+                formik.touched.contactEmail && formik.errors.contactEmail
+              }
+            />
+            <TextField
+              variant="outlined"
+              fullWidth
+              margin="normal"
+              id="website"
+              name="website"
+              label="Website"
+              value={formik.values.website}
+              onChange={formik.handleChange}
+              error={formik.touched.website && Boolean(formik.errors.website)}
+              helperText={formik.touched.website && formik.errors.website}
+            />
+            <TextField
+              variant="outlined"
+              fullWidth
+              margin="normal"
+              id="pointsName"
+              name="pointsName"
+              label="Name For Points"
+              placeholder="points"
+              value={formik.values.pointsName}
+              onChange={formik.handleChange}
+              error={
+                formik.touched.pointsName && Boolean(formik.errors.pointsName)
+              }
+              helperText={
+                formik.touched.pointsName && formik.errors.pointsName
+              }
+            />
+            <FormControlLabel
+              control={
+                <Checkbox
+                  id="closed"
+                  name="closed"
+                  checked={formik.values.closed}
+                  onChange={formik.handleChange}
+                />
+              }
+              label="Closed to new applications"
+            />
             <Button
               type="submit"
               variant="contained"
@@ -151,6 +314,59 @@ const OrgProfileEditor = () => {
         </CardContent>
       </FormCard>
 
+      <FormCard>
+        <CardContent>
+          <Typography variant="h5">Logo</Typography>
+          {logoStatus && (
+            <Alert severity={logoStatus.success ? "success" : "error"}>
+              {logoStatus.message}
+            </Alert>
+          )}
+          {org.logo_url ? (
+            <img
+              src={org.logo_url}
+              alt={`Logo of ${org.name}`}
+              style={{ maxHeight: 120, marginTop: 15 }} // FIXME
+            />
+          ) : (
+            <Typography style={{ marginTop: 15 }}>
+              This organization has no logo.
+            </Typography>
+          )}
+          <div>
+            <Button
+              variant="contained"
+              color="primary"
+              component="label"
+              style={{ marginTop: 15, marginRight: 15 }} // FIXME
+            >
+              Upload JPEG or PNG
+              <input
+                type="file"
+                accept="image/jpeg,image/png"
+                hidden
+                onChange={(e) => {
+                  // Nothing is chosen when the dialog is cancelled.
+                  if (e.target.files.length > 0) {
+                    changeLogo(e.target.files[0]);
+                  }
+                }}
+              />
+            </Button>
+            {org.logo_url && (
+              <Button
+                variant="contained"
+                color="secondary"
+                onClick={() => changeLogo(null)}
+                style={{ marginTop: 15 }} // FIXME
+              >
+                Remove
+              </Button>
+            )}
+          </div>
+        </CardContent>
+      </FormCard>
+
       {/* TODO this is broken, so it is disabled. */}
       {/* <FormCard>
         <CardContent>
@@ -53,6 +53,7 @@ const passwordValidationSchema = yup.object({
   password: yup
     .string("Enter the new password.")
     .min(8, "Password should be of minimum 8 characters in length.")
+    .max(72, "Password should be of maximum 72 characters in length.")
     .required("Password is required."),
   confirm: yup
     .string("Re-enter the new password.")
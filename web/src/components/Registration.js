@@ -53,6 +53,7 @@ const validationSchema = yup.object({
   password: yup
     .string("Enter your password.")
     .min(8, "Password should be of minimum 8 characters in length.")
+    .max(72, "Password should be of maximum 72 characters in length.")
     .required("Password is required."),
   shouldNotify: yup.boolean("Select to sign up for email notifications."),
 });